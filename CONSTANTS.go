@@ -18395,16 +18395,18 @@ N42hHxDOY45DXXeesa07sn+MXmldfhHfIg5CdH8KM47auw5AtRvbS+94A7gqSPF4SrC9RVn1CZBVSRwo
 
 	"/resources/provision/apigateway/outputmapping_json.vtl": {
 		local:   "resources/provision/apigateway/outputmapping_json.vtl",
-		size:    739,
-		modtime: 1544400326,
-		compressed: `
-H4sIAAAAAAAC/6xSu47UQBDM9yuataXzSudxftIFfMFJECKCPk/ZHnbnQU97j+Xr0XjHAiECgossdVdV
-V5WndSGtar7lGLqH1rxGe3s4HZqGXs6PlFgySBeQC2P0LszkOVGcaAFbSC5ADpamKAQeF4oBj5ShGyle
-IeIsKppc2MZjDIofagr5E6YnWlRTfhoGG8ds+C0b9vwzBjNGP3ByMyve+DZcWJF1sLjiEhNkXp1FAfQV
-0XtOyYW5V/hU0L1ggiCMMIv6S1Mv91cWx6+XP/bv5mW3smfvBd9XZO0FOcWQ0ScW9lBI3kwdmgzt2loo
-PVP9I4l16Y6tqYvj6dQ0h2aKUnru2jNupc+dZ864fYZ2d9SmuNe8H36pjqrilyLxtdyrEvfBxkew5dPQ
-x2BprU9gjBYkrAuEdOFALihmYXUx0N54phzLVgt7YwkqwnmQyySw6wh7aNzU/RW1XDie6MMzHY//kSMr
-6/qPxu4yv4P8CgAA//8nYPzf4wIAAA==
+		size:    1028,
+		modtime: 1792358058,
+		compressed: `
+H4sIAAAAAAAC/61TTW/bMAy951dwUYAkQO1chh0K5NAUAbYdEiAtBgzFUDAWE2u1JU+ik3m/fpQ/lnTb
+YYcdDEMi+d7jI6UUrIxF34CnUDkbKAB6khPX3pIGDMC5nPEMewz07i2QzZyWyN7pBoKTMPJIKUALxjId
+PbJx9hcenA3ncO8kZPk9Wl0Ye1zebzef1rvH58ft8+rD5m73GTK0EUVTRG85K2wKhzqF9Yl8w7nUARWC
+aMIrfR8ftpt0pMxhNjG2qjmtkPPZdJKasGolrzvF0zksl8C+prlSo99yYzfTeK8ixSX+NTh7iUvU6pik
+YPtyIwp96LQaoSijwBIrcAfICTX50Pmi4eA8EGY5OEs3EIjbIid9eSPddtkC0l5n0avvnMbiHR1uIWeu
+wu1ioV0WUjzLV+IPZ1OhXGBljsh0xmZRyD/wQtOJCleRP9aCHROSPiMRcZWITJjKKmYnng7kZaCU5lwW
+qmdOTugN7our+H/TMkgZeheKb7WkJsO+JGIqlsTiXitqpMSu2aQ3FJZwPbjxJO0D43k7PDE6+jybvFAT
+/RzqUjk/CE6X1SIONg/E215Rj/gUIb5Evh6iu+hWZFiCO5lt3a9Au7ey/LlMUt7E69cwOB6uX0xbJXq7
+DFP2m63rjPQf+yytRobxHN4sYTz+hz4CI9d/cayDuTTyE8OP3aEEBAAA
 `,
 	},
 
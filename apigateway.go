@@ -4,16 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/apigateway"
+	spartaAPIGateway "github.com/mweagle/Sparta/aws/apigateway"
+	spartaSchema "github.com/mweagle/Sparta/aws/schema"
 	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+var reModelName = regexp.MustCompile("^[a-zA-Z0-9]+$")
+
 var defaultCORSHeaders = map[string]interface{}{
 	"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key",
 	"Access-Control-Allow-Methods": "*",
@@ -27,6 +33,42 @@ const (
 	OutputAPIGatewayURL = "APIGatewayURL"
 )
 
+const (
+	// StageLoggingLevelOff disables execution logging
+	// @enum StageLoggingLevel
+	StageLoggingLevelOff = "OFF"
+	// StageLoggingLevelError logs only error-level execution entries
+	// @enum StageLoggingLevel
+	StageLoggingLevelError = "ERROR"
+	// StageLoggingLevelInfo logs all execution entries
+	// @enum StageLoggingLevel
+	StageLoggingLevelInfo = "INFO"
+)
+
+const (
+	// ContentHandlingConvertToBinary converts a base64 encoded string payload
+	// to the corresponding binary blob
+	// @enum ContentHandling
+	ContentHandlingConvertToBinary = "CONVERT_TO_BINARY"
+	// ContentHandlingConvertToText converts a binary payload to a base64
+	// encoded string
+	// @enum ContentHandling
+	ContentHandlingConvertToText = "CONVERT_TO_TEXT"
+)
+
+// DefaultStageAccessLogFormat is the JSON access log format used when
+// a StageAccessLogSetting doesn't provide an explicit Format
+var DefaultStageAccessLogFormat = `{"requestId":"$context.requestId",` +
+	`"ip":"$context.identity.sourceIp",` +
+	`"caller":"$context.identity.caller",` +
+	`"user":"$context.identity.user",` +
+	`"requestTime":"$context.requestTime",` +
+	`"httpMethod":"$context.httpMethod",` +
+	`"resourcePath":"$context.resourcePath",` +
+	`"status":"$context.status",` +
+	`"protocol":"$context.protocol",` +
+	`"responseLength":"$context.responseLength"}`
+
 func corsMethodResponseParams(api *API) map[string]bool {
 
 	var userDefinedHeaders map[string]interface{}
@@ -118,6 +160,9 @@ func integrationResponses(api *API, userResponses map[int]*IntegrationResponse,
 			SelectionPattern:  gocf.String(eachMethodIntegrationResponse.SelectionPattern),
 			StatusCode:        gocf.String(strconv.Itoa(eachHTTPStatusCode)),
 		}
+		if eachMethodIntegrationResponse.ContentHandling != "" {
+			integrationResponse.ContentHandling = gocf.String(eachMethodIntegrationResponse.ContentHandling)
+		}
 		if len(responseParameters) != 0 {
 			integrationResponse.ResponseParameters = responseParameters
 		}
@@ -189,6 +234,184 @@ func corsOptionsGatewayMethod(api *API, restAPIID gocf.Stringable, resourceID go
 	return corsMethod
 }
 
+// methodSettingResourcePath returns the escaped resource path expected by
+// the MethodSetting ResourcePath property. Slashes in the user path are
+// encoded as "~1".
+func methodSettingResourcePath(resourcePath string) string {
+	if resourcePath == "" || resourcePath == "/*" {
+		return "/*"
+	}
+	return "/" + strings.Replace(resourcePath, "/", "~1", -1)
+}
+
+func stageMethodSettings(stage *Stage) *gocf.APIGatewayDeploymentMethodSettingList {
+	if len(stage.MethodSettings) <= 0 {
+		return nil
+	}
+	var methodSettings gocf.APIGatewayDeploymentMethodSettingList
+	for _, eachSetting := range stage.MethodSettings {
+		httpMethod := eachSetting.HTTPMethod
+		if httpMethod == "" {
+			httpMethod = "*"
+		}
+		methodSetting := gocf.APIGatewayDeploymentMethodSetting{
+			HTTPMethod:         gocf.String(httpMethod),
+			ResourcePath:       gocf.String(methodSettingResourcePath(eachSetting.ResourcePath)),
+			CacheDataEncrypted: gocf.Bool(eachSetting.CacheDataEncrypted),
+			CachingEnabled:     gocf.Bool(eachSetting.CachingEnabled),
+			DataTraceEnabled:   gocf.Bool(eachSetting.DataTraceEnabled),
+			MetricsEnabled:     gocf.Bool(eachSetting.MetricsEnabled),
+		}
+		if eachSetting.CacheTTLInSeconds != 0 {
+			methodSetting.CacheTTLInSeconds = gocf.Integer(eachSetting.CacheTTLInSeconds)
+		}
+		if eachSetting.LoggingLevel != "" {
+			methodSetting.LoggingLevel = gocf.String(eachSetting.LoggingLevel)
+		}
+		if eachSetting.ThrottlingBurstLimit != 0 {
+			methodSetting.ThrottlingBurstLimit = gocf.Integer(eachSetting.ThrottlingBurstLimit)
+		}
+		if eachSetting.ThrottlingRateLimit != 0 {
+			methodSetting.ThrottlingRateLimit = gocf.Integer(eachSetting.ThrottlingRateLimit)
+		}
+		methodSettings = append(methodSettings, methodSetting)
+	}
+	return &methodSettings
+}
+
+// stageDescription returns the StageDescription used when the API
+// deployment creates the stage
+func stageDescription(stage *Stage) *gocf.APIGatewayDeploymentStageDescription {
+	stageDesc := &gocf.APIGatewayDeploymentStageDescription{
+		Description:    gocf.String(stage.Description),
+		Variables:      stage.Variables,
+		MethodSettings: stageMethodSettings(stage),
+	}
+	if stage.CacheClusterEnabled {
+		stageDesc.CacheClusterEnabled = gocf.Bool(stage.CacheClusterEnabled)
+	}
+	if stage.CacheClusterSize != "" {
+		stageDesc.CacheClusterSize = gocf.String(stage.CacheClusterSize)
+	}
+	if stage.AccessLogSetting != nil {
+		logFormat := stage.AccessLogSetting.Format
+		if logFormat == "" {
+			logFormat = DefaultStageAccessLogFormat
+		}
+		stageDesc.AccessLogSetting = &gocf.APIGatewayDeploymentAccessLogSetting{
+			DestinationArn: stage.AccessLogSetting.DestinationArn.String(),
+			Format:         gocf.String(logFormat),
+		}
+	}
+	if stage.LoggingLevel != "" {
+		stageDesc.LoggingLevel = gocf.String(stage.LoggingLevel)
+	}
+	if stage.DataTraceEnabled {
+		stageDesc.DataTraceEnabled = gocf.Bool(stage.DataTraceEnabled)
+	}
+	if stage.MetricsEnabled {
+		stageDesc.MetricsEnabled = gocf.Bool(stage.MetricsEnabled)
+	}
+	if stage.TracingEnabled {
+		stageDesc.TracingEnabled = gocf.Bool(stage.TracingEnabled)
+	}
+	if stage.ThrottlingBurstLimit != 0 {
+		stageDesc.ThrottlingBurstLimit = gocf.Integer(stage.ThrottlingBurstLimit)
+	}
+	if stage.ThrottlingRateLimit != 0 {
+		stageDesc.ThrottlingRateLimit = gocf.Integer(stage.ThrottlingRateLimit)
+	}
+	if stage.CanarySetting != nil {
+		stageDesc.CanarySetting = &gocf.APIGatewayDeploymentCanarySetting{
+			PercentTraffic:         gocf.Integer(stage.CanarySetting.PercentTraffic),
+			StageVariableOverrides: stage.CanarySetting.StageVariableOverrides,
+			UseStageCache:          gocf.Bool(stage.CanarySetting.UseStageCache),
+		}
+	}
+	return stageDesc
+}
+
+// stageDeployment returns the logical name and the Deployment resource that
+// publishes the API to the stage. The stage settings are applied to both new
+// and existing stages. If the stage already exists and has a CanarySetting,
+// the deployment is published as the canary.
+func stageDeployment(serviceName string,
+	stage *Stage,
+	stageInfo *apigateway.Stage,
+	restAPIID *gocf.StringExpr,
+	logger *logrus.Logger) (string, *gocf.APIGatewayDeployment) {
+	if nil == stageInfo {
+		// Use a stable identifier so that we can update the existing deployment
+		return CloudFormationResourceName("APIGatewayDeployment", serviceName),
+			&gocf.APIGatewayDeployment{
+				Description:      gocf.String(stage.Description),
+				RestAPIID:        restAPIID,
+				StageName:        gocf.String(stage.name),
+				StageDescription: stageDescription(stage),
+			}
+	}
+	newDeployment := &gocf.APIGatewayDeployment{
+		Description:      gocf.String("Deployment"),
+		RestAPIID:        restAPIID,
+		StageDescription: stageDescription(stage),
+	}
+	if stageInfo.StageName != nil {
+		newDeployment.StageName = gocf.String(*stageInfo.StageName)
+	}
+	// If there's a canary, publish this deployment as the canary
+	// so that it can be trialled before it's promoted
+	if stage.CanarySetting != nil {
+		newDeployment.Description = gocf.String("Canary Deployment")
+		newDeployment.DeploymentCanarySettings = &gocf.APIGatewayDeploymentDeploymentCanarySettings{
+			PercentTraffic:         gocf.Integer(stage.CanarySetting.PercentTraffic),
+			StageVariableOverrides: stage.CanarySetting.StageVariableOverrides,
+			UseStageCache:          gocf.Bool(stage.CanarySetting.UseStageCache),
+		}
+		// The canary is created by the deployment, not the stage settings
+		newDeployment.StageDescription.CanarySetting = nil
+		logger.WithFields(logrus.Fields{
+			"StageName":      stage.name,
+			"PercentTraffic": stage.CanarySetting.PercentTraffic,
+		}).Info("Publishing API Gateway deployment as canary")
+	}
+	// Use an unstable ID s.t. we can actually create a new deployment event.  Not sure how this
+	// is going to work with deletes...
+	return CloudFormationResourceName("APIGatewayDeployment"), newDeployment
+}
+
+// validate ensures the user supplied Stage values are consistent
+func (stage *Stage) validate() error {
+	if stage.AccessLogSetting != nil && stage.AccessLogSetting.DestinationArn == nil {
+		return fmt.Errorf("stage %s AccessLogSetting requires a non-nil DestinationArn", stage.name)
+	}
+	validLevel := func(level string) bool {
+		switch level {
+		case "", StageLoggingLevelOff, StageLoggingLevelError, StageLoggingLevelInfo:
+			return true
+		}
+		return false
+	}
+	if !validLevel(stage.LoggingLevel) {
+		return fmt.Errorf("stage %s has invalid LoggingLevel: %s", stage.name, stage.LoggingLevel)
+	}
+	for _, eachSetting := range stage.MethodSettings {
+		if !validLevel(eachSetting.LoggingLevel) {
+			return fmt.Errorf("stage %s method setting (%s %s) has invalid LoggingLevel: %s",
+				stage.name,
+				eachSetting.HTTPMethod,
+				eachSetting.ResourcePath,
+				eachSetting.LoggingLevel)
+		}
+	}
+	if stage.CanarySetting != nil &&
+		(stage.CanarySetting.PercentTraffic < 0 || stage.CanarySetting.PercentTraffic > 100) {
+		return fmt.Errorf("stage %s canary PercentTraffic must be in the range [0, 100]: %d",
+			stage.name,
+			stage.CanarySetting.PercentTraffic)
+	}
+	return nil
+}
+
 func apiStageInfo(apiName string,
 	stageName string,
 	session *session.Session,
@@ -336,12 +559,68 @@ type APIGatewayLambdaJSONEvent struct {
 
 // Model proxies the AWS SDK's Model data.  See
 // http://docs.aws.amazon.com/sdk-for-go/api/service/apigateway.html#Model
-//
-// TODO: Support Dynamic Model creation
 type Model struct {
 	Description string `json:",omitempty"`
 	Name        string `json:",omitempty"`
 	Schema      string `json:",omitempty"`
+
+	// Parsed schema used for local validation
+	jsonSchema *spartaSchema.JSONSchema
+}
+
+// parsedSchema returns the JSONSchema for this model, parsing the
+// user supplied Schema string if necessary
+func (model *Model) parsedSchema() (*spartaSchema.JSONSchema, error) {
+	if model.jsonSchema != nil {
+		return model.jsonSchema, nil
+	}
+	var jsonSchema spartaSchema.JSONSchema
+	unmarshalErr := json.Unmarshal([]byte(model.Schema), &jsonSchema)
+	if unmarshalErr != nil {
+		return nil, errors.Wrapf(unmarshalErr, "failed to parse schema for model: %s", model.Name)
+	}
+	model.jsonSchema = &jsonSchema
+	return model.jsonSchema, nil
+}
+
+// NewModel returns a Model whose JSON Schema is reflected from the Go type
+// of the value argument. See aws/schema.NewJSONSchema for the supported
+// struct tags. If name is empty, the Go type name is used. Model names
+// must be alphanumeric.
+func NewModel(name string, value interface{}) (*Model, error) {
+	jsonSchema, jsonSchemaErr := spartaSchema.NewJSONSchema(value)
+	if jsonSchemaErr != nil {
+		return nil, jsonSchemaErr
+	}
+	if name == "" {
+		name = jsonSchema.Title
+	}
+	if !reModelName.MatchString(name) {
+		return nil, fmt.Errorf("invalid model name (must be alphanumeric): %s", name)
+	}
+	schemaBytes, schemaBytesErr := json.Marshal(jsonSchema)
+	if schemaBytesErr != nil {
+		return nil, schemaBytesErr
+	}
+	return &Model{
+		Description: fmt.Sprintf("%s request model", name),
+		Name:        name,
+		Schema:      string(schemaBytes),
+		jsonSchema:  jsonSchema,
+	}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+//
+
+// RequestValidator represents the API Gateway RequestValidator associated
+// with a Method. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/api-gateway-method-request-validation.html
+type RequestValidator struct {
+	// Should the request body be validated against the Method Models?
+	ValidateRequestBody bool
+	// Should the required Method Parameters be validated?
+	ValidateRequestParameters bool
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -363,6 +642,10 @@ type IntegrationResponse struct {
 	Parameters       map[string]interface{} `json:",omitempty"`
 	SelectionPattern string                 `json:",omitempty"`
 	Templates        map[string]string      `json:",omitempty"`
+	// Optional response payload conversion. Set to ContentHandlingConvertToBinary
+	// for methods whose handlers return binary bodies via
+	// aws/apigateway.NewBinaryResponse
+	ContentHandling string `json:",omitempty"`
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -376,6 +659,10 @@ type Integration struct {
 	CacheKeyParameters []string
 	CacheNamespace     string
 	Credentials        string
+	// Optional request payload conversion. Eligible values are
+	// ContentHandlingConvertToBinary and ContentHandlingConvertToText.
+	// If empty, the payload is passed through without conversion.
+	ContentHandling string
 
 	Responses map[int]*IntegrationResponse
 
@@ -412,6 +699,85 @@ type Method struct {
 
 	// Integration response map
 	Integration Integration
+
+	// Optional request validator
+	RequestValidator *RequestValidator
+
+	// Resource that owns this method
+	parentResource *Resource
+}
+
+// SetRequestModel associates the model with the application/json request
+// body and enables API Gateway request body validation. If validateLocally
+// is true, the body is also validated in the Lambda function before the
+// handler is called. Invalid bodies are rejected with an
+// aws/apigateway.Error whose Code is http.StatusBadRequest.
+func (method *Method) SetRequestModel(model *Model, validateLocally bool) error {
+	if model == nil {
+		return fmt.Errorf("model must not be `nil` for method: %s", method.httpMethod)
+	}
+	if !reModelName.MatchString(model.Name) {
+		return fmt.Errorf("invalid model name (must be alphanumeric): %s", model.Name)
+	}
+	method.Models["application/json"] = model
+	if method.RequestValidator == nil {
+		method.RequestValidator = &RequestValidator{}
+	}
+	method.RequestValidator.ValidateRequestBody = true
+
+	if validateLocally {
+		jsonSchema, jsonSchemaErr := model.parsedSchema()
+		if jsonSchemaErr != nil {
+			return jsonSchemaErr
+		}
+		parentLambda := method.parentResource.parentLambda
+		if parentLambda.requestValidators == nil {
+			parentLambda.requestValidators = make(map[string]*spartaSchema.JSONSchema)
+		}
+		validatorKey := requestValidatorKey(method.httpMethod, method.parentResource.pathPart)
+		parentLambda.requestValidators[validatorKey] = jsonSchema
+	}
+	return nil
+}
+
+// requestValidatorKey returns the key used to lookup a request schema for
+// the local validation of API Gateway requests
+func requestValidatorKey(httpMethod string, resourcePath string) string {
+	return fmt.Sprintf("%s /%s",
+		strings.ToUpper(httpMethod),
+		strings.TrimLeft(resourcePath, "/"))
+}
+
+// validateAPIGatewayRequest validates the body of the incoming API Gateway
+// request against the schema registered for the request's method and
+// resource path. Requests that aren't API Gateway requests, or that don't
+// have a registered schema, are ignored.
+func validateAPIGatewayRequest(requestValidators map[string]*spartaSchema.JSONSchema,
+	msg json.RawMessage) error {
+	if len(requestValidators) <= 0 {
+		return nil
+	}
+	var request struct {
+		Method  string          `json:"method"`
+		Body    json.RawMessage `json:"body"`
+		Context struct {
+			ResourcePath string `json:"resourcePath"`
+		} `json:"context"`
+	}
+	unmarshalErr := json.Unmarshal(msg, &request)
+	if unmarshalErr != nil || request.Method == "" {
+		return nil
+	}
+	jsonSchema, exists := requestValidators[requestValidatorKey(request.Method,
+		request.Context.ResourcePath)]
+	if !exists {
+		return nil
+	}
+	validationErr := jsonSchema.ValidateJSON(request.Body)
+	if validationErr != nil {
+		return spartaAPIGateway.NewErrorResponse(http.StatusBadRequest, validationErr)
+	}
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -433,6 +799,71 @@ type Stage struct {
 	CacheClusterSize    string
 	Description         string
 	Variables           map[string]string
+	// Optional access log destination and format. See
+	// https://docs.aws.amazon.com/apigateway/latest/developerguide/set-up-logging.html
+	AccessLogSetting *StageAccessLogSetting
+	// Execution logging level for all methods in the stage. Eligible
+	// values are OFF, ERROR, and INFO. Execution logging requires that
+	// the account level API Gateway CloudWatch role is configured.
+	LoggingLevel string
+	// Should full request/response data be logged for all methods?
+	DataTraceEnabled bool
+	// Should CloudWatch metrics be published for all methods?
+	MetricsEnabled bool
+	// Should X-Ray tracing be enabled for the stage?
+	TracingEnabled bool
+	// Stage-wide throttling values. Zero values use the account defaults.
+	ThrottlingBurstLimit int64
+	ThrottlingRateLimit  int64
+	// Per-method overrides, keyed by resource path and HTTP method
+	MethodSettings []*StageMethodSetting
+	// Optional canary configuration. When the stage already exists, the
+	// new deployment is published as a canary rather than replacing the
+	// current stage deployment.
+	CanarySetting *StageCanarySetting
+}
+
+// StageAccessLogSetting represents the access log configuration for a
+// Stage. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-properties-apigateway-deployment-accesslogsetting.html
+type StageAccessLogSetting struct {
+	// ARN of the CloudWatch Logs log group or Kinesis Data Firehose
+	// delivery stream that receives the access logs
+	DestinationArn gocf.Stringable
+	// Single line format of the access logs, using $context variables.
+	// If empty, DefaultStageAccessLogFormat is used.
+	Format string
+}
+
+// StageMethodSetting represents a per-method setting for a Stage. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-properties-apigateway-deployment-stagedescription-methodsetting.html
+type StageMethodSetting struct {
+	// The API resource path, as in "/hello/world". Use "/*" (or the empty
+	// string) to apply the setting to all resources.
+	ResourcePath string
+	// The HTTP method. Use "*" (or the empty string) to apply the setting
+	// to all methods.
+	HTTPMethod           string
+	CacheDataEncrypted   bool
+	CacheTTLInSeconds    int64
+	CachingEnabled       bool
+	DataTraceEnabled     bool
+	LoggingLevel         string
+	MetricsEnabled       bool
+	ThrottlingBurstLimit int64
+	ThrottlingRateLimit  int64
+}
+
+// StageCanarySetting represents the canary deployment settings for a
+// Stage. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/canary-release.html
+type StageCanarySetting struct {
+	// Percentage (0-100) of traffic routed to the canary deployment
+	PercentTraffic int64
+	// Stage variables that are overridden in the canary deployment
+	StageVariableOverrides map[string]string
+	// Should the canary use the stage cache?
+	UseStageCache bool
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -471,6 +902,10 @@ type API struct {
 	CORSOptions *CORSOptions
 	// Endpoint configuration information
 	EndpointConfiguration *gocf.APIGatewayRestAPIEndpointConfiguration
+	// Binary media types supported by the API, as in "image/png"
+	// or "*/*". See
+	// https://docs.aws.amazon.com/apigateway/latest/developerguide/api-gateway-payload-encodings.html
+	BinaryMediaTypes []string
 }
 
 // LogicalResourceName returns the CloudFormation logical
@@ -490,6 +925,76 @@ func (api *API) RestAPIURL() *gocf.StringExpr {
 		gocf.String(".amazonaws.com"))
 }
 
+// binaryMediaTypes returns the escaped set of binary media types. CloudFormation
+// requires that the "/" separator be encoded as "~1"
+func (api *API) binaryMediaTypes() *gocf.StringListExpr {
+	if len(api.BinaryMediaTypes) <= 0 {
+		return nil
+	}
+	mediaTypes := make([]gocf.Stringable, len(api.BinaryMediaTypes))
+	for eachIndex, eachMediaType := range api.BinaryMediaTypes {
+		mediaTypes[eachIndex] = gocf.String(strings.Replace(eachMediaType, "/", "~1", -1))
+	}
+	return gocf.StringList(mediaTypes...)
+}
+
+// apiMethodModels adds the Model and RequestValidator resources required by
+// the method to the template
+func apiMethodModels(api *API,
+	method *Method,
+	apiGatewayMethod *gocf.APIGatewayMethod,
+	template *gocf.Template) error {
+
+	apiGatewayRestAPIID := gocf.Ref(api.LogicalResourceName())
+	if len(method.Models) != 0 {
+		requestModels := make(map[string]interface{})
+		for eachContentType, eachModel := range method.Models {
+			if !reModelName.MatchString(eachModel.Name) {
+				return fmt.Errorf("invalid model name (must be alphanumeric): %s", eachModel.Name)
+			}
+			var schema interface{}
+			unmarshalErr := json.Unmarshal([]byte(eachModel.Schema), &schema)
+			if unmarshalErr != nil {
+				return errors.Wrapf(unmarshalErr, "failed to parse schema for model: %s", eachModel.Name)
+			}
+			// Models may be shared across methods
+			modelResourceName := CloudFormationResourceName("APIGatewayModel",
+				api.name,
+				eachModel.Name,
+				eachContentType)
+			if _, exists := template.Resources[modelResourceName]; !exists {
+				template.AddResource(modelResourceName, &gocf.APIGatewayModel{
+					ContentType: gocf.String(eachContentType),
+					Description: gocf.String(eachModel.Description),
+					Name:        gocf.String(eachModel.Name),
+					RestAPIID:   apiGatewayRestAPIID.String(),
+					Schema:      schema,
+				})
+			}
+			requestModels[eachContentType] = gocf.Ref(modelResourceName)
+		}
+		apiGatewayMethod.RequestModels = requestModels
+	}
+	if method.RequestValidator != nil {
+		validateBody := method.RequestValidator.ValidateRequestBody
+		validateParams := method.RequestValidator.ValidateRequestParameters
+		// Validators are shared by all methods with the same settings
+		validatorResourceName := CloudFormationResourceName("APIGatewayRequestValidator",
+			api.name,
+			fmt.Sprintf("%t", validateBody),
+			fmt.Sprintf("%t", validateParams))
+		if _, exists := template.Resources[validatorResourceName]; !exists {
+			template.AddResource(validatorResourceName, &gocf.APIGatewayRequestValidator{
+				RestAPIID:                 apiGatewayRestAPIID.String(),
+				ValidateRequestBody:       gocf.Bool(validateBody),
+				ValidateRequestParameters: gocf.Bool(validateParams),
+			})
+		}
+		apiGatewayMethod.RequestValidatorID = gocf.Ref(validatorResourceName).String()
+	}
+	return nil
+}
+
 func (api *API) corsEnabled() bool {
 	return api.CORSEnabled || (api.CORSOptions != nil)
 }
@@ -529,6 +1034,8 @@ func (api *API) export(serviceName string,
 	if api.EndpointConfiguration != nil {
 		apiGatewayRes.EndpointConfiguration = api.EndpointConfiguration
 	}
+	// Any binary types?
+	apiGatewayRes.BinaryMediaTypes = api.binaryMediaTypes()
 	template.AddResource(apiGatewayResName, apiGatewayRes)
 	apiGatewayRestAPIID := gocf.Ref(apiGatewayResName)
 
@@ -611,6 +1118,9 @@ func (api *API) export(serviceName string,
 						gocf.String("/invocations")),
 				},
 			}
+			if eachMethodDef.Integration.ContentHandling != "" {
+				apiGatewayMethod.Integration.ContentHandling = gocf.String(eachMethodDef.Integration.ContentHandling)
+			}
 			// Handle authorization
 			if eachMethodDef.authorizationID != nil {
 				// See https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigateway-method.html#cfn-apigateway-method-authorizationtype
@@ -627,6 +1137,12 @@ func (api *API) export(serviceName string,
 				apiGatewayMethod.RequestParameters = requestParams
 			}
 
+			// Request models and validation
+			modelsErr := apiMethodModels(api, eachMethodDef, apiGatewayMethod, template)
+			if modelsErr != nil {
+				return modelsErr
+			}
+
 			// Add the integration response RegExps
 			apiGatewayMethod.Integration.IntegrationResponses = integrationResponses(api,
 				eachMethodDef.Integration.Responses,
@@ -648,6 +1164,10 @@ func (api *API) export(serviceName string,
 	// END
 
 	if nil != api.stage {
+		validateErr := api.stage.validate()
+		if validateErr != nil {
+			return validateErr
+		}
 		// Is the stack already deployed?
 		stageName := api.stage.name
 		stageInfo, stageInfoErr := apiStageInfo(api.name,
@@ -658,45 +1178,14 @@ func (api *API) export(serviceName string,
 		if nil != stageInfoErr {
 			return stageInfoErr
 		}
-		if nil == stageInfo {
-			// Use a stable identifier so that we can update the existing deployment
-			apiDeploymentResName := CloudFormationResourceName("APIGatewayDeployment",
-				serviceName)
-			apiDeployment := &gocf.APIGatewayDeployment{
-				Description: gocf.String(api.stage.Description),
-				RestAPIID:   apiGatewayRestAPIID.String(),
-				StageName:   gocf.String(stageName),
-				StageDescription: &gocf.APIGatewayDeploymentStageDescription{
-					Description: gocf.String(api.stage.Description),
-					Variables:   api.stage.Variables,
-				},
-			}
-			if api.stage.CacheClusterEnabled {
-				apiDeployment.StageDescription.CacheClusterEnabled =
-					gocf.Bool(api.stage.CacheClusterEnabled)
-			}
-			if api.stage.CacheClusterSize != "" {
-				apiDeployment.StageDescription.CacheClusterSize =
-					gocf.String(api.stage.CacheClusterSize)
-			}
-			deployment := template.AddResource(apiDeploymentResName, apiDeployment)
-			deployment.DependsOn = append(deployment.DependsOn, apiMethodCloudFormationResources...)
-			deployment.DependsOn = append(deployment.DependsOn, apiGatewayResName)
-		} else {
-			newDeployment := &gocf.APIGatewayDeployment{
-				Description: gocf.String("Deployment"),
-				RestAPIID:   apiGatewayRestAPIID.String(),
-			}
-			if stageInfo.StageName != nil {
-				newDeployment.StageName = gocf.String(*stageInfo.StageName)
-			}
-			// Use an unstable ID s.t. we can actually create a new deployment event.  Not sure how this
-			// is going to work with deletes...
-			deploymentResName := CloudFormationResourceName("APIGatewayDeployment")
-			deployment := template.AddResource(deploymentResName, newDeployment)
-			deployment.DependsOn = append(deployment.DependsOn, apiMethodCloudFormationResources...)
-			deployment.DependsOn = append(deployment.DependsOn, apiGatewayResName)
-		}
+		apiDeploymentResName, apiDeployment := stageDeployment(serviceName,
+			api.stage,
+			stageInfo,
+			apiGatewayRestAPIID.String(),
+			logger)
+		deployment := template.AddResource(apiDeploymentResName, apiDeployment)
+		deployment.DependsOn = append(deployment.DependsOn, apiMethodCloudFormationResources...)
+		deployment.DependsOn = append(deployment.DependsOn, apiGatewayResName)
 		template.Outputs[OutputAPIGatewayURL] = &gocf.Output{
 			Description: "API Gateway URL",
 			Value: gocf.Join("",
@@ -790,6 +1279,7 @@ func (resource *Resource) NewMethod(httpMethod string,
 	}
 
 	method := &Method{
+		parentResource:          resource,
 		httpMethod:              httpMethod,
 		defaultHTTPResponseCode: defaultHTTPStatusCode,
 		Parameters:              make(map[string]bool),
@@ -2,13 +2,17 @@ package sparta
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
 	spartaAPIGateway "github.com/mweagle/Sparta/aws/apigateway"
 	spartaAWSEvents "github.com/mweagle/Sparta/aws/events"
+	gocf "github.com/mweagle/go-cloudformation"
 	"github.com/sirupsen/logrus"
 )
 
@@ -75,3 +79,234 @@ func TestAPIGateway(t *testing.T) {
 		false,
 		nil)
 }
+
+func TestAPIGatewayStageSettings(t *testing.T) {
+	stage := NewStage("v1")
+	stage.LoggingLevel = StageLoggingLevelInfo
+	stage.MetricsEnabled = true
+	stage.TracingEnabled = true
+	stage.AccessLogSetting = &StageAccessLogSetting{
+		DestinationArn: gocf.String("arn:aws:logs:us-west-2:123412341234:log-group:API-Gateway-Access-Logs"),
+	}
+	stage.MethodSettings = []*StageMethodSetting{
+		{
+			ResourcePath:         "/test",
+			HTTPMethod:           "GET",
+			CachingEnabled:       true,
+			CacheTTLInSeconds:    60,
+			ThrottlingBurstLimit: 100,
+			ThrottlingRateLimit:  50,
+		},
+	}
+	stage.CanarySetting = &StageCanarySetting{
+		PercentTraffic: 10,
+	}
+	apiGateway := NewAPIGateway("SpartaAPIGateway", stage)
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	apiGatewayResource, _ := apiGateway.NewResource("/test", lambdaFn)
+	apiGatewayResource.NewMethod("GET", http.StatusOK)
+
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		apiGateway,
+		nil,
+		nil,
+		false,
+		nil)
+}
+
+func TestAPIGatewayStageSettingsUpdate(t *testing.T) {
+	logger, _ := NewLogger("info")
+	stage := NewStage("v1")
+	stage.LoggingLevel = StageLoggingLevelInfo
+	stage.TracingEnabled = true
+	stage.ThrottlingRateLimit = 50
+
+	// Both the initial and subsequent deployments apply the stage settings
+	for _, eachStageInfo := range []*apigateway.Stage{nil,
+		{StageName: aws.String("v1")}} {
+		_, deployment := stageDeployment("TestService",
+			stage,
+			eachStageInfo,
+			gocf.String("restAPIID"),
+			logger)
+		stageDesc := deployment.StageDescription
+		if stageDesc == nil ||
+			stageDesc.LoggingLevel.Literal != StageLoggingLevelInfo ||
+			stageDesc.TracingEnabled == nil ||
+			stageDesc.ThrottlingRateLimit == nil ||
+			deployment.StageName.Literal != "v1" {
+			t.Fatalf("Stage settings not applied to deployment (existing stage: %t): %#v",
+				eachStageInfo != nil,
+				stageDesc)
+		}
+	}
+
+	// Subsequent canary deployments are published via the deployment canary
+	// settings rather than the stage settings
+	stage.CanarySetting = &StageCanarySetting{
+		PercentTraffic: 10,
+	}
+	_, deployment := stageDeployment("TestService",
+		stage,
+		&apigateway.Stage{StageName: aws.String("v1")},
+		gocf.String("restAPIID"),
+		logger)
+	if deployment.DeploymentCanarySettings == nil ||
+		deployment.StageDescription.CanarySetting != nil ||
+		deployment.StageDescription.LoggingLevel.Literal != StageLoggingLevelInfo {
+		t.Fatalf("Unexpected canary deployment: %#v", deployment)
+	}
+}
+
+func TestAPIGatewayStageSettingsInvalidLogLevel(t *testing.T) {
+	stage := NewStage("v1")
+	stage.LoggingLevel = "VERBOSE"
+	apiGateway := NewAPIGateway("SpartaAPIGateway", stage)
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	apiGatewayResource, _ := apiGateway.NewResource("/test", lambdaFn)
+	apiGatewayResource.NewMethod("GET", http.StatusOK)
+
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		apiGateway,
+		nil,
+		nil,
+		false,
+		assertError("Invalid stage LoggingLevel"))
+}
+
+func TestMethodSettingResourcePath(t *testing.T) {
+	testCases := map[string]string{
+		"":            "/*",
+		"/*":          "/*",
+		"/hello":      "/~1hello",
+		"/hello/{id}": "/~1hello~1{id}",
+	}
+	for eachInput, eachExpected := range testCases {
+		actual := methodSettingResourcePath(eachInput)
+		if actual != eachExpected {
+			t.Fatalf("Expected %s for input %s, got %s", eachExpected, eachInput, actual)
+		}
+	}
+}
+
+func TestAPIGatewayBinaryMediaTypes(t *testing.T) {
+	stage := NewStage("v1")
+	apiGateway := NewAPIGateway("SpartaAPIGateway", stage)
+	apiGateway.BinaryMediaTypes = []string{"image/png", "application/pdf"}
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	apiGatewayResource, _ := apiGateway.NewResource("/test", lambdaFn)
+	method, _ := apiGatewayResource.NewMethod("GET", http.StatusOK)
+	method.Integration.Responses[http.StatusOK].ContentHandling = ContentHandlingConvertToBinary
+
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		apiGateway,
+		nil,
+		nil,
+		false,
+		nil)
+}
+
+func TestAPIGatewayBinaryResponse(t *testing.T) {
+	resp := spartaAPIGateway.NewBinaryResponse(http.StatusOK,
+		[]byte{0x89, 0x50, 0x4E, 0x47},
+		"image/png")
+	if !resp.IsBase64Encoded {
+		t.Fatal("Binary response was not flagged as base64 encoded")
+	}
+	jsonBytes, jsonBytesErr := json.Marshal(resp)
+	if jsonBytesErr != nil {
+		t.Fatal(jsonBytesErr)
+	}
+	var decoded map[string]interface{}
+	decodeErr := json.Unmarshal(jsonBytes, &decoded)
+	if decodeErr != nil {
+		t.Fatal(decodeErr)
+	}
+	if decoded["body"] != "iVBORw==" {
+		t.Fatalf("Unexpected base64 body: %#v", decoded["body"])
+	}
+	if decoded["isBase64Encoded"] != true {
+		t.Fatalf("Unexpected isBase64Encoded value: %#v", decoded["isBase64Encoded"])
+	}
+	headers, _ := decoded["headers"].(map[string]interface{})
+	if headers["content-type"] != "image/png" {
+		t.Fatalf("Unexpected headers: %#v", decoded["headers"])
+	}
+	// NewResponse doesn't encode []byte bodies
+	textResp := spartaAPIGateway.NewResponse(http.StatusOK, []byte(`{"hello":"world"}`))
+	if textResp.IsBase64Encoded {
+		t.Fatal("NewResponse flagged []byte body as base64 encoded")
+	}
+}
+
+type testValidatedRequest struct {
+	Name string `json:"name" validate:"required"`
+	Age  int    `json:"age,omitempty" validate:"min=0"`
+}
+
+func TestAPIGatewayRequestModel(t *testing.T) {
+	stage := NewStage("v1")
+	apiGateway := NewAPIGateway("SpartaAPIGateway", stage)
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	apiGatewayResource, _ := apiGateway.NewResource("/test", lambdaFn)
+	method, _ := apiGatewayResource.NewMethod("POST", http.StatusOK)
+	model, modelErr := NewModel("", &testValidatedRequest{})
+	if modelErr != nil {
+		t.Fatal(modelErr)
+	}
+	setErr := method.SetRequestModel(model, true)
+	if setErr != nil {
+		t.Fatal(setErr)
+	}
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		apiGateway,
+		nil,
+		nil,
+		false,
+		nil)
+
+	// Local validation
+	validRequest := []byte(`{"method":"POST","body":{"name":"Sparta"},"context":{"resourcePath":"/test"}}`)
+	validErr := validateAPIGatewayRequest(lambdaFn.requestValidators, validRequest)
+	if validErr != nil {
+		t.Fatalf("Failed to accept valid request: %s", validErr)
+	}
+	invalidRequest := []byte(`{"method":"POST","body":{"age":-1},"context":{"resourcePath":"/test"}}`)
+	invalidErr := validateAPIGatewayRequest(lambdaFn.requestValidators, invalidRequest)
+	if invalidErr == nil {
+		t.Fatal("Failed to reject invalid request")
+	}
+	apiErr, apiErrOk := invalidErr.(*spartaAPIGateway.Error)
+	if !apiErrOk || apiErr.Code != http.StatusBadRequest {
+		t.Fatalf("Unexpected validation error: %#v", invalidErr)
+	}
+	// Other routes are ignored
+	otherRequest := []byte(`{"method":"GET","body":{},"context":{"resourcePath":"/test"}}`)
+	otherErr := validateAPIGatewayRequest(lambdaFn.requestValidators, otherRequest)
+	if otherErr != nil {
+		t.Fatalf("Unexpected validation of unmodeled request: %s", otherErr)
+	}
+}
+
+func TestAPIGatewayInvalidModelName(t *testing.T) {
+	_, modelErr := NewModel("Invalid-Name", &testValidatedRequest{})
+	if modelErr == nil {
+		t.Fatal("Failed to reject non-alphanumeric model name")
+	}
+}
@@ -0,0 +1,656 @@
+package sparta
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	spartaAPIGateway "github.com/mweagle/Sparta/aws/apigateway"
+	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OutputHTTPAPIURL is the keyname used in the CloudFormation Output
+	// that stores the HTTP API provisioned URL
+	// @enum OutputKey
+	OutputHTTPAPIURL = "HTTPAPIURL"
+	// OutputWebSocketAPIURL is the keyname used in the CloudFormation Output
+	// that stores the WebSocket API provisioned URL
+	// @enum OutputKey
+	OutputWebSocketAPIURL = "WebSocketAPIURL"
+)
+
+const (
+	// WebSocketRouteConnect is the route key invoked when a client connects
+	// @enum WebSocketRouteKey
+	WebSocketRouteConnect = "$connect"
+	// WebSocketRouteDisconnect is the route key invoked when a client
+	// disconnects
+	// @enum WebSocketRouteKey
+	WebSocketRouteDisconnect = "$disconnect"
+	// WebSocketRouteDefault is the route key invoked when no other
+	// route key matches
+	// @enum WebSocketRouteKey
+	WebSocketRouteDefault = "$default"
+)
+
+const (
+	// HTTPAPIDefaultStageName is the name of the HTTP API stage that is
+	// served from the base URL of the API
+	HTTPAPIDefaultStageName = "$default"
+	// HTTPAPIDefaultRouteKey is the catch-all HTTP API route key
+	HTTPAPIDefaultRouteKey = "$default"
+)
+
+// Eligible HTTP API route methods
+var httpAPIRouteMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	"ANY":              true,
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Shared
+////////////////////////////////////////////////////////////////////////////////
+
+// APIV2Stage represents an API Gateway v2 stage for either an HTTP or
+// WebSocket API. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-stage.html
+type APIV2Stage struct {
+	name string
+	// Should changes to the API be automatically deployed? If false, an
+	// explicit deployment is created.
+	AutoDeploy bool
+	// Stage description
+	Description string
+	// Stage variables
+	StageVariables map[string]string
+	// Optional access log destination and format
+	AccessLogSetting *StageAccessLogSetting
+	// Default route throttling values. Zero values use the account defaults.
+	ThrottlingBurstLimit int64
+	ThrottlingRateLimit  int64
+}
+
+// NewAPIV2Stage returns a new APIV2Stage that automatically deploys
+// changes to the API
+func NewAPIV2Stage(name string) *APIV2Stage {
+	return &APIV2Stage{
+		name:           name,
+		AutoDeploy:     true,
+		StageVariables: make(map[string]string),
+	}
+}
+
+// apiV2Integration returns the shared Lambda proxy integration for the
+// given API and handler
+func apiV2Integration(apiResourceName string,
+	handler *LambdaAWSInfo,
+	payloadFormatVersion string,
+	template *gocf.Template) string {
+
+	integrationResourceName := CloudFormationResourceName(fmt.Sprintf("%sIntegration", apiResourceName),
+		handler.LogicalResourceName())
+	if _, exists := template.Resources[integrationResourceName]; exists {
+		return integrationResourceName
+	}
+	integration := &spartaCF.APIGatewayV2Integration{
+		APIID:           gocf.Ref(apiResourceName).String(),
+		IntegrationType: gocf.String("AWS_PROXY"),
+		IntegrationURI: gocf.Join("",
+			gocf.String("arn:aws:apigateway:"),
+			gocf.Ref("AWS::Region"),
+			gocf.String(":lambda:path/2015-03-31/functions/"),
+			gocf.GetAtt(handler.LogicalResourceName(), "Arn"),
+			gocf.String("/invocations")),
+	}
+	if payloadFormatVersion != "" {
+		integration.PayloadFormatVersion = gocf.String(payloadFormatVersion)
+	}
+	template.AddResource(integrationResourceName, integration)
+
+	// And the permission to invoke the handler
+	permissionResourceName := CloudFormationResourceName(fmt.Sprintf("%sLambdaPerm", apiResourceName),
+		handler.LogicalResourceName())
+	template.AddResource(permissionResourceName, &gocf.LambdaPermission{
+		Action:       gocf.String("lambda:InvokeFunction"),
+		FunctionName: gocf.GetAtt(handler.LogicalResourceName(), "Arn"),
+		Principal:    gocf.String(APIGatewayPrincipal),
+		SourceArn: gocf.Join("",
+			gocf.String("arn:aws:execute-api:"),
+			gocf.Ref("AWS::Region"),
+			gocf.String(":"),
+			gocf.Ref("AWS::AccountId"),
+			gocf.String(":"),
+			gocf.Ref(apiResourceName),
+			gocf.String("/*")),
+	})
+	return integrationResourceName
+}
+
+// apiV2Stage adds the stage, and an optional deployment, to the template
+func apiV2Stage(apiResourceName string,
+	stage *APIV2Stage,
+	routeResourceNames []string,
+	template *gocf.Template) error {
+
+	stageResource := &spartaCF.APIGatewayV2Stage{
+		APIID:      gocf.Ref(apiResourceName).String(),
+		StageName:  gocf.String(stage.name),
+		AutoDeploy: gocf.Bool(stage.AutoDeploy),
+	}
+	if stage.Description != "" {
+		stageResource.Description = gocf.String(stage.Description)
+	}
+	if len(stage.StageVariables) != 0 {
+		stageResource.StageVariables = stage.StageVariables
+	}
+	if stage.AccessLogSetting != nil {
+		if stage.AccessLogSetting.DestinationArn == nil {
+			return errors.Errorf("stage %s AccessLogSetting requires a non-nil DestinationArn", stage.name)
+		}
+		logFormat := stage.AccessLogSetting.Format
+		if logFormat == "" {
+			logFormat = DefaultStageAccessLogFormat
+		}
+		stageResource.AccessLogSettings = &spartaCF.APIGatewayV2StageAccessLogSettings{
+			DestinationArn: stage.AccessLogSetting.DestinationArn.String(),
+			Format:         gocf.String(logFormat),
+		}
+	}
+	if stage.ThrottlingBurstLimit != 0 || stage.ThrottlingRateLimit != 0 {
+		stageResource.DefaultRouteSettings = &spartaCF.APIGatewayV2StageRouteSettings{
+			ThrottlingBurstLimit: gocf.Integer(stage.ThrottlingBurstLimit),
+			ThrottlingRateLimit:  gocf.Integer(stage.ThrottlingRateLimit),
+		}
+	}
+	stageResourceName := CloudFormationResourceName(fmt.Sprintf("%sStage", apiResourceName),
+		stage.name)
+
+	// If it's not automatically deployed, create a deployment that depends on
+	// the routes
+	if !stage.AutoDeploy {
+		deploymentResourceName := CloudFormationResourceName(fmt.Sprintf("%sDeployment", apiResourceName))
+		deployment := template.AddResource(deploymentResourceName, &spartaCF.APIGatewayV2Deployment{
+			APIID: gocf.Ref(apiResourceName).String(),
+		})
+		deployment.DependsOn = append(deployment.DependsOn, routeResourceNames...)
+		stageResource.DeploymentID = gocf.Ref(deploymentResourceName).String()
+	}
+	template.AddResource(stageResourceName, stageResource)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// HTTP API
+////////////////////////////////////////////////////////////////////////////////
+
+// HTTPAPICORSConfiguration represents the CORS settings for an HTTP API. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-cors.html
+type HTTPAPICORSConfiguration struct {
+	AllowCredentials bool
+	AllowHeaders     []string
+	AllowMethods     []string
+	AllowOrigins     []string
+	ExposeHeaders    []string
+	MaxAge           int64
+}
+
+// HTTPAPIJWTAuthorizer represents a JWT authorizer for an HTTP API. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api-jwt-authorizer.html
+type HTTPAPIJWTAuthorizer struct {
+	name string
+	// The base domain of the identity provider that issues the tokens
+	Issuer string
+	// The intended recipients of the JWT
+	Audience []string
+	// The identity source for the token. Defaults to
+	// $request.header.Authorization
+	IdentitySource []string
+}
+
+// HTTPAPIRoute represents a single HTTP API route that is handled by a
+// Lambda function
+type HTTPAPIRoute struct {
+	routeKey   string
+	handler    *LambdaAWSInfo
+	authorizer *HTTPAPIJWTAuthorizer
+	// Scopes that the JWT must include to invoke the route
+	AuthorizationScopes []string
+	// Optional operation name for the route
+	OperationName string
+}
+
+// WithAuthorizer associates the JWT authorizer with the route. Any scopes
+// are required to be present in the JWT.
+func (route *HTTPAPIRoute) WithAuthorizer(authorizer *HTTPAPIJWTAuthorizer,
+	scopes ...string) *HTTPAPIRoute {
+	route.authorizer = authorizer
+	route.AuthorizationScopes = scopes
+	return route
+}
+
+// HTTPAPI represents an API Gateway v2 HTTP API. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/http-api.html
+type HTTPAPI struct {
+	name  string
+	stage *APIV2Stage
+	// API Description
+	Description string
+	// Optional CORS configuration
+	CORSConfiguration *HTTPAPICORSConfiguration
+	routes            []*HTTPAPIRoute
+	authorizers       []*HTTPAPIJWTAuthorizer
+}
+
+// NewHTTPAPI returns a new HTTP API. If stage is nil, an automatically
+// deployed HTTPAPIDefaultStageName stage is created.
+func NewHTTPAPI(name string, stage *APIV2Stage) *HTTPAPI {
+	if stage == nil {
+		stage = NewAPIV2Stage(HTTPAPIDefaultStageName)
+	}
+	return &HTTPAPI{
+		name:  name,
+		stage: stage,
+	}
+}
+
+// LogicalResourceName returns the CloudFormation logical
+// resource name for this API
+func (api *HTTPAPI) LogicalResourceName() string {
+	return CloudFormationResourceName("HTTPAPI", api.name)
+}
+
+// URL returns the dynamically assigned HTTP API URL including the
+// scheme and any stage path
+func (api *HTTPAPI) URL() *gocf.StringExpr {
+	if api.stage.name == HTTPAPIDefaultStageName {
+		return gocf.GetAtt(api.LogicalResourceName(), "ApiEndpoint")
+	}
+	return gocf.Join("",
+		gocf.GetAtt(api.LogicalResourceName(), "ApiEndpoint"),
+		gocf.String("/"),
+		gocf.String(api.stage.name))
+}
+
+// NewJWTAuthorizer returns a new JWT authorizer that can be associated
+// with routes via HTTPAPIRoute.WithAuthorizer
+func (api *HTTPAPI) NewJWTAuthorizer(name string,
+	issuer string,
+	audience ...string) (*HTTPAPIJWTAuthorizer, error) {
+	for _, eachAuthorizer := range api.authorizers {
+		if eachAuthorizer.name == name {
+			return nil, errors.Errorf("JWT authorizer %s already defined for HTTP API: %s",
+				name,
+				api.name)
+		}
+	}
+	if issuer == "" || len(audience) <= 0 {
+		return nil, errors.Errorf("JWT authorizer %s requires a non-empty issuer and audience", name)
+	}
+	authorizer := &HTTPAPIJWTAuthorizer{
+		name:           name,
+		Issuer:         issuer,
+		Audience:       audience,
+		IdentitySource: []string{"$request.header.Authorization"},
+	}
+	api.authorizers = append(api.authorizers, authorizer)
+	return authorizer, nil
+}
+
+// NewRoute associates the route key with the handler. The route key is
+// either HTTPAPIDefaultRouteKey or of the form "METHOD /path", as in
+// "GET /pets/{id}". The method may be ANY to match all methods.
+func (api *HTTPAPI) NewRoute(routeKey string, handler *LambdaAWSInfo) (*HTTPAPIRoute, error) {
+	if handler == nil {
+		return nil, errors.Errorf("handler must not be `nil` for HTTP API route: %s", routeKey)
+	}
+	if routeKey != HTTPAPIDefaultRouteKey {
+		routeParts := strings.SplitN(routeKey, " ", 2)
+		if len(routeParts) != 2 ||
+			!httpAPIRouteMethods[routeParts[0]] ||
+			!strings.HasPrefix(routeParts[1], "/") {
+			return nil, errors.Errorf("invalid HTTP API route key (must be `METHOD /path`): %s", routeKey)
+		}
+	}
+	for _, eachRoute := range api.routes {
+		if eachRoute.routeKey == routeKey {
+			return nil, errors.Errorf("route %s already defined for HTTP API: %s", routeKey, api.name)
+		}
+	}
+	route := &HTTPAPIRoute{
+		routeKey: routeKey,
+		handler:  handler,
+	}
+	api.routes = append(api.routes, route)
+	return route, nil
+}
+
+// export marshals the HTTP API to the CloudFormation template
+func (api *HTTPAPI) export(serviceName string, template *gocf.Template) error {
+	if len(api.routes) <= 0 {
+		return errors.Errorf("HTTP API %s must have at least one route", api.name)
+	}
+	apiResourceName := api.LogicalResourceName()
+	apiResource := &spartaCF.APIGatewayV2API{
+		Name:         gocf.String(api.name),
+		ProtocolType: gocf.String("HTTP"),
+	}
+	if api.Description == "" {
+		apiResource.Description = gocf.String(fmt.Sprintf("%s HTTP API", serviceName))
+	} else {
+		apiResource.Description = gocf.String(api.Description)
+	}
+	if api.CORSConfiguration != nil {
+		cors := &spartaCF.APIGatewayV2APICors{
+			AllowCredentials: gocf.Bool(api.CORSConfiguration.AllowCredentials),
+			AllowHeaders:     stringListExpr(api.CORSConfiguration.AllowHeaders),
+			AllowMethods:     stringListExpr(api.CORSConfiguration.AllowMethods),
+			AllowOrigins:     stringListExpr(api.CORSConfiguration.AllowOrigins),
+			ExposeHeaders:    stringListExpr(api.CORSConfiguration.ExposeHeaders),
+		}
+		if api.CORSConfiguration.MaxAge != 0 {
+			cors.MaxAge = gocf.Integer(api.CORSConfiguration.MaxAge)
+		}
+		apiResource.CorsConfiguration = cors
+	}
+	template.AddResource(apiResourceName, apiResource)
+
+	// Authorizers
+	authorizerResourceNames := make(map[*HTTPAPIJWTAuthorizer]string)
+	for _, eachAuthorizer := range api.authorizers {
+		authorizerResourceName := CloudFormationResourceName(fmt.Sprintf("%sAuthorizer", apiResourceName),
+			eachAuthorizer.name)
+		template.AddResource(authorizerResourceName, &spartaCF.APIGatewayV2Authorizer{
+			APIID:          gocf.Ref(apiResourceName).String(),
+			AuthorizerType: gocf.String("JWT"),
+			Name:           gocf.String(eachAuthorizer.name),
+			IdentitySource: stringListExpr(eachAuthorizer.IdentitySource),
+			JwtConfiguration: &spartaCF.APIGatewayV2AuthorizerJWTConfig{
+				Audience: stringListExpr(eachAuthorizer.Audience),
+				Issuer:   gocf.String(eachAuthorizer.Issuer),
+			},
+		})
+		authorizerResourceNames[eachAuthorizer] = authorizerResourceName
+	}
+
+	// Routes
+	var routeResourceNames []string
+	for _, eachRoute := range api.routes {
+		integrationResourceName := apiV2Integration(apiResourceName,
+			eachRoute.handler,
+			"2.0",
+			template)
+		routeResource := &spartaCF.APIGatewayV2Route{
+			APIID:    gocf.Ref(apiResourceName).String(),
+			RouteKey: gocf.String(eachRoute.routeKey),
+			Target: gocf.Join("",
+				gocf.String("integrations/"),
+				gocf.Ref(integrationResourceName)),
+			AuthorizationType: gocf.String("NONE"),
+		}
+		if eachRoute.OperationName != "" {
+			routeResource.OperationName = gocf.String(eachRoute.OperationName)
+		}
+		if eachRoute.authorizer != nil {
+			authorizerResourceName, exists := authorizerResourceNames[eachRoute.authorizer]
+			if !exists {
+				return errors.Errorf("route %s authorizer %s is not registered with HTTP API: %s",
+					eachRoute.routeKey,
+					eachRoute.authorizer.name,
+					api.name)
+			}
+			routeResource.AuthorizationType = gocf.String("JWT")
+			routeResource.AuthorizerID = gocf.Ref(authorizerResourceName).String()
+			if len(eachRoute.AuthorizationScopes) != 0 {
+				routeResource.AuthorizationScopes = stringListExpr(eachRoute.AuthorizationScopes)
+			}
+		}
+		routeResourceName := CloudFormationResourceName(fmt.Sprintf("%sRoute", apiResourceName),
+			eachRoute.routeKey)
+		template.AddResource(routeResourceName, routeResource)
+		routeResourceNames = append(routeResourceNames, routeResourceName)
+	}
+	stageErr := apiV2Stage(apiResourceName, api.stage, routeResourceNames, template)
+	if stageErr != nil {
+		return stageErr
+	}
+	template.Outputs[OutputHTTPAPIURL] = &gocf.Output{
+		Description: "HTTP API URL",
+		Value:       api.URL(),
+	}
+	return nil
+}
+
+// ServiceDecorator returns a ServiceDecoratorHookHandler that adds the HTTP
+// API resources to the service template. Include it in the
+// WorkflowHooks.ServiceDecorators slice supplied to MainEx.
+func (api *HTTPAPI) ServiceDecorator() ServiceDecoratorHookHandler {
+	return ServiceDecoratorHookFunc(func(context map[string]interface{},
+		serviceName string,
+		template *gocf.Template,
+		S3Bucket string,
+		S3Key string,
+		buildID string,
+		awsSession *session.Session,
+		noop bool,
+		logger *logrus.Logger) error {
+		return api.export(serviceName, template)
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WebSocket API
+////////////////////////////////////////////////////////////////////////////////
+
+// WebSocketAPI represents an API Gateway v2 WebSocket API. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/apigateway-websocket-api.html
+type WebSocketAPI struct {
+	name  string
+	stage *APIV2Stage
+	// API Description
+	Description string
+	// Expression used to select the route for an incoming message.
+	// Defaults to $request.body.action
+	RouteSelectionExpression string
+	routeKeys                []string
+	routes                   map[string]*LambdaAWSInfo
+}
+
+// NewWebSocketAPI returns a new WebSocket API. The stage must be non-nil.
+func NewWebSocketAPI(name string, stage *APIV2Stage) *WebSocketAPI {
+	return &WebSocketAPI{
+		name:                     name,
+		stage:                    stage,
+		RouteSelectionExpression: "$request.body.action",
+		routes:                   make(map[string]*LambdaAWSInfo),
+	}
+}
+
+// LogicalResourceName returns the CloudFormation logical
+// resource name for this API
+func (api *WebSocketAPI) LogicalResourceName() string {
+	return CloudFormationResourceName("WebSocketAPI", api.name)
+}
+
+// endpoint returns the stage URL for the given scheme
+func (api *WebSocketAPI) endpoint(scheme string) (*gocf.StringExpr, error) {
+	if api.stage == nil {
+		return nil, errors.Errorf("WebSocket API %s requires a non-nil stage", api.name)
+	}
+	return gocf.Join("",
+		gocf.String(scheme),
+		gocf.Ref(api.LogicalResourceName()),
+		gocf.String(".execute-api."),
+		gocf.Ref("AWS::Region"),
+		gocf.String(".amazonaws.com/"),
+		gocf.String(api.stage.name)), nil
+}
+
+// URL returns the dynamically assigned WebSocket URL clients connect to
+func (api *WebSocketAPI) URL() (*gocf.StringExpr, error) {
+	return api.endpoint("wss://")
+}
+
+// ManagementEndpoint returns the dynamically assigned management API
+// endpoint used to post messages back to connected clients
+func (api *WebSocketAPI) ManagementEndpoint() (*gocf.StringExpr, error) {
+	return api.endpoint("https://")
+}
+
+// NewRoute associates the route key with the handler. Route keys include
+// WebSocketRouteConnect, WebSocketRouteDisconnect, WebSocketRouteDefault
+// and any custom value matched by the RouteSelectionExpression. The
+// handler is granted permission to post to connections via the management
+// API, whose endpoint is published in the handler's environment. See
+// aws/apigateway.NewWebSocketClient. Handlers that use a pre-existing IAM
+// role must already have the execute-api:ManageConnections privilege.
+func (api *WebSocketAPI) NewRoute(routeKey string, handler *LambdaAWSInfo) error {
+	managementEndpoint, managementEndpointErr := api.ManagementEndpoint()
+	if managementEndpointErr != nil {
+		return managementEndpointErr
+	}
+	if handler == nil {
+		return errors.Errorf("handler must not be `nil` for WebSocket API route: %s", routeKey)
+	}
+	if routeKey == "" {
+		return errors.Errorf("WebSocket API route key must not be empty")
+	}
+	if _, exists := api.routes[routeKey]; exists {
+		return errors.Errorf("route %s already defined for WebSocket API: %s", routeKey, api.name)
+	}
+	// Publish the endpoint and grant access to the management API
+	// the first time the handler is registered
+	for _, eachHandler := range api.routes {
+		if eachHandler == handler {
+			api.routes[routeKey] = handler
+			api.routeKeys = append(api.routeKeys, routeKey)
+			return nil
+		}
+	}
+	api.routes[routeKey] = handler
+	api.routeKeys = append(api.routeKeys, routeKey)
+
+	if handler.Options == nil {
+		handler.Options = defaultLambdaFunctionOptions()
+	}
+	if handler.Options.Environment == nil {
+		handler.Options.Environment = make(map[string]*gocf.StringExpr)
+	}
+	handler.Options.Environment[spartaAPIGateway.EnvVarWebSocketEndpoint] = managementEndpoint
+	if handler.RoleDefinition != nil {
+		handler.RoleDefinition.Privileges = append(handler.RoleDefinition.Privileges,
+			IAMRolePrivilege{
+				Actions: []string{"execute-api:ManageConnections"},
+				Resource: gocf.Join("",
+					gocf.String("arn:aws:execute-api:"),
+					gocf.Ref("AWS::Region"),
+					gocf.String(":"),
+					gocf.Ref("AWS::AccountId"),
+					gocf.String(":"),
+					gocf.Ref(api.LogicalResourceName()),
+					gocf.String("/"),
+					gocf.String(api.stage.name),
+					gocf.String("/*/@connections/*")),
+			})
+	}
+	return nil
+}
+
+// export marshals the WebSocket API to the CloudFormation template
+func (api *WebSocketAPI) export(serviceName string,
+	template *gocf.Template,
+	logger *logrus.Logger) error {
+	apiURL, apiURLErr := api.URL()
+	if apiURLErr != nil {
+		return apiURLErr
+	}
+	if len(api.routeKeys) <= 0 {
+		return errors.Errorf("WebSocket API %s must have at least one route", api.name)
+	}
+	apiResourceName := api.LogicalResourceName()
+	apiResource := &spartaCF.APIGatewayV2API{
+		Name:                     gocf.String(api.name),
+		ProtocolType:             gocf.String("WEBSOCKET"),
+		RouteSelectionExpression: gocf.String(api.RouteSelectionExpression),
+	}
+	if api.Description == "" {
+		apiResource.Description = gocf.String(fmt.Sprintf("%s WebSocket API", serviceName))
+	} else {
+		apiResource.Description = gocf.String(api.Description)
+	}
+	template.AddResource(apiResourceName, apiResource)
+
+	var routeResourceNames []string
+	for _, eachRouteKey := range api.routeKeys {
+		handler := api.routes[eachRouteKey]
+		if handler.RoleDefinition == nil {
+			logger.WithFields(logrus.Fields{
+				"Function": handler.lambdaFunctionName(),
+				"RouteKey": eachRouteKey,
+			}).Warn("WebSocket handler uses a pre-existing IAM role. Ensure it has the execute-api:ManageConnections privilege.")
+		}
+		integrationResourceName := apiV2Integration(apiResourceName,
+			handler,
+			"",
+			template)
+		routeResourceName := CloudFormationResourceName(fmt.Sprintf("%sRoute", apiResourceName),
+			eachRouteKey)
+		template.AddResource(routeResourceName, &spartaCF.APIGatewayV2Route{
+			APIID:             gocf.Ref(apiResourceName).String(),
+			RouteKey:          gocf.String(eachRouteKey),
+			AuthorizationType: gocf.String("NONE"),
+			Target: gocf.Join("",
+				gocf.String("integrations/"),
+				gocf.Ref(integrationResourceName)),
+		})
+		routeResourceNames = append(routeResourceNames, routeResourceName)
+	}
+	stageErr := apiV2Stage(apiResourceName, api.stage, routeResourceNames, template)
+	if stageErr != nil {
+		return stageErr
+	}
+	template.Outputs[OutputWebSocketAPIURL] = &gocf.Output{
+		Description: "WebSocket API URL",
+		Value:       apiURL,
+	}
+	return nil
+}
+
+// ServiceDecorator returns a ServiceDecoratorHookHandler that adds the
+// WebSocket API resources to the service template. Include it in the
+// WorkflowHooks.ServiceDecorators slice supplied to MainEx.
+func (api *WebSocketAPI) ServiceDecorator() ServiceDecoratorHookHandler {
+	return ServiceDecoratorHookFunc(func(context map[string]interface{},
+		serviceName string,
+		template *gocf.Template,
+		S3Bucket string,
+		S3Key string,
+		buildID string,
+		awsSession *session.Session,
+		noop bool,
+		logger *logrus.Logger) error {
+		return api.export(serviceName, template, logger)
+	})
+}
+
+// stringListExpr transforms a string slice into a StringListExpr
+func stringListExpr(values []string) *gocf.StringListExpr {
+	if len(values) <= 0 {
+		return nil
+	}
+	stringables := make([]gocf.Stringable, len(values))
+	for eachIndex, eachValue := range values {
+		stringables[eachIndex] = gocf.String(eachValue)
+	}
+	return gocf.StringList(stringables...)
+}
@@ -0,0 +1,111 @@
+package sparta
+
+import (
+	"testing"
+)
+
+func TestHTTPAPI(t *testing.T) {
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	httpAPI := NewHTTPAPI("SpartaHTTPAPI", nil)
+	httpAPI.CORSConfiguration = &HTTPAPICORSConfiguration{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"GET", "POST"},
+	}
+	authorizer, authorizerErr := httpAPI.NewJWTAuthorizer("Cognito",
+		"https://cognito-idp.us-west-2.amazonaws.com/us-west-2_example",
+		"clientID")
+	if authorizerErr != nil {
+		t.Fatal(authorizerErr)
+	}
+	_, routeErr := httpAPI.NewRoute("GET /pets/{id}", lambdaFn)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	route, routeErr := httpAPI.NewRoute("POST /pets", lambdaFn)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	route.WithAuthorizer(authorizer, "pets/write")
+
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		nil,
+		nil,
+		&WorkflowHooks{
+			ServiceDecorators: []ServiceDecoratorHookHandler{httpAPI.ServiceDecorator()},
+		},
+		false,
+		nil)
+}
+
+func TestHTTPAPIInvalidRouteKey(t *testing.T) {
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	httpAPI := NewHTTPAPI("SpartaHTTPAPI", nil)
+	for _, eachRouteKey := range []string{"GET", "FETCH /pets", "GET pets", ""} {
+		_, routeErr := httpAPI.NewRoute(eachRouteKey, lambdaFn)
+		if routeErr == nil {
+			t.Fatalf("Failed to reject invalid route key: %s", eachRouteKey)
+		}
+	}
+	_, routeErr := httpAPI.NewRoute(HTTPAPIDefaultRouteKey, lambdaFn)
+	if routeErr != nil {
+		t.Fatal(routeErr)
+	}
+	_, routeErr = httpAPI.NewRoute(HTTPAPIDefaultRouteKey, lambdaFn)
+	if routeErr == nil {
+		t.Fatal("Failed to reject duplicate route key")
+	}
+}
+
+func TestWebSocketAPI(t *testing.T) {
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	stage := NewAPIV2Stage("v1")
+	stage.AutoDeploy = false
+	webSocketAPI := NewWebSocketAPI("SpartaWebSocketAPI", stage)
+	for _, eachRouteKey := range []string{WebSocketRouteConnect,
+		WebSocketRouteDisconnect,
+		WebSocketRouteDefault} {
+		routeErr := webSocketAPI.NewRoute(eachRouteKey, lambdaFn)
+		if routeErr != nil {
+			t.Fatal(routeErr)
+		}
+	}
+	if len(lambdaFn.RoleDefinition.Privileges) != 1 {
+		t.Fatalf("Expected ManageConnections privileges, got: %#v",
+			lambdaFn.RoleDefinition.Privileges)
+	}
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		nil,
+		nil,
+		&WorkflowHooks{
+			ServiceDecorators: []ServiceDecoratorHookHandler{webSocketAPI.ServiceDecorator()},
+		},
+		false,
+		nil)
+}
+
+func TestWebSocketAPINilStage(t *testing.T) {
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	webSocketAPI := NewWebSocketAPI("SpartaWebSocketAPI", nil)
+	routeErr := webSocketAPI.NewRoute(WebSocketRouteDefault, lambdaFn)
+	if routeErr == nil {
+		t.Fatal("Failed to reject WebSocket API route without a stage")
+	}
+	_, endpointErr := webSocketAPI.ManagementEndpoint()
+	if endpointErr == nil {
+		t.Fatal("Failed to reject WebSocket API management endpoint without a stage")
+	}
+}
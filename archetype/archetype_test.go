@@ -2,12 +2,16 @@ package archetype
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 
 	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
 	sparta "github.com/mweagle/Sparta"
 	spartaTesting "github.com/mweagle/Sparta/testing"
 	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/sirupsen/logrus"
 )
 
 func TestReactorName(t *testing.T) {
@@ -53,6 +57,34 @@ func (at *archetypeTest) OnKinesisMessage(ctx context.Context,
 	return nil, nil
 }
 
+func (at *archetypeTest) OnSQSMessages(ctx context.Context,
+	sqsEvent awsLambdaEvents.SQSEvent) (interface{}, error) {
+	return ProcessSQSMessages(ctx, sqsEvent, func(ctx context.Context,
+		message awsLambdaEvents.SQSMessage) error {
+		if message.Body == "" {
+			return errors.New("empty message")
+		}
+		return nil
+	}), nil
+}
+
+func (at *archetypeTest) OnCognitoPreSignUp(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPreSignup) (awsLambdaEvents.CognitoEventUserPoolsPreSignup, error) {
+	event.Response.AutoConfirmUser = true
+	return event, nil
+}
+
+func (at *archetypeTest) OnCognitoPostConfirmation(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPostConfirmation) (awsLambdaEvents.CognitoEventUserPoolsPostConfirmation, error) {
+	return event, nil
+}
+
+func (at *archetypeTest) OnKafkaRecord(ctx context.Context,
+	record KafkaRecord,
+	payload interface{}) error {
+	return nil
+}
+
 func TestS3Archetype(t *testing.T) {
 	testStruct := &archetypeTest{}
 
@@ -215,3 +247,202 @@ func TestCloudWatchScheduled(t *testing.T) {
 	}
 	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
 }
+
+func TestSQSArchetype(t *testing.T) {
+	testStruct := &archetypeTest{}
+
+	lambdaFn, lambdaFnErr := NewSQSReactor(testStruct,
+		nil,
+		&SQSReactorOptions{
+			BatchSize:       5,
+			MaxReceiveCount: 3,
+		},
+		nil)
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to instantiate SQSReactor: %s", lambdaFnErr.Error())
+	}
+	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
+
+	lambdaFn, lambdaFnErr = NewSQSReactor(SQSReactorFunc(testStruct.OnSQSMessages),
+		gocf.String("arn:aws:sqs:us-west-2:123412341234:queue"),
+		nil,
+		nil)
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to instantiate SQSReactor: %s", lambdaFnErr.Error())
+	}
+	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
+}
+
+func TestSQSPartialBatchFailure(t *testing.T) {
+	testStruct := &archetypeTest{}
+	response, responseErr := testStruct.OnSQSMessages(context.Background(),
+		awsLambdaEvents.SQSEvent{
+			Records: []awsLambdaEvents.SQSMessage{
+				{MessageId: "first", Body: "hello"},
+				{MessageId: "second"},
+				{MessageId: "third", Body: "world"},
+			},
+		})
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	batchResponse := response.(*SQSBatchResponse)
+	if len(batchResponse.BatchItemFailures) != 1 ||
+		batchResponse.BatchItemFailures[0].ItemIdentifier != "second" {
+		t.Fatalf("Unexpected batch item failures: %#v", batchResponse.BatchItemFailures)
+	}
+}
+
+func TestCognitoArchetype(t *testing.T) {
+	testStruct := &archetypeTest{}
+
+	// Existing user pool
+	lambdaFn, lambdaFnErr := NewCognitoUserPoolReactor(testStruct,
+		"us-west-2_aBcDeFgHi",
+		nil)
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to instantiate CognitoUserPoolReactor: %s", lambdaFnErr.Error())
+	}
+	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
+
+	// User pool in the same template
+	userPoolResourceName := "CognitoArchetypeUserPool"
+	lambdaFn, lambdaFnErr = NewCognitoUserPoolReactor(CognitoPreSignUpReactorFunc(testStruct.OnCognitoPreSignUp),
+		gocf.Ref(userPoolResourceName),
+		nil)
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to instantiate CognitoUserPoolReactor: %s", lambdaFnErr.Error())
+	}
+	lambdaFn.Decorators = append(lambdaFn.Decorators,
+		sparta.TemplateDecoratorHookFunc(func(serviceName string,
+			lambdaResourceName string,
+			lambdaResource gocf.LambdaFunction,
+			resourceMetadata map[string]interface{},
+			S3Bucket string,
+			S3Key string,
+			buildID string,
+			template *gocf.Template,
+			context map[string]interface{},
+			logger *logrus.Logger) error {
+			template.AddResource(userPoolResourceName, &gocf.CognitoUserPool{
+				UserPoolName: gocf.String("CognitoArchetype"),
+			})
+			return nil
+		}))
+	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
+
+	// Reactors must handle at least one trigger
+	_, lambdaFnErr = NewCognitoUserPoolReactor(SNSReactorFunc(testStruct.OnSNSEvent),
+		"us-west-2_aBcDeFgHi",
+		nil)
+	if lambdaFnErr == nil {
+		t.Fatalf("Failed to reject reactor without Cognito triggers")
+	}
+}
+
+func TestCognitoReactorDispatch(t *testing.T) {
+	reactorLambda := cognitoReactorLambda(cognitoTriggerHandlers(&archetypeTest{}))
+	response, responseErr := reactorLambda(context.Background(),
+		json.RawMessage(`{"triggerSource": "PreSignUp_SignUp", "userName": "sparta"}`))
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+	preSignUp := response.(awsLambdaEvents.CognitoEventUserPoolsPreSignup)
+	if !preSignUp.Response.AutoConfirmUser || preSignUp.UserName != "sparta" {
+		t.Fatalf("Unexpected PreSignUp response: %#v", preSignUp)
+	}
+	_, responseErr = reactorLambda(context.Background(),
+		json.RawMessage(`{"triggerSource": "TokenGeneration_HostedAuth"}`))
+	if responseErr == nil {
+		t.Fatalf("Failed to reject unhandled trigger source")
+	}
+}
+
+type kafkaTestReading struct {
+	Sensor string  `json:"sensor"`
+	Value  float64 `json:"value"`
+}
+
+func TestKafkaArchetype(t *testing.T) {
+	testStruct := &archetypeTest{}
+
+	// Amazon MSK cluster
+	lambdaFn, lambdaFnErr := NewKafkaReactor(testStruct,
+		kafkaTestReading{},
+		gocf.String("arn:aws:kafka:us-west-2:123412341234:cluster/telemetry/abcd"),
+		sparta.KafkaEventSource{
+			Topics: []string{"telemetry"},
+		},
+		"LATEST",
+		100,
+		nil)
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to instantiate KafkaReactor: %s", lambdaFnErr.Error())
+	}
+	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
+
+	// Self-managed cluster
+	lambdaFn, lambdaFnErr = NewKafkaReactor(KafkaReactorFunc(testStruct.OnKafkaRecord),
+		nil,
+		nil,
+		sparta.KafkaEventSource{
+			Topics:                  []string{"telemetry"},
+			BootstrapServers:        []string{"broker1:9096"},
+			AuthenticationType:      sparta.KafkaAuthenticationSASLSCRAM512,
+			AuthenticationSecretArn: "arn:aws:secretsmanager:us-west-2:123412341234:secret:kafka",
+		},
+		"TRIM_HORIZON",
+		10,
+		nil)
+	if lambdaFnErr != nil {
+		t.Fatalf("Failed to instantiate KafkaReactor: %s", lambdaFnErr.Error())
+	}
+	spartaTesting.Provision(t, []*sparta.LambdaAWSInfo{lambdaFn}, nil)
+}
+
+func TestKafkaReactorDecode(t *testing.T) {
+	var readings []*kafkaTestReading
+	reactor := KafkaReactorFunc(func(ctx context.Context,
+		record KafkaRecord,
+		payload interface{}) error {
+		readings = append(readings, payload.(*kafkaTestReading))
+		return nil
+	})
+	var kafkaEvent KafkaEvent
+	unmarshalErr := json.Unmarshal([]byte(`{
+		"eventSource": "aws:kafka",
+		"records": {
+			"telemetry-1": [{
+				"topic": "telemetry",
+				"partition": 1,
+				"offset": 15,
+				"value": "eyJzZW5zb3IiOiAiYiIsICJ2YWx1ZSI6IDJ9",
+				"headers": [{"source": [115, 112, 97, 114, 116, 97]}]
+			}],
+			"telemetry-0": [{
+				"topic": "telemetry",
+				"partition": 0,
+				"offset": 3,
+				"value": "eyJzZW5zb3IiOiAiYSIsICJ2YWx1ZSI6IDF9"
+			}]
+		}
+	}`), &kafkaEvent)
+	if unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	headerValue := string(kafkaEvent.Records["telemetry-1"][0].Headers[0]["source"])
+	if headerValue != "sparta" {
+		t.Fatalf("Unexpected Kafka header value: %s", headerValue)
+	}
+	reactorLambda := kafkaReactorLambda(reactor, reflect.TypeOf(kafkaTestReading{}))
+	_, reactorErr := reactorLambda(context.Background(), kafkaEvent)
+	if reactorErr != nil {
+		t.Fatal(reactorErr)
+	}
+	if len(readings) != 2 ||
+		readings[0].Sensor != "a" ||
+		readings[1].Sensor != "b" ||
+		readings[1].Value != 2 {
+		t.Fatalf("Unexpected Kafka payloads: %#v", readings)
+	}
+}
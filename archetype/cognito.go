@@ -0,0 +1,437 @@
+package archetype
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	sparta "github.com/mweagle/Sparta"
+	"github.com/pkg/errors"
+)
+
+// CognitoPreSignUpReactor represents a lambda function that handles the
+// PreSignUp user pool trigger
+type CognitoPreSignUpReactor interface {
+	// OnCognitoPreSignUp is called before a user is signed up. Set the
+	// Response fields to confirm or verify the user
+	OnCognitoPreSignUp(ctx context.Context,
+		event awsLambdaEvents.CognitoEventUserPoolsPreSignup) (awsLambdaEvents.CognitoEventUserPoolsPreSignup, error)
+}
+
+// CognitoPreSignUpReactorFunc is a free function that adapts a
+// CognitoPreSignUpReactor compliant signature into a function that exposes an
+// OnCognitoPreSignUp function
+type CognitoPreSignUpReactorFunc func(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPreSignup) (awsLambdaEvents.CognitoEventUserPoolsPreSignup, error)
+
+// OnCognitoPreSignUp satisfies the CognitoPreSignUpReactor interface
+func (reactorFunc CognitoPreSignUpReactorFunc) OnCognitoPreSignUp(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPreSignup) (awsLambdaEvents.CognitoEventUserPoolsPreSignup, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoPreSignUpReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoPostConfirmationReactor represents a lambda function that handles the
+// PostConfirmation user pool trigger
+type CognitoPostConfirmationReactor interface {
+	// OnCognitoPostConfirmation is called after a user is confirmed
+	OnCognitoPostConfirmation(ctx context.Context,
+		event awsLambdaEvents.CognitoEventUserPoolsPostConfirmation) (awsLambdaEvents.CognitoEventUserPoolsPostConfirmation, error)
+}
+
+// CognitoPostConfirmationReactorFunc is a free function that adapts a
+// CognitoPostConfirmationReactor compliant signature into a function that exposes an
+// OnCognitoPostConfirmation function
+type CognitoPostConfirmationReactorFunc func(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPostConfirmation) (awsLambdaEvents.CognitoEventUserPoolsPostConfirmation, error)
+
+// OnCognitoPostConfirmation satisfies the CognitoPostConfirmationReactor interface
+func (reactorFunc CognitoPostConfirmationReactorFunc) OnCognitoPostConfirmation(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPostConfirmation) (awsLambdaEvents.CognitoEventUserPoolsPostConfirmation, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoPostConfirmationReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoPreAuthenticationReactor represents a lambda function that handles the
+// PreAuthentication user pool trigger
+type CognitoPreAuthenticationReactor interface {
+	// OnCognitoPreAuthentication is called before a user is authenticated.
+	// Return an error to deny the sign in
+	OnCognitoPreAuthentication(ctx context.Context,
+		event CognitoEventUserPoolsPreAuthentication) (CognitoEventUserPoolsPreAuthentication, error)
+}
+
+// CognitoPreAuthenticationReactorFunc is a free function that adapts a
+// CognitoPreAuthenticationReactor compliant signature into a function that exposes an
+// OnCognitoPreAuthentication function
+type CognitoPreAuthenticationReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsPreAuthentication) (CognitoEventUserPoolsPreAuthentication, error)
+
+// OnCognitoPreAuthentication satisfies the CognitoPreAuthenticationReactor interface
+func (reactorFunc CognitoPreAuthenticationReactorFunc) OnCognitoPreAuthentication(ctx context.Context,
+	event CognitoEventUserPoolsPreAuthentication) (CognitoEventUserPoolsPreAuthentication, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoPreAuthenticationReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoPostAuthenticationReactor represents a lambda function that handles the
+// PostAuthentication user pool trigger
+type CognitoPostAuthenticationReactor interface {
+	// OnCognitoPostAuthentication is called after a user is authenticated
+	OnCognitoPostAuthentication(ctx context.Context,
+		event CognitoEventUserPoolsPostAuthentication) (CognitoEventUserPoolsPostAuthentication, error)
+}
+
+// CognitoPostAuthenticationReactorFunc is a free function that adapts a
+// CognitoPostAuthenticationReactor compliant signature into a function that exposes an
+// OnCognitoPostAuthentication function
+type CognitoPostAuthenticationReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsPostAuthentication) (CognitoEventUserPoolsPostAuthentication, error)
+
+// OnCognitoPostAuthentication satisfies the CognitoPostAuthenticationReactor interface
+func (reactorFunc CognitoPostAuthenticationReactorFunc) OnCognitoPostAuthentication(ctx context.Context,
+	event CognitoEventUserPoolsPostAuthentication) (CognitoEventUserPoolsPostAuthentication, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoPostAuthenticationReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoPreTokenGenerationReactor represents a lambda function that handles the
+// PreTokenGeneration user pool trigger
+type CognitoPreTokenGenerationReactor interface {
+	// OnCognitoPreTokenGeneration is called before the user's tokens are
+	// generated. Set the Response fields to override the claims
+	OnCognitoPreTokenGeneration(ctx context.Context,
+		event awsLambdaEvents.CognitoEventUserPoolsPreTokenGen) (awsLambdaEvents.CognitoEventUserPoolsPreTokenGen, error)
+}
+
+// CognitoPreTokenGenerationReactorFunc is a free function that adapts a
+// CognitoPreTokenGenerationReactor compliant signature into a function that exposes an
+// OnCognitoPreTokenGeneration function
+type CognitoPreTokenGenerationReactorFunc func(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPreTokenGen) (awsLambdaEvents.CognitoEventUserPoolsPreTokenGen, error)
+
+// OnCognitoPreTokenGeneration satisfies the CognitoPreTokenGenerationReactor interface
+func (reactorFunc CognitoPreTokenGenerationReactorFunc) OnCognitoPreTokenGeneration(ctx context.Context,
+	event awsLambdaEvents.CognitoEventUserPoolsPreTokenGen) (awsLambdaEvents.CognitoEventUserPoolsPreTokenGen, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoPreTokenGenerationReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoCustomMessageReactor represents a lambda function that handles the
+// CustomMessage user pool trigger
+type CognitoCustomMessageReactor interface {
+	// OnCognitoCustomMessage is called before a verification, invitation or
+	// MFA message is sent. Set the Response fields to customize the message
+	OnCognitoCustomMessage(ctx context.Context,
+		event CognitoEventUserPoolsCustomMessage) (CognitoEventUserPoolsCustomMessage, error)
+}
+
+// CognitoCustomMessageReactorFunc is a free function that adapts a
+// CognitoCustomMessageReactor compliant signature into a function that exposes an
+// OnCognitoCustomMessage function
+type CognitoCustomMessageReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsCustomMessage) (CognitoEventUserPoolsCustomMessage, error)
+
+// OnCognitoCustomMessage satisfies the CognitoCustomMessageReactor interface
+func (reactorFunc CognitoCustomMessageReactorFunc) OnCognitoCustomMessage(ctx context.Context,
+	event CognitoEventUserPoolsCustomMessage) (CognitoEventUserPoolsCustomMessage, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoCustomMessageReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoDefineAuthChallengeReactor represents a lambda function that handles the
+// DefineAuthChallenge user pool trigger
+type CognitoDefineAuthChallengeReactor interface {
+	// OnCognitoDefineAuthChallenge is called to determine the next step of a
+	// custom authentication flow
+	OnCognitoDefineAuthChallenge(ctx context.Context,
+		event CognitoEventUserPoolsDefineAuthChallenge) (CognitoEventUserPoolsDefineAuthChallenge, error)
+}
+
+// CognitoDefineAuthChallengeReactorFunc is a free function that adapts a
+// CognitoDefineAuthChallengeReactor compliant signature into a function that exposes an
+// OnCognitoDefineAuthChallenge function
+type CognitoDefineAuthChallengeReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsDefineAuthChallenge) (CognitoEventUserPoolsDefineAuthChallenge, error)
+
+// OnCognitoDefineAuthChallenge satisfies the CognitoDefineAuthChallengeReactor interface
+func (reactorFunc CognitoDefineAuthChallengeReactorFunc) OnCognitoDefineAuthChallenge(ctx context.Context,
+	event CognitoEventUserPoolsDefineAuthChallenge) (CognitoEventUserPoolsDefineAuthChallenge, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoDefineAuthChallengeReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoCreateAuthChallengeReactor represents a lambda function that handles the
+// CreateAuthChallenge user pool trigger
+type CognitoCreateAuthChallengeReactor interface {
+	// OnCognitoCreateAuthChallenge is called to create a custom authentication
+	// challenge
+	OnCognitoCreateAuthChallenge(ctx context.Context,
+		event CognitoEventUserPoolsCreateAuthChallenge) (CognitoEventUserPoolsCreateAuthChallenge, error)
+}
+
+// CognitoCreateAuthChallengeReactorFunc is a free function that adapts a
+// CognitoCreateAuthChallengeReactor compliant signature into a function that exposes an
+// OnCognitoCreateAuthChallenge function
+type CognitoCreateAuthChallengeReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsCreateAuthChallenge) (CognitoEventUserPoolsCreateAuthChallenge, error)
+
+// OnCognitoCreateAuthChallenge satisfies the CognitoCreateAuthChallengeReactor interface
+func (reactorFunc CognitoCreateAuthChallengeReactorFunc) OnCognitoCreateAuthChallenge(ctx context.Context,
+	event CognitoEventUserPoolsCreateAuthChallenge) (CognitoEventUserPoolsCreateAuthChallenge, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoCreateAuthChallengeReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoVerifyAuthChallengeReactor represents a lambda function that handles the
+// VerifyAuthChallengeResponse user pool trigger
+type CognitoVerifyAuthChallengeReactor interface {
+	// OnCognitoVerifyAuthChallenge is called to verify the answer to a custom
+	// authentication challenge
+	OnCognitoVerifyAuthChallenge(ctx context.Context,
+		event CognitoEventUserPoolsVerifyAuthChallenge) (CognitoEventUserPoolsVerifyAuthChallenge, error)
+}
+
+// CognitoVerifyAuthChallengeReactorFunc is a free function that adapts a
+// CognitoVerifyAuthChallengeReactor compliant signature into a function that exposes an
+// OnCognitoVerifyAuthChallenge function
+type CognitoVerifyAuthChallengeReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsVerifyAuthChallenge) (CognitoEventUserPoolsVerifyAuthChallenge, error)
+
+// OnCognitoVerifyAuthChallenge satisfies the CognitoVerifyAuthChallengeReactor interface
+func (reactorFunc CognitoVerifyAuthChallengeReactorFunc) OnCognitoVerifyAuthChallenge(ctx context.Context,
+	event CognitoEventUserPoolsVerifyAuthChallenge) (CognitoEventUserPoolsVerifyAuthChallenge, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoVerifyAuthChallengeReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// CognitoUserMigrationReactor represents a lambda function that handles the
+// UserMigration user pool trigger
+type CognitoUserMigrationReactor interface {
+	// OnCognitoUserMigration is called when a user isn't found in the user
+	// pool. Set the Response fields to migrate the user
+	OnCognitoUserMigration(ctx context.Context,
+		event CognitoEventUserPoolsMigrateUser) (CognitoEventUserPoolsMigrateUser, error)
+}
+
+// CognitoUserMigrationReactorFunc is a free function that adapts a
+// CognitoUserMigrationReactor compliant signature into a function that exposes an
+// OnCognitoUserMigration function
+type CognitoUserMigrationReactorFunc func(ctx context.Context,
+	event CognitoEventUserPoolsMigrateUser) (CognitoEventUserPoolsMigrateUser, error)
+
+// OnCognitoUserMigration satisfies the CognitoUserMigrationReactor interface
+func (reactorFunc CognitoUserMigrationReactorFunc) OnCognitoUserMigration(ctx context.Context,
+	event CognitoEventUserPoolsMigrateUser) (CognitoEventUserPoolsMigrateUser, error) {
+	return reactorFunc(ctx, event)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc CognitoUserMigrationReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// cognitoTriggerHandler handles the JSON event of a single trigger
+type cognitoTriggerHandler func(ctx context.Context, data json.RawMessage) (interface{}, error)
+
+// cognitoTriggerHandlers returns the handlers for each of the Cognito
+// reactor interfaces that the reactor implements
+func cognitoTriggerHandlers(reactor interface{}) map[sparta.CognitoUserPoolTrigger]cognitoTriggerHandler {
+	handlers := make(map[sparta.CognitoUserPoolTrigger]cognitoTriggerHandler)
+	if typedReactor, isReactor := reactor.(CognitoPreSignUpReactor); isReactor {
+		handlers[sparta.CognitoPreSignUp] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event awsLambdaEvents.CognitoEventUserPoolsPreSignup
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoPreSignUp(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoPostConfirmationReactor); isReactor {
+		handlers[sparta.CognitoPostConfirmation] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event awsLambdaEvents.CognitoEventUserPoolsPostConfirmation
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoPostConfirmation(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoPreAuthenticationReactor); isReactor {
+		handlers[sparta.CognitoPreAuthentication] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsPreAuthentication
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoPreAuthentication(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoPostAuthenticationReactor); isReactor {
+		handlers[sparta.CognitoPostAuthentication] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsPostAuthentication
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoPostAuthentication(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoPreTokenGenerationReactor); isReactor {
+		handlers[sparta.CognitoPreTokenGeneration] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event awsLambdaEvents.CognitoEventUserPoolsPreTokenGen
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoPreTokenGeneration(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoCustomMessageReactor); isReactor {
+		handlers[sparta.CognitoCustomMessage] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsCustomMessage
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoCustomMessage(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoDefineAuthChallengeReactor); isReactor {
+		handlers[sparta.CognitoDefineAuthChallenge] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsDefineAuthChallenge
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoDefineAuthChallenge(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoCreateAuthChallengeReactor); isReactor {
+		handlers[sparta.CognitoCreateAuthChallenge] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsCreateAuthChallenge
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoCreateAuthChallenge(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoVerifyAuthChallengeReactor); isReactor {
+		handlers[sparta.CognitoVerifyAuthChallengeResponse] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsVerifyAuthChallenge
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoVerifyAuthChallenge(ctx, event)
+		}
+	}
+	if typedReactor, isReactor := reactor.(CognitoUserMigrationReactor); isReactor {
+		handlers[sparta.CognitoUserMigration] = func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+			var event CognitoEventUserPoolsMigrateUser
+			if unmarshalErr := json.Unmarshal(data, &event); unmarshalErr != nil {
+				return nil, unmarshalErr
+			}
+			return typedReactor.OnCognitoUserMigration(ctx, event)
+		}
+	}
+	return handlers
+}
+
+// cognitoTriggerForSource returns the LambdaConfig trigger that sends events
+// with the triggerSource (eg, "PreSignUp_AdminCreateUser")
+func cognitoTriggerForSource(triggerSource string) sparta.CognitoUserPoolTrigger {
+	sourcePrefix := strings.SplitN(triggerSource, "_", 2)[0]
+	if sourcePrefix == "TokenGeneration" {
+		return sparta.CognitoPreTokenGeneration
+	}
+	return sparta.CognitoUserPoolTrigger(sourcePrefix)
+}
+
+// cognitoReactorLambda returns the lambda function that dispatches each
+// event to the handler for the event's trigger
+func cognitoReactorLambda(handlers map[sparta.CognitoUserPoolTrigger]cognitoTriggerHandler) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, data json.RawMessage) (interface{}, error) {
+		var header awsLambdaEvents.CognitoEventUserPoolsHeader
+		if unmarshalErr := json.Unmarshal(data, &header); unmarshalErr != nil {
+			return nil, errors.Wrapf(unmarshalErr, "attempting to unmarshal Cognito event")
+		}
+		handler, handlerExists := handlers[cognitoTriggerForSource(header.TriggerSource)]
+		if !handlerExists {
+			return nil, errors.Errorf("unsupported Cognito trigger source: %s", header.TriggerSource)
+		}
+		return handler(ctx, data)
+	}
+}
+
+// NewCognitoUserPoolReactor returns a lambda function that handles the
+// Cognito user pool triggers of each Cognito*Reactor interface that the
+// reactor implements. The userPool is a gocf.Ref to an
+// AWS::Cognito::UserPool resource in the same template, or the ID of an
+// existing user pool.
+func NewCognitoUserPoolReactor(reactor interface{},
+	userPool interface{},
+	additionalLambdaPermissions []sparta.IAMRolePrivilege) (*sparta.LambdaAWSInfo, error) {
+
+	handlers := cognitoTriggerHandlers(reactor)
+	if len(handlers) <= 0 {
+		return nil, errors.Errorf("reactor %s doesn't implement any Cognito reactor interfaces",
+			reactorName(reactor))
+	}
+	triggers := make([]sparta.CognitoUserPoolTrigger, 0, len(handlers))
+	for eachTrigger := range handlers {
+		triggers = append(triggers, eachTrigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool {
+		return triggers[i] < triggers[j]
+	})
+
+	lambdaFn, lambdaFnErr := sparta.NewAWSLambda(reactorName(reactor),
+		cognitoReactorLambda(handlers),
+		sparta.IAMRoleDefinition{})
+	if lambdaFnErr != nil {
+		return nil, errors.Wrapf(lambdaFnErr, "attempting to create reactor")
+	}
+
+	lambdaFn.Permissions = append(lambdaFn.Permissions, sparta.CognitoUserPoolPermission{
+		UserPool: userPool,
+		Triggers: triggers,
+	})
+	if len(additionalLambdaPermissions) != 0 {
+		lambdaFn.RoleDefinition.Privileges = additionalLambdaPermissions
+	}
+	return lambdaFn, nil
+}
@@ -0,0 +1,186 @@
+package archetype
+
+import (
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+)
+
+// The Cognito user pool trigger events that aren't defined by
+// github.com/aws/aws-lambda-go/events. The JSON names match the
+// event payloads documented at
+// https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-identity-pools-working-with-aws-lambda-triggers.html
+
+// CognitoEventUserPoolsPreAuthentication is sent by Cognito User Pools when a
+// user submits their information to be authenticated
+type CognitoEventUserPoolsPreAuthentication struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsPreAuthenticationRequest  `json:"request"`
+	Response CognitoEventUserPoolsPreAuthenticationResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsPreAuthenticationRequest contains the request portion
+// of a PreAuthentication event
+type CognitoEventUserPoolsPreAuthenticationRequest struct {
+	UserAttributes map[string]string `json:"userAttributes"`
+	ValidationData map[string]string `json:"validationData"`
+	UserNotFound   bool              `json:"userNotFound"`
+}
+
+// CognitoEventUserPoolsPreAuthenticationResponse contains the response
+// portion of a PreAuthentication event
+type CognitoEventUserPoolsPreAuthenticationResponse struct {
+}
+
+// CognitoEventUserPoolsPostAuthentication is sent by Cognito User Pools after
+// a user is authenticated
+type CognitoEventUserPoolsPostAuthentication struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsPostAuthenticationRequest  `json:"request"`
+	Response CognitoEventUserPoolsPostAuthenticationResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsPostAuthenticationRequest contains the request portion
+// of a PostAuthentication event
+type CognitoEventUserPoolsPostAuthenticationRequest struct {
+	NewDeviceUsed  bool              `json:"newDeviceUsed"`
+	UserAttributes map[string]string `json:"userAttributes"`
+	ClientMetadata map[string]string `json:"clientMetadata"`
+}
+
+// CognitoEventUserPoolsPostAuthenticationResponse contains the response
+// portion of a PostAuthentication event
+type CognitoEventUserPoolsPostAuthenticationResponse struct {
+}
+
+// CognitoEventUserPoolsCustomMessage is sent by Cognito User Pools before a
+// verification, invitation or MFA message is sent
+type CognitoEventUserPoolsCustomMessage struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsCustomMessageRequest  `json:"request"`
+	Response CognitoEventUserPoolsCustomMessageResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsCustomMessageRequest contains the request portion of a
+// CustomMessage event
+type CognitoEventUserPoolsCustomMessageRequest struct {
+	UserAttributes    map[string]interface{} `json:"userAttributes"`
+	CodeParameter     string                 `json:"codeParameter"`
+	UsernameParameter string                 `json:"usernameParameter"`
+	ClientMetadata    map[string]string      `json:"clientMetadata"`
+}
+
+// CognitoEventUserPoolsCustomMessageResponse contains the response portion of
+// a CustomMessage event
+type CognitoEventUserPoolsCustomMessageResponse struct {
+	SMSMessage   string `json:"smsMessage"`
+	EmailMessage string `json:"emailMessage"`
+	EmailSubject string `json:"emailSubject"`
+}
+
+// CognitoEventUserPoolsChallengeResult is a challenge that was presented to
+// the user during the current authentication flow, with its result
+type CognitoEventUserPoolsChallengeResult struct {
+	ChallengeName     string `json:"challengeName"`
+	ChallengeResult   bool   `json:"challengeResult"`
+	ChallengeMetadata string `json:"challengeMetadata"`
+}
+
+// CognitoEventUserPoolsDefineAuthChallenge is sent by Cognito User Pools to
+// start or continue a custom authentication flow
+type CognitoEventUserPoolsDefineAuthChallenge struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsDefineAuthChallengeRequest  `json:"request"`
+	Response CognitoEventUserPoolsDefineAuthChallengeResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsDefineAuthChallengeRequest contains the request portion
+// of a DefineAuthChallenge event
+type CognitoEventUserPoolsDefineAuthChallengeRequest struct {
+	UserAttributes map[string]string                       `json:"userAttributes"`
+	Session        []*CognitoEventUserPoolsChallengeResult `json:"session"`
+	ClientMetadata map[string]string                       `json:"clientMetadata"`
+	UserNotFound   bool                                    `json:"userNotFound"`
+}
+
+// CognitoEventUserPoolsDefineAuthChallengeResponse contains the response
+// portion of a DefineAuthChallenge event
+type CognitoEventUserPoolsDefineAuthChallengeResponse struct {
+	ChallengeName      string `json:"challengeName"`
+	IssueTokens        bool   `json:"issueTokens"`
+	FailAuthentication bool   `json:"failAuthentication"`
+}
+
+// CognitoEventUserPoolsCreateAuthChallenge is sent by Cognito User Pools to
+// create the challenge that is presented to the user
+type CognitoEventUserPoolsCreateAuthChallenge struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsCreateAuthChallengeRequest  `json:"request"`
+	Response CognitoEventUserPoolsCreateAuthChallengeResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsCreateAuthChallengeRequest contains the request portion
+// of a CreateAuthChallenge event
+type CognitoEventUserPoolsCreateAuthChallengeRequest struct {
+	UserAttributes map[string]string                       `json:"userAttributes"`
+	ChallengeName  string                                  `json:"challengeName"`
+	Session        []*CognitoEventUserPoolsChallengeResult `json:"session"`
+	ClientMetadata map[string]string                       `json:"clientMetadata"`
+	UserNotFound   bool                                    `json:"userNotFound"`
+}
+
+// CognitoEventUserPoolsCreateAuthChallengeResponse contains the response
+// portion of a CreateAuthChallenge event
+type CognitoEventUserPoolsCreateAuthChallengeResponse struct {
+	PublicChallengeParameters  map[string]string `json:"publicChallengeParameters"`
+	PrivateChallengeParameters map[string]string `json:"privateChallengeParameters"`
+	ChallengeMetadata          string            `json:"challengeMetadata"`
+}
+
+// CognitoEventUserPoolsVerifyAuthChallenge is sent by Cognito User Pools to
+// verify the user's answer to a custom challenge
+type CognitoEventUserPoolsVerifyAuthChallenge struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsVerifyAuthChallengeRequest  `json:"request"`
+	Response CognitoEventUserPoolsVerifyAuthChallengeResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsVerifyAuthChallengeRequest contains the request portion
+// of a VerifyAuthChallengeResponse event
+type CognitoEventUserPoolsVerifyAuthChallengeRequest struct {
+	UserAttributes             map[string]string `json:"userAttributes"`
+	PrivateChallengeParameters map[string]string `json:"privateChallengeParameters"`
+	ChallengeAnswer            interface{}       `json:"challengeAnswer"`
+	ClientMetadata             map[string]string `json:"clientMetadata"`
+	UserNotFound               bool              `json:"userNotFound"`
+}
+
+// CognitoEventUserPoolsVerifyAuthChallengeResponse contains the response
+// portion of a VerifyAuthChallengeResponse event
+type CognitoEventUserPoolsVerifyAuthChallengeResponse struct {
+	AnswerCorrect bool `json:"answerCorrect"`
+}
+
+// CognitoEventUserPoolsMigrateUser is sent by Cognito User Pools when a user
+// doesn't exist in the pool at sign in or during the forgot password flow
+type CognitoEventUserPoolsMigrateUser struct {
+	awsLambdaEvents.CognitoEventUserPoolsHeader
+	Request  CognitoEventUserPoolsMigrateUserRequest  `json:"request"`
+	Response CognitoEventUserPoolsMigrateUserResponse `json:"response"`
+}
+
+// CognitoEventUserPoolsMigrateUserRequest contains the request portion of a
+// UserMigration event
+type CognitoEventUserPoolsMigrateUserRequest struct {
+	Password       string            `json:"password"`
+	ValidationData map[string]string `json:"validationData"`
+	ClientMetadata map[string]string `json:"clientMetadata"`
+}
+
+// CognitoEventUserPoolsMigrateUserResponse contains the response portion of a
+// UserMigration event
+type CognitoEventUserPoolsMigrateUserResponse struct {
+	UserAttributes         map[string]string `json:"userAttributes"`
+	FinalUserStatus        string            `json:"finalUserStatus,omitempty"`
+	MessageAction          string            `json:"messageAction,omitempty"`
+	DesiredDeliveryMediums []string          `json:"desiredDeliveryMediums,omitempty"`
+	ForceAliasCreation     bool              `json:"forceAliasCreation"`
+}
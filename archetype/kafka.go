@@ -0,0 +1,198 @@
+package archetype
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"sort"
+
+	sparta "github.com/mweagle/Sparta"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+)
+
+// KafkaEvent is the event delivered by an Amazon MSK or self-managed Apache
+// Kafka EventSourceMapping. Records are keyed by "topic-partition".
+type KafkaEvent struct {
+	EventSource      string                   `json:"eventSource"`
+	EventSourceARN   string                   `json:"eventSourceArn"`
+	Records          map[string][]KafkaRecord `json:"records"`
+	BootstrapServers string                   `json:"bootstrapServers"`
+}
+
+// KafkaHeaderValue is a record header value. The values are delivered as
+// arrays of signed bytes.
+type KafkaHeaderValue []byte
+
+// UnmarshalJSON decodes the signed byte array
+func (headerValue *KafkaHeaderValue) UnmarshalJSON(data []byte) error {
+	var signedBytes []int8
+	if unmarshalErr := json.Unmarshal(data, &signedBytes); unmarshalErr != nil {
+		return unmarshalErr
+	}
+	*headerValue = make(KafkaHeaderValue, len(signedBytes))
+	for eachIndex, eachByte := range signedBytes {
+		(*headerValue)[eachIndex] = byte(eachByte)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the value as a signed byte array
+func (headerValue KafkaHeaderValue) MarshalJSON() ([]byte, error) {
+	signedBytes := make([]int8, len(headerValue))
+	for eachIndex, eachByte := range headerValue {
+		signedBytes[eachIndex] = int8(eachByte)
+	}
+	return json.Marshal(signedBytes)
+}
+
+// KafkaRecord is a single Kafka record. The Key and Value are base64
+// encoded.
+type KafkaRecord struct {
+	Topic         string                        `json:"topic"`
+	Partition     int64                         `json:"partition"`
+	Offset        int64                         `json:"offset"`
+	Timestamp     int64                         `json:"timestamp"`
+	TimestampType string                        `json:"timestampType"`
+	Key           string                        `json:"key,omitempty"`
+	Value         string                        `json:"value,omitempty"`
+	Headers       []map[string]KafkaHeaderValue `json:"headers"`
+}
+
+// KeyBytes returns the decoded record key
+func (record *KafkaRecord) KeyBytes() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(record.Key)
+}
+
+// ValueBytes returns the decoded record value
+func (record *KafkaRecord) ValueBytes() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(record.Value)
+}
+
+// DecodeValue unmarshals the record's JSON value into v
+func (record *KafkaRecord) DecodeValue(v interface{}) error {
+	valueBytes, valueBytesErr := record.ValueBytes()
+	if valueBytesErr != nil {
+		return errors.Wrapf(valueBytesErr, "attempting to decode Kafka record value")
+	}
+	return json.Unmarshal(valueBytes, v)
+}
+
+// KafkaReactor represents a lambda function that responds to Kafka records
+type KafkaReactor interface {
+	// OnKafkaRecord is called for each record in the batch. The payload is
+	// a pointer to a new value of the reactor's payload type that holds the
+	// record's decoded JSON value. Returning an error fails the batch,
+	// which is retried.
+	OnKafkaRecord(ctx context.Context,
+		record KafkaRecord,
+		payload interface{}) error
+}
+
+// KafkaReactorFunc is a free function that adapts a KafkaReactor
+// compliant signature into a function that exposes an OnKafkaRecord
+// function
+type KafkaReactorFunc func(ctx context.Context,
+	record KafkaRecord,
+	payload interface{}) error
+
+// OnKafkaRecord satisfies the KafkaReactor interface
+func (reactorFunc KafkaReactorFunc) OnKafkaRecord(ctx context.Context,
+	record KafkaRecord,
+	payload interface{}) error {
+	return reactorFunc(ctx, record, payload)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc KafkaReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// kafkaReactorLambda returns the lambda function that decodes each record's
+// value into a new payloadType value and calls the reactor. Records are
+// delivered in partition and offset order.
+func kafkaReactorLambda(reactor KafkaReactor,
+	payloadType reflect.Type) func(context.Context, KafkaEvent) (interface{}, error) {
+	return func(ctx context.Context, kafkaEvent KafkaEvent) (interface{}, error) {
+		partitionKeys := make([]string, 0, len(kafkaEvent.Records))
+		for eachKey := range kafkaEvent.Records {
+			partitionKeys = append(partitionKeys, eachKey)
+		}
+		sort.Strings(partitionKeys)
+		for _, eachKey := range partitionKeys {
+			for _, eachRecord := range kafkaEvent.Records[eachKey] {
+				var payload interface{}
+				if payloadType != nil {
+					payloadValue := reflect.New(payloadType)
+					decodeErr := eachRecord.DecodeValue(payloadValue.Interface())
+					if decodeErr != nil {
+						return nil, errors.Wrapf(decodeErr,
+							"attempting to decode record %s@%d",
+							eachKey,
+							eachRecord.Offset)
+					}
+					payload = payloadValue.Interface()
+				} else {
+					valueBytes, valueBytesErr := eachRecord.ValueBytes()
+					if valueBytesErr != nil {
+						return nil, errors.Wrapf(valueBytesErr,
+							"attempting to decode record %s@%d",
+							eachKey,
+							eachRecord.Offset)
+					}
+					payload = valueBytes
+				}
+				reactorErr := reactor.OnKafkaRecord(ctx, eachRecord, payload)
+				if reactorErr != nil {
+					return nil, reactorErr
+				}
+			}
+		}
+		return nil, nil
+	}
+}
+
+// NewKafkaReactor returns a Kafka reactor lambda function. The payloadType
+// is a value of the type each record value is unmarshalled into (eg,
+// `TelemetryReading{}`). If it's nil, the payload is the decoded []byte
+// value. The clusterArn is the Amazon MSK cluster ARN, or nil for a
+// self-managed cluster whose BootstrapServers are defined by kafkaSource.
+func NewKafkaReactor(reactor KafkaReactor,
+	payloadType interface{},
+	clusterArn gocf.Stringable,
+	kafkaSource sparta.KafkaEventSource,
+	startingPosition string,
+	batchSize int64,
+	additionalLambdaPermissions []sparta.IAMRolePrivilege) (*sparta.LambdaAWSInfo, error) {
+
+	var payloadReflectType reflect.Type
+	if payloadType != nil {
+		payloadReflectType = reflect.TypeOf(payloadType)
+		if payloadReflectType.Kind() == reflect.Ptr {
+			payloadReflectType = payloadReflectType.Elem()
+		}
+	}
+	lambdaFn, lambdaFnErr := sparta.NewAWSLambda(reactorName(reactor),
+		kafkaReactorLambda(reactor, payloadReflectType),
+		sparta.IAMRoleDefinition{})
+	if lambdaFnErr != nil {
+		return nil, errors.Wrapf(lambdaFnErr, "attempting to create reactor")
+	}
+
+	eventSourceMapping := &sparta.EventSourceMapping{
+		StartingPosition: startingPosition,
+		BatchSize:        batchSize,
+		Kafka:            &kafkaSource,
+	}
+	if clusterArn != nil {
+		eventSourceMapping.EventSourceArn = clusterArn
+	}
+	lambdaFn.EventSourceMappings = append(lambdaFn.EventSourceMappings,
+		eventSourceMapping)
+	if len(additionalLambdaPermissions) != 0 {
+		lambdaFn.RoleDefinition.Privileges = additionalLambdaPermissions
+	}
+	return lambdaFn, nil
+}
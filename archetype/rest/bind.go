@@ -0,0 +1,302 @@
+package rest
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	spartaAPIGateway "github.com/mweagle/Sparta/aws/apigateway"
+	spartaAWSEvents "github.com/mweagle/Sparta/aws/events"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TagPath is the struct tag that binds a field to a URL path parameter
+	TagPath = "path"
+	// TagQuery is the struct tag that binds a field to a querystring parameter
+	TagQuery = "query"
+	// TagHeader is the struct tag that binds a field to a request header
+	TagHeader = "header"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// StatusCoder is implemented by typed response values that override the
+// MethodHandler DefaultCode
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// ResponseHeaderer is implemented by typed response values that include
+// additional response headers
+type ResponseHeaderer interface {
+	ResponseHeaders() map[string]string
+}
+
+// boundRequest is the API Gateway request envelope whose body
+// is deferred until the request type is known
+type boundRequest struct {
+	spartaAWSEvents.APIGatewayEnvelope
+	Body json.RawMessage `json:"body"`
+}
+
+// Bind populates the fields of the struct pointed to by target from the
+// API Gateway request. The JSON body is unmarshalled into target, after
+// which fields tagged with `path:"name"`, `query:"name"` or
+// `header:"name"` are set from the corresponding request parameter.
+// Header names are matched case-insensitively.
+func Bind(request *spartaAWSEvents.APIGatewayRequest, target interface{}) error {
+	// Round trip the request so that the body is available as JSON
+	requestBytes, requestBytesErr := json.Marshal(request)
+	if requestBytesErr != nil {
+		return requestBytesErr
+	}
+	return bindRequest(requestBytes, target)
+}
+
+func bindRequest(msg json.RawMessage, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr ||
+		targetValue.IsNil() ||
+		targetValue.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("bind target must be a non-nil struct pointer, got: %T", target)
+	}
+	var request boundRequest
+	unmarshalErr := json.Unmarshal(msg, &request)
+	if unmarshalErr != nil {
+		return errors.Wrapf(unmarshalErr, "unmarshalling API Gateway request")
+	}
+	// Body first, so that the parameters take precedence
+	body := request.Body
+	if len(body) != 0 && body[0] == '"' {
+		// Non-JSON content types are delivered as a string. Try to
+		// decode the string contents.
+		var bodyString string
+		if json.Unmarshal(body, &bodyString) == nil {
+			body = json.RawMessage(bodyString)
+		}
+	}
+	if len(body) != 0 && string(body) != "null" && string(body) != `""` {
+		bodyErr := json.Unmarshal(body, target)
+		if bodyErr != nil {
+			return errors.Wrapf(bodyErr, "unmarshalling request body")
+		}
+	}
+	headers := make(map[string]string, len(request.Headers))
+	for eachKey, eachValue := range request.Headers {
+		headers[strings.ToLower(eachKey)] = eachValue
+	}
+	return bindParams(targetValue.Elem(), request.PathParams, request.QueryParams, headers)
+}
+
+func bindParams(structValue reflect.Value,
+	pathParams map[string]string,
+	queryParams map[string]string,
+	headers map[string]string) error {
+
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+		// Embedded structs contribute their fields
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embeddedErr := bindParams(fieldValue, pathParams, queryParams, headers)
+			if embeddedErr != nil {
+				return embeddedErr
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		var paramValue string
+		var paramExists bool
+		var paramName string
+		if name, ok := field.Tag.Lookup(TagPath); ok {
+			paramName = fmt.Sprintf("path parameter `%s`", name)
+			paramValue, paramExists = pathParams[name]
+		} else if name, ok := field.Tag.Lookup(TagQuery); ok {
+			paramName = fmt.Sprintf("querystring parameter `%s`", name)
+			paramValue, paramExists = queryParams[name]
+		} else if name, ok := field.Tag.Lookup(TagHeader); ok {
+			paramName = fmt.Sprintf("header `%s`", name)
+			paramValue, paramExists = headers[strings.ToLower(name)]
+		} else {
+			continue
+		}
+		if !paramExists {
+			continue
+		}
+		setErr := setFieldValue(fieldValue, paramValue)
+		if setErr != nil {
+			return errors.Wrapf(setErr, "binding %s to field %s", paramName, field.Name)
+		}
+	}
+	return nil
+}
+
+// setFieldValue parses the string value into the field
+func setFieldValue(fieldValue reflect.Value, value string) error {
+	if fieldValue.Kind() == reflect.Ptr {
+		ptrValue := reflect.New(fieldValue.Type().Elem())
+		setErr := setFieldValue(ptrValue.Elem(), value)
+		if setErr != nil {
+			return setErr
+		}
+		fieldValue.Set(ptrValue)
+		return nil
+	}
+	if reflect.PtrTo(fieldValue.Type()).Implements(textUnmarshalerType) {
+		return fieldValue.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch fieldValue.Kind() {
+	case reflect.String:
+		fieldValue.SetString(value)
+	case reflect.Bool:
+		boolVal, boolErr := strconv.ParseBool(value)
+		if boolErr != nil {
+			return boolErr
+		}
+		fieldValue.SetBool(boolVal)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intVal, intErr := strconv.ParseInt(value, 10, fieldValue.Type().Bits())
+		if intErr != nil {
+			return intErr
+		}
+		fieldValue.SetInt(intVal)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintVal, uintErr := strconv.ParseUint(value, 10, fieldValue.Type().Bits())
+		if uintErr != nil {
+			return uintErr
+		}
+		fieldValue.SetUint(uintVal)
+	case reflect.Float32, reflect.Float64:
+		floatVal, floatErr := strconv.ParseFloat(value, fieldValue.Type().Bits())
+		if floatErr != nil {
+			return floatErr
+		}
+		fieldValue.SetFloat(floatVal)
+	case reflect.Slice:
+		// Comma separated values
+		parts := strings.Split(value, ",")
+		sliceValue := reflect.MakeSlice(fieldValue.Type(), len(parts), len(parts))
+		for eachIndex, eachPart := range parts {
+			setErr := setFieldValue(sliceValue.Index(eachIndex), strings.TrimSpace(eachPart))
+			if setErr != nil {
+				return setErr
+			}
+		}
+		fieldValue.Set(sliceValue)
+	default:
+		return errors.Errorf("unsupported field type: %s", fieldValue.Type())
+	}
+	return nil
+}
+
+// boundParamNames returns the API Gateway method request parameter names
+// for the tagged fields of the request type
+func boundParamNames(requestType reflect.Type) []string {
+	for requestType.Kind() == reflect.Ptr {
+		requestType = requestType.Elem()
+	}
+	paramNames := []string{}
+	if requestType.Kind() != reflect.Struct {
+		return paramNames
+	}
+	for i := 0; i < requestType.NumField(); i++ {
+		field := requestType.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			paramNames = append(paramNames, boundParamNames(field.Type)...)
+			continue
+		}
+		if name, ok := field.Tag.Lookup(TagQuery); ok {
+			paramNames = append(paramNames, fmt.Sprintf("method.request.querystring.%s", name))
+		} else if name, ok := field.Tag.Lookup(TagHeader); ok {
+			paramNames = append(paramNames, fmt.Sprintf("method.request.header.%s", name))
+		}
+	}
+	return paramNames
+}
+
+// typedHandler returns a Lambda handler that binds the incoming API Gateway
+// request to the user handler's request type and marshals the typed
+// response. The user handler must be of the form:
+//
+//	func([context.Context,] *RequestType) (ResponseType, error)
+func typedHandler(handler interface{}, defaultCode int) (interface{}, reflect.Type, error) {
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := handlerValue.Type()
+	if handlerType.Kind() != reflect.Func {
+		return nil, nil, errors.Errorf("typed handler must be a function, got: %T", handler)
+	}
+	takesContext := handlerType.NumIn() > 0 && handlerType.In(0).Implements(contextType)
+	expectedArgs := 1
+	if takesContext {
+		expectedArgs = 2
+	}
+	if handlerType.NumIn() != expectedArgs {
+		return nil, nil, errors.Errorf("typed handler %T must accept a single request argument, with an optional leading context.Context", handler)
+	}
+	if handlerType.NumOut() != 2 || !handlerType.Out(1).Implements(errorType) {
+		return nil, nil, errors.Errorf("typed handler %T must return (ResponseType, error)", handler)
+	}
+	requestType := handlerType.In(expectedArgs - 1)
+	requestIsPtr := requestType.Kind() == reflect.Ptr
+	requestStructType := requestType
+	if requestIsPtr {
+		requestStructType = requestType.Elem()
+	}
+	if requestStructType.Kind() != reflect.Struct {
+		return nil, nil, errors.Errorf("typed handler %T request argument must be a struct or struct pointer", handler)
+	}
+
+	lambdaHandler := func(ctx context.Context, msg json.RawMessage) (interface{}, error) {
+		requestValue := reflect.New(requestStructType)
+		bindErr := bindRequest(msg, requestValue.Interface())
+		if bindErr != nil {
+			return nil, spartaAPIGateway.NewErrorResponse(http.StatusBadRequest, bindErr)
+		}
+		if !requestIsPtr {
+			requestValue = requestValue.Elem()
+		}
+		args := []reflect.Value{}
+		if takesContext {
+			args = append(args, reflect.ValueOf(ctx))
+		}
+		args = append(args, requestValue)
+		results := handlerValue.Call(args)
+		if errVal, isErr := results[1].Interface().(error); isErr && errVal != nil {
+			if apiErr, isAPIErr := errVal.(*spartaAPIGateway.Error); isAPIErr {
+				return nil, apiErr
+			}
+			return nil, spartaAPIGateway.NewErrorResponse(http.StatusInternalServerError, errVal)
+		}
+		return typedResponse(results[0].Interface(), defaultCode), nil
+	}
+	return lambdaHandler, requestType, nil
+}
+
+// typedResponse marshals the typed handler's response value into an
+// API Gateway response
+func typedResponse(value interface{}, defaultCode int) *spartaAPIGateway.Response {
+	if response, isResponse := value.(*spartaAPIGateway.Response); isResponse {
+		return response
+	}
+	statusCode := defaultCode
+	if coder, isCoder := value.(StatusCoder); isCoder && coder.StatusCode() != 0 {
+		statusCode = coder.StatusCode()
+	}
+	var headers []map[string]string
+	if headerer, isHeaderer := value.(ResponseHeaderer); isHeaderer {
+		headers = append(headers, headerer.ResponseHeaders())
+	}
+	return spartaAPIGateway.NewResponse(statusCode, value, headers...)
+}
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	spartaAPIGateway "github.com/mweagle/Sparta/aws/apigateway"
+	spartaAWSEvents "github.com/mweagle/Sparta/aws/events"
+)
+
+type testBindRequest struct {
+	ID    int      `path:"id" json:"-"`
+	Page  *int     `query:"page" json:"-"`
+	Tags  []string `query:"tags" json:"-"`
+	Trace string   `header:"X-Trace" json:"-"`
+	Name  string   `json:"name"`
+}
+
+type testBindResponse struct {
+	Name string `json:"name"`
+}
+
+func (resp *testBindResponse) StatusCode() int {
+	return http.StatusCreated
+}
+
+func testBindMockRequest(t *testing.T, id string) *spartaAWSEvents.APIGatewayRequest {
+	mockRequest, mockRequestErr := spartaAWSEvents.NewAPIGatewayMockRequest("bindTest",
+		http.MethodPost,
+		map[string]string{
+			"method.request.path.id":          id,
+			"method.request.querystring.page": "3",
+			"method.request.querystring.tags": "red, green",
+			"method.request.header.x-trace":   "traceID",
+		},
+		map[string]string{
+			"name": "Sparta",
+		})
+	if mockRequestErr != nil {
+		t.Fatal(mockRequestErr)
+	}
+	return mockRequest
+}
+
+func TestBind(t *testing.T) {
+	var request testBindRequest
+	bindErr := Bind(testBindMockRequest(t, "42"), &request)
+	if bindErr != nil {
+		t.Fatal(bindErr)
+	}
+	if request.ID != 42 ||
+		request.Page == nil ||
+		*request.Page != 3 ||
+		len(request.Tags) != 2 ||
+		request.Trace != "traceID" ||
+		request.Name != "Sparta" {
+		t.Fatalf("Unexpected bound request: %#v", request)
+	}
+}
+
+func TestTypedHandler(t *testing.T) {
+	handler, requestType, handlerErr := typedHandler(func(ctx context.Context,
+		request *testBindRequest) (*testBindResponse, error) {
+		return &testBindResponse{Name: request.Name}, nil
+	}, http.StatusOK)
+	if handlerErr != nil {
+		t.Fatal(handlerErr)
+	}
+	if len(boundParamNames(requestType)) != 3 {
+		t.Fatalf("Unexpected bound params: %#v", boundParamNames(requestType))
+	}
+	lambdaHandler := handler.(func(context.Context, json.RawMessage) (interface{}, error))
+
+	// Valid request
+	msg, _ := json.Marshal(testBindMockRequest(t, "42"))
+	resp, respErr := lambdaHandler(context.Background(), msg)
+	if respErr != nil {
+		t.Fatal(respErr)
+	}
+	if resp.(*spartaAPIGateway.Response).Code != http.StatusCreated {
+		t.Fatalf("Unexpected response: %#v", resp)
+	}
+	// Invalid path param
+	msg, _ = json.Marshal(testBindMockRequest(t, "notAnInt"))
+	_, respErr = lambdaHandler(context.Background(), msg)
+	apiErr, apiErrOk := respErr.(*spartaAPIGateway.Error)
+	if !apiErrOk || apiErr.Code != http.StatusBadRequest {
+		t.Fatalf("Failed to reject unbindable request: %#v", respErr)
+	}
+}
+
+func TestTypedHandlerInvalidSignature(t *testing.T) {
+	invalidHandlers := []interface{}{
+		"notAFunction",
+		func(ctx context.Context) (interface{}, error) { return nil, nil },
+		func(request string) (interface{}, error) { return nil, nil },
+		func(request *testBindRequest) error { return nil },
+	}
+	for _, eachHandler := range invalidHandlers {
+		_, _, handlerErr := typedHandler(eachHandler, http.StatusOK)
+		if handlerErr == nil {
+			t.Fatalf("Failed to reject invalid typed handler: %T", eachHandler)
+		}
+	}
+}
@@ -33,6 +33,7 @@ type MethodHandler struct {
 	privileges  []sparta.IAMRolePrivilege
 	options     *sparta.LambdaFunctionOptions
 	headers     []string
+	typed       bool
 }
 
 // StatusCodes is a fluent builder to append additional HTTP status codes
@@ -81,6 +82,31 @@ func NewMethodHandler(handler interface{}, defaultCode int) *MethodHandler {
 	}
 }
 
+// NewTypedMethodHandler is a constructor function to return a new
+// MethodHandler whose handler binds the API Gateway request to a typed
+// request struct and marshals the typed response. The handler must be of
+// the form:
+//
+//	func([context.Context,] *RequestType) (ResponseType, error)
+//
+// RequestType fields are populated from the JSON body and from the path,
+// querystring and header values named by `path`, `query` and `header`
+// struct tags. See Bind for more information. ResponseType values are
+// returned with the defaultCode status unless they implement StatusCoder.
+// Additional headers are returned for values that implement
+// ResponseHeaderer. A *aws/apigateway.Response value is returned as-is.
+//
+// Requests that cannot be bound are rejected with http.StatusBadRequest.
+// Errors that are not *aws/apigateway.Error values are returned with
+// http.StatusInternalServerError.
+func NewTypedMethodHandler(handler interface{}, defaultCode int) *MethodHandler {
+	return &MethodHandler{
+		DefaultCode: defaultCode,
+		Handler:     handler,
+		typed:       true,
+	}
+}
+
 // ResourceDefinition represents a set of handlers for a given URL path
 type ResourceDefinition struct {
 	URL            string
@@ -132,6 +158,7 @@ func RegisterResource(apiGateway *sparta.API, resource Resource) ([]*sparta.Lamb
 	// Local function to handle registering the function with API Gateway
 	createAPIGEntry := func(methodName string,
 		methodHandler *MethodHandler,
+		boundParams []string,
 		handler *sparta.LambdaAWSInfo) error {
 		apiGWResource, apiGWResourceErr := apiGateway.NewResource(definition.URL, handler)
 		if apiGWResourceErr != nil {
@@ -158,6 +185,13 @@ func RegisterResource(apiGateway *sparta.API, resource Resource) ([]*sparta.Lamb
 		for eachQueryParam := range queryParams {
 			apiMethod.Parameters[fmt.Sprintf("method.request.querystring.%s", eachQueryParam)] = true
 		}
+		// Typed handlers may bind additional optional querystring and header
+		// values
+		for _, eachBoundParam := range boundParams {
+			if _, exists := apiMethod.Parameters[eachBoundParam]; !exists {
+				apiMethod.Parameters[eachBoundParam] = false
+			}
+		}
 		// Any headers?
 		// We used to need to whitelist these, but the header management has been moved
 		// into the VTL templating overrides and can be removed from here.
@@ -195,8 +229,20 @@ func RegisterResource(apiGateway *sparta.API, resource Resource) ([]*sparta.Lamb
 				definition.URL,
 				allHTTPMethods)
 		}
+		lambdaHandler := eachMethodDefinition.Handler
+		var boundParams []string
+		if eachMethodDefinition.typed {
+			typedLambdaHandler, requestType, typedErr := typedHandler(eachMethodDefinition.Handler,
+				eachMethodDefinition.DefaultCode)
+			if typedErr != nil {
+				return nil, errors.Wrapf(typedErr,
+					"attempting to register url `%s %s`", eachMethod, definition.URL)
+			}
+			lambdaHandler = typedLambdaHandler
+			boundParams = boundParamNames(requestType)
+		}
 		lambdaFn, lambdaFnErr := sparta.NewAWSLambda(lambdaName(eachMethod),
-			eachMethodDefinition.Handler,
+			lambdaHandler,
 			sparta.IAMRoleDefinition{})
 
 		if lambdaFnErr != nil {
@@ -217,7 +263,7 @@ func RegisterResource(apiGateway *sparta.API, resource Resource) ([]*sparta.Lamb
 		}
 
 		// Register the route...
-		apiGWRegistrationErr := createAPIGEntry(eachMethod, eachMethodDefinition, lambdaFn)
+		apiGWRegistrationErr := createAPIGEntry(eachMethod, eachMethodDefinition, boundParams, lambdaFn)
 		if apiGWRegistrationErr != nil {
 			return nil, errors.Wrapf(apiGWRegistrationErr, "attemping to create resource for method: %s", http.MethodHead)
 		}
@@ -0,0 +1,210 @@
+package archetype
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	sparta "github.com/mweagle/Sparta"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// sqsReportBatchItemFailures is the EventSourceMapping response type that
+// enables partial batch responses
+const sqsReportBatchItemFailures = "ReportBatchItemFailures"
+
+// SQSReactor represents a lambda function that responds to SQS messages
+type SQSReactor interface {
+	// OnSQSMessages when a batch of SQS messages is received. Return an
+	// SQSBatchResponse to only retry the messages that failed.
+	OnSQSMessages(ctx context.Context,
+		sqsEvent awsLambdaEvents.SQSEvent) (interface{}, error)
+}
+
+// SQSReactorFunc is a free function that adapts a SQSReactor
+// compliant signature into a function that exposes an OnSQSMessages
+// function
+type SQSReactorFunc func(ctx context.Context,
+	sqsEvent awsLambdaEvents.SQSEvent) (interface{}, error)
+
+// OnSQSMessages satisfies the SQSReactor interface
+func (reactorFunc SQSReactorFunc) OnSQSMessages(ctx context.Context,
+	sqsEvent awsLambdaEvents.SQSEvent) (interface{}, error) {
+	return reactorFunc(ctx, sqsEvent)
+}
+
+// ReactorName provides the name of the reactor func
+func (reactorFunc SQSReactorFunc) ReactorName() string {
+	return runtime.FuncForPC(reflect.ValueOf(reactorFunc).Pointer()).Name()
+}
+
+// SQSBatchItemFailure identifies a message that failed to process
+type SQSBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// SQSBatchResponse is the partial batch response of an SQSReactor. Only
+// the messages in BatchItemFailures are returned to the queue, the rest
+// of the batch is deleted.
+// Ref: https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html#services-sqs-batchfailurereporting
+type SQSBatchResponse struct {
+	BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+}
+
+// AddFailure records that the message with the given ID failed
+func (response *SQSBatchResponse) AddFailure(messageID string) {
+	response.BatchItemFailures = append(response.BatchItemFailures,
+		SQSBatchItemFailure{ItemIdentifier: messageID})
+}
+
+// SQSMessageHandler processes a single SQS message
+type SQSMessageHandler func(ctx context.Context,
+	message awsLambdaEvents.SQSMessage) error
+
+// ProcessSQSMessages calls the handler for each message in the batch and
+// returns the SQSBatchResponse that includes the messages whose handler
+// returned an error
+func ProcessSQSMessages(ctx context.Context,
+	sqsEvent awsLambdaEvents.SQSEvent,
+	handler SQSMessageHandler) *SQSBatchResponse {
+	response := &SQSBatchResponse{
+		BatchItemFailures: []SQSBatchItemFailure{},
+	}
+	logger, _ := ctx.Value(sparta.ContextKeyLogger).(*logrus.Logger)
+	for _, eachMessage := range sqsEvent.Records {
+		handlerErr := handler(ctx, eachMessage)
+		if handlerErr != nil {
+			if logger != nil {
+				logger.WithFields(logrus.Fields{
+					"MessageID": eachMessage.MessageId,
+					"Error":     handlerErr.Error(),
+				}).Warn("Failed to process SQS message")
+			}
+			response.AddFailure(eachMessage.MessageId)
+		}
+	}
+	return response
+}
+
+// SQSReactorOptions are the optional settings for an SQSReactor
+type SQSReactorOptions struct {
+	// BatchSize is the maximum number of messages in each batch
+	BatchSize int64
+	// MaximumBatchingWindowInSeconds is the maximum time to gather
+	// messages before invoking the reactor
+	MaximumBatchingWindowInSeconds int64
+	// MaxReceiveCount is the number of times a message is received before
+	// it's moved to the dead letter queue. Defaults to 5.
+	MaxReceiveCount int64
+	// VisibilityTimeout is the visibility timeout of the managed queue.
+	// Defaults to six times the function timeout.
+	VisibilityTimeout int64
+}
+
+// sqsReactorQueueDecorator returns the decorator that provisions the queue
+// and the dead letter queue
+func sqsReactorQueueDecorator(queueResourceName string,
+	dlqResourceName string,
+	options SQSReactorOptions) sparta.TemplateDecoratorHookFunc {
+	return func(serviceName string,
+		lambdaResourceName string,
+		lambdaResource gocf.LambdaFunction,
+		resourceMetadata map[string]interface{},
+		S3Bucket string,
+		S3Key string,
+		buildID string,
+		template *gocf.Template,
+		context map[string]interface{},
+		logger *logrus.Logger) error {
+
+		// The queue visibility timeout must be at least the function timeout
+		visibilityTimeout := options.VisibilityTimeout
+		if visibilityTimeout == 0 {
+			functionTimeout := int64(3)
+			if lambdaResource.Timeout != nil && lambdaResource.Timeout.Literal != 0 {
+				functionTimeout = lambdaResource.Timeout.Literal
+			}
+			visibilityTimeout = 6 * functionTimeout
+		}
+		maxReceiveCount := options.MaxReceiveCount
+		if maxReceiveCount == 0 {
+			maxReceiveCount = 5
+		}
+		template.AddResource(dlqResourceName, &gocf.SQSQueue{
+			// Keep the failed messages for the maximum period
+			MessageRetentionPeriod: gocf.Integer(1209600),
+		})
+		template.AddResource(queueResourceName, &gocf.SQSQueue{
+			VisibilityTimeout: gocf.Integer(visibilityTimeout),
+			RedrivePolicy: map[string]interface{}{
+				"deadLetterTargetArn": gocf.GetAtt(dlqResourceName, "Arn"),
+				"maxReceiveCount":     maxReceiveCount,
+			},
+		})
+		// Outputs so that producers can find the queues
+		template.Outputs[queueResourceName] = &gocf.Output{
+			Description: "SQSReactor queue URL",
+			Value:       gocf.Ref(queueResourceName),
+		}
+		template.Outputs[dlqResourceName] = &gocf.Output{
+			Description: "SQSReactor dead letter queue URL",
+			Value:       gocf.Ref(dlqResourceName),
+		}
+		return nil
+	}
+}
+
+// NewSQSReactor returns an SQS reactor lambda function. If sqsQueueArn is
+// nil, the reactor provisions a queue with a dead letter queue that receives
+// the messages that can't be processed. Otherwise the reactor subscribes
+// to the existing queue, whose redrive policy is unchanged. The
+// EventSourceMapping reports batch item failures, so reactors that return an
+// SQSBatchResponse only retry the failed messages.
+func NewSQSReactor(reactor SQSReactor,
+	sqsQueueArn gocf.Stringable,
+	options *SQSReactorOptions,
+	additionalLambdaPermissions []sparta.IAMRolePrivilege) (*sparta.LambdaAWSInfo, error) {
+
+	reactorLambda := func(ctx context.Context, sqsEvent awsLambdaEvents.SQSEvent) (interface{}, error) {
+		return reactor.OnSQSMessages(ctx, sqsEvent)
+	}
+
+	lambdaFn, lambdaFnErr := sparta.NewAWSLambda(reactorName(reactor),
+		reactorLambda,
+		sparta.IAMRoleDefinition{})
+	if lambdaFnErr != nil {
+		return nil, errors.Wrapf(lambdaFnErr, "attempting to create reactor")
+	}
+	if options == nil {
+		options = &SQSReactorOptions{}
+	}
+
+	// Managed queue?
+	if sqsQueueArn == nil {
+		queueResourceName := sparta.CloudFormationResourceName("SQSReactorQueue",
+			lambdaFn.LogicalResourceName())
+		dlqResourceName := sparta.CloudFormationResourceName("SQSReactorDLQ",
+			lambdaFn.LogicalResourceName())
+		sqsQueueArn = gocf.GetAtt(queueResourceName, "Arn")
+		lambdaFn.Decorators = append(lambdaFn.Decorators,
+			sqsReactorQueueDecorator(queueResourceName, dlqResourceName, *options))
+	}
+	batchSize := options.BatchSize
+	if batchSize == 0 {
+		batchSize = 10
+	}
+	lambdaFn.EventSourceMappings = append(lambdaFn.EventSourceMappings,
+		&sparta.EventSourceMapping{
+			EventSourceArn:                 sqsQueueArn,
+			BatchSize:                      batchSize,
+			MaximumBatchingWindowInSeconds: options.MaximumBatchingWindowInSeconds,
+			FunctionResponseTypes:          []string{sqsReportBatchItemFailures},
+		})
+	if len(additionalLambdaPermissions) != 0 {
+		lambdaFn.RoleDefinition.Privileges = additionalLambdaPermissions
+	}
+	return lambdaFn, nil
+}
@@ -1,6 +1,7 @@
 package apigateway
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -58,28 +59,34 @@ func NewErrorResponse(statusCode int, messages ...interface{}) *Error {
 // Response is the type returned by an API Gateway function. Note that a
 // non 2xx HTTP status code should be returned as a Response
 // type rather than an Error. Errors should be reserved for Lambda
-// functions that fail to execute.
+// functions that fail to execute. Binary bodies returned by
+// NewBinaryResponse are base64 encoded and flagged with IsBase64Encoded
+// so that the integration response mapping can return them to API
+// Gateway for conversion.
 type Response struct {
-	Code    int               `json:"code,omitempty"`
-	Body    interface{}       `json:"body,omitempty"`
-	Headers map[string]string `json:"headers,omitempty"`
+	Code            int               `json:"code,omitempty"`
+	Body            interface{}       `json:"body,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded,omitempty"`
 }
 
 // canonicalResponse is the type for the canonicalized response with the
 // headers lowercased to match any API-Gateway case sensitive whitelist
 // matching
 type canonicalResponse struct {
-	Code    int               `json:"code,omitempty"`
-	Body    interface{}       `json:"body,omitempty"`
-	Headers map[string]string `json:"headers,omitempty"`
+	Code            int               `json:"code,omitempty"`
+	Body            interface{}       `json:"body,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	IsBase64Encoded bool              `json:"isBase64Encoded,omitempty"`
 }
 
 // MarshalJSON is a custom marshaller to ensure that the marshalled
 // headers are always lowercase
 func (resp *Response) MarshalJSON() ([]byte, error) {
 	canonicalResponse := canonicalResponse{
-		Code: resp.Code,
-		Body: resp.Body,
+		Code:            resp.Code,
+		Body:            resp.Body,
+		IsBase64Encoded: resp.IsBase64Encoded,
 	}
 	if len(resp.Headers) != 0 {
 		canonicalResponse.Headers = make(map[string]string)
@@ -106,3 +113,21 @@ func NewResponse(code int, body interface{}, headers ...map[string]string) *Resp
 	}
 	return response
 }
+
+// NewBinaryResponse returns an API Gateway response object whose body
+// is the base64 encoded binary payload, flagged with IsBase64Encoded. The
+// contentType value is used as the Content-Type response header. The
+// associated sparta.IntegrationResponse must set ContentHandling to
+// CONVERT_TO_BINARY and the sparta.API must include the contentType in its
+// BinaryMediaTypes.
+func NewBinaryResponse(code int,
+	body []byte,
+	contentType string,
+	headers ...map[string]string) *Response {
+	headers = append(headers, map[string]string{
+		"Content-Type": contentType,
+	})
+	response := NewResponse(code, base64.StdEncoding.EncodeToString(body), headers...)
+	response.IsBase64Encoded = true
+	return response
+}
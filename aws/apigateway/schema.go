@@ -0,0 +1,19 @@
+package apigateway
+
+import (
+	spartaSchema "github.com/mweagle/Sparta/aws/schema"
+)
+
+// JSONSchemaDraft04 is the JSON Schema version supported by API Gateway
+// models. It's an alias of aws/schema.JSONSchemaDraft04.
+const JSONSchemaDraft04 = spartaSchema.JSONSchemaDraft04
+
+// JSONSchema is an alias of aws/schema.JSONSchema, which was moved so that
+// EventBridge detail-types can share the reflection.
+type JSONSchema = spartaSchema.JSONSchema
+
+// NewJSONSchema returns the JSON Schema reflected from the type of value.
+// See aws/schema.NewJSONSchema.
+func NewJSONSchema(value interface{}) (*JSONSchema, error) {
+	return spartaSchema.NewJSONSchema(value)
+}
@@ -0,0 +1,77 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+)
+
+// EnvVarWebSocketEndpoint is the environment variable that stores the
+// management API endpoint of the WebSocket API that invokes a function
+const EnvVarWebSocketEndpoint = "SPARTA_WEBSOCKET_ENDPOINT"
+
+// WebSocketClient posts messages to clients connected to a WebSocket API
+type WebSocketClient struct {
+	endpoint string
+	svc      *apigatewaymanagementapi.ApiGatewayManagementApi
+}
+
+// Endpoint returns the management API endpoint used by this client
+func (client *WebSocketClient) Endpoint() string {
+	return client.endpoint
+}
+
+// PostToConnection sends data to the connected client. []byte and string
+// values are sent as-is, all other values are JSON encoded.
+func (client *WebSocketClient) PostToConnection(ctx context.Context,
+	connectionID string,
+	data interface{}) error {
+	var payload []byte
+	switch typedData := data.(type) {
+	case []byte:
+		payload = typedData
+	case string:
+		payload = []byte(typedData)
+	default:
+		jsonBytes, jsonBytesErr := json.Marshal(data)
+		if jsonBytesErr != nil {
+			return jsonBytesErr
+		}
+		payload = jsonBytes
+	}
+	_, postErr := client.svc.PostToConnectionWithContext(ctx,
+		&apigatewaymanagementapi.PostToConnectionInput{
+			ConnectionId: aws.String(connectionID),
+			Data:         payload,
+		})
+	return postErr
+}
+
+// NewWebSocketClient returns a client for the WebSocket API whose management
+// endpoint is published in the EnvVarWebSocketEndpoint environment variable.
+// The variable is set for every function registered with
+// sparta.WebSocketAPI.NewRoute.
+func NewWebSocketClient(awsSession *session.Session) (*WebSocketClient, error) {
+	endpoint := os.Getenv(EnvVarWebSocketEndpoint)
+	if endpoint == "" {
+		return nil, fmt.Errorf("environment variable %s is not defined", EnvVarWebSocketEndpoint)
+	}
+	return NewWebSocketClientWithEndpoint(awsSession, endpoint), nil
+}
+
+// NewWebSocketClientWithEndpoint returns a client for the WebSocket API
+// management endpoint. The endpoint is of the form
+// https://{api-id}.execute-api.{region}.amazonaws.com/{stage}
+func NewWebSocketClientWithEndpoint(awsSession *session.Session,
+	endpoint string) *WebSocketClient {
+	return &WebSocketClient{
+		endpoint: endpoint,
+		svc: apigatewaymanagementapi.New(awsSession,
+			aws.NewConfig().WithEndpoint(endpoint)),
+	}
+}
@@ -0,0 +1,823 @@
+package cloudformation
+
+import (
+	gocf "github.com/mweagle/go-cloudformation"
+)
+
+// This file includes CloudFormation resource types, or resource properties,
+// that are not yet available in the vendored go-cloudformation library.
+// Each type is registered with the go-cloudformation resource provider
+// so that templates that include them can be unmarshalled.
+
+func nativeResourceProvider(resourceType string) gocf.ResourceProperties {
+	switch resourceType {
+	case "AWS::ApiGatewayV2::Api":
+		return &APIGatewayV2API{}
+	case "AWS::ApiGatewayV2::Authorizer":
+		return &APIGatewayV2Authorizer{}
+	case "AWS::ApiGatewayV2::Deployment":
+		return &APIGatewayV2Deployment{}
+	case "AWS::ApiGatewayV2::Integration":
+		return &APIGatewayV2Integration{}
+	case "AWS::ApiGatewayV2::Route":
+		return &APIGatewayV2Route{}
+	case "AWS::ApiGatewayV2::Stage":
+		return &APIGatewayV2Stage{}
+	case "AWS::ElasticLoadBalancingV2::Listener":
+		return &ElasticLoadBalancingV2Listener{}
+	case "AWS::ElasticLoadBalancingV2::ListenerRule":
+		return &ElasticLoadBalancingV2ListenerRule{}
+	case "AWS::ElasticLoadBalancingV2::TargetGroup":
+		return &ElasticLoadBalancingV2TargetGroup{}
+	case "AWS::Events::Archive":
+		return &EventsArchive{}
+	case "AWS::Events::EventBus":
+		return &EventsEventBus{}
+	case "AWS::Events::EventBusPolicy":
+		return &EventsEventBusPolicy{}
+	case "AWS::Events::Rule":
+		return &EventsRule{}
+	case "AWS::IoT::TopicRule":
+		return &IoTTopicRule{}
+	case "AWS::Lambda::EventInvokeConfig":
+		return &LambdaEventInvokeConfig{}
+	case "AWS::Lambda::EventSourceMapping":
+		return &LambdaEventSourceMapping{}
+	case "AWS::S3::AccessPoint":
+		return &S3AccessPoint{}
+	case "AWS::S3ObjectLambda::AccessPoint":
+		return &S3ObjectLambdaAccessPoint{}
+	case "AWS::StepFunctions::StateMachine":
+		return &StepFunctionsStateMachine{}
+	}
+	return nil
+}
+
+func init() {
+	gocf.RegisterCustomResourceProvider(nativeResourceProvider)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// API Gateway V2
+////////////////////////////////////////////////////////////////////////////////
+
+// APIGatewayV2API represents the AWS::ApiGatewayV2::Api resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-api.html
+type APIGatewayV2API struct {
+	Name                     *gocf.StringExpr     `json:"Name,omitempty"`
+	Description              *gocf.StringExpr     `json:"Description,omitempty"`
+	ProtocolType             *gocf.StringExpr     `json:"ProtocolType,omitempty"`
+	RouteSelectionExpression *gocf.StringExpr     `json:"RouteSelectionExpression,omitempty"`
+	CorsConfiguration        *APIGatewayV2APICors `json:"CorsConfiguration,omitempty"`
+	Tags                     map[string]string    `json:"Tags,omitempty"`
+}
+
+// CfnResourceType returns AWS::ApiGatewayV2::Api to implement the
+// ResourceProperties interface
+func (s APIGatewayV2API) CfnResourceType() string {
+	return "AWS::ApiGatewayV2::Api"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s APIGatewayV2API) CfnResourceAttributes() []string {
+	return []string{"ApiEndpoint"}
+}
+
+// APIGatewayV2APICors represents the CorsConfiguration property of an
+// HTTP API
+type APIGatewayV2APICors struct {
+	AllowCredentials *gocf.BoolExpr       `json:"AllowCredentials,omitempty"`
+	AllowHeaders     *gocf.StringListExpr `json:"AllowHeaders,omitempty"`
+	AllowMethods     *gocf.StringListExpr `json:"AllowMethods,omitempty"`
+	AllowOrigins     *gocf.StringListExpr `json:"AllowOrigins,omitempty"`
+	ExposeHeaders    *gocf.StringListExpr `json:"ExposeHeaders,omitempty"`
+	MaxAge           *gocf.IntegerExpr    `json:"MaxAge,omitempty"`
+}
+
+// APIGatewayV2Authorizer represents the AWS::ApiGatewayV2::Authorizer
+// resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-authorizer.html
+type APIGatewayV2Authorizer struct {
+	APIID            *gocf.StringExpr                 `json:"ApiId,omitempty"`
+	AuthorizerType   *gocf.StringExpr                 `json:"AuthorizerType,omitempty"`
+	AuthorizerURI    *gocf.StringExpr                 `json:"AuthorizerUri,omitempty"`
+	IdentitySource   *gocf.StringListExpr             `json:"IdentitySource,omitempty"`
+	JwtConfiguration *APIGatewayV2AuthorizerJWTConfig `json:"JwtConfiguration,omitempty"`
+	Name             *gocf.StringExpr                 `json:"Name,omitempty"`
+}
+
+// CfnResourceType returns AWS::ApiGatewayV2::Authorizer to implement the
+// ResourceProperties interface
+func (s APIGatewayV2Authorizer) CfnResourceType() string {
+	return "AWS::ApiGatewayV2::Authorizer"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s APIGatewayV2Authorizer) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// APIGatewayV2AuthorizerJWTConfig represents the JwtConfiguration property
+// of a JWT authorizer
+type APIGatewayV2AuthorizerJWTConfig struct {
+	Audience *gocf.StringListExpr `json:"Audience,omitempty"`
+	Issuer   *gocf.StringExpr     `json:"Issuer,omitempty"`
+}
+
+// APIGatewayV2Deployment represents the AWS::ApiGatewayV2::Deployment
+// resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-deployment.html
+type APIGatewayV2Deployment struct {
+	APIID       *gocf.StringExpr `json:"ApiId,omitempty"`
+	Description *gocf.StringExpr `json:"Description,omitempty"`
+	StageName   *gocf.StringExpr `json:"StageName,omitempty"`
+}
+
+// CfnResourceType returns AWS::ApiGatewayV2::Deployment to implement the
+// ResourceProperties interface
+func (s APIGatewayV2Deployment) CfnResourceType() string {
+	return "AWS::ApiGatewayV2::Deployment"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s APIGatewayV2Deployment) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// APIGatewayV2Integration represents the AWS::ApiGatewayV2::Integration
+// resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-integration.html
+type APIGatewayV2Integration struct {
+	APIID                *gocf.StringExpr  `json:"ApiId,omitempty"`
+	Description          *gocf.StringExpr  `json:"Description,omitempty"`
+	IntegrationMethod    *gocf.StringExpr  `json:"IntegrationMethod,omitempty"`
+	IntegrationType      *gocf.StringExpr  `json:"IntegrationType,omitempty"`
+	IntegrationURI       *gocf.StringExpr  `json:"IntegrationUri,omitempty"`
+	PayloadFormatVersion *gocf.StringExpr  `json:"PayloadFormatVersion,omitempty"`
+	TimeoutInMillis      *gocf.IntegerExpr `json:"TimeoutInMillis,omitempty"`
+}
+
+// CfnResourceType returns AWS::ApiGatewayV2::Integration to implement the
+// ResourceProperties interface
+func (s APIGatewayV2Integration) CfnResourceType() string {
+	return "AWS::ApiGatewayV2::Integration"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s APIGatewayV2Integration) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// APIGatewayV2Route represents the AWS::ApiGatewayV2::Route resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-route.html
+type APIGatewayV2Route struct {
+	APIID                            *gocf.StringExpr     `json:"ApiId,omitempty"`
+	AuthorizationScopes              *gocf.StringListExpr `json:"AuthorizationScopes,omitempty"`
+	AuthorizationType                *gocf.StringExpr     `json:"AuthorizationType,omitempty"`
+	AuthorizerID                     *gocf.StringExpr     `json:"AuthorizerId,omitempty"`
+	OperationName                    *gocf.StringExpr     `json:"OperationName,omitempty"`
+	RouteKey                         *gocf.StringExpr     `json:"RouteKey,omitempty"`
+	RouteResponseSelectionExpression *gocf.StringExpr     `json:"RouteResponseSelectionExpression,omitempty"`
+	Target                           *gocf.StringExpr     `json:"Target,omitempty"`
+}
+
+// CfnResourceType returns AWS::ApiGatewayV2::Route to implement the
+// ResourceProperties interface
+func (s APIGatewayV2Route) CfnResourceType() string {
+	return "AWS::ApiGatewayV2::Route"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s APIGatewayV2Route) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// APIGatewayV2Stage represents the AWS::ApiGatewayV2::Stage resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-apigatewayv2-stage.html
+type APIGatewayV2Stage struct {
+	AccessLogSettings    *APIGatewayV2StageAccessLogSettings `json:"AccessLogSettings,omitempty"`
+	APIID                *gocf.StringExpr                    `json:"ApiId,omitempty"`
+	AutoDeploy           *gocf.BoolExpr                      `json:"AutoDeploy,omitempty"`
+	DefaultRouteSettings *APIGatewayV2StageRouteSettings     `json:"DefaultRouteSettings,omitempty"`
+	DeploymentID         *gocf.StringExpr                    `json:"DeploymentId,omitempty"`
+	Description          *gocf.StringExpr                    `json:"Description,omitempty"`
+	StageName            *gocf.StringExpr                    `json:"StageName,omitempty"`
+	StageVariables       map[string]string                   `json:"StageVariables,omitempty"`
+}
+
+// CfnResourceType returns AWS::ApiGatewayV2::Stage to implement the
+// ResourceProperties interface
+func (s APIGatewayV2Stage) CfnResourceType() string {
+	return "AWS::ApiGatewayV2::Stage"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s APIGatewayV2Stage) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// APIGatewayV2StageAccessLogSettings represents the AccessLogSettings
+// property of a Stage
+type APIGatewayV2StageAccessLogSettings struct {
+	DestinationArn *gocf.StringExpr `json:"DestinationArn,omitempty"`
+	Format         *gocf.StringExpr `json:"Format,omitempty"`
+}
+
+// APIGatewayV2StageRouteSettings represents the DefaultRouteSettings
+// property of a Stage
+type APIGatewayV2StageRouteSettings struct {
+	DataTraceEnabled       *gocf.BoolExpr    `json:"DataTraceEnabled,omitempty"`
+	DetailedMetricsEnabled *gocf.BoolExpr    `json:"DetailedMetricsEnabled,omitempty"`
+	LoggingLevel           *gocf.StringExpr  `json:"LoggingLevel,omitempty"`
+	ThrottlingBurstLimit   *gocf.IntegerExpr `json:"ThrottlingBurstLimit,omitempty"`
+	ThrottlingRateLimit    *gocf.IntegerExpr `json:"ThrottlingRateLimit,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Elastic Load Balancing V2
+////////////////////////////////////////////////////////////////////////////////
+
+// ElasticLoadBalancingV2Listener represents the
+// AWS::ElasticLoadBalancingV2::Listener resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-elasticloadbalancingv2-listener.html
+type ElasticLoadBalancingV2Listener struct {
+	DefaultActions  []ElasticLoadBalancingV2Action `json:"DefaultActions,omitempty"`
+	LoadBalancerArn *gocf.StringExpr               `json:"LoadBalancerArn,omitempty"`
+	Port            *gocf.IntegerExpr              `json:"Port,omitempty"`
+	Protocol        *gocf.StringExpr               `json:"Protocol,omitempty"`
+}
+
+// CfnResourceType returns AWS::ElasticLoadBalancingV2::Listener to implement
+// the ResourceProperties interface
+func (s ElasticLoadBalancingV2Listener) CfnResourceType() string {
+	return "AWS::ElasticLoadBalancingV2::Listener"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s ElasticLoadBalancingV2Listener) CfnResourceAttributes() []string {
+	return []string{"ListenerArn"}
+}
+
+// ElasticLoadBalancingV2ListenerRule represents the
+// AWS::ElasticLoadBalancingV2::ListenerRule resource, including the
+// condition configurations. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-elasticloadbalancingv2-listenerrule.html
+type ElasticLoadBalancingV2ListenerRule struct {
+	Actions     []ElasticLoadBalancingV2Action        `json:"Actions,omitempty"`
+	Conditions  []ElasticLoadBalancingV2RuleCondition `json:"Conditions,omitempty"`
+	ListenerArn *gocf.StringExpr                      `json:"ListenerArn,omitempty"`
+	Priority    *gocf.IntegerExpr                     `json:"Priority,omitempty"`
+}
+
+// CfnResourceType returns AWS::ElasticLoadBalancingV2::ListenerRule to
+// implement the ResourceProperties interface
+func (s ElasticLoadBalancingV2ListenerRule) CfnResourceType() string {
+	return "AWS::ElasticLoadBalancingV2::ListenerRule"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s ElasticLoadBalancingV2ListenerRule) CfnResourceAttributes() []string {
+	return []string{"IsDefault", "RuleArn"}
+}
+
+// ElasticLoadBalancingV2Action represents a listener or listener rule
+// action
+type ElasticLoadBalancingV2Action struct {
+	FixedResponseConfig *ElasticLoadBalancingV2FixedResponseConfig `json:"FixedResponseConfig,omitempty"`
+	TargetGroupArn      *gocf.StringExpr                           `json:"TargetGroupArn,omitempty"`
+	Type                *gocf.StringExpr                           `json:"Type,omitempty"`
+}
+
+// ElasticLoadBalancingV2FixedResponseConfig represents the response of a
+// fixed-response action
+type ElasticLoadBalancingV2FixedResponseConfig struct {
+	ContentType *gocf.StringExpr `json:"ContentType,omitempty"`
+	MessageBody *gocf.StringExpr `json:"MessageBody,omitempty"`
+	StatusCode  *gocf.StringExpr `json:"StatusCode,omitempty"`
+}
+
+// ElasticLoadBalancingV2RuleCondition represents a listener rule condition.
+// Each condition sets the Field and the matching configuration.
+type ElasticLoadBalancingV2RuleCondition struct {
+	Field                   *gocf.StringExpr                           `json:"Field,omitempty"`
+	HostHeaderConfig        *ElasticLoadBalancingV2RuleConditionValues `json:"HostHeaderConfig,omitempty"`
+	HTTPHeaderConfig        *ElasticLoadBalancingV2HTTPHeaderConfig    `json:"HttpHeaderConfig,omitempty"`
+	HTTPRequestMethodConfig *ElasticLoadBalancingV2RuleConditionValues `json:"HttpRequestMethodConfig,omitempty"`
+	PathPatternConfig       *ElasticLoadBalancingV2RuleConditionValues `json:"PathPatternConfig,omitempty"`
+}
+
+// ElasticLoadBalancingV2RuleConditionValues represents the values that a
+// host header, request method or path pattern condition matches
+type ElasticLoadBalancingV2RuleConditionValues struct {
+	Values *gocf.StringListExpr `json:"Values,omitempty"`
+}
+
+// ElasticLoadBalancingV2HTTPHeaderConfig represents the header name and
+// values that an http-header condition matches
+type ElasticLoadBalancingV2HTTPHeaderConfig struct {
+	HTTPHeaderName *gocf.StringExpr     `json:"HttpHeaderName,omitempty"`
+	Values         *gocf.StringListExpr `json:"Values,omitempty"`
+}
+
+// ElasticLoadBalancingV2TargetGroup represents the
+// AWS::ElasticLoadBalancingV2::TargetGroup resource, including the lambda
+// target type. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-elasticloadbalancingv2-targetgroup.html
+type ElasticLoadBalancingV2TargetGroup struct {
+	HealthCheckEnabled    *gocf.BoolExpr                               `json:"HealthCheckEnabled,omitempty"`
+	HealthCheckPath       *gocf.StringExpr                             `json:"HealthCheckPath,omitempty"`
+	Name                  *gocf.StringExpr                             `json:"Name,omitempty"`
+	TargetGroupAttributes []ElasticLoadBalancingV2TargetGroupAttribute `json:"TargetGroupAttributes,omitempty"`
+	TargetType            *gocf.StringExpr                             `json:"TargetType,omitempty"`
+	Targets               []ElasticLoadBalancingV2TargetDescription    `json:"Targets,omitempty"`
+}
+
+// CfnResourceType returns AWS::ElasticLoadBalancingV2::TargetGroup to
+// implement the ResourceProperties interface
+func (s ElasticLoadBalancingV2TargetGroup) CfnResourceType() string {
+	return "AWS::ElasticLoadBalancingV2::TargetGroup"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s ElasticLoadBalancingV2TargetGroup) CfnResourceAttributes() []string {
+	return []string{"LoadBalancerArns", "TargetGroupFullName", "TargetGroupName"}
+}
+
+// ElasticLoadBalancingV2TargetGroupAttribute represents a target group
+// attribute
+type ElasticLoadBalancingV2TargetGroupAttribute struct {
+	Key   *gocf.StringExpr `json:"Key,omitempty"`
+	Value *gocf.StringExpr `json:"Value,omitempty"`
+}
+
+// ElasticLoadBalancingV2TargetDescription represents a registered target
+type ElasticLoadBalancingV2TargetDescription struct {
+	ID *gocf.StringExpr `json:"Id,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Events
+////////////////////////////////////////////////////////////////////////////////
+
+// EventsArchive represents the AWS::Events::Archive resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-events-archive.html
+type EventsArchive struct {
+	ArchiveName   *gocf.StringExpr  `json:"ArchiveName,omitempty"`
+	Description   *gocf.StringExpr  `json:"Description,omitempty"`
+	EventPattern  interface{}       `json:"EventPattern,omitempty"`
+	RetentionDays *gocf.IntegerExpr `json:"RetentionDays,omitempty"`
+	SourceArn     *gocf.StringExpr  `json:"SourceArn,omitempty"`
+}
+
+// CfnResourceType returns AWS::Events::Archive to implement the
+// ResourceProperties interface
+func (s EventsArchive) CfnResourceType() string {
+	return "AWS::Events::Archive"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s EventsArchive) CfnResourceAttributes() []string {
+	return []string{"Arn"}
+}
+
+// EventsEventBus represents the AWS::Events::EventBus resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-events-eventbus.html
+type EventsEventBus struct {
+	EventSourceName *gocf.StringExpr `json:"EventSourceName,omitempty"`
+	Name            *gocf.StringExpr `json:"Name,omitempty"`
+}
+
+// CfnResourceType returns AWS::Events::EventBus to implement the
+// ResourceProperties interface
+func (s EventsEventBus) CfnResourceType() string {
+	return "AWS::Events::EventBus"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s EventsEventBus) CfnResourceAttributes() []string {
+	return []string{"Arn", "Name", "Policy"}
+}
+
+// EventsEventBusPolicy represents the AWS::Events::EventBusPolicy resource,
+// which grants other accounts permission to put events to an event bus. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-events-eventbuspolicy.html
+type EventsEventBusPolicy struct {
+	Action       *gocf.StringExpr               `json:"Action,omitempty"`
+	Condition    *EventsEventBusPolicyCondition `json:"Condition,omitempty"`
+	EventBusName *gocf.StringExpr               `json:"EventBusName,omitempty"`
+	Principal    *gocf.StringExpr               `json:"Principal,omitempty"`
+	StatementID  *gocf.StringExpr               `json:"StatementId,omitempty"`
+}
+
+// CfnResourceType returns AWS::Events::EventBusPolicy to implement the
+// ResourceProperties interface
+func (s EventsEventBusPolicy) CfnResourceType() string {
+	return "AWS::Events::EventBusPolicy"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s EventsEventBusPolicy) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// EventsEventBusPolicyCondition represents the condition that limits the
+// principals of an event bus policy, for example to an AWS Organization
+type EventsEventBusPolicyCondition struct {
+	Key   *gocf.StringExpr `json:"Key,omitempty"`
+	Type  *gocf.StringExpr `json:"Type,omitempty"`
+	Value *gocf.StringExpr `json:"Value,omitempty"`
+}
+
+// EventsRule represents the AWS::Events::Rule resource, including the
+// EventBusName property. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-events-rule.html
+type EventsRule struct {
+	Description        *gocf.StringExpr   `json:"Description,omitempty"`
+	EventBusName       *gocf.StringExpr   `json:"EventBusName,omitempty"`
+	EventPattern       interface{}        `json:"EventPattern,omitempty"`
+	Name               *gocf.StringExpr   `json:"Name,omitempty"`
+	RoleArn            *gocf.StringExpr   `json:"RoleArn,omitempty"`
+	ScheduleExpression *gocf.StringExpr   `json:"ScheduleExpression,omitempty"`
+	State              *gocf.StringExpr   `json:"State,omitempty"`
+	Targets            []EventsRuleTarget `json:"Targets,omitempty"`
+}
+
+// CfnResourceType returns AWS::Events::Rule to implement the
+// ResourceProperties interface
+func (s EventsRule) CfnResourceType() string {
+	return "AWS::Events::Rule"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s EventsRule) CfnResourceAttributes() []string {
+	return []string{"Arn"}
+}
+
+// EventsRuleTarget represents a rule target. At most one of Input,
+// InputPath and InputTransformer may be set.
+type EventsRuleTarget struct {
+	Arn              *gocf.StringExpr            `json:"Arn,omitempty"`
+	ID               *gocf.StringExpr            `json:"Id,omitempty"`
+	Input            *gocf.StringExpr            `json:"Input,omitempty"`
+	InputPath        *gocf.StringExpr            `json:"InputPath,omitempty"`
+	InputTransformer *EventsRuleInputTransformer `json:"InputTransformer,omitempty"`
+	RoleArn          *gocf.StringExpr            `json:"RoleArn,omitempty"`
+}
+
+// EventsRuleInputTransformer represents the InputTransformer property of a
+// rule target
+type EventsRuleInputTransformer struct {
+	InputPathsMap map[string]string `json:"InputPathsMap,omitempty"`
+	InputTemplate *gocf.StringExpr  `json:"InputTemplate,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// IoT
+////////////////////////////////////////////////////////////////////////////////
+
+// IoTTopicRule represents the AWS::IoT::TopicRule resource, including the
+// ErrorAction property. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-iot-topicrule.html
+type IoTTopicRule struct {
+	RuleName         *gocf.StringExpr     `json:"RuleName,omitempty"`
+	TopicRulePayload *IoTTopicRulePayload `json:"TopicRulePayload,omitempty"`
+}
+
+// CfnResourceType returns AWS::IoT::TopicRule to implement the
+// ResourceProperties interface
+func (s IoTTopicRule) CfnResourceType() string {
+	return "AWS::IoT::TopicRule"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s IoTTopicRule) CfnResourceAttributes() []string {
+	return []string{"Arn"}
+}
+
+// IoTTopicRulePayload represents the TopicRulePayload property of a topic
+// rule
+type IoTTopicRulePayload struct {
+	Actions          []IoTTopicRuleAction `json:"Actions,omitempty"`
+	AwsIotSQLVersion *gocf.StringExpr     `json:"AwsIotSqlVersion,omitempty"`
+	Description      *gocf.StringExpr     `json:"Description,omitempty"`
+	ErrorAction      *IoTTopicRuleAction  `json:"ErrorAction,omitempty"`
+	RuleDisabled     *gocf.BoolExpr       `json:"RuleDisabled,omitempty"`
+	SQL              *gocf.StringExpr     `json:"Sql,omitempty"`
+}
+
+// IoTTopicRuleAction represents a topic rule action. Each action sets one
+// of the action types.
+type IoTTopicRuleAction struct {
+	CloudwatchLogs *IoTTopicRuleCloudwatchLogsAction `json:"CloudwatchLogs,omitempty"`
+	Lambda         *IoTTopicRuleLambdaAction         `json:"Lambda,omitempty"`
+	S3             *IoTTopicRuleS3Action             `json:"S3,omitempty"`
+	SNS            *IoTTopicRuleSNSAction            `json:"Sns,omitempty"`
+	SQS            *IoTTopicRuleSQSAction            `json:"Sqs,omitempty"`
+}
+
+// IoTTopicRuleCloudwatchLogsAction represents an action that writes the
+// message to a CloudWatch Logs log group
+type IoTTopicRuleCloudwatchLogsAction struct {
+	LogGroupName *gocf.StringExpr `json:"LogGroupName,omitempty"`
+	RoleArn      *gocf.StringExpr `json:"RoleArn,omitempty"`
+}
+
+// IoTTopicRuleLambdaAction represents an action that invokes a Lambda
+// function
+type IoTTopicRuleLambdaAction struct {
+	FunctionArn *gocf.StringExpr `json:"FunctionArn,omitempty"`
+}
+
+// IoTTopicRuleS3Action represents an action that writes the message to an
+// S3 object
+type IoTTopicRuleS3Action struct {
+	BucketName *gocf.StringExpr `json:"BucketName,omitempty"`
+	Key        *gocf.StringExpr `json:"Key,omitempty"`
+	RoleArn    *gocf.StringExpr `json:"RoleArn,omitempty"`
+}
+
+// IoTTopicRuleSNSAction represents an action that publishes the message to
+// an SNS topic
+type IoTTopicRuleSNSAction struct {
+	MessageFormat *gocf.StringExpr `json:"MessageFormat,omitempty"`
+	RoleArn       *gocf.StringExpr `json:"RoleArn,omitempty"`
+	TargetArn     *gocf.StringExpr `json:"TargetArn,omitempty"`
+}
+
+// IoTTopicRuleSQSAction represents an action that sends the message to an
+// SQS queue
+type IoTTopicRuleSQSAction struct {
+	QueueURL  *gocf.StringExpr `json:"QueueUrl,omitempty"`
+	RoleArn   *gocf.StringExpr `json:"RoleArn,omitempty"`
+	UseBase64 *gocf.BoolExpr   `json:"UseBase64,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Lambda
+////////////////////////////////////////////////////////////////////////////////
+
+// LambdaEventInvokeConfig represents the AWS::Lambda::EventInvokeConfig
+// resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-lambda-eventinvokeconfig.html
+type LambdaEventInvokeConfig struct {
+	DestinationConfig        *LambdaEventInvokeConfigDestinationConfig `json:"DestinationConfig,omitempty"`
+	FunctionName             *gocf.StringExpr                          `json:"FunctionName,omitempty"`
+	MaximumEventAgeInSeconds *gocf.IntegerExpr                         `json:"MaximumEventAgeInSeconds,omitempty"`
+	MaximumRetryAttempts     *gocf.IntegerExpr                         `json:"MaximumRetryAttempts,omitempty"`
+	Qualifier                *gocf.StringExpr                          `json:"Qualifier,omitempty"`
+}
+
+// CfnResourceType returns AWS::Lambda::EventInvokeConfig to implement the
+// ResourceProperties interface
+func (s LambdaEventInvokeConfig) CfnResourceType() string {
+	return "AWS::Lambda::EventInvokeConfig"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s LambdaEventInvokeConfig) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// LambdaEventInvokeConfigDestinationConfig represents the destinations of
+// asynchronous invocation records
+type LambdaEventInvokeConfigDestinationConfig struct {
+	OnFailure *LambdaEventInvokeConfigDestination `json:"OnFailure,omitempty"`
+	OnSuccess *LambdaEventInvokeConfigDestination `json:"OnSuccess,omitempty"`
+}
+
+// LambdaEventInvokeConfigDestination represents an asynchronous invocation
+// destination
+type LambdaEventInvokeConfigDestination struct {
+	Destination *gocf.StringExpr `json:"Destination,omitempty"`
+}
+
+// LambdaEventSourceMapping represents the AWS::Lambda::EventSourceMapping
+// resource, including the stream and queue error handling properties and
+// the Apache Kafka properties. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-lambda-eventsourcemapping.html
+type LambdaEventSourceMapping struct {
+	AmazonManagedKafkaEventSourceConfig *LambdaEventSourceMappingKafkaConfig         `json:"AmazonManagedKafkaEventSourceConfig,omitempty"`
+	BatchSize                           *gocf.IntegerExpr                            `json:"BatchSize,omitempty"`
+	BisectBatchOnFunctionError          *gocf.BoolExpr                               `json:"BisectBatchOnFunctionError,omitempty"`
+	DestinationConfig                   *LambdaEventSourceMappingDestinationConfig   `json:"DestinationConfig,omitempty"`
+	Enabled                             *gocf.BoolExpr                               `json:"Enabled,omitempty"`
+	EventSourceArn                      *gocf.StringExpr                             `json:"EventSourceArn,omitempty"`
+	FilterCriteria                      *LambdaEventSourceMappingFilterCriteria      `json:"FilterCriteria,omitempty"`
+	FunctionName                        *gocf.StringExpr                             `json:"FunctionName,omitempty"`
+	FunctionResponseTypes               *gocf.StringListExpr                         `json:"FunctionResponseTypes,omitempty"`
+	MaximumBatchingWindowInSeconds      *gocf.IntegerExpr                            `json:"MaximumBatchingWindowInSeconds,omitempty"`
+	MaximumRecordAgeInSeconds           *gocf.IntegerExpr                            `json:"MaximumRecordAgeInSeconds,omitempty"`
+	MaximumRetryAttempts                *gocf.IntegerExpr                            `json:"MaximumRetryAttempts,omitempty"`
+	ParallelizationFactor               *gocf.IntegerExpr                            `json:"ParallelizationFactor,omitempty"`
+	SelfManagedEventSource              *LambdaEventSourceMappingSelfManagedSource   `json:"SelfManagedEventSource,omitempty"`
+	SelfManagedKafkaEventSourceConfig   *LambdaEventSourceMappingKafkaConfig         `json:"SelfManagedKafkaEventSourceConfig,omitempty"`
+	SourceAccessConfigurations          []LambdaEventSourceMappingSourceAccessConfig `json:"SourceAccessConfigurations,omitempty"`
+	StartingPosition                    *gocf.StringExpr                             `json:"StartingPosition,omitempty"`
+	StartingPositionTimestamp           float64                                      `json:"StartingPositionTimestamp,omitempty"`
+	Topics                              *gocf.StringListExpr                         `json:"Topics,omitempty"`
+}
+
+// CfnResourceType returns AWS::Lambda::EventSourceMapping to implement the
+// ResourceProperties interface
+func (s LambdaEventSourceMapping) CfnResourceType() string {
+	return "AWS::Lambda::EventSourceMapping"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s LambdaEventSourceMapping) CfnResourceAttributes() []string {
+	return []string{}
+}
+
+// LambdaEventSourceMappingDestinationConfig represents the DestinationConfig
+// property of an EventSourceMapping
+type LambdaEventSourceMappingDestinationConfig struct {
+	OnFailure *LambdaEventSourceMappingOnFailure `json:"OnFailure,omitempty"`
+}
+
+// LambdaEventSourceMappingOnFailure represents the destination for
+// discarded batches
+type LambdaEventSourceMappingOnFailure struct {
+	Destination *gocf.StringExpr `json:"Destination,omitempty"`
+}
+
+// LambdaEventSourceMappingFilterCriteria represents the FilterCriteria
+// property of an EventSourceMapping
+type LambdaEventSourceMappingFilterCriteria struct {
+	Filters []LambdaEventSourceMappingFilter `json:"Filters,omitempty"`
+}
+
+// LambdaEventSourceMappingFilter represents a single event filter pattern
+type LambdaEventSourceMappingFilter struct {
+	Pattern string `json:"Pattern,omitempty"`
+}
+
+// LambdaEventSourceMappingKafkaConfig represents the Amazon MSK or
+// self-managed Apache Kafka configuration of an EventSourceMapping
+type LambdaEventSourceMappingKafkaConfig struct {
+	ConsumerGroupID *gocf.StringExpr `json:"ConsumerGroupId,omitempty"`
+}
+
+// LambdaEventSourceMappingSelfManagedSource represents the
+// SelfManagedEventSource property of an EventSourceMapping
+type LambdaEventSourceMappingSelfManagedSource struct {
+	Endpoints *LambdaEventSourceMappingEndpoints `json:"Endpoints,omitempty"`
+}
+
+// LambdaEventSourceMappingEndpoints represents the self-managed Apache
+// Kafka bootstrap servers
+type LambdaEventSourceMappingEndpoints struct {
+	KafkaBootstrapServers *gocf.StringListExpr `json:"KafkaBootstrapServers,omitempty"`
+}
+
+// LambdaEventSourceMappingSourceAccessConfig represents the authentication
+// secret or VPC setting of an EventSourceMapping
+type LambdaEventSourceMappingSourceAccessConfig struct {
+	Type *gocf.StringExpr `json:"Type,omitempty"`
+	URI  *gocf.StringExpr `json:"URI,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// S3
+////////////////////////////////////////////////////////////////////////////////
+
+// S3AccessPoint represents the AWS::S3::AccessPoint resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-s3-accesspoint.html
+type S3AccessPoint struct {
+	Bucket                         *gocf.StringExpr                `json:"Bucket,omitempty"`
+	Name                           *gocf.StringExpr                `json:"Name,omitempty"`
+	Policy                         interface{}                     `json:"Policy,omitempty"`
+	PublicAccessBlockConfiguration *S3AccessPointPublicAccessBlock `json:"PublicAccessBlockConfiguration,omitempty"`
+	VpcConfiguration               *S3AccessPointVpcConfiguration  `json:"VpcConfiguration,omitempty"`
+}
+
+// CfnResourceType returns AWS::S3::AccessPoint to implement the
+// ResourceProperties interface
+func (s S3AccessPoint) CfnResourceType() string {
+	return "AWS::S3::AccessPoint"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s S3AccessPoint) CfnResourceAttributes() []string {
+	return []string{"Alias", "Arn", "Name", "NetworkOrigin"}
+}
+
+// S3AccessPointPublicAccessBlock represents the public access settings of
+// an access point
+type S3AccessPointPublicAccessBlock struct {
+	BlockPublicAcls       *gocf.BoolExpr `json:"BlockPublicAcls,omitempty"`
+	BlockPublicPolicy     *gocf.BoolExpr `json:"BlockPublicPolicy,omitempty"`
+	IgnorePublicAcls      *gocf.BoolExpr `json:"IgnorePublicAcls,omitempty"`
+	RestrictPublicBuckets *gocf.BoolExpr `json:"RestrictPublicBuckets,omitempty"`
+}
+
+// S3AccessPointVpcConfiguration restricts an access point to a VPC
+type S3AccessPointVpcConfiguration struct {
+	VpcID *gocf.StringExpr `json:"VpcId,omitempty"`
+}
+
+// S3ObjectLambdaAccessPoint represents the AWS::S3ObjectLambda::AccessPoint
+// resource. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-s3objectlambda-accesspoint.html
+type S3ObjectLambdaAccessPoint struct {
+	Name                      *gocf.StringExpr             `json:"Name,omitempty"`
+	ObjectLambdaConfiguration *S3ObjectLambdaConfiguration `json:"ObjectLambdaConfiguration,omitempty"`
+}
+
+// CfnResourceType returns AWS::S3ObjectLambda::AccessPoint to implement the
+// ResourceProperties interface
+func (s S3ObjectLambdaAccessPoint) CfnResourceType() string {
+	return "AWS::S3ObjectLambda::AccessPoint"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s S3ObjectLambdaAccessPoint) CfnResourceAttributes() []string {
+	return []string{"Arn", "CreationDate"}
+}
+
+// S3ObjectLambdaConfiguration represents the supporting access point and
+// the transformations of an Object Lambda access point
+type S3ObjectLambdaConfiguration struct {
+	AllowedFeatures              *gocf.StringListExpr                 `json:"AllowedFeatures,omitempty"`
+	CloudWatchMetricsEnabled     *gocf.BoolExpr                       `json:"CloudWatchMetricsEnabled,omitempty"`
+	SupportingAccessPoint        *gocf.StringExpr                     `json:"SupportingAccessPoint,omitempty"`
+	TransformationConfigurations []S3ObjectLambdaTransformationConfig `json:"TransformationConfigurations,omitempty"`
+}
+
+// S3ObjectLambdaTransformationConfig represents the S3 actions that are
+// transformed by a lambda function
+type S3ObjectLambdaTransformationConfig struct {
+	Actions               *gocf.StringListExpr                 `json:"Actions,omitempty"`
+	ContentTransformation *S3ObjectLambdaContentTransformation `json:"ContentTransformation,omitempty"`
+}
+
+// S3ObjectLambdaContentTransformation represents the lambda function that
+// transforms the object content
+type S3ObjectLambdaContentTransformation struct {
+	AwsLambda *S3ObjectLambdaAwsLambda `json:"AwsLambda,omitempty"`
+}
+
+// S3ObjectLambdaAwsLambda represents the transformation function and its
+// optional payload
+type S3ObjectLambdaAwsLambda struct {
+	FunctionArn     *gocf.StringExpr `json:"FunctionArn,omitempty"`
+	FunctionPayload *gocf.StringExpr `json:"FunctionPayload,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Step Functions
+////////////////////////////////////////////////////////////////////////////////
+
+// StepFunctionsStateMachine represents the AWS::StepFunctions::StateMachine
+// resource, including the properties added for Express workflows. See
+// https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/aws-resource-stepfunctions-statemachine.html
+type StepFunctionsStateMachine struct {
+	DefinitionString     *gocf.StringExpr                  `json:"DefinitionString,omitempty"`
+	LoggingConfiguration *StepFunctionsStateMachineLogging `json:"LoggingConfiguration,omitempty"`
+	RoleArn              *gocf.StringExpr                  `json:"RoleArn,omitempty"`
+	StateMachineName     *gocf.StringExpr                  `json:"StateMachineName,omitempty"`
+	StateMachineType     *gocf.StringExpr                  `json:"StateMachineType,omitempty"`
+	Tags                 []StepFunctionsStateMachineTag    `json:"Tags,omitempty"`
+	TracingConfiguration *StepFunctionsStateMachineTracing `json:"TracingConfiguration,omitempty"`
+}
+
+// CfnResourceType returns AWS::StepFunctions::StateMachine to implement
+// the ResourceProperties interface
+func (s StepFunctionsStateMachine) CfnResourceType() string {
+	return "AWS::StepFunctions::StateMachine"
+}
+
+// CfnResourceAttributes returns the attributes produced by this resource
+func (s StepFunctionsStateMachine) CfnResourceAttributes() []string {
+	return []string{"Arn", "Name"}
+}
+
+// StepFunctionsStateMachineLogging represents the LoggingConfiguration
+// property of a StateMachine
+type StepFunctionsStateMachineLogging struct {
+	Destinations         []StepFunctionsStateMachineLogDestination `json:"Destinations,omitempty"`
+	IncludeExecutionData *gocf.BoolExpr                            `json:"IncludeExecutionData,omitempty"`
+	Level                *gocf.StringExpr                          `json:"Level,omitempty"`
+}
+
+// StepFunctionsStateMachineLogDestination represents a log destination
+type StepFunctionsStateMachineLogDestination struct {
+	CloudWatchLogsLogGroup *StepFunctionsStateMachineLogGroup `json:"CloudWatchLogsLogGroup,omitempty"`
+}
+
+// StepFunctionsStateMachineLogGroup represents the CloudWatch Logs log group
+// destination
+type StepFunctionsStateMachineLogGroup struct {
+	LogGroupArn *gocf.StringExpr `json:"LogGroupArn,omitempty"`
+}
+
+// StepFunctionsStateMachineTracing represents the TracingConfiguration
+// property of a StateMachine
+type StepFunctionsStateMachineTracing struct {
+	Enabled *gocf.BoolExpr `json:"Enabled,omitempty"`
+}
+
+// StepFunctionsStateMachineTag represents a StateMachine tag
+type StepFunctionsStateMachineTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
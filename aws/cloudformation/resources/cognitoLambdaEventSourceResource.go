@@ -0,0 +1,134 @@
+package resources
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// CognitoLambdaEventSourceResourceRequest defines the request properties to
+// configure the LambdaConfig triggers of a Cognito user pool
+type CognitoLambdaEventSourceResourceRequest struct {
+	LambdaTargetArn *gocf.StringExpr
+	UserPoolID      *gocf.StringExpr
+	// Triggers are the LambdaConfig field names (eg, PreSignUp)
+	Triggers []string
+}
+
+// CognitoLambdaEventSourceResource updates the LambdaConfig of a user pool
+// that's provisioned outside of the stack
+type CognitoLambdaEventSourceResource struct {
+	gocf.CloudFormationCustomResource
+	CognitoLambdaEventSourceResourceRequest
+}
+
+// userPoolUpdateInput returns the UpdateUserPool input that preserves the
+// current pool settings. UpdateUserPool resets the settings that aren't
+// included in the request to their default values.
+func userPoolUpdateInput(userPool *cognitoidentityprovider.UserPoolType) *cognitoidentityprovider.UpdateUserPoolInput {
+	updateInput := &cognitoidentityprovider.UpdateUserPoolInput{
+		AdminCreateUserConfig:       userPool.AdminCreateUserConfig,
+		AutoVerifiedAttributes:      userPool.AutoVerifiedAttributes,
+		DeviceConfiguration:         userPool.DeviceConfiguration,
+		EmailConfiguration:          userPool.EmailConfiguration,
+		EmailVerificationMessage:    userPool.EmailVerificationMessage,
+		EmailVerificationSubject:    userPool.EmailVerificationSubject,
+		LambdaConfig:                userPool.LambdaConfig,
+		MfaConfiguration:            userPool.MfaConfiguration,
+		Policies:                    userPool.Policies,
+		SmsAuthenticationMessage:    userPool.SmsAuthenticationMessage,
+		SmsConfiguration:            userPool.SmsConfiguration,
+		SmsVerificationMessage:      userPool.SmsVerificationMessage,
+		UserPoolAddOns:              userPool.UserPoolAddOns,
+		UserPoolId:                  userPool.Id,
+		UserPoolTags:                userPool.UserPoolTags,
+		VerificationMessageTemplate: userPool.VerificationMessageTemplate,
+	}
+	if updateInput.LambdaConfig == nil {
+		updateInput.LambdaConfig = &cognitoidentityprovider.LambdaConfigType{}
+	}
+	// The day count is deprecated and can't be sent together with
+	// TemporaryPasswordValidityDays
+	if updateInput.AdminCreateUserConfig != nil &&
+		updateInput.Policies != nil &&
+		updateInput.Policies.PasswordPolicy != nil {
+		updateInput.AdminCreateUserConfig.UnusedAccountValidityDays = nil
+	}
+	return updateInput
+}
+
+func (command CognitoLambdaEventSourceResource) updateRegistration(isTargetActive bool,
+	session *session.Session,
+	event *CloudFormationLambdaEvent,
+	logger *logrus.Logger) (map[string]interface{}, error) {
+
+	unmarshalErr := json.Unmarshal(event.ResourceProperties, &command)
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	cognitoSvc := cognitoidentityprovider.New(session)
+	describeResp, describeErr := cognitoSvc.DescribeUserPool(&cognitoidentityprovider.DescribeUserPoolInput{
+		UserPoolId: aws.String(command.UserPoolID.Literal),
+	})
+	if describeErr != nil {
+		return nil, describeErr
+	}
+	updateInput := userPoolUpdateInput(describeResp.UserPool)
+	lambdaConfig := reflect.ValueOf(updateInput.LambdaConfig).Elem()
+	for _, eachTrigger := range command.Triggers {
+		triggerField := lambdaConfig.FieldByName(eachTrigger)
+		if !triggerField.IsValid() {
+			return nil, errors.Errorf("unsupported Cognito user pool trigger: %s", eachTrigger)
+		}
+		existingArn, _ := triggerField.Interface().(*string)
+		logger.WithFields(logrus.Fields{
+			"UserPoolID": command.UserPoolID.Literal,
+			"Trigger":    eachTrigger,
+			"Existing":   aws.StringValue(existingArn),
+			"LambdaArn":  command.LambdaTargetArn.Literal,
+			"Active":     isTargetActive,
+		}).Info("Updating Cognito user pool trigger")
+
+		if isTargetActive {
+			triggerField.Set(reflect.ValueOf(aws.String(command.LambdaTargetArn.Literal)))
+		} else if aws.StringValue(existingArn) == command.LambdaTargetArn.Literal {
+			// Only remove the triggers that still refer to this function
+			triggerField.Set(reflect.Zero(triggerField.Type()))
+		}
+	}
+	_, updateErr := cognitoSvc.UpdateUserPool(updateInput)
+	return nil, updateErr
+}
+
+// IAMPrivileges returns the IAM privs for this custom action
+func (command *CognitoLambdaEventSourceResource) IAMPrivileges() []string {
+	return []string{"cognito-idp:DescribeUserPool",
+		"cognito-idp:UpdateUserPool"}
+}
+
+// Create implements the custom resource create operation
+func (command CognitoLambdaEventSourceResource) Create(awsSession *session.Session,
+	event *CloudFormationLambdaEvent,
+	logger *logrus.Logger) (map[string]interface{}, error) {
+	return command.updateRegistration(true, awsSession, event, logger)
+}
+
+// Update implements the custom resource update operation
+func (command CognitoLambdaEventSourceResource) Update(awsSession *session.Session,
+	event *CloudFormationLambdaEvent,
+	logger *logrus.Logger) (map[string]interface{}, error) {
+	return command.updateRegistration(true, awsSession, event, logger)
+}
+
+// Delete implements the custom resource delete operation
+func (command CognitoLambdaEventSourceResource) Delete(awsSession *session.Session,
+	event *CloudFormationLambdaEvent,
+	logger *logrus.Logger) (map[string]interface{}, error) {
+	return command.updateRegistration(false, awsSession, event, logger)
+}
@@ -49,6 +49,8 @@ var (
 	SESLambdaEventSource = cloudFormationResourceType("SESEventSource")
 	// CloudWatchLogsLambdaEventSource is the typename for SESLambdaEventSourceResource
 	CloudWatchLogsLambdaEventSource = cloudFormationResourceType("CloudWatchLogsEventSource")
+	// CognitoLambdaEventSource is the typename for CognitoLambdaEventSourceResource
+	CognitoLambdaEventSource = cloudFormationResourceType("CognitoEventSource")
 	// ZipToS3Bucket is the typename for ZipToS3Bucket
 	ZipToS3Bucket = cloudFormationResourceType("ZipToS3Bucket")
 	// S3ArtifactPublisher is the typename for publishing an S3Artifact
@@ -63,6 +65,8 @@ func customTypeProvider(resourceType string) gocf.ResourceProperties {
 		return &S3LambdaEventSourceResource{}
 	case CloudWatchLogsLambdaEventSource:
 		return &CloudWatchLogsLambdaEventSourceResource{}
+	case CognitoLambdaEventSource:
+		return &CognitoLambdaEventSourceResource{}
 	case CodeCommitLambdaEventSource:
 		return &CodeCommitLambdaEventSourceResource{}
 	case SNSLambdaEventSource:
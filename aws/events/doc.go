@@ -1,4 +1,7 @@
 /*Package events defines event types that are un/marshalled to and from other
 AWS services. It is a provisional namespace to fill gaps that exist as new
-event types are officially published by AWS via https://github.com/aws/aws-lambda-go/tree/master/events */
+event types are officially published by AWS via https://github.com/aws/aws-lambda-go/tree/master/events
+
+It also includes the EventBusPublisher, which publishes typed events to an
+EventBridge event bus. */
 package events
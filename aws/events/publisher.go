@@ -0,0 +1,164 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	spartaSchema "github.com/mweagle/Sparta/aws/schema"
+)
+
+// maxPutEventsEntries is the maximum number of entries in a single PutEvents
+// request
+const maxPutEventsEntries = 10
+
+// eventBusDetailType is a registered detail type and the JSON Schema that
+// published details must satisfy
+type eventBusDetailType struct {
+	name   string
+	schema *spartaSchema.JSONSchema
+}
+
+// EventBusPublisher publishes typed events to an EventBridge event bus. The
+// detail-type of each event is determined by the Go type of the detail
+// value, which must be registered with RegisterDetailType.
+type EventBusPublisher struct {
+	eventBusName string
+	source       string
+	detailTypes  map[reflect.Type]*eventBusDetailType
+	svc          eventbridgeiface.EventBridgeAPI
+}
+
+// NewEventBusPublisher returns a publisher for the event bus. The eventBusName
+// is the name or ARN of the event bus, and source is the value of the
+// event's `source` field.
+func NewEventBusPublisher(awsSession *session.Session,
+	eventBusName string,
+	source string) *EventBusPublisher {
+	return &EventBusPublisher{
+		eventBusName: eventBusName,
+		source:       source,
+		detailTypes:  make(map[reflect.Type]*eventBusDetailType),
+		svc:          eventbridge.New(awsSession),
+	}
+}
+
+// EventBusName returns the name or ARN of the event bus
+func (publisher *EventBusPublisher) EventBusName() string {
+	return publisher.eventBusName
+}
+
+// RegisterDetailType associates the detail-type name with the Go type of
+// the sample value. Published values of that type are validated against
+// the JSON Schema reflected from the type. See
+// aws/schema.NewJSONSchema for the supported struct tags.
+func (publisher *EventBusPublisher) RegisterDetailType(detailType string,
+	sample interface{}) error {
+	if detailType == "" {
+		return fmt.Errorf("event bus detail-type must not be empty")
+	}
+	schema, schemaErr := spartaSchema.NewJSONSchema(sample)
+	if schemaErr != nil {
+		return schemaErr
+	}
+	sampleType := indirectType(reflect.TypeOf(sample))
+	if existing, exists := publisher.detailTypes[sampleType]; exists {
+		return fmt.Errorf("type %s is already registered as detail-type: %s",
+			sampleType,
+			existing.name)
+	}
+	for _, eachDetailType := range publisher.detailTypes {
+		if eachDetailType.name == detailType {
+			return fmt.Errorf("detail-type %s is already registered", detailType)
+		}
+	}
+	publisher.detailTypes[sampleType] = &eventBusDetailType{
+		name:   detailType,
+		schema: schema,
+	}
+	return nil
+}
+
+// PutEvents publishes the detail values to the event bus. Each value must
+// be of a registered type and satisfy its JSON Schema. Values are published
+// in batches of up to 10 events. An error is returned if any entry could
+// not be published.
+func (publisher *EventBusPublisher) PutEvents(ctx context.Context,
+	details ...interface{}) error {
+	entries := make([]*eventbridge.PutEventsRequestEntry, 0, len(details))
+	for _, eachDetail := range details {
+		entry, entryErr := publisher.requestEntry(eachDetail)
+		if entryErr != nil {
+			return entryErr
+		}
+		entries = append(entries, entry)
+	}
+	var failures []string
+	for len(entries) != 0 {
+		batchSize := len(entries)
+		if batchSize > maxPutEventsEntries {
+			batchSize = maxPutEventsEntries
+		}
+		putEventsResp, putEventsErr := publisher.svc.PutEventsWithContext(ctx,
+			&eventbridge.PutEventsInput{
+				Entries: entries[0:batchSize],
+			})
+		if putEventsErr != nil {
+			return putEventsErr
+		}
+		if aws.Int64Value(putEventsResp.FailedEntryCount) != 0 {
+			for eachIndex, eachResult := range putEventsResp.Entries {
+				if eachResult.ErrorCode != nil {
+					failures = append(failures, fmt.Sprintf("%s (%s): %s",
+						aws.StringValue(entries[eachIndex].DetailType),
+						aws.StringValue(eachResult.ErrorCode),
+						aws.StringValue(eachResult.ErrorMessage)))
+				}
+			}
+		}
+		entries = entries[batchSize:]
+	}
+	if len(failures) != 0 {
+		return fmt.Errorf("failed to publish events: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (publisher *EventBusPublisher) requestEntry(detail interface{}) (*eventbridge.PutEventsRequestEntry, error) {
+	if detail == nil {
+		return nil, fmt.Errorf("event bus detail must not be nil")
+	}
+	detailType, exists := publisher.detailTypes[indirectType(reflect.TypeOf(detail))]
+	if !exists {
+		return nil, fmt.Errorf("type %T is not a registered detail-type for event bus: %s",
+			detail,
+			publisher.eventBusName)
+	}
+	detailJSON, detailJSONErr := json.Marshal(detail)
+	if detailJSONErr != nil {
+		return nil, detailJSONErr
+	}
+	validateErr := detailType.schema.ValidateJSON(detailJSON)
+	if validateErr != nil {
+		return nil, fmt.Errorf("invalid %s detail: %s", detailType.name, validateErr)
+	}
+	return &eventbridge.PutEventsRequestEntry{
+		EventBusName: aws.String(publisher.eventBusName),
+		Source:       aws.String(publisher.source),
+		DetailType:   aws.String(detailType.name),
+		Detail:       aws.String(string(detailJSON)),
+	}, nil
+}
+
+func indirectType(valueType reflect.Type) reflect.Type {
+	for valueType != nil && valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	return valueType
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+)
+
+type testOrderPlaced struct {
+	OrderID string  `json:"orderId" validate:"required"`
+	Total   float64 `json:"total" validate:"min=0"`
+}
+
+type mockEventBridge struct {
+	eventbridgeiface.EventBridgeAPI
+	inputs []*eventbridge.PutEventsInput
+}
+
+func (mock *mockEventBridge) PutEventsWithContext(ctx aws.Context,
+	input *eventbridge.PutEventsInput,
+	opts ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	mock.inputs = append(mock.inputs, input)
+	return &eventbridge.PutEventsOutput{
+		FailedEntryCount: aws.Int64(0),
+	}, nil
+}
+
+func TestEventBusPublisher(t *testing.T) {
+	mockSvc := &mockEventBridge{}
+	publisher := &EventBusPublisher{
+		eventBusName: "orders",
+		source:       "com.example.orders",
+		detailTypes:  make(map[reflect.Type]*eventBusDetailType),
+		svc:          mockSvc,
+	}
+	registerErr := publisher.RegisterDetailType("OrderPlaced", testOrderPlaced{})
+	if registerErr != nil {
+		t.Fatal(registerErr)
+	}
+	var details []interface{}
+	for i := 0; i != 12; i++ {
+		details = append(details, &testOrderPlaced{OrderID: "order", Total: 10})
+	}
+	putErr := publisher.PutEvents(context.Background(), details...)
+	if putErr != nil {
+		t.Fatal(putErr)
+	}
+	if len(mockSvc.inputs) != 2 ||
+		len(mockSvc.inputs[0].Entries) != maxPutEventsEntries ||
+		len(mockSvc.inputs[1].Entries) != 2 {
+		t.Fatalf("Unexpected PutEvents batches: %#v", mockSvc.inputs)
+	}
+	entry := mockSvc.inputs[0].Entries[0]
+	if aws.StringValue(entry.DetailType) != "OrderPlaced" ||
+		aws.StringValue(entry.EventBusName) != "orders" ||
+		aws.StringValue(entry.Detail) != `{"orderId":"order","total":10}` {
+		t.Fatalf("Unexpected PutEvents entry: %#v", entry)
+	}
+
+	// Unregistered types and invalid details are rejected
+	if publisher.PutEvents(context.Background(), map[string]string{}) == nil {
+		t.Fatal("Failed to reject unregistered detail type")
+	}
+	if publisher.PutEvents(context.Background(), testOrderPlaced{Total: -1}) == nil {
+		t.Fatal("Failed to reject invalid detail")
+	}
+}
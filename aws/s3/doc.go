@@ -20,5 +20,9 @@ into source-specific data.  Example:
         }
       }
     }
+
+The package also includes the typed events for functions that transform S3 Object Lambda
+access point requests (ObjectLambdaEvent) and for functions invoked by S3 Batch Operations
+jobs (BatchJobEvent and BatchJobResponse).
 */
 package s3
@@ -0,0 +1,186 @@
+package s3
+
+import (
+	"net/url"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// S3 Object Lambda
+////////////////////////////////////////////////////////////////////////////////
+
+// ObjectLambdaUserIdentity is the identity of the caller that made the
+// request to the Object Lambda access point
+type ObjectLambdaUserIdentity struct {
+	Type        string `json:"type"`
+	PrincipalID string `json:"principalId"`
+	ARN         string `json:"arn"`
+	AccountID   string `json:"accountId"`
+	AccessKeyID string `json:"accessKeyId"`
+}
+
+// ObjectLambdaUserRequest is the original request made to the Object Lambda
+// access point
+type ObjectLambdaUserRequest struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// ObjectLambdaConfiguration is the configuration of the Object Lambda
+// access point that invoked the function
+type ObjectLambdaConfiguration struct {
+	AccessPointARN           string `json:"accessPointArn"`
+	SupportingAccessPointARN string `json:"supportingAccessPointArn"`
+	Payload                  string `json:"payload"`
+}
+
+// ObjectLambdaGetObjectContext is the context of a GetObject request. The
+// transformed object must be returned with WriteGetObjectResponse, using
+// the OutputRoute and OutputToken values.
+type ObjectLambdaGetObjectContext struct {
+	InputS3URL  string `json:"inputS3Url"`
+	OutputRoute string `json:"outputRoute"`
+	OutputToken string `json:"outputToken"`
+}
+
+// ObjectLambdaInputContext is the context of a HeadObject, ListObjects or
+// ListObjectsV2 request. InputS3URL is a presigned URL for the supporting
+// access point.
+type ObjectLambdaInputContext struct {
+	InputS3URL string `json:"inputS3Url"`
+}
+
+// ObjectLambdaEvent is the event sent to a function that transforms the
+// requests made to an S3 Object Lambda access point. Exactly one of the
+// context fields is set. See
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/olap-event-context.html
+type ObjectLambdaEvent struct {
+	XAmzRequestID        string                        `json:"xAmzRequestId"`
+	GetObjectContext     *ObjectLambdaGetObjectContext `json:"getObjectContext,omitempty"`
+	HeadObjectContext    *ObjectLambdaInputContext     `json:"headObjectContext,omitempty"`
+	ListObjectsContext   *ObjectLambdaInputContext     `json:"listObjectsContext,omitempty"`
+	ListObjectsV2Context *ObjectLambdaInputContext     `json:"listObjectsV2Context,omitempty"`
+	Configuration        ObjectLambdaConfiguration     `json:"configuration"`
+	UserRequest          ObjectLambdaUserRequest       `json:"userRequest"`
+	UserIdentity         ObjectLambdaUserIdentity      `json:"userIdentity"`
+	ProtocolVersion      string                        `json:"protocolVersion"`
+}
+
+// ObjectLambdaListResponse is the response returned by a function that
+// transforms HeadObject, ListObjects or ListObjectsV2 requests. Set the
+// result field that matches the request.
+type ObjectLambdaListResponse struct {
+	StatusCode         int                    `json:"statusCode"`
+	ErrorCode          string                 `json:"errorCode,omitempty"`
+	ErrorMessage       string                 `json:"errorMessage,omitempty"`
+	Headers            map[string]string      `json:"headers,omitempty"`
+	ListResultXML      string                 `json:"listResultXml,omitempty"`
+	ListBucketResult   map[string]interface{} `json:"listBucketResult,omitempty"`
+	ListBucketResultV2 map[string]interface{} `json:"listBucketResultV2,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// S3 Batch Operations
+////////////////////////////////////////////////////////////////////////////////
+
+// BatchJobResultCode is the outcome of an S3 Batch Operations task
+type BatchJobResultCode string
+
+const (
+	// BatchJobResultSucceeded indicates that the task completed
+	BatchJobResultSucceeded BatchJobResultCode = "Succeeded"
+	// BatchJobResultTemporaryFailure indicates that the task should be
+	// retried
+	BatchJobResultTemporaryFailure BatchJobResultCode = "TemporaryFailure"
+	// BatchJobResultPermanentFailure indicates that the task failed and
+	// should not be retried
+	BatchJobResultPermanentFailure BatchJobResultCode = "PermanentFailure"
+)
+
+// BatchJob is the S3 Batch Operations job that invoked the function.
+// UserArguments are only available with the 2.0 invocation schema.
+type BatchJob struct {
+	ID            string            `json:"id"`
+	UserArguments map[string]string `json:"userArguments,omitempty"`
+}
+
+// BatchJobTask is a single object to process. S3BucketARN is set for the
+// 1.0 invocation schema and S3Bucket for the 2.0 invocation schema. The
+// S3Key is URL encoded.
+type BatchJobTask struct {
+	TaskID      string `json:"taskId"`
+	S3Key       string `json:"s3Key"`
+	S3VersionID string `json:"s3VersionId"`
+	S3BucketARN string `json:"s3BucketArn,omitempty"`
+	S3Bucket    string `json:"s3Bucket,omitempty"`
+}
+
+// BucketName returns the name of the task's bucket for either invocation
+// schema
+func (task *BatchJobTask) BucketName() string {
+	if task.S3Bucket != "" {
+		return task.S3Bucket
+	}
+	return strings.TrimPrefix(task.S3BucketARN, "arn:aws:s3:::")
+}
+
+// Key returns the URL decoded object key
+func (task *BatchJobTask) Key() (string, error) {
+	return url.QueryUnescape(task.S3Key)
+}
+
+// BatchJobEvent is the event sent to a function that is invoked by an S3
+// Batch Operations job. See
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/batch-ops-invoke-lambda.html
+type BatchJobEvent struct {
+	InvocationSchemaVersion string         `json:"invocationSchemaVersion"`
+	InvocationID            string         `json:"invocationId"`
+	Job                     BatchJob       `json:"job"`
+	Tasks                   []BatchJobTask `json:"tasks"`
+}
+
+// BatchJobResult is the outcome of a single task
+type BatchJobResult struct {
+	TaskID       string             `json:"taskId"`
+	ResultCode   BatchJobResultCode `json:"resultCode"`
+	ResultString string             `json:"resultString"`
+}
+
+// BatchJobResponse is the response returned by a function that is invoked
+// by an S3 Batch Operations job. Each task in the event must have a result.
+type BatchJobResponse struct {
+	InvocationSchemaVersion string             `json:"invocationSchemaVersion"`
+	TreatMissingKeysAs      BatchJobResultCode `json:"treatMissingKeysAs"`
+	InvocationID            string             `json:"invocationId"`
+	Results                 []BatchJobResult   `json:"results"`
+}
+
+// NewResponse returns an empty response for the event. Tasks without a
+// result are treated as permanent failures.
+func (event *BatchJobEvent) NewResponse() *BatchJobResponse {
+	return &BatchJobResponse{
+		InvocationSchemaVersion: event.InvocationSchemaVersion,
+		TreatMissingKeysAs:      BatchJobResultPermanentFailure,
+		InvocationID:            event.InvocationID,
+		Results:                 make([]BatchJobResult, 0, len(event.Tasks)),
+	}
+}
+
+// AddResult records the outcome of the task
+func (response *BatchJobResponse) AddResult(task BatchJobTask,
+	resultCode BatchJobResultCode,
+	resultString string) {
+	response.Results = append(response.Results, BatchJobResult{
+		TaskID:       task.TaskID,
+		ResultCode:   resultCode,
+		ResultString: resultString,
+	})
+}
+
+// AddError records a failed task. The resultCode should be either
+// BatchJobResultTemporaryFailure or BatchJobResultPermanentFailure.
+func (response *BatchJobResponse) AddError(task BatchJobTask,
+	resultCode BatchJobResultCode,
+	err error) {
+	response.AddResult(task, resultCode, err.Error())
+}
@@ -0,0 +1,4 @@
+/*Package schema reflects JSON Schema (Draft 4) documents from Go types and
+validates values against them. The schemas are used by API Gateway request
+models and by the typed EventBridge detail-types.*/
+package schema
@@ -0,0 +1,470 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDraft04 is the JSON Schema version supported by API Gateway
+// models. See
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/models-mappings.html
+const JSONSchemaDraft04 = "http://json-schema.org/draft-04/schema#"
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// JSONSchema is the subset of JSON Schema Draft 4 that is reflected from
+// Go types and supported by API Gateway request validation. Nullable
+// schemas also accept a JSON null and are serialized with a type array
+// (eg: `"type": ["string", "null"]`).
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int64                 `json:"minLength,omitempty"`
+	MaxLength            *int64                 `json:"maxLength,omitempty"`
+	MinItems             *int64                 `json:"minItems,omitempty"`
+	MaxItems             *int64                 `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Nullable             bool                   `json:"-"`
+}
+
+// MarshalJSON serializes nullable types as a type array
+func (schema JSONSchema) MarshalJSON() ([]byte, error) {
+	type jsonSchema JSONSchema
+	if !schema.Nullable || schema.Type == "" {
+		return json.Marshal(jsonSchema(schema))
+	}
+	return json.Marshal(&struct {
+		jsonSchema
+		Type []string `json:"type"`
+	}{
+		jsonSchema: jsonSchema(schema),
+		Type:       []string{schema.Type, "null"},
+	})
+}
+
+// UnmarshalJSON accepts the type as either a string or a type array. A
+// "null" entry in the type array sets Nullable.
+func (schema *JSONSchema) UnmarshalJSON(data []byte) error {
+	type jsonSchema JSONSchema
+	typedSchema := struct {
+		*jsonSchema
+		Type json.RawMessage `json:"type,omitempty"`
+	}{
+		jsonSchema: (*jsonSchema)(schema),
+	}
+	unmarshalErr := json.Unmarshal(data, &typedSchema)
+	if unmarshalErr != nil {
+		return unmarshalErr
+	}
+	schema.Type = ""
+	schema.Nullable = false
+	if len(typedSchema.Type) == 0 {
+		return nil
+	}
+	var typeName string
+	if json.Unmarshal(typedSchema.Type, &typeName) == nil {
+		schema.Type = typeName
+		return nil
+	}
+	var typeNames []string
+	unmarshalErr = json.Unmarshal(typedSchema.Type, &typeNames)
+	if unmarshalErr != nil {
+		return fmt.Errorf("JSON Schema type must be a string or an array of strings: %s",
+			string(typedSchema.Type))
+	}
+	for _, eachType := range typeNames {
+		switch {
+		case eachType == "null":
+			schema.Nullable = true
+		case schema.Type == "":
+			schema.Type = eachType
+		default:
+			return fmt.Errorf("JSON Schema type arrays may only include one type and null: %s",
+				string(typedSchema.Type))
+		}
+	}
+	// A type array of only null is the null type
+	if schema.Type == "" && schema.Nullable {
+		schema.Type = "null"
+		schema.Nullable = false
+	}
+	return nil
+}
+
+// NewJSONSchema returns the JSON Schema reflected from the Go type of the
+// value argument. Property names are taken from `json` struct tags. The
+// `validate` struct tag supports the following go-playground/validator
+// compatible rules:
+//
+//   required   - the property is required
+//   min=N      - minimum value, string length, or array length
+//   max=N      - maximum value, string length, or array length
+//   len=N      - exact string or array length
+//   oneof=a b  - the value must be one of the space separated values
+//
+// A `description` struct tag, if present, is used as the property
+// description.
+func NewJSONSchema(value interface{}) (*JSONSchema, error) {
+	valueType := reflect.TypeOf(value)
+	if valueType == nil {
+		return nil, fmt.Errorf("unable to create JSON Schema for nil value")
+	}
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	schema, schemaErr := reflectSchema(valueType, make(map[reflect.Type]bool))
+	if schemaErr != nil {
+		return nil, schemaErr
+	}
+	schema.Schema = JSONSchemaDraft04
+	schema.Title = valueType.Name()
+	return schema, nil
+}
+
+func reflectSchema(valueType reflect.Type, visiting map[reflect.Type]bool) (*JSONSchema, error) {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	switch valueType {
+	case timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	case rawMessageType:
+		return &JSONSchema{}, nil
+	}
+
+	switch valueType.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+	case reflect.Slice, reflect.Array:
+		// []byte is marshalled as a base64 string
+		if valueType.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string"}, nil
+		}
+		itemSchema, itemSchemaErr := reflectSchema(valueType.Elem(), visiting)
+		if itemSchemaErr != nil {
+			return nil, itemSchemaErr
+		}
+		return &JSONSchema{Type: "array", Items: itemSchema}, nil
+	case reflect.Map:
+		if valueType.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported JSON Schema map key type: %s", valueType.Key())
+		}
+		valueSchema, valueSchemaErr := reflectSchema(valueType.Elem(), visiting)
+		if valueSchemaErr != nil {
+			return nil, valueSchemaErr
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: valueSchema}, nil
+	case reflect.Struct:
+		// Recursive types are represented as untyped objects
+		if visiting[valueType] {
+			return &JSONSchema{Type: "object"}, nil
+		}
+		visiting[valueType] = true
+		defer delete(visiting, valueType)
+
+		schema := &JSONSchema{
+			Type:       "object",
+			Properties: make(map[string]*JSONSchema),
+		}
+		reflectErr := reflectStructFields(valueType, schema, visiting)
+		if reflectErr != nil {
+			return nil, reflectErr
+		}
+		sort.Strings(schema.Required)
+		return schema, nil
+	}
+	return nil, fmt.Errorf("unsupported JSON Schema type: %s", valueType)
+}
+
+func reflectStructFields(structType reflect.Type,
+	schema *JSONSchema,
+	visiting map[reflect.Type]bool) error {
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		tagParts := strings.Split(jsonTag, ",")
+		propertyName := tagParts[0]
+
+		// Embedded structs without an explicit name are flattened
+		if field.Anonymous && propertyName == "" {
+			embeddedType := field.Type
+			for embeddedType.Kind() == reflect.Ptr {
+				embeddedType = embeddedType.Elem()
+			}
+			if embeddedType.Kind() == reflect.Struct {
+				embeddedErr := reflectStructFields(embeddedType, schema, visiting)
+				if embeddedErr != nil {
+					return embeddedErr
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if propertyName == "" {
+			propertyName = field.Name
+		}
+		propertySchema, propertySchemaErr := reflectSchema(field.Type, visiting)
+		if propertySchemaErr != nil {
+			return fmt.Errorf("field %s.%s: %s", structType.Name(), field.Name, propertySchemaErr)
+		}
+		propertySchema.Description = field.Tag.Get("description")
+		required, rulesErr := applyValidateRules(propertySchema, field.Tag.Get("validate"))
+		if rulesErr != nil {
+			return fmt.Errorf("field %s.%s: %s", structType.Name(), field.Name, rulesErr)
+		}
+		if required {
+			schema.Required = append(schema.Required, propertyName)
+		} else {
+			// Optional properties may be explicitly null
+			switch field.Type.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				propertySchema.Nullable = true
+			default:
+				for _, eachOption := range tagParts[1:] {
+					if eachOption == "omitempty" {
+						propertySchema.Nullable = true
+					}
+				}
+			}
+		}
+		schema.Properties[propertyName] = propertySchema
+	}
+	return nil
+}
+
+// applyValidateRules annotates the schema with the supported validate
+// tag rules and returns whether the property is required
+func applyValidateRules(schema *JSONSchema, validateTag string) (bool, error) {
+	if validateTag == "" {
+		return false, nil
+	}
+	required := false
+	for _, eachRule := range strings.Split(validateTag, ",") {
+		ruleParts := strings.SplitN(eachRule, "=", 2)
+		ruleName := ruleParts[0]
+		ruleValue := ""
+		if len(ruleParts) > 1 {
+			ruleValue = ruleParts[1]
+		}
+		switch ruleName {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			limit, limitErr := strconv.ParseFloat(ruleValue, 64)
+			if limitErr != nil {
+				return false, fmt.Errorf("invalid %s rule value: %s", ruleName, ruleValue)
+			}
+			intLimit := int64(limit)
+			switch schema.Type {
+			case "string":
+				if ruleName != "max" {
+					schema.MinLength = &intLimit
+				}
+				if ruleName != "min" {
+					schema.MaxLength = &intLimit
+				}
+			case "array":
+				if ruleName != "max" {
+					schema.MinItems = &intLimit
+				}
+				if ruleName != "min" {
+					schema.MaxItems = &intLimit
+				}
+			case "integer", "number":
+				if ruleName != "max" {
+					schema.Minimum = &limit
+				}
+				if ruleName != "min" {
+					schema.Maximum = &limit
+				}
+			default:
+				return false, fmt.Errorf("rule %s unsupported for JSON Schema type: %s", ruleName, schema.Type)
+			}
+		case "oneof":
+			for _, eachValue := range strings.Fields(ruleValue) {
+				switch schema.Type {
+				case "integer", "number":
+					numberValue, numberValueErr := strconv.ParseFloat(eachValue, 64)
+					if numberValueErr != nil {
+						return false, fmt.Errorf("invalid oneof value: %s", eachValue)
+					}
+					schema.Enum = append(schema.Enum, numberValue)
+				default:
+					schema.Enum = append(schema.Enum, eachValue)
+				}
+			}
+		default:
+			// Other validator rules don't have a JSON Schema equivalent
+		}
+	}
+	return required, nil
+}
+
+// Validate confirms that the decoded JSON value satisfies the schema. The
+// value should be the result of unmarshalling JSON into an interface{}.
+// All validation failures are included in the returned error.
+func (schema *JSONSchema) Validate(value interface{}) error {
+	var failures []string
+	schema.validate("$", value, &failures)
+	if len(failures) != 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// ValidateJSON is a convenience function that unmarshals the JSON data
+// and validates it against the schema.
+func (schema *JSONSchema) ValidateJSON(data []byte) error {
+	var value interface{}
+	if len(data) != 0 {
+		unmarshalErr := json.Unmarshal(data, &value)
+		if unmarshalErr != nil {
+			return fmt.Errorf("invalid JSON: %s", unmarshalErr)
+		}
+	}
+	return schema.Validate(value)
+}
+
+func (schema *JSONSchema) validate(path string, value interface{}, failures *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*failures = append(*failures, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+	if value == nil && schema.Nullable {
+		return
+	}
+	if !schema.matchesType(value) {
+		fail("expected type %s", schema.Type)
+		return
+	}
+	if len(schema.Enum) != 0 {
+		matched := false
+		for _, eachEnum := range schema.Enum {
+			if reflect.DeepEqual(eachEnum, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value must be one of %v", schema.Enum)
+		}
+	}
+	switch typedValue := value.(type) {
+	case string:
+		length := int64(len([]rune(typedValue)))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("length must be at least %d", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("length must be at most %d", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			matched, matchedErr := regexp.MatchString(schema.Pattern, typedValue)
+			if matchedErr != nil || !matched {
+				fail("value must match pattern %s", schema.Pattern)
+			}
+		}
+	case float64:
+		if schema.Minimum != nil && typedValue < *schema.Minimum {
+			fail("value must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && typedValue > *schema.Maximum {
+			fail("value must be at most %v", *schema.Maximum)
+		}
+	case []interface{}:
+		length := int64(len(typedValue))
+		if schema.MinItems != nil && length < *schema.MinItems {
+			fail("must contain at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && length > *schema.MaxItems {
+			fail("must contain at most %d items", *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for eachIndex, eachItem := range typedValue {
+				schema.Items.validate(fmt.Sprintf("%s[%d]", path, eachIndex), eachItem, failures)
+			}
+		}
+	case map[string]interface{}:
+		for _, eachRequired := range schema.Required {
+			if _, exists := typedValue[eachRequired]; !exists {
+				*failures = append(*failures, fmt.Sprintf("%s.%s: is required", path, eachRequired))
+			}
+		}
+		// Sort the keys so that the error messages are stable
+		keys := make([]string, 0, len(typedValue))
+		for eachKey := range typedValue {
+			keys = append(keys, eachKey)
+		}
+		sort.Strings(keys)
+		for _, eachKey := range keys {
+			propertyPath := fmt.Sprintf("%s.%s", path, eachKey)
+			if propertySchema, exists := schema.Properties[eachKey]; exists {
+				propertySchema.validate(propertyPath, typedValue[eachKey], failures)
+			} else if schema.AdditionalProperties != nil {
+				schema.AdditionalProperties.validate(propertyPath, typedValue[eachKey], failures)
+			}
+		}
+	}
+}
+
+func (schema *JSONSchema) matchesType(value interface{}) bool {
+	switch schema.Type {
+	case "":
+		return true
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "null":
+		return value == nil
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSchemaAddress struct {
+	Street string `json:"street" validate:"required"`
+	Zip    string `json:"zip,omitempty" validate:"len=5"`
+}
+
+type testSchemaRequest struct {
+	Name      string             `json:"name" validate:"required,min=1,max=16"`
+	Age       int                `json:"age,omitempty" validate:"min=0,max=150"`
+	Color     string             `json:"color,omitempty" validate:"oneof=red green blue"`
+	Tags      []string           `json:"tags,omitempty" validate:"max=3"`
+	Address   *testSchemaAddress `json:"address,omitempty"`
+	Metadata  map[string]int     `json:"metadata,omitempty"`
+	Timestamp time.Time          `json:"timestamp,omitempty"`
+	Ignored   string             `json:"-"`
+}
+
+func TestNewJSONSchema(t *testing.T) {
+	schema, schemaErr := NewJSONSchema(&testSchemaRequest{})
+	if schemaErr != nil {
+		t.Fatal(schemaErr)
+	}
+	if schema.Schema != JSONSchemaDraft04 {
+		t.Fatalf("Unexpected $schema value: %s", schema.Schema)
+	}
+	if len(schema.Required) != 1 || schema.Required[0] != "name" {
+		t.Fatalf("Unexpected required properties: %#v", schema.Required)
+	}
+	if _, exists := schema.Properties["Ignored"]; exists {
+		t.Fatal("Ignored property was included in schema")
+	}
+	if schema.Properties["address"].Type != "object" ||
+		schema.Properties["address"].Required[0] != "street" {
+		t.Fatalf("Unexpected address schema: %#v", schema.Properties["address"])
+	}
+	if schema.Properties["timestamp"].Format != "date-time" {
+		t.Fatalf("Unexpected timestamp schema: %#v", schema.Properties["timestamp"])
+	}
+	if !schema.Properties["address"].Nullable ||
+		!schema.Properties["age"].Nullable ||
+		schema.Properties["name"].Nullable {
+		t.Fatalf("Unexpected nullable properties: %#v", schema.Properties)
+	}
+	// Make sure it's serializable
+	jsonBytes, jsonErr := json.Marshal(schema)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if !strings.Contains(string(jsonBytes), `"type":["object","null"]`) ||
+		!strings.Contains(string(jsonBytes), `"name":{"type":"string"`) {
+		t.Fatalf("Unexpected nullable JSON Schema types: %s", string(jsonBytes))
+	}
+}
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, schemaErr := NewJSONSchema(testSchemaRequest{})
+	if schemaErr != nil {
+		t.Fatal(schemaErr)
+	}
+	validBodies := []string{
+		`{"name":"Sparta"}`,
+		`{"name":"Sparta","age":42,"color":"red","tags":["a","b"]}`,
+		`{"name":"Sparta","address":{"street":"Main","zip":"98101"},"metadata":{"a":1}}`,
+		`{"name":"Sparta","age":null,"tags":null,"address":null,"metadata":null}`,
+	}
+	for _, eachBody := range validBodies {
+		validateErr := schema.ValidateJSON([]byte(eachBody))
+		if validateErr != nil {
+			t.Fatalf("Failed to validate body %s: %s", eachBody, validateErr)
+		}
+	}
+	invalidBodies := map[string]string{
+		`{}`:                                 "$.name: is required",
+		`{"name":""}`:                        "length must be at least 1",
+		`{"name":"Sparta","age":4.5}`:        "expected type integer",
+		`{"name":"Sparta","age":151}`:        "value must be at most 150",
+		`{"name":"Sparta","color":"purple"}`: "value must be one of",
+		`{"name":"Sparta","tags":["a","b","c","d"]}`: "at most 3 items",
+		`{"name":"Sparta","address":{}}`:             "$.address.street: is required",
+		`{"name":"Sparta","metadata":{"a":"b"}}`:     "$.metadata.a: expected type integer",
+		`{"name":null}`:                              "$.name: expected type string",
+		`[]`:                                         "expected type object",
+		`{"name":`:                                   "invalid JSON",
+	}
+	for eachBody, eachExpected := range invalidBodies {
+		validateErr := schema.ValidateJSON([]byte(eachBody))
+		if validateErr == nil {
+			t.Fatalf("Failed to reject invalid body: %s", eachBody)
+		}
+		if !strings.Contains(validateErr.Error(), eachExpected) {
+			t.Fatalf("Expected error containing %q for body %s, got: %s",
+				eachExpected,
+				eachBody,
+				validateErr)
+		}
+	}
+}
+
+func TestJSONSchemaUnmarshal(t *testing.T) {
+	schema, schemaErr := NewJSONSchema(testSchemaRequest{})
+	if schemaErr != nil {
+		t.Fatal(schemaErr)
+	}
+	jsonBytes, jsonErr := json.Marshal(schema)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	// Make sure the type arrays round trip
+	var parsedSchema JSONSchema
+	unmarshalErr := json.Unmarshal(jsonBytes, &parsedSchema)
+	if unmarshalErr != nil {
+		t.Fatalf("Failed to parse JSON Schema %s: %s", string(jsonBytes), unmarshalErr)
+	}
+	if parsedSchema.Properties["age"].Type != "integer" ||
+		!parsedSchema.Properties["age"].Nullable ||
+		parsedSchema.Properties["name"].Nullable {
+		t.Fatalf("Unexpected parsed properties: %#v", parsedSchema.Properties)
+	}
+	parsedBytes, parsedErr := json.Marshal(parsedSchema)
+	if parsedErr != nil {
+		t.Fatal(parsedErr)
+	}
+	if string(parsedBytes) != string(jsonBytes) {
+		t.Fatalf("JSON Schema didn't round trip.\nExpected: %s\nGot: %s",
+			string(jsonBytes),
+			string(parsedBytes))
+	}
+	validateErr := parsedSchema.ValidateJSON([]byte(`{"name":"Sparta","age":null}`))
+	if validateErr != nil {
+		t.Fatalf("Failed to validate nullable property: %s", validateErr)
+	}
+
+	invalidSchemas := []string{
+		`{"type":["string","integer"]}`,
+		`{"type":42}`,
+	}
+	for _, eachSchema := range invalidSchemas {
+		var invalidSchema JSONSchema
+		if json.Unmarshal([]byte(eachSchema), &invalidSchema) == nil {
+			t.Fatalf("Failed to reject unsupported JSON Schema type: %s", eachSchema)
+		}
+	}
+}
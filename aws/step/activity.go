@@ -0,0 +1,360 @@
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+	sparta "github.com/mweagle/Sparta"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Activity
+////////////////////////////////////////////////////////////////////////////////
+
+// Activity represents an AWS::StepFunctions::Activity resource. Activities
+// are tasks that are completed by workers that poll Step Functions for
+// work, rather than by a Lambda function or a service integration.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/concepts-activities.html
+type Activity struct {
+	// Name is the activity name
+	Name string
+}
+
+// LogicalResourceName returns the CloudFormation logical resource name
+// of the activity
+func (activity *Activity) LogicalResourceName() string {
+	return sparta.CloudFormationResourceName("Activity", activity.Name)
+}
+
+// OutputName returns the name of the stack output whose value is the
+// activity ARN
+func (activity *Activity) OutputName() string {
+	return fmt.Sprintf("%sArn", activity.LogicalResourceName())
+}
+
+// ARN returns the activity ARN expression
+func (activity *Activity) ARN() *gocf.StringExpr {
+	return gocf.Ref(activity.LogicalResourceName()).String()
+}
+
+// WorkerPrivilege returns the privilege an activity worker requires to
+// poll for tasks and report their status
+func (activity *Activity) WorkerPrivilege() sparta.IAMRolePrivilege {
+	return sparta.IAMRolePrivilege{
+		Actions: []string{"states:GetActivityTask",
+			"states:SendTaskSuccess",
+			"states:SendTaskFailure",
+			"states:SendTaskHeartbeat"},
+		Resource: activity.ARN(),
+	}
+}
+
+// decorateTemplate adds the activity resource and the ARN output to
+// the template
+func (activity *Activity) decorateTemplate(template *gocf.Template) {
+	template.AddResource(activity.LogicalResourceName(), &gocf.StepFunctionsActivity{
+		Name: gocf.String(activity.Name),
+	})
+	template.Outputs[activity.OutputName()] = &gocf.Output{
+		Description: fmt.Sprintf("%s activity ARN", activity.Name),
+		Value:       activity.ARN(),
+	}
+}
+
+// stateMachineActivities returns the activities used by the states in
+// the machine, including the states in ParallelState branches and
+// MapState iterators
+func stateMachineActivities(sm *StateMachine) []*Activity {
+	activities := make([]*Activity, 0)
+	for _, eachState := range sm.uniqueStates {
+		switch typedState := eachState.(type) {
+		case *ActivityTaskState:
+			if typedState.activity != nil {
+				activities = append(activities, typedState.activity)
+			}
+		case *ParallelState:
+			activities = append(activities, stateMachineActivities(&typedState.States)...)
+		case *MapState:
+			activities = append(activities, stateMachineActivities(&typedState.Iterator)...)
+		}
+	}
+	return activities
+}
+
+// NewActivity returns a new Activity with the given name
+func NewActivity(name string) *Activity {
+	return &Activity{
+		Name: name,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ActivityTaskState
+////////////////////////////////////////////////////////////////////////////////
+
+// ActivityTaskState is a task that is completed by an ActivityWorker
+type ActivityTaskState struct {
+	BaseTask
+	activity *Activity
+}
+
+// Activity returns the activity that completes this task
+func (ats *ActivityTaskState) Activity() *Activity {
+	return ats.activity
+}
+
+// MarshalJSON for custom marshalling
+func (ats *ActivityTaskState) MarshalJSON() ([]byte, error) {
+	if ats.activity == nil {
+		return nil, errors.Errorf("ActivityTaskState %s doesn't define an Activity", ats.name)
+	}
+	additionalParams := ats.BaseTask.additionalParams()
+	additionalParams["Resource"] = ats.activity.ARN()
+	if ats.Parameters != nil {
+		additionalParams["Parameters"] = ats.Parameters
+	}
+	return ats.marshalStateJSON("Task", additionalParams)
+}
+
+// NewActivityWorker returns an ActivityWorker for this state. If the
+// state defines a heartbeat, the worker sends heartbeats at half the
+// HeartbeatSeconds interval while the handler runs.
+func (ats *ActivityTaskState) NewActivityWorker(awsSession *session.Session,
+	activityArn string,
+	handler ActivityHandler) *ActivityWorker {
+	worker := NewActivityWorker(awsSession, activityArn, handler)
+	if ats.HeartbeatSeconds > 0 {
+		worker.HeartbeatInterval = ats.HeartbeatSeconds / 2
+	}
+	return worker
+}
+
+// NewActivityTaskState returns an initialized ActivityTaskState
+func NewActivityTaskState(stateName string, activity *Activity) *ActivityTaskState {
+	return &ActivityTaskState{
+		BaseTask: BaseTask{
+			baseInnerState: baseInnerState{
+				name: stateName,
+				id:   rand.Int63(),
+			},
+		},
+		activity: activity,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ActivityWorker
+////////////////////////////////////////////////////////////////////////////////
+
+// activityWorkerErrorDelay is the delay before polling resumes after
+// an error
+const activityWorkerErrorDelay = time.Second
+
+// ActivityHandler is the user function that completes an activity task.
+// The input is the task's JSON input. The result is marshalled as the
+// task output. Return a TaskError to report a named error.
+type ActivityHandler func(ctx context.Context, input json.RawMessage) (interface{}, error)
+
+// ActivityWorker polls an activity for tasks and runs the handler for
+// each task
+type ActivityWorker struct {
+	// ActivityArn is the ARN of the activity to poll. See Activity.OutputName
+	// for the stack output that publishes the ARN.
+	ActivityArn string
+	// WorkerName is reported to Step Functions. Defaults to the hostname.
+	WorkerName string
+	// HeartbeatInterval is the interval between SendTaskHeartbeat calls
+	// while the handler runs. Zero disables heartbeats.
+	HeartbeatInterval time.Duration
+	// Concurrency is the number of tasks that are processed concurrently.
+	// Defaults to 1.
+	Concurrency int
+	handler     ActivityHandler
+	sfnSvc      sfniface.SFNAPI
+	logger      *logrus.Logger
+}
+
+// WithLogger sets the worker logger
+func (aw *ActivityWorker) WithLogger(logger *logrus.Logger) *ActivityWorker {
+	aw.logger = logger
+	return aw
+}
+
+// Run polls for and processes tasks until the context is done. Errors
+// are logged and polling resumes after a short delay.
+func (aw *ActivityWorker) Run(ctx context.Context) error {
+	if aw.handler == nil {
+		return errors.Errorf("ActivityWorker for %s doesn't define a handler", aw.ActivityArn)
+	}
+	concurrency := aw.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i != concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				_, pollErr := aw.PollOnce(ctx)
+				if pollErr != nil && ctx.Err() == nil {
+					aw.logger.WithFields(logrus.Fields{
+						"ActivityArn": aw.ActivityArn,
+						"Error":       pollErr,
+					}).Error("Failed to process activity task")
+					select {
+					case <-ctx.Done():
+					case <-time.After(activityWorkerErrorDelay):
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// PollOnce long polls for a single task and processes it. It returns
+// false if no task was available.
+func (aw *ActivityWorker) PollOnce(ctx context.Context) (bool, error) {
+	workerName := aw.WorkerName
+	if workerName == "" {
+		workerName, _ = os.Hostname()
+	}
+	taskOutput, taskOutputErr := aw.sfnSvc.GetActivityTaskWithContext(ctx,
+		&sfn.GetActivityTaskInput{
+			ActivityArn: aws.String(aw.ActivityArn),
+			WorkerName:  aws.String(workerName),
+		})
+	if taskOutputErr != nil {
+		return false, errors.Wrapf(taskOutputErr, "attempting to get activity task")
+	}
+	// Polls time out after 60s without a task
+	if taskOutput.TaskToken == nil || *taskOutput.TaskToken == "" {
+		return false, nil
+	}
+	return true, aw.processTask(ctx,
+		*taskOutput.TaskToken,
+		json.RawMessage(aws.StringValue(taskOutput.Input)))
+}
+
+// processTask runs the handler and reports the result
+func (aw *ActivityWorker) processTask(ctx context.Context,
+	taskToken string,
+	input json.RawMessage) error {
+	handlerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	callback := &TaskCallback{
+		TaskToken: taskToken,
+		sfnSvc:    aw.sfnSvc,
+	}
+
+	// Heartbeats
+	heartbeatDone := make(chan struct{})
+	var heartbeatWG sync.WaitGroup
+	if aw.HeartbeatInterval > 0 {
+		heartbeatWG.Add(1)
+		go func() {
+			defer heartbeatWG.Done()
+			ticker := time.NewTicker(aw.HeartbeatInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-heartbeatDone:
+					return
+				case <-handlerCtx.Done():
+					return
+				case <-ticker.C:
+					heartbeatErr := callback.Heartbeat(handlerCtx)
+					if heartbeatErr != nil {
+						aw.logger.WithFields(logrus.Fields{
+							"Error": heartbeatErr,
+						}).Warn("Failed to send activity heartbeat")
+						// The task is no longer running, so stop the handler
+						if isTaskExpiredError(heartbeatErr) {
+							cancel()
+							return
+						}
+					}
+				}
+			}
+		}()
+	}
+	result, resultErr := aw.invokeHandler(handlerCtx, input)
+	close(heartbeatDone)
+	heartbeatWG.Wait()
+
+	// Report the result using the parent context, since the handler
+	// context may have been canceled
+	if resultErr != nil {
+		// Use the same error names as Lambda functions
+		errorName, cause := taskErrorDetails(resultErr)
+		aw.logger.WithFields(logrus.Fields{
+			"Error": errorName,
+			"Cause": cause,
+		}).Warn("Activity task failed")
+		return reportTaskStatusError(callback.Failure(ctx, resultErr))
+	}
+	return reportTaskStatusError(callback.Success(ctx, result))
+}
+
+// invokeHandler runs the handler. A panic is returned as a
+// StatesTaskFailed error so that the task fails immediately rather
+// than timing out.
+func (aw *ActivityWorker) invokeHandler(ctx context.Context,
+	input json.RawMessage) (result interface{}, resultErr error) {
+	defer func() {
+		if panicValue := recover(); panicValue != nil {
+			aw.logger.WithFields(logrus.Fields{
+				"Panic": panicValue,
+			}).Error("Activity handler panicked")
+			result = nil
+			resultErr = NewTaskError(StatesTaskFailed, fmt.Sprintf("panic: %v", panicValue))
+		}
+	}()
+	return aw.handler(ctx, input)
+}
+
+// isTaskExpiredError returns true if the error indicates that the task
+// has timed out or no longer exists
+func isTaskExpiredError(err error) bool {
+	awsErr, ok := errors.Cause(err).(awserr.Error)
+	return ok &&
+		(awsErr.Code() == sfn.ErrCodeTaskTimedOut ||
+			awsErr.Code() == sfn.ErrCodeTaskDoesNotExist)
+}
+
+// reportTaskStatusError ignores the errors for tasks that have already
+// timed out, since there is nothing to report them to
+func reportTaskStatusError(err error) error {
+	if err == nil || isTaskExpiredError(err) {
+		return nil
+	}
+	return errors.Wrapf(err, "attempting to report activity task status")
+}
+
+// NewActivityWorker returns an ActivityWorker that runs the handler for
+// each task of the activity
+func NewActivityWorker(awsSession *session.Session,
+	activityArn string,
+	handler ActivityHandler) *ActivityWorker {
+	return &ActivityWorker{
+		ActivityArn: activityArn,
+		handler:     handler,
+		sfnSvc:      sfn.New(awsSession),
+		logger:      logrus.New(),
+	}
+}
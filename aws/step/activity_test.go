@@ -0,0 +1,127 @@
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+	"github.com/sirupsen/logrus"
+)
+
+type mockActivitySFN struct {
+	sfniface.SFNAPI
+	mutex      sync.Mutex
+	heartbeats int
+	output     string
+	errorName  string
+}
+
+func (mock *mockActivitySFN) GetActivityTaskWithContext(ctx aws.Context,
+	input *sfn.GetActivityTaskInput,
+	opts ...request.Option) (*sfn.GetActivityTaskOutput, error) {
+	return &sfn.GetActivityTaskOutput{
+		TaskToken: aws.String("token"),
+		Input:     aws.String(`{"seconds": 1}`),
+	}, nil
+}
+
+func (mock *mockActivitySFN) SendTaskHeartbeatWithContext(ctx aws.Context,
+	input *sfn.SendTaskHeartbeatInput,
+	opts ...request.Option) (*sfn.SendTaskHeartbeatOutput, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	mock.heartbeats++
+	return &sfn.SendTaskHeartbeatOutput{}, nil
+}
+
+func (mock *mockActivitySFN) SendTaskSuccessWithContext(ctx aws.Context,
+	input *sfn.SendTaskSuccessInput,
+	opts ...request.Option) (*sfn.SendTaskSuccessOutput, error) {
+	mock.output = aws.StringValue(input.Output)
+	return &sfn.SendTaskSuccessOutput{}, nil
+}
+
+func (mock *mockActivitySFN) SendTaskFailureWithContext(ctx aws.Context,
+	input *sfn.SendTaskFailureInput,
+	opts ...request.Option) (*sfn.SendTaskFailureOutput, error) {
+	mock.errorName = aws.StringValue(input.Error)
+	return &sfn.SendTaskFailureOutput{}, nil
+}
+
+type activityRequest struct {
+	Seconds int `json:"seconds"`
+}
+
+func TestActivityWorker(t *testing.T) {
+	activity := NewActivity("LongRunningJob")
+	activityState := NewActivityTaskState("runJob", activity)
+	activityState.WithTimeout(time.Minute).
+		WithHeartbeat(20 * time.Millisecond)
+
+	handler := func(ctx context.Context, input json.RawMessage) (interface{}, error) {
+		var request activityRequest
+		unmarshalErr := json.Unmarshal(input, &request)
+		if unmarshalErr != nil {
+			return nil, unmarshalErr
+		}
+		time.Sleep(50 * time.Millisecond)
+		if request.Seconds == 0 {
+			return nil, NewTaskError("Job.Invalid", "no duration")
+		}
+		return map[string]int{"elapsed": request.Seconds}, nil
+	}
+	mockSFN := &mockActivitySFN{}
+	worker := activityState.NewActivityWorker(session.Must(session.NewSession()),
+		"arn:aws:states:us-west-2:123412341234:activity:LongRunningJob",
+		handler)
+	worker.sfnSvc = mockSFN
+	worker.WithLogger(logrus.New())
+	processed, processedErr := worker.PollOnce(context.Background())
+	if processedErr != nil {
+		t.Fatal(processedErr)
+	}
+	if !processed || mockSFN.output != `{"elapsed":1}` {
+		t.Fatalf("Unexpected activity output: %s", mockSFN.output)
+	}
+	if mockSFN.heartbeats == 0 {
+		t.Fatalf("Failed to send activity heartbeat")
+	}
+
+	// The same handler can be used in the LocalExecutor
+	execution, executionErr := NewLocalExecutor(NewStateMachine("ActivityMachine", activityState)).
+		WithActivityHandler(activity, handler).
+		Execute(context.Background(), map[string]interface{}{
+			"seconds": 0,
+		})
+	if executionErr != nil {
+		t.Fatal(executionErr)
+	}
+	if execution.Status != LocalExecutionFailed || execution.Error != "Job.Invalid" {
+		t.Fatalf("Unexpected execution: %#v", execution)
+	}
+}
+
+func TestActivityWorkerPanic(t *testing.T) {
+	handler := func(ctx context.Context, input json.RawMessage) (interface{}, error) {
+		panic("unexpected input")
+	}
+	mockSFN := &mockActivitySFN{}
+	worker := NewActivityWorker(session.Must(session.NewSession()),
+		"arn:aws:states:us-west-2:123412341234:activity:LongRunningJob",
+		handler)
+	worker.sfnSvc = mockSFN
+	processed, processedErr := worker.PollOnce(context.Background())
+	if processedErr != nil {
+		t.Fatal(processedErr)
+	}
+	if !processed || mockSFN.errorName != string(StatesTaskFailed) {
+		t.Fatalf("Expected panic to fail the task, got error: %s", mockSFN.errorName)
+	}
+}
@@ -3,6 +3,7 @@ package step
 import (
 	"math/rand"
 
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -35,6 +36,19 @@ func (bts *BatchTaskState) MarshalJSON() ([]byte, error) {
 		&bts.parameters)
 }
 
+// statePolicyStatements returns the privileges to submit the job and
+// wait for it to complete
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/batch-iam.html
+func (bts *BatchTaskState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(gocf.String("*"),
+			"batch:SubmitJob",
+			"batch:DescribeJobs",
+			"batch:TerminateJob"),
+		syncEventRuleStatement("StepFunctionsGetEventsForBatchJobsRule"),
+	}
+}
+
 // NewBatchTaskState returns an initialized BatchTaskState
 func NewBatchTaskState(stateName string,
 	parameters BatchTaskParameters) *BatchTaskState {
@@ -0,0 +1,133 @@
+package step
+
+import (
+	"context"
+	"encoding/json"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+	"github.com/pkg/errors"
+)
+
+// TaskTokenAttributeName is the name of the SQS and SNS message
+// attribute, and the Lambda payload key, that holds the task token for
+// `.waitForTaskToken` tasks
+const TaskTokenAttributeName = "TaskToken"
+
+// waitForTaskTokenSuffix is the resource suffix of the callback
+// integration pattern
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connect-to-resource.html#connect-wait-token
+const waitForTaskTokenSuffix = ".waitForTaskToken"
+
+// taskTokenMessageAttributes returns a copy of the message attributes
+// that includes the task token
+func taskTokenMessageAttributes(messageAttributes map[string]interface{}) map[string]interface{} {
+	attributes := make(map[string]interface{}, len(messageAttributes)+1)
+	for eachKey, eachValue := range messageAttributes {
+		attributes[eachKey] = eachValue
+	}
+	attributes[TaskTokenAttributeName] = map[string]interface{}{
+		"DataType":      "String",
+		"StringValue.$": ContextTaskToken.String(),
+	}
+	return attributes
+}
+
+// TaskTokenPayload is the payload delivered to the function of a
+// LambdaTaskState that waits for a task token. Input is the state input,
+// or the resolved Parameters if the state defines them.
+type TaskTokenPayload struct {
+	TaskToken string          `json:"TaskToken"`
+	Input     json.RawMessage `json:"Input,omitempty"`
+}
+
+// TaskTokenFromSQSMessage returns the task token attribute of a message
+// sent by an SQSTaskState that waits for a task token
+func TaskTokenFromSQSMessage(message awsLambdaEvents.SQSMessage) (string, bool) {
+	attribute, exists := message.MessageAttributes[TaskTokenAttributeName]
+	if !exists || attribute.StringValue == nil {
+		return "", false
+	}
+	return *attribute.StringValue, true
+}
+
+// TaskTokenFromSNSEntity returns the task token attribute of a message
+// published by an SNSTaskState that waits for a task token
+func TaskTokenFromSNSEntity(entity awsLambdaEvents.SNSEntity) (string, bool) {
+	attribute, exists := entity.MessageAttributes[TaskTokenAttributeName]
+	if !exists {
+		return "", false
+	}
+	attributeMap, isMap := attribute.(map[string]interface{})
+	if !isMap {
+		return "", false
+	}
+	token, isString := attributeMap["Value"].(string)
+	return token, isString
+}
+
+// TaskCallback reports the result of a task that waits for a task token.
+// Handlers that receive the token call Success or Failure to resume the
+// execution, and Heartbeat to keep tasks with a HeartbeatSeconds value
+// alive.
+type TaskCallback struct {
+	TaskToken string
+	sfnSvc    sfniface.SFNAPI
+}
+
+// Success completes the task with the JSON marshalled output
+func (tc *TaskCallback) Success(ctx context.Context, output interface{}) error {
+	outputJSON, outputJSONErr := json.Marshal(output)
+	if outputJSONErr != nil {
+		return errors.Wrapf(outputJSONErr, "attempting to marshal task output")
+	}
+	_, successErr := tc.sfnSvc.SendTaskSuccessWithContext(ctx,
+		&sfn.SendTaskSuccessInput{
+			TaskToken: aws.String(tc.TaskToken),
+			Output:    aws.String(string(outputJSON)),
+		})
+	return errors.Wrapf(successErr, "attempting to send task success")
+}
+
+// Failure fails the task. The error name is the TaskError ErrorName, or
+// the error type name for other errors, so that Catch and Retry
+// statements can match it.
+func (tc *TaskCallback) Failure(ctx context.Context, err error) error {
+	errorName, cause := taskErrorDetails(err)
+	_, failureErr := tc.sfnSvc.SendTaskFailureWithContext(ctx,
+		&sfn.SendTaskFailureInput{
+			TaskToken: aws.String(tc.TaskToken),
+			Error:     aws.String(errorName),
+			Cause:     aws.String(cause),
+		})
+	return errors.Wrapf(failureErr, "attempting to send task failure")
+}
+
+// Heartbeat reports that the task is still in progress
+func (tc *TaskCallback) Heartbeat(ctx context.Context) error {
+	_, heartbeatErr := tc.sfnSvc.SendTaskHeartbeatWithContext(ctx,
+		&sfn.SendTaskHeartbeatInput{
+			TaskToken: aws.String(tc.TaskToken),
+		})
+	return errors.Wrapf(heartbeatErr, "attempting to send task heartbeat")
+}
+
+// taskErrorDetails returns the error name and cause that are reported
+// for a failed task
+func taskErrorDetails(err error) (string, string) {
+	if failure, isFailure := taskFailure(err); isFailure {
+		return failure.ErrorName, failure.Cause
+	}
+	return string(StatesTaskFailed), err.Error()
+}
+
+// NewTaskCallback returns a TaskCallback for the task token
+func NewTaskCallback(awsSession *session.Session, taskToken string) *TaskCallback {
+	return &TaskCallback{
+		TaskToken: taskToken,
+		sfnSvc:    sfn.New(awsSession),
+	}
+}
@@ -0,0 +1,119 @@
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	sparta "github.com/mweagle/Sparta"
+)
+
+func TestWaitForTaskToken(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda("ApprovalRequest",
+		lambdaRollDie,
+		sparta.IAMRoleDefinition{})
+	lambdaState := NewLambdaTaskState("requestApproval", lambdaFn).
+		WithWaitForTaskToken()
+	sqsState := NewSQSTaskState("enqueueApproval", SQSTaskParameters{
+		MessageBody: "Approve?",
+		MessageAttributes: map[string]interface{}{
+			"Source": map[string]interface{}{
+				"DataType":    "String",
+				"StringValue": "workflow",
+			},
+		},
+	}).WithWaitForTaskToken()
+	snsState := NewSNSTaskState("notifyApprover", SNSTaskParameters{
+		Message: "Approve?",
+	}).WithWaitForTaskToken()
+
+	testCases := []struct {
+		state             MachineState
+		expectedFragments []string
+	}{
+		{
+			lambdaState,
+			[]string{`"arn:aws:states:::lambda:invoke.waitForTaskToken"`,
+				`"TaskToken.$":"$$.Task.Token"`,
+				`"Input.$":"$"`},
+		},
+		{
+			sqsState,
+			[]string{`"arn:aws:states:::sqs:sendMessage.waitForTaskToken"`,
+				`"TaskToken":{"DataType":"String","StringValue.$":"$$.Task.Token"}`,
+				`"Source":`},
+		},
+		{
+			snsState,
+			[]string{`"arn:aws:states:::sns:publish.waitForTaskToken"`,
+				`"TaskToken":{"DataType":"String","StringValue.$":"$$.Task.Token"}`},
+		},
+	}
+	for _, eachTestCase := range testCases {
+		stateJSON, stateJSONErr := json.Marshal(eachTestCase.state)
+		if stateJSONErr != nil {
+			t.Fatal(stateJSONErr)
+		}
+		for _, eachFragment := range eachTestCase.expectedFragments {
+			if !strings.Contains(string(stateJSON), eachFragment) {
+				t.Fatalf("Expected state %s to include %s, got: %s",
+					eachTestCase.state.Name(),
+					eachFragment,
+					string(stateJSON))
+			}
+		}
+	}
+	// The user attributes aren't modified
+	if len(sqsState.parameters.MessageAttributes) != 1 {
+		t.Fatalf("Expected the SQS message attributes to be unmodified")
+	}
+}
+
+func TestTaskCallback(t *testing.T) {
+	token, tokenExists := TaskTokenFromSQSMessage(awsLambdaEvents.SQSMessage{
+		MessageAttributes: map[string]awsLambdaEvents.SQSMessageAttribute{
+			TaskTokenAttributeName: {
+				StringValue: aws.String("sqsToken"),
+				DataType:    "String",
+			},
+		},
+	})
+	if !tokenExists || token != "sqsToken" {
+		t.Fatalf("Failed to read SQS task token, got: %s", token)
+	}
+	token, tokenExists = TaskTokenFromSNSEntity(awsLambdaEvents.SNSEntity{
+		MessageAttributes: map[string]interface{}{
+			TaskTokenAttributeName: map[string]interface{}{
+				"Type":  "String",
+				"Value": "snsToken",
+			},
+		},
+	})
+	if !tokenExists || token != "snsToken" {
+		t.Fatalf("Failed to read SNS task token, got: %s", token)
+	}
+
+	mockSFN := &mockActivitySFN{}
+	callback := &TaskCallback{
+		TaskToken: token,
+		sfnSvc:    mockSFN,
+	}
+	ctx := context.Background()
+	if heartbeatErr := callback.Heartbeat(ctx); heartbeatErr != nil {
+		t.Fatal(heartbeatErr)
+	}
+	if successErr := callback.Success(ctx, map[string]bool{"approved": true}); successErr != nil {
+		t.Fatal(successErr)
+	}
+	if failureErr := callback.Failure(ctx, NewTaskError("Approval.Rejected", "denied")); failureErr != nil {
+		t.Fatal(failureErr)
+	}
+	if mockSFN.heartbeats != 1 ||
+		mockSFN.output != `{"approved":true}` ||
+		mockSFN.errorName != "Approval.Rejected" {
+		t.Fatalf("Unexpected callback results: %#v", mockSFN)
+	}
+}
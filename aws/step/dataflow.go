@@ -0,0 +1,386 @@
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The data flow checks propagate the Go types of the LambdaTaskState
+// handler results through the machine and verify that the paths used by
+// downstream states exist in those types. A nil reflect.Type is an
+// unknown type, such as the execution input or a service integration
+// result, and isn't checked.
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// lambdaHandlerTypes returns the input and output types of a Go AWS
+// Lambda compliant handler. Either type is nil if it can't be
+// determined.
+func lambdaHandlerTypes(handler interface{}) (reflect.Type, reflect.Type) {
+	if handler == nil {
+		return nil, nil
+	}
+	handlerType := reflect.TypeOf(handler)
+	if handlerType.Kind() != reflect.Func {
+		return nil, nil
+	}
+	var inputType reflect.Type
+	switch handlerType.NumIn() {
+	case 1:
+		if !handlerType.In(0).Implements(contextType) {
+			inputType = handlerType.In(0)
+		}
+	case 2:
+		inputType = handlerType.In(1)
+	}
+	var outputType reflect.Type
+	if handlerType.NumOut() == 2 {
+		outputType = handlerType.Out(0)
+	}
+	return inputType, outputType
+}
+
+// jsonPathType returns the type of the value that the path selects in
+// a value of dataType. The result is nil if the type can't be determined.
+// Context object paths and invalid paths aren't checked.
+func jsonPathType(dataType reflect.Type, path string) (reflect.Type, error) {
+	if dataType == nil || path == "" || strings.HasPrefix(path, "$$") {
+		return nil, nil
+	}
+	tokens, tokensErr := jsonPathTokens(path)
+	if tokensErr != nil {
+		return nil, nil
+	}
+	currentType := dataType
+	for _, eachToken := range tokens {
+		for currentType.Kind() == reflect.Ptr {
+			currentType = currentType.Elem()
+		}
+		// Types with custom marshalling aren't checked
+		if currentType.Kind() == reflect.Interface ||
+			reflect.PtrTo(currentType).Implements(jsonUnmarshalerType) {
+			return nil, nil
+		}
+		switch typedToken := eachToken.(type) {
+		case string:
+			switch currentType.Kind() {
+			case reflect.Struct:
+				fieldType, found := jsonFieldType(currentType, typedToken)
+				if !found {
+					return nil, errors.Errorf("field `%s` isn't defined by %s", typedToken, currentType)
+				}
+				currentType = fieldType
+			case reflect.Map:
+				if currentType.Key().Kind() != reflect.String {
+					return nil, nil
+				}
+				currentType = currentType.Elem()
+			default:
+				return nil, errors.Errorf("field `%s` requires an object, but %s is a %s",
+					typedToken,
+					currentType,
+					currentType.Kind())
+			}
+		case int:
+			switch currentType.Kind() {
+			case reflect.Slice, reflect.Array:
+				currentType = currentType.Elem()
+			default:
+				return nil, errors.Errorf("index %d requires an array, but %s is a %s",
+					typedToken,
+					currentType,
+					currentType.Kind())
+			}
+		}
+	}
+	return currentType, nil
+}
+
+// jsonFieldType returns the type of the struct field with the JSON name,
+// including the fields of embedded structs
+func jsonFieldType(structType reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i != structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldName := jsonFieldName(field)
+		if fieldName == "" {
+			continue
+		}
+		_, hasTag := field.Tag.Lookup("json")
+		embeddedType := field.Type
+		for embeddedType.Kind() == reflect.Ptr {
+			embeddedType = embeddedType.Elem()
+		}
+		if field.Anonymous && !hasTag && embeddedType.Kind() == reflect.Struct {
+			if fieldType, found := jsonFieldType(embeddedType, name); found {
+				return fieldType, true
+			}
+			continue
+		}
+		if fieldName == name {
+			return field.Type, true
+		}
+	}
+	return nil, false
+}
+
+// parameterPaths returns the input paths selected by the `.$` keys of a
+// Parameters template
+func parameterPaths(params interface{}) []string {
+	paths := []string{}
+	switch typedParams := params.(type) {
+	case map[string]interface{}:
+		for eachKey, eachValue := range typedParams {
+			path, isPath := eachValue.(string)
+			if strings.HasSuffix(eachKey, ".$") && isPath {
+				paths = append(paths, path)
+				continue
+			}
+			paths = append(paths, parameterPaths(eachValue)...)
+		}
+	case []interface{}:
+		for _, eachValue := range typedParams {
+			paths = append(paths, parameterPaths(eachValue)...)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// choiceVariables returns the Variable paths used by the choice rule,
+// including the nested And, Or and Not rules
+func choiceVariables(choice ChoiceBranch) []string {
+	choiceJSON, choiceJSONErr := json.Marshal(choice)
+	if choiceJSONErr != nil {
+		return nil
+	}
+	var choiceData interface{}
+	if json.Unmarshal(choiceJSON, &choiceData) != nil {
+		return nil
+	}
+	variables := []string{}
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			for eachKey, eachValue := range typedValue {
+				if variable, isString := eachValue.(string); isString && eachKey == "Variable" {
+					variables = append(variables, variable)
+					continue
+				}
+				walk(eachValue)
+			}
+		case []interface{}:
+			for _, eachValue := range typedValue {
+				walk(eachValue)
+			}
+		}
+	}
+	walk(choiceData)
+	return variables
+}
+
+// stateDataFlow checks the paths of a state against its input type and
+// returns the state's output type
+func stateDataFlow(state MachineState, inputType reflect.Type) (reflect.Type, []error) {
+	dataFlowErrors := []error{}
+	checkPath := func(dataType reflect.Type, fieldName string, path string) reflect.Type {
+		pathType, pathErr := jsonPathType(dataType, path)
+		if pathErr != nil {
+			dataFlowErrors = append(dataFlowErrors,
+				stateValidationError(state, "%s `%s` doesn't match %s: %s",
+					fieldName,
+					path,
+					dataType,
+					pathErr))
+		}
+		return pathType
+	}
+	var bis *baseInnerState
+	if innerState, isInnerState := state.(interface {
+		innerState() *baseInnerState
+	}); isInnerState {
+		bis = innerState.innerState()
+	}
+	if bis == nil {
+		return nil, dataFlowErrors
+	}
+	effectiveInputType := inputType
+	if bis.inputPath != "" {
+		effectiveInputType = checkPath(inputType, "InputPath", bis.inputPath)
+	}
+
+	// The result is the output unless a ResultPath merges it into the input
+	var resultType reflect.Type
+	resultPath := ""
+	switch typedState := state.(type) {
+	case *LambdaTaskState:
+		if typedState.lambdaFn != nil {
+			_, resultType = lambdaHandlerTypes(typedState.lambdaFn.HandlerSymbol())
+		}
+		// The callback result is sent with SendTaskSuccess and isn't
+		// the handler's return type
+		if typedState.ResultSelector != nil || typedState.waitForTaskToken {
+			resultType = nil
+		}
+		resultPath = typedState.ResultPath
+	case *PassState:
+		if typedState.Result != nil {
+			resultType = reflect.TypeOf(typedState.Result)
+		} else if typedState.Parameters == nil {
+			resultType = effectiveInputType
+		}
+		resultPath = typedState.ResultPath
+	case *ChoiceState:
+		for _, eachChoice := range typedState.Choices {
+			for _, eachVariable := range choiceVariables(eachChoice) {
+				checkPath(effectiveInputType, "Variable", eachVariable)
+			}
+		}
+		resultType = effectiveInputType
+	case *WaitDynamicUntil:
+		checkPath(effectiveInputType, "TimestampPath", typedState.TimestampPath)
+		resultType = effectiveInputType
+	case *WaitDelay, *WaitUntil:
+		resultType = effectiveInputType
+	case *MapState:
+		itemsPath := typedState.ItemsPath
+		if itemsPath == "" {
+			itemsPath = "$"
+		}
+		itemsType := checkPath(effectiveInputType, "ItemsPath", itemsPath)
+		if itemsType != nil &&
+			itemsType.Kind() != reflect.Slice &&
+			itemsType.Kind() != reflect.Array {
+			dataFlowErrors = append(dataFlowErrors,
+				stateValidationError(state, "ItemsPath `%s` selects %s, which isn't an array",
+					itemsPath,
+					itemsType))
+		}
+		for _, eachPath := range parameterPaths(typedState.Parameters) {
+			checkPath(effectiveInputType, "Parameters path", eachPath)
+		}
+		// The iterator input is each item, unless Parameters are used
+		var iteratorInputType reflect.Type
+		if typedState.Parameters == nil &&
+			itemsType != nil &&
+			(itemsType.Kind() == reflect.Slice || itemsType.Kind() == reflect.Array) {
+			iteratorInputType = itemsType.Elem()
+		}
+		dataFlowErrors = append(dataFlowErrors,
+			typedState.Iterator.checkDataFlow(iteratorInputType)...)
+	case *ParallelState:
+		dataFlowErrors = append(dataFlowErrors,
+			typedState.States.checkDataFlow(effectiveInputType)...)
+	}
+	// Parameters select from the effective input
+	switch typedState := state.(type) {
+	case *PassState:
+		for _, eachPath := range parameterPaths(typedState.Parameters) {
+			checkPath(effectiveInputType, "Parameters path", eachPath)
+		}
+	case interface {
+		baseTask() *BaseTask
+	}:
+		for _, eachPath := range parameterPaths(typedState.baseTask().Parameters) {
+			checkPath(effectiveInputType, "Parameters path", eachPath)
+		}
+	}
+	var outputType reflect.Type
+	if resultPath == "" || resultPath == "$" {
+		outputType = resultType
+	}
+	if bis.outputPath != "" {
+		outputType = checkPath(outputType, "OutputPath", bis.outputPath)
+	}
+	return outputType, dataFlowErrors
+}
+
+// checkDataFlow propagates the state output types through the machine
+// and returns the errors for the paths that don't exist in the types.
+// States that are reachable by more than one transition only have a
+// known input type if every transition supplies the same type.
+func (sm *StateMachine) checkDataFlow(startInputType reflect.Type) []error {
+	if sm.startAt == nil {
+		return nil
+	}
+	// Missing entries haven't been reached, nil entries are unknown
+	inputTypes := make(map[string]reflect.Type)
+	pending := []MachineState{}
+	mergeInput := func(state MachineState, inputType reflect.Type) {
+		if state == nil {
+			return
+		}
+		existingType, exists := inputTypes[state.Name()]
+		if exists && existingType == inputType {
+			return
+		}
+		if exists && existingType == nil {
+			return
+		}
+		if exists {
+			inputType = nil
+		}
+		inputTypes[state.Name()] = inputType
+		pending = append(pending, state)
+	}
+	mergeInput(sm.startAt, startInputType)
+	for len(pending) != 0 {
+		state := pending[0]
+		pending = pending[1:]
+		outputType, _ := stateDataFlow(state, inputTypes[state.Name()])
+
+		var catchers []*TaskCatch
+		switch typedState := state.(type) {
+		case *ChoiceState:
+			for _, eachChoice := range typedState.Choices {
+				mergeInput(eachChoice.nextState(), outputType)
+			}
+			if typedState.Default != nil {
+				mergeInput(typedState.Default, outputType)
+			}
+			continue
+		case *ParallelState:
+			catchers = typedState.Catchers
+		case *MapState:
+			catchers = typedState.Catchers
+		case interface {
+			baseTask() *BaseTask
+		}:
+			catchers = typedState.baseTask().Catchers
+		}
+		if transitionState, isTransitionState := state.(TransitionState); isTransitionState {
+			// The error output of a Catch is merged into the input
+			adjacentStates := transitionState.AdjacentStates()
+			for index, eachAdjacentState := range adjacentStates {
+				if index >= len(adjacentStates)-len(catchers) {
+					mergeInput(eachAdjacentState, nil)
+				} else {
+					mergeInput(eachAdjacentState, outputType)
+				}
+			}
+		}
+	}
+
+	// Check each reachable state with its final input type
+	stateNames := make([]string, 0, len(inputTypes))
+	for eachName := range inputTypes {
+		stateNames = append(stateNames, eachName)
+	}
+	sort.Strings(stateNames)
+	dataFlowErrors := []error{}
+	for _, eachName := range stateNames {
+		state, exists := sm.uniqueStates[eachName]
+		if !exists {
+			continue
+		}
+		_, stateErrors := stateDataFlow(state, inputTypes[eachName])
+		dataFlowErrors = append(dataFlowErrors, stateErrors...)
+	}
+	return dataFlowErrors
+}
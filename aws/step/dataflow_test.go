@@ -0,0 +1,126 @@
+package step
+
+import (
+	"context"
+	"testing"
+
+	sparta "github.com/mweagle/Sparta"
+)
+
+type dataFlowOrderItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+type dataFlowAudit struct {
+	Approver string `json:"approver"`
+}
+
+type dataFlowOrder struct {
+	dataFlowAudit
+	ID       string              `json:"id"`
+	Customer string              `json:"customer,omitempty"`
+	Items    []dataFlowOrderItem `json:"items"`
+	Meta     map[string]string   `json:"meta"`
+	internal string
+}
+
+func lambdaLoadOrder(ctx context.Context) (dataFlowOrder, error) {
+	return dataFlowOrder{}, nil
+}
+
+func TestFieldPath(t *testing.T) {
+	var order dataFlowOrder
+	testCases := []struct {
+		fieldPtr     interface{}
+		expectedPath JSONPath
+	}{
+		{&order.ID, "$.id"},
+		{&order.Customer, "$.customer"},
+		{&order.Approver, "$.approver"},
+		{&order.Meta, "$.meta"},
+	}
+	for _, eachTestCase := range testCases {
+		path, pathErr := FieldPath(&order, eachTestCase.fieldPtr)
+		if pathErr != nil {
+			t.Fatal(pathErr)
+		}
+		if path != eachTestCase.expectedPath {
+			t.Fatalf("Expected path %s, got %s", eachTestCase.expectedPath, path)
+		}
+	}
+	if _, pathErr := FieldPath(&order, &order.internal); pathErr == nil {
+		t.Fatalf("Failed to reject unexported field")
+	}
+	itemPath := MustFieldPath(&order, &order.Items).Index(0).Field("sku")
+	if itemPath != "$.items[0].sku" {
+		t.Fatalf("Unexpected item path: %s", itemPath)
+	}
+	if RootPath.Field("order id") != "$['order id']" {
+		t.Fatalf("Unexpected bracket path: %s", RootPath.Field("order id"))
+	}
+}
+
+func TestDataFlow(t *testing.T) {
+	newOrderMachine := func(variable JSONPath, itemsPath JSONPath) *StateMachine {
+		lambdaFn, _ := sparta.NewAWSLambda("DataFlowLoadOrder",
+			lambdaLoadOrder,
+			sparta.IAMRoleDefinition{})
+		loadState := NewLambdaTaskState("loadOrder", lambdaFn)
+		successState := NewSuccessState("success")
+		mapState := NewMapState("eachItem",
+			*NewStateMachine("eachItemIterator",
+				NewPassState("countItem", nil).WithOutputPath("$.quantity"))).
+			WithItemsPath(itemsPath.String())
+		mapState.Next(successState)
+		choiceState := NewChoiceState("checkCustomer",
+			&And{
+				Comparison: []Comparison{
+					&StringEquals{
+						Variable: variable.String(),
+						Value:    "ACME",
+					},
+				},
+				Next: mapState,
+			}).WithDefault(successState)
+		loadState.Next(choiceState)
+		return NewStateMachine("DataFlowMachine", loadState)
+	}
+	var order dataFlowOrder
+	testValidationErrors(t,
+		newOrderMachine(MustFieldPath(&order, &order.Customer),
+			MustFieldPath(&order, &order.Items)))
+	testValidationErrors(t,
+		newOrderMachine(RootPath.Field("customerName"),
+			MustFieldPath(&order, &order.Items)),
+		"state `checkCustomer`: Variable `$.customerName` doesn't match step.dataFlowOrder")
+	testValidationErrors(t,
+		newOrderMachine(MustFieldPath(&order, &order.Customer),
+			MustFieldPath(&order, &order.ID)),
+		"state `eachItem`: ItemsPath `$.id` selects string, which isn't an array")
+	testValidationErrors(t,
+		newOrderMachine(MustFieldPath(&order, &order.Customer),
+			RootPath.Field("items").Index(0)),
+		"state `eachItem`: ItemsPath `$.items[0]` selects step.dataFlowOrderItem")
+}
+
+func TestDataFlowWaitForTaskToken(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda("DataFlowRequestApproval",
+		lambdaLoadOrder,
+		sparta.IAMRoleDefinition{})
+	// The approval decision is only in the TaskCallback result
+	approvalState := NewLambdaTaskState("requestApproval", lambdaFn).WithWaitForTaskToken()
+	approvedState := NewSuccessState("approved")
+	choiceState := NewChoiceState("checkApproval",
+		&And{
+			Comparison: []Comparison{
+				&StringEquals{
+					Variable: "$.decision",
+					Value:    "approved",
+				},
+			},
+			Next: approvedState,
+		}).WithDefault(NewFailState("rejected", "Rejected", nil))
+	approvalState.Next(choiceState)
+	testValidationErrors(t, NewStateMachine("DataFlowCallbackMachine", approvalState))
+}
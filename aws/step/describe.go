@@ -0,0 +1,222 @@
+package step
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	sparta "github.com/mweagle/Sparta"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	nodeColorStateMachine = "#CD2264"
+	nodeColorState        = "#E7157B"
+	iconStepFunctions     = "AWSIcons/Application Services/ApplicationServices_AWSStepFunctions.svg"
+	iconCloudWatch        = "AWSIcons/Management Tools/ManagementTools_AmazonCloudWatch.svg"
+	iconS3Bucket          = "AWSIcons/Storage/Storage_AmazonS3_bucket.svg"
+	iconAPIGateway        = "AWSIcons/Application Services/ApplicationServices_AmazonAPIGateway.svg"
+)
+
+// stateNodeName returns the describe node name for the state. State
+// names are only unique within a ParallelState branch or MapState
+// iterator, so the name is prefixed with the enclosing scope.
+func stateNodeName(scope string, state MachineState) string {
+	return fmt.Sprintf("%s.%s", scope, state.Name())
+}
+
+// describeStates adds the nodes and links for the states in the machine,
+// including the states in ParallelState branches and MapState iterators.
+// The scope is the node name prefix of the states.
+func (sm *StateMachine) describeStates(states *StateMachine,
+	scope string,
+	descriptionInfo *sparta.DescriptionInfo) {
+	stateNames := make([]string, 0, len(states.uniqueStates))
+	for eachName := range states.uniqueStates {
+		stateNames = append(stateNames, eachName)
+	}
+	sort.Strings(stateNames)
+
+	addLink := func(from MachineState, to MachineState, label string) {
+		if to == nil {
+			return
+		}
+		descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+			From:  stateNodeName(scope, from),
+			To:    stateNodeName(scope, to),
+			Label: label,
+		})
+	}
+	for _, eachName := range stateNames {
+		eachState := states.uniqueStates[eachName]
+		descriptionInfo.Nodes = append(descriptionInfo.Nodes, sparta.DescriptionNode{
+			Name:  stateNodeName(scope, eachState),
+			Color: nodeColorState,
+			Icon:  iconStepFunctions,
+		})
+
+		// Transitions
+		var catchers []*TaskCatch
+		switch typedState := eachState.(type) {
+		case *ChoiceState:
+			for _, eachChoice := range typedState.Choices {
+				addLink(typedState, eachChoice.nextState(), "Choice")
+			}
+			if typedState.Default != nil {
+				addLink(typedState, typedState.Default, "Default")
+			}
+		case *ParallelState:
+			catchers = typedState.Catchers
+			branchScope := stateNodeName(scope, typedState)
+			if typedState.States.startAt != nil {
+				descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+					From:  branchScope,
+					To:    stateNodeName(branchScope, typedState.States.startAt),
+					Label: "Branch",
+				})
+			}
+			sm.describeStates(&typedState.States, branchScope, descriptionInfo)
+		case *MapState:
+			catchers = typedState.Catchers
+			iteratorScope := stateNodeName(scope, typedState)
+			if typedState.Iterator.startAt != nil {
+				descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+					From:  iteratorScope,
+					To:    stateNodeName(iteratorScope, typedState.Iterator.startAt),
+					Label: "Iterator",
+				})
+			}
+			sm.describeStates(&typedState.Iterator, iteratorScope, descriptionInfo)
+		case interface {
+			baseTask() *BaseTask
+		}:
+			catchers = typedState.baseTask().Catchers
+		}
+		if transitionState, isTransitionState := eachState.(TransitionState); isTransitionState {
+			// AdjacentStates lists the Next state before the Catch states
+			adjacentStates := transitionState.AdjacentStates()
+			for index, eachAdjacentState := range adjacentStates {
+				label := "Next"
+				if index >= len(adjacentStates)-len(catchers) {
+					label = "Catch"
+				}
+				addLink(eachState, eachAdjacentState, label)
+			}
+		}
+
+		// Resources the state uses
+		switch typedState := eachState.(type) {
+		case *LambdaTaskState:
+			if typedState.lambdaFn != nil {
+				descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+					From:  stateNodeName(scope, typedState),
+					To:    typedState.lambdaFn.LogicalResourceName(),
+					Label: "Invoke",
+				})
+			}
+		case *ActivityTaskState:
+			if typedState.activity != nil {
+				activityNodeName := fmt.Sprintf("Activity: %s", typedState.activity.Name)
+				descriptionInfo.Nodes = append(descriptionInfo.Nodes, sparta.DescriptionNode{
+					Name:  activityNodeName,
+					Color: nodeColorState,
+					Icon:  iconStepFunctions,
+				})
+				descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+					From:  stateNodeName(scope, typedState),
+					To:    activityNodeName,
+					Label: "Activity",
+				})
+			}
+		}
+	}
+}
+
+// DescribeService returns the state graph for the `describe` output.
+// Include the StateMachine in the WorkflowHooks ServiceDecorators slice,
+// rather than the StateMachineDecorator() value, to add the graph to the
+// report.
+func (sm *StateMachine) DescribeService(serviceName string) (*sparta.DescriptionInfo, error) {
+	descriptionInfo := &sparta.DescriptionInfo{
+		Nodes: []sparta.DescriptionNode{
+			{
+				Name:  sm.name,
+				Color: nodeColorStateMachine,
+				Icon:  iconStepFunctions,
+			},
+		},
+		Links: []sparta.DescriptionLink{
+			{
+				From: sm.name,
+				To:   serviceName,
+			},
+		},
+	}
+	if sm.startAt != nil {
+		descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+			From:  sm.name,
+			To:    stateNodeName(sm.name, sm.startAt),
+			Label: "StartAt",
+		})
+	}
+	sm.describeStates(sm, sm.name, descriptionInfo)
+
+	// Triggers
+	triggerNames := make([]string, 0)
+	triggerIcons := make(map[string]string)
+	for eachName := range sm.eventTriggers {
+		triggerNames = append(triggerNames, eachName)
+		triggerIcons[eachName] = iconCloudWatch
+	}
+	for eachName, eachTrigger := range sm.s3Triggers {
+		triggerName := fmt.Sprintf("%s (%s)", eachName, eachTrigger.BucketName)
+		triggerNames = append(triggerNames, triggerName)
+		triggerIcons[triggerName] = iconS3Bucket
+	}
+	if sm.apiGatewayTrigger != nil {
+		methods := sm.apiGatewayTrigger.Methods
+		for eachPath, eachHTTPMethods := range methods {
+			for _, eachHTTPMethod := range eachHTTPMethods {
+				triggerName := fmt.Sprintf("%s - %s", eachHTTPMethod, eachPath)
+				triggerNames = append(triggerNames, triggerName)
+				triggerIcons[triggerName] = iconAPIGateway
+			}
+		}
+	}
+	sort.Strings(triggerNames)
+	for _, eachName := range triggerNames {
+		descriptionInfo.Nodes = append(descriptionInfo.Nodes, sparta.DescriptionNode{
+			Name: eachName,
+			Icon: triggerIcons[eachName],
+		})
+		descriptionInfo.Links = append(descriptionInfo.Links, sparta.DescriptionLink{
+			From:  eachName,
+			To:    sm.name,
+			Label: "StartExecution",
+		})
+	}
+	return descriptionInfo, nil
+}
+
+// DecorateService satisfies the sparta.ServiceDecoratorHookHandler
+// interface using the StateMachineDecorator() hook
+func (sm *StateMachine) DecorateService(context map[string]interface{},
+	serviceName string,
+	template *gocf.Template,
+	S3Bucket string,
+	S3Key string,
+	buildID string,
+	awsSession *session.Session,
+	noop bool,
+	logger *logrus.Logger) error {
+	return sm.StateMachineDecorator()(context,
+		serviceName,
+		template,
+		S3Bucket,
+		S3Key,
+		buildID,
+		awsSession,
+		noop,
+		logger)
+}
@@ -0,0 +1,101 @@
+package step
+
+import (
+	"testing"
+
+	sparta "github.com/mweagle/Sparta"
+)
+
+func TestDescribeStateMachine(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda("DescribeRollDie",
+		lambdaRollDie,
+		sparta.IAMRoleDefinition{})
+	lambdaTaskState := NewLambdaTaskState("lambdaRollDie", lambdaFn)
+	successState := NewSuccessState("success")
+	failState := NewFailState("failed", "RollFailed", nil)
+	choiceState := NewChoiceState("checkRoll",
+		&And{
+			Comparison: []Comparison{
+				&NumericGreaterThan{
+					Variable: "$.roll",
+					Value:    3,
+				},
+			},
+			Next: successState,
+		}).WithDefault(failState)
+	lambdaTaskState.WithCatchers(NewTaskCatch(failState, StatesAll))
+	lambdaTaskState.Next(choiceState)
+
+	sm := NewStateMachine("DescribeMachine", lambdaTaskState).
+		WithS3Triggers(map[string]S3Trigger{
+			"Uploads": {BucketName: "my-upload-bucket"},
+		})
+	descriptionInfo, descriptionInfoErr := sm.DescribeService("DescribeService")
+	if descriptionInfoErr != nil {
+		t.Fatal(descriptionInfoErr)
+	}
+	// Machine, four states and the trigger
+	if len(descriptionInfo.Nodes) != 6 {
+		t.Fatalf("Expected 6 nodes, got %d: %#v", len(descriptionInfo.Nodes), descriptionInfo.Nodes)
+	}
+	expectedLinks := []sparta.DescriptionLink{
+		{From: "DescribeMachine", To: "DescribeMachine.lambdaRollDie", Label: "StartAt"},
+		{From: "DescribeMachine.lambdaRollDie", To: "DescribeMachine.checkRoll", Label: "Next"},
+		{From: "DescribeMachine.lambdaRollDie", To: "DescribeMachine.failed", Label: "Catch"},
+		{From: "DescribeMachine.lambdaRollDie", To: lambdaFn.LogicalResourceName(), Label: "Invoke"},
+		{From: "DescribeMachine.checkRoll", To: "DescribeMachine.success", Label: "Choice"},
+		{From: "DescribeMachine.checkRoll", To: "DescribeMachine.failed", Label: "Default"},
+		{From: "Uploads (my-upload-bucket)", To: "DescribeMachine", Label: "StartExecution"},
+	}
+	for _, eachExpected := range expectedLinks {
+		found := false
+		for _, eachLink := range descriptionInfo.Links {
+			if eachLink == eachExpected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Expected link %#v, got: %#v", eachExpected, descriptionInfo.Links)
+		}
+	}
+}
+
+func TestDescribeNestedStateNames(t *testing.T) {
+	// Each branch has a state named `work`
+	firstParallelState := NewParallelState("first",
+		*NewStateMachine("firstBranch", NewPassState("work", nil)))
+	secondParallelState := NewParallelState("second",
+		*NewStateMachine("secondBranch", NewPassState("work", nil)))
+	firstParallelState.Next(secondParallelState)
+
+	sm := NewStateMachine("NestedMachine", firstParallelState)
+	descriptionInfo, descriptionInfoErr := sm.DescribeService("DescribeService")
+	if descriptionInfoErr != nil {
+		t.Fatal(descriptionInfoErr)
+	}
+	nodeNames := make(map[string]bool)
+	for _, eachNode := range descriptionInfo.Nodes {
+		nodeNames[eachNode.Name] = true
+	}
+	for _, eachExpected := range []string{"NestedMachine.first.work", "NestedMachine.second.work"} {
+		if !nodeNames[eachExpected] {
+			t.Fatalf("Expected node %s, got: %#v", eachExpected, descriptionInfo.Nodes)
+		}
+	}
+	expectedLink := sparta.DescriptionLink{
+		From:  "NestedMachine.second",
+		To:    "NestedMachine.second.work",
+		Label: "Branch",
+	}
+	found := false
+	for _, eachLink := range descriptionInfo.Links {
+		if eachLink == expectedLink {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatalf("Expected link %#v, got: %#v", expectedLink, descriptionInfo.Links)
+	}
+}
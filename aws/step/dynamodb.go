@@ -4,6 +4,7 @@ import (
 	"math/rand"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -43,6 +44,14 @@ func (dgis *DynamoDBGetItemState) MarshalJSON() ([]byte, error) {
 		&dgis.parameters)
 }
 
+// statePolicyStatements returns the privileges to read the item
+func (dgis *DynamoDBGetItemState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(stringableResourceARN(dgis.parameters.TableName, "dynamodb", "table/"),
+			"dynamodb:GetItem"),
+	}
+}
+
 // NewDynamoDBGetItemState returns an initialized DynamoDB GetItem state
 func NewDynamoDBGetItemState(stateName string,
 	parameters DynamoDBGetItemParameters) *DynamoDBGetItemState {
@@ -98,6 +107,14 @@ func (dgis *DynamoDBPutItemState) MarshalJSON() ([]byte, error) {
 		&dgis.parameters)
 }
 
+// statePolicyStatements returns the privileges to write the item
+func (dgis *DynamoDBPutItemState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(stringableResourceARN(dgis.parameters.TableName, "dynamodb", "table/"),
+			"dynamodb:PutItem"),
+	}
+}
+
 // NewDynamoDBPutItemState returns an initialized DynamoDB PutItem state
 func NewDynamoDBPutItemState(stateName string,
 	parameters DynamoDBPutItemParameters) *DynamoDBPutItemState {
@@ -3,6 +3,7 @@ package step
 import (
 	"math/rand"
 
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -24,6 +25,12 @@ type FargateTaskParameters struct {
 	PlacementStrategy    []map[string]string          `json:",omitempty"`
 	PlatformVersion      string                       `json:",omitempty"`
 	TaskDefinition       gocf.Stringable              `json:",omitempty"`
+	// TaskRoleArn and ExecutionRoleArn are the IAM roles referenced by the
+	// TaskDefinition. They aren't included in the state parameters, but the
+	// state machine is granted iam:PassRole to the roles. If neither is
+	// set, iam:PassRole is granted to every role passed to ECS tasks.
+	TaskRoleArn      gocf.Stringable `json:"-"`
+	ExecutionRoleArn gocf.Stringable `json:"-"`
 }
 
 // FargateTaskState represents a FargateTask
@@ -40,6 +47,50 @@ func (fts *FargateTaskState) MarshalJSON() ([]byte, error) {
 		&fts.parameters)
 }
 
+// statePolicyStatements returns the privileges to run the task and
+// wait for it to complete
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/ecs-iam.html
+func (fts *FargateTaskState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	taskDefinition := gocf.String("*")
+	if fts.parameters.TaskDefinition != nil {
+		taskDefinition = fts.parameters.TaskDefinition.String()
+	}
+	statements := []spartaIAM.PolicyStatement{
+		allowStatement(taskDefinition, "ecs:RunTask"),
+		allowStatement(gocf.String("*"),
+			"ecs:StopTask",
+			"ecs:DescribeTasks"),
+		syncEventRuleStatement("StepFunctionsGetEventsForECSTaskRule"),
+	}
+	return append(statements, fargatePassRoleStatements(fts.parameters.TaskRoleArn,
+		fts.parameters.ExecutionRoleArn)...)
+}
+
+// fargatePassRoleStatements returns the privilege to pass the task
+// definition's roles to ECS
+func fargatePassRoleStatements(roleArns ...gocf.Stringable) []spartaIAM.PolicyStatement {
+	var roleResources []*gocf.StringExpr
+	for _, eachRoleArn := range roleArns {
+		if eachRoleArn != nil {
+			roleResources = append(roleResources, eachRoleArn.String())
+		}
+	}
+	if len(roleResources) == 0 {
+		roleResources = append(roleResources, gocf.String("*"))
+	}
+	statements := make([]spartaIAM.PolicyStatement, 0, len(roleResources))
+	for _, eachResource := range roleResources {
+		passRole := allowStatement(eachResource, "iam:PassRole")
+		passRole.Condition = map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"iam:PassedToService": "ecs-tasks.amazonaws.com",
+			},
+		}
+		statements = append(statements, passRole)
+	}
+	return statements
+}
+
 // NewFargateTaskState returns an initialized FargateTaskState
 func NewFargateTaskState(stateName string, parameters FargateTaskParameters) *FargateTaskState {
 	ft := &FargateTaskState{
@@ -3,6 +3,7 @@ package step
 import (
 	"math/rand"
 
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -33,6 +34,19 @@ func (gs *GlueState) MarshalJSON() ([]byte, error) {
 		&gs.parameters)
 }
 
+// statePolicyStatements returns the privileges to start the job run
+// and wait for it to complete
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/glue-iam.html
+func (gs *GlueState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(stringableNamedResourceARN(gs.parameters.JobName, "glue", "job/"),
+			"glue:StartJobRun",
+			"glue:GetJobRun",
+			"glue:GetJobRuns",
+			"glue:BatchStopJobRun"),
+	}
+}
+
 // NewGlueState returns an initialized GlueState
 func NewGlueState(stateName string,
 	parameters GlueParameters) *GlueState {
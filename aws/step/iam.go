@@ -0,0 +1,177 @@
+package step
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
+	gocf "github.com/mweagle/go-cloudformation"
+)
+
+// statePrivileger is implemented by states that require IAM privileges
+// in the role assumed by the state machine. The statements are merged
+// into the StatesIAMRole created by the StateMachine decorator.
+type statePrivileger interface {
+	statePolicyStatements() []spartaIAM.PolicyStatement
+}
+
+// regionalARN returns an ARN expression for a resource in the current
+// region and account
+func regionalARN(service string, resource string) *gocf.StringExpr {
+	return gocf.Join("",
+		gocf.String(fmt.Sprintf("arn:aws:%s:", service)),
+		gocf.Ref("AWS::Region"),
+		gocf.String(":"),
+		gocf.Ref("AWS::AccountId"),
+		gocf.String(":"),
+		gocf.String(resource))
+}
+
+// allowStatement returns an Allow statement for the actions and resource
+func allowStatement(resource *gocf.StringExpr, actions ...string) spartaIAM.PolicyStatement {
+	return spartaIAM.PolicyStatement{
+		Effect:   "Allow",
+		Action:   actions,
+		Resource: resource,
+	}
+}
+
+// syncEventRuleStatement returns the statement that allows Step Functions
+// to manage the CloudWatch Events rule it uses to monitor the completion
+// of `.sync` service integrations.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/stepfunctions-iam.html
+func syncEventRuleStatement(ruleName string) spartaIAM.PolicyStatement {
+	return allowStatement(regionalARN("events", fmt.Sprintf("rule/%s", ruleName)),
+		"events:PutTargets",
+		"events:PutRule",
+		"events:DescribeRule")
+}
+
+// stringableRef returns the logical resource name if the value is a
+// `Ref` to a resource in this template, or the literal value if the
+// value is a string. Other intrinsic functions return two empty values.
+func stringableRef(value gocf.Stringable) (string, string) {
+	if value == nil || value.String() == nil {
+		return "", ""
+	}
+	jsonBytes, jsonBytesErr := json.Marshal(value.String())
+	if jsonBytesErr != nil {
+		return "", ""
+	}
+	var literal string
+	if json.Unmarshal(jsonBytes, &literal) == nil {
+		return "", literal
+	}
+	var refFunc struct {
+		Ref string
+	}
+	if json.Unmarshal(jsonBytes, &refFunc) == nil &&
+		refFunc.Ref != "" &&
+		!strings.HasPrefix(refFunc.Ref, "AWS::") {
+		return refFunc.Ref, ""
+	}
+	return "", ""
+}
+
+// stringableResourceARN returns the ARN of the named resource. If the
+// value is a `Ref` to a resource in this template, the ARN is the
+// resource's `Arn` attribute. Otherwise the ARN is built from the
+// resource name, or scoped to every resource of the type if the name
+// can't be determined.
+func stringableResourceARN(value gocf.Stringable,
+	service string,
+	resourceType string) *gocf.StringExpr {
+	refName, literal := stringableRef(value)
+	if refName != "" {
+		return gocf.GetAtt(refName, "Arn")
+	}
+	if strings.HasPrefix(literal, "arn:") {
+		return gocf.String(literal)
+	}
+	if literal == "" {
+		literal = "*"
+	}
+	return regionalARN(service, fmt.Sprintf("%s%s", resourceType, literal))
+}
+
+// stringableNamedResourceARN returns the ARN of the named resource for
+// resource types without an `Arn` attribute. If the value is a `Ref` to a
+// resource in this template, the ARN is built from the resource name that
+// the `Ref` evaluates to.
+func stringableNamedResourceARN(value gocf.Stringable,
+	service string,
+	resourceType string) *gocf.StringExpr {
+	refName, _ := stringableRef(value)
+	if refName == "" {
+		return stringableResourceARN(value, service, resourceType)
+	}
+	return gocf.Join("",
+		gocf.String(fmt.Sprintf("arn:aws:%s:", service)),
+		gocf.Ref("AWS::Region"),
+		gocf.String(":"),
+		gocf.Ref("AWS::AccountId"),
+		gocf.String(fmt.Sprintf(":%s", resourceType)),
+		gocf.Ref(refName))
+}
+
+// sqsQueueARN returns the ARN of the queue with the given URL
+func sqsQueueARN(queueURL gocf.Stringable) *gocf.StringExpr {
+	refName, literal := stringableRef(queueURL)
+	if refName != "" {
+		return gocf.GetAtt(refName, "Arn")
+	}
+	// https://sqs.us-east-1.amazonaws.com/123456789012/MyQueue
+	urlParts := strings.Split(literal, "/")
+	if len(urlParts) == 5 && strings.HasPrefix(urlParts[2], "sqs.") {
+		hostParts := strings.Split(urlParts[2], ".")
+		if len(hostParts) >= 2 {
+			return gocf.String(fmt.Sprintf("arn:aws:sqs:%s:%s:%s",
+				hostParts[1],
+				urlParts[3],
+				urlParts[4]))
+		}
+	}
+	return regionalARN("sqs", "*")
+}
+
+// stateMachinePolicyStatements returns the statements required by all
+// the states in the machine, including the states in ParallelState
+// branches and MapState iterators. Duplicate statements are only
+// included once.
+func stateMachinePolicyStatements(sm *StateMachine) []spartaIAM.PolicyStatement {
+	stateNames := make([]string, 0, len(sm.uniqueStates))
+	for eachName := range sm.uniqueStates {
+		stateNames = append(stateNames, eachName)
+	}
+	sort.Strings(stateNames)
+
+	statements := make([]spartaIAM.PolicyStatement, 0)
+	for _, eachName := range stateNames {
+		switch typedState := sm.uniqueStates[eachName].(type) {
+		case statePrivileger:
+			statements = append(statements, typedState.statePolicyStatements()...)
+		case *ParallelState:
+			statements = append(statements,
+				stateMachinePolicyStatements(&typedState.States)...)
+		case *MapState:
+			statements = append(statements,
+				stateMachinePolicyStatements(&typedState.Iterator)...)
+		}
+	}
+	// Dedupe
+	uniqueStatements := make([]spartaIAM.PolicyStatement, 0)
+	existingStatements := make(map[string]bool)
+	for _, eachStatement := range statements {
+		statementJSON, statementJSONErr := json.Marshal(eachStatement)
+		if statementJSONErr == nil {
+			if existingStatements[string(statementJSON)] {
+				continue
+			}
+			existingStatements[string(statementJSON)] = true
+		}
+		uniqueStatements = append(uniqueStatements, eachStatement)
+	}
+	return uniqueStatements
+}
@@ -0,0 +1,154 @@
+package step
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	gocf "github.com/mweagle/go-cloudformation"
+)
+
+func TestStateMachinePolicyStatements(t *testing.T) {
+	snsState := NewSNSTaskState("notify", SNSTaskParameters{
+		Message:  "Hello",
+		TopicArn: gocf.String("arn:aws:sns:us-west-2:123412341234:MyTopic"),
+	})
+	sqsState := NewSQSTaskState("enqueue", SQSTaskParameters{
+		MessageBody: "Hello",
+		QueueURL:    gocf.String("https://sqs.us-west-2.amazonaws.com/123412341234/MyQueue"),
+	})
+	dynamoState := NewDynamoDBGetItemState("lookup", DynamoDBGetItemParameters{
+		TableName: gocf.Ref("MyTable"),
+	})
+	batchState := NewBatchTaskState("submit", BatchTaskParameters{
+		JobName: "MyJob",
+	})
+	// Duplicate statements are merged
+	batchRetryState := NewBatchTaskState("resubmit", BatchTaskParameters{
+		JobName: "MyJob",
+	})
+	snsState.Next(sqsState)
+	sqsState.Next(dynamoState)
+	dynamoState.Next(batchState)
+	batchState.Next(batchRetryState)
+
+	statements := stateMachinePolicyStatements(NewStateMachine("PolicyMachine", snsState))
+	statementsJSON, statementsJSONErr := json.Marshal(statements)
+	if statementsJSONErr != nil {
+		t.Fatal(statementsJSONErr)
+	}
+	expectedFragments := []string{
+		`"sns:Publish"`,
+		`"arn:aws:sns:us-west-2:123412341234:MyTopic"`,
+		`"sqs:SendMessage"`,
+		`"arn:aws:sqs:us-west-2:123412341234:MyQueue"`,
+		`"dynamodb:GetItem"`,
+		`"batch:SubmitJob"`,
+		`"events:PutRule"`,
+		`rule/StepFunctionsGetEventsForBatchJobsRule`,
+	}
+	for _, eachFragment := range expectedFragments {
+		if !strings.Contains(string(statementsJSON), eachFragment) {
+			t.Fatalf("Expected policy statements to include %s, got: %s",
+				eachFragment,
+				string(statementsJSON))
+		}
+	}
+	// SNS, SQS, DynamoDB and the two Batch statements
+	if len(statements) != 5 {
+		t.Fatalf("Expected 5 statements, got %d: %s", len(statements), string(statementsJSON))
+	}
+}
+
+func TestFargatePassRoleStatements(t *testing.T) {
+	for _, eachTestCase := range []struct {
+		parameters        FargateTaskParameters
+		expectedResources []string
+	}{
+		{
+			FargateTaskParameters{
+				TaskDefinition:   gocf.Ref("TaskDefinition"),
+				TaskRoleArn:      gocf.GetAtt("TaskRole", "Arn"),
+				ExecutionRoleArn: gocf.String("arn:aws:iam::123412341234:role/ExecutionRole"),
+			},
+			[]string{
+				`{"Fn::GetAtt":["TaskRole","Arn"]}`,
+				`"arn:aws:iam::123412341234:role/ExecutionRole"`,
+			},
+		},
+		{
+			FargateTaskParameters{
+				TaskDefinition: gocf.Ref("TaskDefinition"),
+			},
+			[]string{`"*"`},
+		},
+	} {
+		fargateState := NewFargateTaskState("runTask", eachTestCase.parameters)
+		var passRoleStatements []string
+		for _, eachStatement := range fargateState.statePolicyStatements() {
+			if eachStatement.Action[0] != "iam:PassRole" {
+				continue
+			}
+			statementJSON, statementJSONErr := json.Marshal(eachStatement)
+			if statementJSONErr != nil {
+				t.Fatal(statementJSONErr)
+			}
+			passRoleStatements = append(passRoleStatements, string(statementJSON))
+		}
+		if len(passRoleStatements) != len(eachTestCase.expectedResources) {
+			t.Fatalf("Expected %d iam:PassRole statements, got: %v",
+				len(eachTestCase.expectedResources),
+				passRoleStatements)
+		}
+		for eachIndex, eachResource := range eachTestCase.expectedResources {
+			if !strings.Contains(passRoleStatements[eachIndex], eachResource) ||
+				!strings.Contains(passRoleStatements[eachIndex], `"ecs-tasks.amazonaws.com"`) {
+				t.Fatalf("Unexpected iam:PassRole statement: %s", passRoleStatements[eachIndex])
+			}
+		}
+		// The roles aren't RunTask parameters
+		stateJSON, stateJSONErr := json.Marshal(fargateState)
+		if stateJSONErr != nil {
+			t.Fatal(stateJSONErr)
+		}
+		if strings.Contains(string(stateJSON), "RoleArn") {
+			t.Fatalf("Unexpected role parameters: %s", string(stateJSON))
+		}
+	}
+}
+
+func TestGluePolicyStatements(t *testing.T) {
+	for _, eachTestCase := range []struct {
+		jobName          gocf.Stringable
+		expectedResource *gocf.StringExpr
+	}{
+		{
+			gocf.String("MyJob"),
+			regionalARN("glue", "job/MyJob"),
+		},
+		{
+			// AWS::Glue::Job doesn't have an Arn attribute
+			gocf.Ref("MyJob"),
+			gocf.Join("",
+				gocf.String("arn:aws:glue:"),
+				gocf.Ref("AWS::Region"),
+				gocf.String(":"),
+				gocf.Ref("AWS::AccountId"),
+				gocf.String(":job/"),
+				gocf.Ref("MyJob")),
+		},
+	} {
+		glueState := NewGlueState("runJob", GlueParameters{
+			JobName: eachTestCase.jobName,
+		})
+		statements := glueState.statePolicyStatements()
+		if len(statements) != 1 {
+			t.Fatalf("Expected 1 statement, got: %#v", statements)
+		}
+		if !reflect.DeepEqual(statements[0].Resource, eachTestCase.expectedResource) {
+			resourceJSON, _ := json.Marshal(statements[0].Resource)
+			t.Fatalf("Unexpected Glue job resource: %s", string(resourceJSON))
+		}
+	}
+}
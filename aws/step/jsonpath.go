@@ -0,0 +1,308 @@
+package step
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The subset of JSONPath (https://goessner.net/articles/JsonPath/) that
+// is supported by the InputPath, OutputPath, ResultPath and Choice rule
+// Variable fields. Paths are rooted at `$` and may include dot-notation
+// (`$.a.b`), bracket-notation (`$['a']`) and array indices (`$.a[0]`).
+
+// jsonPathTokens returns the path as a list of map keys (string) and
+// array indices (int)
+func jsonPathTokens(path string) ([]interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.Errorf("JSONPath must begin with `$`: %s", path)
+	}
+	tokens := []interface{}{}
+	remaining := path[1:]
+	for len(remaining) != 0 {
+		switch remaining[0] {
+		case '.':
+			remaining = remaining[1:]
+			end := strings.IndexAny(remaining, ".[")
+			if end < 0 {
+				end = len(remaining)
+			}
+			key := remaining[:end]
+			if key == "" || strings.ContainsAny(key, "*@?()") {
+				return nil, errors.Errorf("invalid JSONPath field in: %s", path)
+			}
+			tokens = append(tokens, key)
+			remaining = remaining[end:]
+		case '[':
+			end := strings.Index(remaining, "]")
+			if end < 0 {
+				return nil, errors.Errorf("unterminated JSONPath bracket in: %s", path)
+			}
+			selector := remaining[1:end]
+			remaining = remaining[end+1:]
+			if len(selector) >= 2 &&
+				(selector[0] == '\'' || selector[0] == '"') &&
+				selector[len(selector)-1] == selector[0] {
+				tokens = append(tokens, selector[1:len(selector)-1])
+				continue
+			}
+			index, indexErr := strconv.Atoi(selector)
+			if indexErr != nil || index < 0 {
+				return nil, errors.Errorf("unsupported JSONPath selector `[%s]` in: %s", selector, path)
+			}
+			tokens = append(tokens, index)
+		default:
+			return nil, errors.Errorf("invalid JSONPath: %s", path)
+		}
+	}
+	return tokens, nil
+}
+
+// validateJSONPath returns an error if the path isn't a supported JSONPath
+func validateJSONPath(path string) error {
+	_, tokensErr := jsonPathTokens(path)
+	return tokensErr
+}
+
+// jsonPathValue returns the value in data at path. The data value
+// must be a JSON-decoded value (map[string]interface{}, []interface{}, ...)
+func jsonPathValue(data interface{}, path string) (interface{}, error) {
+	tokens, tokensErr := jsonPathTokens(path)
+	if tokensErr != nil {
+		return nil, tokensErr
+	}
+	current := data
+	for _, eachToken := range tokens {
+		switch typedToken := eachToken.(type) {
+		case string:
+			mapValue, mapValueOk := current.(map[string]interface{})
+			if !mapValueOk {
+				return nil, errors.Errorf("JSONPath %s: field `%s` requires an object", path, typedToken)
+			}
+			value, exists := mapValue[typedToken]
+			if !exists {
+				return nil, errors.Errorf("JSONPath %s: field `%s` not found", path, typedToken)
+			}
+			current = value
+		case int:
+			arrayValue, arrayValueOk := current.([]interface{})
+			if !arrayValueOk {
+				return nil, errors.Errorf("JSONPath %s: index %d requires an array", path, typedToken)
+			}
+			if typedToken >= len(arrayValue) {
+				return nil, errors.Errorf("JSONPath %s: index %d out of range", path, typedToken)
+			}
+			current = arrayValue[typedToken]
+		}
+	}
+	return current, nil
+}
+
+// jsonPathSet returns a copy of data with value inserted at path. Missing
+// intermediate objects are created. An empty path or `$` returns value.
+func jsonPathSet(data interface{}, path string, value interface{}) (interface{}, error) {
+	tokens, tokensErr := jsonPathTokens(path)
+	if tokensErr != nil {
+		return nil, tokensErr
+	}
+	return jsonPathSetTokens(data, tokens, value, path)
+}
+
+func jsonPathSetTokens(data interface{},
+	tokens []interface{},
+	value interface{},
+	path string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	switch typedToken := tokens[0].(type) {
+	case string:
+		var mapValue map[string]interface{}
+		switch typedData := data.(type) {
+		case nil:
+			mapValue = make(map[string]interface{})
+		case map[string]interface{}:
+			mapValue = make(map[string]interface{}, len(typedData)+1)
+			for eachKey, eachValue := range typedData {
+				mapValue[eachKey] = eachValue
+			}
+		default:
+			return nil, fmt.Errorf("JSONPath %s: field `%s` requires an object", path, typedToken)
+		}
+		childValue, childValueErr := jsonPathSetTokens(mapValue[typedToken], tokens[1:], value, path)
+		if childValueErr != nil {
+			return nil, childValueErr
+		}
+		mapValue[typedToken] = childValue
+		return mapValue, nil
+	case int:
+		arrayValue, arrayValueOk := data.([]interface{})
+		if !arrayValueOk || typedToken >= len(arrayValue) {
+			return nil, errors.Errorf("JSONPath %s: index %d is not an existing array element", path, typedToken)
+		}
+		arrayCopy := append([]interface{}{}, arrayValue...)
+		childValue, childValueErr := jsonPathSetTokens(arrayCopy[typedToken], tokens[1:], value, path)
+		if childValueErr != nil {
+			return nil, childValueErr
+		}
+		arrayCopy[typedToken] = childValue
+		return arrayCopy, nil
+	}
+	return nil, errors.Errorf("JSONPath %s: unsupported token", path)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// JSONPath builder
+////////////////////////////////////////////////////////////////////////////////
+
+// JSONPath is a JSONPath expression. Build paths from RootPath, which
+// selects the state input, ContextPath, which selects the context object,
+// or FieldPath, which derives the path from a Go struct field.
+type JSONPath string
+
+const (
+	// RootPath selects the entire state input
+	RootPath JSONPath = "$"
+	// ContextPath selects the entire context object
+	// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/input-output-contextobject.html
+	ContextPath JSONPath = "$$"
+	// ContextExecutionID selects the execution ARN
+	ContextExecutionID JSONPath = "$$.Execution.Id"
+	// ContextExecutionInput selects the execution input
+	ContextExecutionInput JSONPath = "$$.Execution.Input"
+	// ContextExecutionName selects the execution name
+	ContextExecutionName JSONPath = "$$.Execution.Name"
+	// ContextExecutionStartTime selects the execution start time
+	ContextExecutionStartTime JSONPath = "$$.Execution.StartTime"
+	// ContextStateName selects the current state name
+	ContextStateName JSONPath = "$$.State.Name"
+	// ContextStateEnteredTime selects the time the current state was entered
+	ContextStateEnteredTime JSONPath = "$$.State.EnteredTime"
+	// ContextStateRetryCount selects the number of retries of the current
+	// state
+	ContextStateRetryCount JSONPath = "$$.State.RetryCount"
+	// ContextStateMachineID selects the state machine ARN
+	ContextStateMachineID JSONPath = "$$.StateMachine.Id"
+	// ContextStateMachineName selects the state machine name
+	ContextStateMachineName JSONPath = "$$.StateMachine.Name"
+	// ContextTaskToken selects the task token of a `.waitForTaskToken` task
+	ContextTaskToken JSONPath = "$$.Task.Token"
+	// ContextMapItemIndex selects the index of the MapState item
+	ContextMapItemIndex JSONPath = "$$.Map.Item.Index"
+	// ContextMapItemValue selects the value of the MapState item
+	ContextMapItemValue JSONPath = "$$.Map.Item.Value"
+)
+
+// reJSONPathIdentifier matches the field names that can use dot-notation
+var reJSONPathIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Field returns the path that selects the named field
+func (path JSONPath) Field(name string) JSONPath {
+	if reJSONPathIdentifier.MatchString(name) {
+		return JSONPath(fmt.Sprintf("%s.%s", path, name))
+	}
+	return JSONPath(fmt.Sprintf("%s['%s']", path, name))
+}
+
+// Index returns the path that selects the array element
+func (path JSONPath) Index(index int) JSONPath {
+	return JSONPath(fmt.Sprintf("%s[%d]", path, index))
+}
+
+// String returns the path expression
+func (path JSONPath) String() string {
+	return string(path)
+}
+
+// jsonFieldName returns the JSON name of the struct field, or an empty
+// string if the field isn't marshalled
+func jsonFieldName(field reflect.StructField) string {
+	if field.PkgPath != "" && !field.Anonymous {
+		return ""
+	}
+	tagName := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tagName == "-" && field.Tag.Get("json") == "-" {
+		return ""
+	}
+	if tagName != "" {
+		return tagName
+	}
+	return field.Name
+}
+
+// fieldPathTokens returns the JSON field names that lead from the
+// struct value to the field at the address
+func fieldPathTokens(structValue reflect.Value,
+	fieldAddr uintptr,
+	fieldType reflect.Type) ([]string, bool) {
+	for i := 0; i != structValue.NumField(); i++ {
+		field := structValue.Type().Field(i)
+		fieldValue := structValue.Field(i)
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		// Embedded structs without a tag are flattened
+		_, hasTag := field.Tag.Lookup("json")
+		isFlattened := field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct
+		if !isFlattened &&
+			fieldValue.UnsafeAddr() == fieldAddr &&
+			field.Type == fieldType {
+			return []string{name}, true
+		}
+		if fieldValue.Kind() == reflect.Struct {
+			childTokens, found := fieldPathTokens(fieldValue, fieldAddr, fieldType)
+			if found {
+				if isFlattened {
+					return childTokens, true
+				}
+				return append([]string{name}, childTokens...), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// FieldPath returns the path that selects the struct field, using the
+// field's JSON name. The structPtr argument is a pointer to a struct
+// value and fieldPtr is a pointer to one of its, possibly nested, fields:
+//
+//	var roll RollResponse
+//	path, _ := step.FieldPath(&roll, &roll.Value) // $.value
+func FieldPath(structPtr interface{}, fieldPtr interface{}) (JSONPath, error) {
+	structValue := reflect.ValueOf(structPtr)
+	if structValue.Kind() != reflect.Ptr || structValue.Elem().Kind() != reflect.Struct {
+		return "", errors.Errorf("FieldPath requires a pointer to a struct, got %T", structPtr)
+	}
+	fieldValue := reflect.ValueOf(fieldPtr)
+	if fieldValue.Kind() != reflect.Ptr || fieldValue.IsNil() {
+		return "", errors.Errorf("FieldPath requires a pointer to a struct field, got %T", fieldPtr)
+	}
+	tokens, found := fieldPathTokens(structValue.Elem(),
+		fieldValue.Pointer(),
+		fieldValue.Type().Elem())
+	if !found {
+		return "", errors.Errorf("FieldPath field %T isn't a marshalled field of %T",
+			fieldPtr,
+			structPtr)
+	}
+	path := RootPath
+	for _, eachToken := range tokens {
+		path = path.Field(eachToken)
+	}
+	return path, nil
+}
+
+// MustFieldPath is the FieldPath variant that panics if the field isn't
+// part of the struct
+func MustFieldPath(structPtr interface{}, fieldPtr interface{}) JSONPath {
+	path, pathErr := FieldPath(structPtr, fieldPtr)
+	if pathErr != nil {
+		panic(pathErr)
+	}
+	return path
+}
@@ -0,0 +1,1185 @@
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/*******************************************************************************
+  _    ___   ___   _   _
+ | |  / _ \ / __| /_\ | |
+ | |_| (_) | (__ / _ \| |__
+ |____\___/ \___/_/ \_\____|
+
+/******************************************************************************/
+
+const (
+	// LocalExecutionSucceeded is the status of a LocalExecution that
+	// completed successfully
+	LocalExecutionSucceeded = "SUCCEEDED"
+	// LocalExecutionFailed is the status of a LocalExecution that failed
+	LocalExecutionFailed = "FAILED"
+)
+
+const (
+	// LocalEventStateEntered is recorded when a state is entered
+	LocalEventStateEntered = "StateEntered"
+	// LocalEventStateExited is recorded when a state is exited
+	LocalEventStateExited = "StateExited"
+	// LocalEventTaskFailed is recorded when a Task or Parallel state fails
+	LocalEventTaskFailed = "TaskFailed"
+	// LocalEventTaskRetry is recorded when a failed state is retried
+	LocalEventTaskRetry = "TaskRetry"
+	// LocalEventTaskCaught is recorded when a failure is handled by a
+	// TaskCatch
+	LocalEventTaskCaught = "TaskCaught"
+)
+
+// defaultLocalMaxTransitions mirrors the AWS execution history limit and
+// guards against infinite loops
+const defaultLocalMaxTransitions = 25000
+
+// Clock is the time source used by the LocalExecutor
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// Sleep blocks for the duration, or until the context is done
+	Sleep(ctx context.Context, duration time.Duration) error
+}
+
+// VirtualClock is a Clock whose Sleep function immediately advances the
+// current time. It allows Wait states and retry backoffs to be tested
+// without waiting.
+type VirtualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// Now returns the current virtual time
+func (vc *VirtualClock) Now() time.Time {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	return vc.now
+}
+
+// Sleep advances the virtual time by duration
+func (vc *VirtualClock) Sleep(ctx context.Context, duration time.Duration) error {
+	vc.mutex.Lock()
+	defer vc.mutex.Unlock()
+	if duration > 0 {
+		vc.now = vc.now.Add(duration)
+	}
+	return ctx.Err()
+}
+
+// NewVirtualClock returns a VirtualClock initialized to start
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{
+		now: start,
+	}
+}
+
+type wallClock struct {
+}
+
+func (wc *wallClock) Now() time.Time {
+	return time.Now()
+}
+
+func (wc *wallClock) Sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WallClock returns a Clock backed by the system time. Sleep blocks
+// for the full duration.
+func WallClock() Clock {
+	return &wallClock{}
+}
+
+// TaskError is an error with an explicit Step Functions error name. Return
+// a TaskError from a LocalTaskHandler or Lambda function to exercise the
+// TaskRetry and TaskCatch handlers that match the name.
+type TaskError struct {
+	ErrorName string
+	Cause     string
+}
+
+// Error returns the error name and cause
+func (te *TaskError) Error() string {
+	if te.Cause == "" {
+		return te.ErrorName
+	}
+	return fmt.Sprintf("%s: %s", te.ErrorName, te.Cause)
+}
+
+// NewTaskError returns a new TaskError
+func NewTaskError(errorName StateError, cause string) *TaskError {
+	return &TaskError{
+		ErrorName: string(errorName),
+		Cause:     cause,
+	}
+}
+
+// LocalTaskHandler is an in-process implementation of a Task state. The
+// input is the state's effective input. For service integration tasks
+// the input is the resolved task Parameters.
+type LocalTaskHandler func(ctx context.Context, input interface{}) (interface{}, error)
+
+// LocalExecutionEvent is an entry in the LocalExecution history
+type LocalExecutionEvent struct {
+	Type      string
+	StateName string
+	Timestamp time.Time
+	Input     interface{} `json:",omitempty"`
+	Output    interface{} `json:",omitempty"`
+	Error     string      `json:",omitempty"`
+	Cause     string      `json:",omitempty"`
+}
+
+// LocalExecution is the result of a LocalExecutor run
+type LocalExecution struct {
+	Name      string
+	Status    string
+	Output    interface{}
+	Error     string
+	Cause     string
+	StartDate time.Time
+	StopDate  time.Time
+	History   []*LocalExecutionEvent
+}
+
+// UnmarshalOutput unmarshals the execution output into value
+func (le *LocalExecution) UnmarshalOutput(value interface{}) error {
+	jsonBytes, jsonBytesErr := json.Marshal(le.Output)
+	if jsonBytesErr != nil {
+		return jsonBytesErr
+	}
+	return json.Unmarshal(jsonBytes, value)
+}
+
+// StateNames returns the names of the states that were entered,
+// in order
+func (le *LocalExecution) StateNames() []string {
+	stateNames := []string{}
+	for _, eachEvent := range le.History {
+		if eachEvent.Type == LocalEventStateEntered {
+			stateNames = append(stateNames, eachEvent.StateName)
+		}
+	}
+	return stateNames
+}
+
+// LocalExecutor runs a StateMachine in-process so that workflows can be
+// unit tested without being provisioned. LambdaTaskState handlers are
+// invoked directly. Service integration tasks (SNS, SQS, DynamoDB, Batch,
+// ...) and tasks that wait for a task token must be mocked with
+// WithStateHandler or WithResourceHandler.
+// ActivityTaskState handlers are registered with WithActivityHandler.
+type LocalExecutor struct {
+	stateMachine     *StateMachine
+	clock            Clock
+	stateHandlers    map[string]LocalTaskHandler
+	resourceHandlers map[string]LocalTaskHandler
+	activityHandlers map[string]LocalTaskHandler
+	// MaxTransitions is the maximum number of state transitions before the
+	// execution is aborted. Defaults to 25000.
+	MaxTransitions int
+}
+
+// WithClock is the fluent builder for the executor time source. Defaults
+// to a VirtualClock that starts at the time the executor was created.
+func (le *LocalExecutor) WithClock(clock Clock) *LocalExecutor {
+	le.clock = clock
+	return le
+}
+
+// WithStateHandler registers a handler for the named Task state. It
+// takes precedence over any LambdaTaskState function or resource
+// handler.
+func (le *LocalExecutor) WithStateHandler(stateName string, handler LocalTaskHandler) *LocalExecutor {
+	le.stateHandlers[stateName] = handler
+	return le
+}
+
+// WithResourceHandler registers a handler for all Task states that use
+// the service integration resource, as in "arn:aws:states:::sns:publish"
+func (le *LocalExecutor) WithResourceHandler(resource string, handler LocalTaskHandler) *LocalExecutor {
+	le.resourceHandlers[resource] = handler
+	return le
+}
+
+// WithActivityHandler registers the ActivityHandler for all
+// ActivityTaskState states that use the activity. This is typically the
+// same handler that is supplied to the ActivityWorker.
+func (le *LocalExecutor) WithActivityHandler(activity *Activity, handler ActivityHandler) *LocalExecutor {
+	le.activityHandlers[activity.Name] = func(ctx context.Context, input interface{}) (interface{}, error) {
+		inputJSON, inputJSONErr := json.Marshal(input)
+		if inputJSONErr != nil {
+			return nil, &localHandlerError{inputJSONErr}
+		}
+		return handler(ctx, json.RawMessage(inputJSON))
+	}
+	return le
+}
+
+// Clock returns the executor time source
+func (le *LocalExecutor) Clock() Clock {
+	return le.clock
+}
+
+// Execute runs the state machine with the given input, which must be
+// JSON serializable. A returned error indicates that the execution
+// couldn't be run. Workflow failures are reported by the
+// LocalExecution Status, Error and Cause fields.
+func (le *LocalExecutor) Execute(ctx context.Context, input interface{}) (*LocalExecution, error) {
+	normalizedInput, normalizedInputErr := normalizeJSON(input)
+	if normalizedInputErr != nil {
+		return nil, errors.Wrapf(normalizedInputErr, "attempting to marshal execution input")
+	}
+	execution := &LocalExecution{
+		Name:      fmt.Sprintf("%s-%d", le.stateMachine.name, le.clock.Now().UnixNano()),
+		StartDate: le.clock.Now(),
+		History:   make([]*LocalExecutionEvent, 0),
+	}
+	run := &localRun{
+		executor:       le,
+		execution:      execution,
+		executionInput: normalizedInput,
+	}
+	output, failure, runErr := run.runMachine(ctx, le.stateMachine, normalizedInput)
+	if runErr != nil {
+		return nil, runErr
+	}
+	execution.StopDate = le.clock.Now()
+	if failure != nil {
+		execution.Status = LocalExecutionFailed
+		execution.Error = failure.ErrorName
+		execution.Cause = failure.Cause
+	} else {
+		execution.Status = LocalExecutionSucceeded
+		execution.Output = output
+	}
+	return execution, nil
+}
+
+// NewLocalExecutor returns a LocalExecutor for the state machine
+func NewLocalExecutor(stateMachine *StateMachine) *LocalExecutor {
+	return &LocalExecutor{
+		stateMachine:     stateMachine,
+		clock:            NewVirtualClock(time.Now().UTC()),
+		stateHandlers:    make(map[string]LocalTaskHandler),
+		resourceHandlers: make(map[string]LocalTaskHandler),
+		activityHandlers: make(map[string]LocalTaskHandler),
+		MaxTransitions:   defaultLocalMaxTransitions,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// localRun
+////////////////////////////////////////////////////////////////////////////////
+
+// localRun is the mutable state of a single execution
+type localRun struct {
+	executor       *LocalExecutor
+	execution      *LocalExecution
+	executionInput interface{}
+	transitions    int
+}
+
+func (run *localRun) record(eventType string,
+	stateName string,
+	input interface{},
+	output interface{},
+	failure *TaskError) {
+	event := &LocalExecutionEvent{
+		Type:      eventType,
+		StateName: stateName,
+		Timestamp: run.executor.clock.Now(),
+		Input:     input,
+		Output:    output,
+	}
+	if failure != nil {
+		event.Error = failure.ErrorName
+		event.Cause = failure.Cause
+	}
+	run.execution.History = append(run.execution.History, event)
+}
+
+// runMachine runs the state machine, a ParallelState branch or a MapState
+// iteration until it reaches a terminal state
+func (run *localRun) runMachine(ctx context.Context,
+	stateMachine *StateMachine,
+	input interface{}) (interface{}, *TaskError, error) {
+
+	if stateMachine.startAt == nil {
+		return nil, nil, errors.Errorf("state machine %s doesn't define a start state", stateMachine.name)
+	}
+	var state MachineState = stateMachine.startAt
+	for state != nil {
+		run.transitions++
+		if run.transitions > run.executor.MaxTransitions {
+			return nil, nil, errors.Errorf("execution exceeded %d state transitions",
+				run.executor.MaxTransitions)
+		}
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		run.record(LocalEventStateEntered, state.Name(), input, nil, nil)
+		output, next, failure, stateErr := run.runState(ctx, state, input)
+		if stateErr != nil {
+			return nil, nil, errors.Wrapf(stateErr, "state %s", state.Name())
+		}
+		if failure != nil {
+			run.record(LocalEventTaskFailed, state.Name(), nil, nil, failure)
+			return nil, failure, nil
+		}
+		run.record(LocalEventStateExited, state.Name(), nil, output, nil)
+		input = output
+		state = next
+	}
+	return input, nil, nil
+}
+
+// runState runs a single state and returns its output together with
+// the next state. A nil next state ends the execution.
+func (run *localRun) runState(ctx context.Context,
+	state MachineState,
+	rawInput interface{}) (interface{}, MachineState, *TaskError, error) {
+
+	var bis *baseInnerState
+	if innerStater, ok := state.(interface {
+		innerState() *baseInnerState
+	}); ok {
+		bis = innerStater.innerState()
+	} else {
+		return nil, nil, nil, errors.Errorf("unsupported state type: %T", state)
+	}
+	effectiveInput, inputErr := applyJSONPath(rawInput, bis.inputPath)
+	if inputErr != nil {
+		return nil, nil, runtimeFailure(inputErr), nil
+	}
+	// Shared output filter
+	filterOutput := func(output interface{}, next MachineState) (interface{}, MachineState, *TaskError, error) {
+		filtered, filteredErr := applyJSONPath(output, bis.outputPath)
+		if filteredErr != nil {
+			return nil, nil, runtimeFailure(filteredErr), nil
+		}
+		return filtered, next, nil, nil
+	}
+
+	switch typedState := state.(type) {
+	case *PassState:
+		result := effectiveInput
+		if typedState.Parameters != nil {
+			resolvedParams, resolvedParamsErr := run.resolveStateParameters(typedState.Parameters,
+				effectiveInput,
+				run.contextObject(typedState.name))
+			if resolvedParamsErr != nil {
+				return nil, nil, runtimeFailure(resolvedParamsErr), nil
+			}
+			result = resolvedParams
+		}
+		if typedState.Result != nil {
+			normalized, normalizedErr := normalizeJSON(typedState.Result)
+			if normalizedErr != nil {
+				return nil, nil, nil, normalizedErr
+			}
+			result = normalized
+		}
+		output, outputErr := applyResultPath(rawInput, typedState.ResultPath, result)
+		if outputErr != nil {
+			return nil, nil, runtimeFailure(outputErr), nil
+		}
+		return filterOutput(output, typedState.next)
+
+	case *ChoiceState:
+		for _, eachChoice := range typedState.Choices {
+			comparison, comparisonOk := eachChoice.(Comparison)
+			if !comparisonOk {
+				return nil, nil, nil, errors.Errorf("choice %T is not a Comparison", eachChoice)
+			}
+			matched, matchedErr := evaluateComparison(comparison, effectiveInput)
+			if matchedErr != nil {
+				return nil, nil, runtimeFailure(matchedErr), nil
+			}
+			if matched {
+				return filterOutput(effectiveInput, eachChoice.nextState())
+			}
+		}
+		if typedState.Default != nil {
+			return filterOutput(effectiveInput, typedState.Default)
+		}
+		return nil, nil, &TaskError{
+			ErrorName: string(StatesNoChoiceMatched),
+			Cause:     fmt.Sprintf("no Choice rule matched in state %s", typedState.name),
+		}, nil
+
+	case *WaitDelay:
+		sleepErr := run.executor.clock.Sleep(ctx, typedState.delay)
+		if sleepErr != nil {
+			return nil, nil, nil, sleepErr
+		}
+		return filterOutput(effectiveInput, typedState.next)
+
+	case *WaitUntil:
+		sleepErr := run.executor.clock.Sleep(ctx, typedState.Timestamp.Sub(run.executor.clock.Now()))
+		if sleepErr != nil {
+			return nil, nil, nil, sleepErr
+		}
+		return filterOutput(effectiveInput, typedState.next)
+
+	case *WaitDynamicUntil:
+		timestamp, timestampErr := timestampVariable(effectiveInput, typedState.TimestampPath)
+		if timestampErr != nil {
+			return nil, nil, runtimeFailure(timestampErr), nil
+		}
+		sleepErr := run.executor.clock.Sleep(ctx, timestamp.Sub(run.executor.clock.Now()))
+		if sleepErr != nil {
+			return nil, nil, nil, sleepErr
+		}
+		return filterOutput(effectiveInput, typedState.next)
+
+	case *SuccessState:
+		return filterOutput(effectiveInput, nil)
+
+	case *FailState:
+		failure := &TaskError{
+			ErrorName: typedState.ErrorName,
+		}
+		if typedState.Cause != nil {
+			failure.Cause = typedState.Cause.Error()
+		}
+		return nil, nil, failure, nil
+
+	case *ParallelState:
+		branchMachine := &typedState.States
+		return run.runWithRetries(ctx,
+			typedState.name,
+			rawInput,
+			typedState.ResultPath,
+			bis.outputPath,
+			typedState.Retriers,
+			typedState.Catchers,
+			typedState.next,
+			0,
+			func(ctx context.Context) (interface{}, error) {
+				branchOutput, branchFailure, branchErr := run.runMachine(ctx, branchMachine, effectiveInput)
+				if branchErr != nil {
+					return nil, &localHandlerError{branchErr}
+				}
+				if branchFailure != nil {
+					return nil, branchFailure
+				}
+				return []interface{}{branchOutput}, nil
+			})
+
+	case *MapState:
+		iteratorMachine := &typedState.Iterator
+		return run.runWithRetries(ctx,
+			typedState.name,
+			rawInput,
+			typedState.ResultPath,
+			bis.outputPath,
+			typedState.Retriers,
+			typedState.Catchers,
+			typedState.next,
+			0,
+			run.resultSelectorInvoker(typedState.name,
+				typedState.ResultSelector,
+				func(ctx context.Context) (interface{}, error) {
+					return run.runMapIterations(ctx, typedState, iteratorMachine, effectiveInput)
+				}))
+	}
+
+	// Everything else should be a task
+	taskState, taskStateOk := state.(interface {
+		baseTask() *BaseTask
+	})
+	if !taskStateOk {
+		return nil, nil, nil, errors.Errorf("unsupported state type: %T", state)
+	}
+	bt := taskState.baseTask()
+	invoker, invokerErr := run.taskInvoker(state, effectiveInput)
+	if invokerErr != nil {
+		return nil, nil, nil, invokerErr
+	}
+	return run.runWithRetries(ctx,
+		bt.name,
+		rawInput,
+		bt.ResultPath,
+		bis.outputPath,
+		bt.Retriers,
+		bt.Catchers,
+		bt.next,
+		bt.TimeoutSeconds,
+		run.resultSelectorInvoker(bt.name, bt.ResultSelector, invoker))
+}
+
+// runMapIterations runs the MapState Iterator for each item. Iterations
+// are run sequentially, so MaxConcurrency doesn't apply.
+func (run *localRun) runMapIterations(ctx context.Context,
+	mapState *MapState,
+	iteratorMachine *StateMachine,
+	effectiveInput interface{}) (interface{}, error) {
+	itemsValue, itemsValueErr := applyJSONPath(effectiveInput, mapState.ItemsPath)
+	if itemsValueErr != nil {
+		return nil, runtimeFailure(itemsValueErr)
+	}
+	items, itemsOk := itemsValue.([]interface{})
+	if !itemsOk {
+		return nil, &TaskError{
+			ErrorName: string(StatesRuntime),
+			Cause: fmt.Sprintf("ItemsPath for state %s must select an array, got: %T",
+				mapState.name,
+				itemsValue),
+		}
+	}
+	results := make([]interface{}, len(items))
+	for eachIndex, eachItem := range items {
+		iterationInput := eachItem
+		if mapState.Parameters != nil {
+			contextObject := run.contextObject(mapState.name)
+			contextObject["Map"] = map[string]interface{}{
+				"Item": map[string]interface{}{
+					"Index": eachIndex,
+					"Value": eachItem,
+				},
+			}
+			resolvedParams, resolvedParamsErr := run.resolveStateParameters(mapState.Parameters,
+				effectiveInput,
+				contextObject)
+			if resolvedParamsErr != nil {
+				return nil, runtimeFailure(resolvedParamsErr)
+			}
+			iterationInput = resolvedParams
+		}
+		iterationOutput, iterationFailure, iterationErr := run.runMachine(ctx,
+			iteratorMachine,
+			iterationInput)
+		if iterationErr != nil {
+			return nil, &localHandlerError{iterationErr}
+		}
+		if iterationFailure != nil {
+			return nil, iterationFailure
+		}
+		results[eachIndex] = iterationOutput
+	}
+	return results, nil
+}
+
+// resultSelectorInvoker wraps the invoker so that the ResultSelector is
+// applied to successful results
+func (run *localRun) resultSelectorInvoker(stateName string,
+	resultSelector map[string]interface{},
+	invoker func(ctx context.Context) (interface{}, error)) func(ctx context.Context) (interface{}, error) {
+	if resultSelector == nil {
+		return invoker
+	}
+	return func(ctx context.Context) (interface{}, error) {
+		result, resultErr := invoker(ctx)
+		if resultErr != nil {
+			return nil, resultErr
+		}
+		normalizedResult, normalizedResultErr := normalizeJSON(result)
+		if normalizedResultErr != nil {
+			return nil, &localHandlerError{normalizedResultErr}
+		}
+		selected, selectedErr := run.resolveStateParameters(resultSelector,
+			normalizedResult,
+			run.contextObject(stateName))
+		if selectedErr != nil {
+			return nil, runtimeFailure(selectedErr)
+		}
+		return selected, nil
+	}
+}
+
+// resolveStateParameters resolves the user supplied Parameters or
+// ResultSelector template against the input
+func (run *localRun) resolveStateParameters(params map[string]interface{},
+	input interface{},
+	contextObject interface{}) (interface{}, error) {
+	normalizedParams, normalizedParamsErr := normalizeJSON(params)
+	if normalizedParamsErr != nil {
+		return nil, normalizedParamsErr
+	}
+	return resolveParameters(normalizedParams, input, contextObject)
+}
+
+// taskInvoker returns the function that executes the task state
+func (run *localRun) taskInvoker(state MachineState,
+	effectiveInput interface{}) (func(ctx context.Context) (interface{}, error), error) {
+
+	stateHandler, stateHandlerExists := run.executor.stateHandlers[state.Name()]
+
+	// Lambda functions are called directly, unless they wait for a task
+	// token. Those are handled like service integrations, since the result
+	// is the value returned by the TaskCallback.
+	if lambdaState, isLambda := state.(*LambdaTaskState); isLambda &&
+		!lambdaState.waitForTaskToken {
+		lambdaInput := effectiveInput
+		if lambdaState.Parameters != nil {
+			resolvedParams, resolvedParamsErr := run.resolveStateParameters(lambdaState.Parameters,
+				effectiveInput,
+				run.contextObject(state.Name()))
+			if resolvedParamsErr != nil {
+				return func(ctx context.Context) (interface{}, error) {
+					return nil, runtimeFailure(resolvedParamsErr)
+				}, nil
+			}
+			lambdaInput = resolvedParams
+		}
+		if stateHandlerExists {
+			return func(ctx context.Context) (interface{}, error) {
+				return stateHandler(ctx, lambdaInput)
+			}, nil
+		}
+		if lambdaState.lambdaFn == nil || lambdaState.lambdaFn.HandlerSymbol() == nil {
+			return nil, errors.Errorf("LambdaTaskState %s doesn't have a handler", state.Name())
+		}
+		handlerSymbol := lambdaState.lambdaFn.HandlerSymbol()
+		return func(ctx context.Context) (interface{}, error) {
+			return invokeLambdaHandler(ctx, handlerSymbol, lambdaInput)
+		}, nil
+	}
+
+	// Service integrations require a handler. Use the marshalled
+	// state to determine the resource and parameters.
+	stateJSON, stateJSONErr := json.Marshal(state)
+	if stateJSONErr != nil {
+		return nil, errors.Wrapf(stateJSONErr, "attempting to marshal task state")
+	}
+	var taskDefinition struct {
+		Resource   interface{}
+		Parameters interface{}
+	}
+	unmarshalErr := json.Unmarshal(stateJSON, &taskDefinition)
+	if unmarshalErr != nil {
+		return nil, errors.Wrapf(unmarshalErr, "attempting to unmarshal task state")
+	}
+	resource, _ := taskDefinition.Resource.(string)
+	handler := stateHandler
+	if !stateHandlerExists {
+		resourceHandler, resourceHandlerExists := run.executor.resourceHandlers[resource]
+		if activityState, isActivity := state.(*ActivityTaskState); isActivity &&
+			activityState.activity != nil {
+			resource = activityState.activity.Name
+			resourceHandler, resourceHandlerExists = run.executor.activityHandlers[resource]
+		}
+		if !resourceHandlerExists {
+			return nil, errors.Errorf("no LocalTaskHandler registered for state %s or resource %s",
+				state.Name(),
+				resource)
+		}
+		handler = resourceHandler
+	}
+	return func(ctx context.Context) (interface{}, error) {
+		taskInput := effectiveInput
+		if taskDefinition.Parameters != nil {
+			resolvedParams, resolvedParamsErr := resolveParameters(taskDefinition.Parameters,
+				effectiveInput,
+				run.contextObject(state.Name()))
+			if resolvedParamsErr != nil {
+				return nil, &TaskError{
+					ErrorName: string(StatesRuntime),
+					Cause:     resolvedParamsErr.Error(),
+				}
+			}
+			taskInput = resolvedParams
+		}
+		return handler(ctx, taskInput)
+	}, nil
+}
+
+// runWithRetries invokes the task, applying the Retry and Catch policies
+func (run *localRun) runWithRetries(ctx context.Context,
+	stateName string,
+	rawInput interface{},
+	resultPath string,
+	outputPath string,
+	retriers []*TaskRetry,
+	catchers []*TaskCatch,
+	next MachineState,
+	timeout time.Duration,
+	invoker func(ctx context.Context) (interface{}, error)) (interface{}, MachineState, *TaskError, error) {
+
+	retryCounts := make(map[*TaskRetry]int)
+	for {
+		invokeCtx := ctx
+		var cancel context.CancelFunc
+		if timeout > 0 {
+			invokeCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		result, resultErr := invoker(invokeCtx)
+		timedOut := timeout > 0 && invokeCtx.Err() == context.DeadlineExceeded
+		if cancel != nil {
+			cancel()
+		}
+		if ctx.Err() != nil {
+			return nil, nil, nil, ctx.Err()
+		}
+		if resultErr != nil && timedOut {
+			resultErr = &TaskError{
+				ErrorName: string(StatesTimeout),
+				Cause:     fmt.Sprintf("state %s exceeded its %s timeout", stateName, timeout),
+			}
+		}
+		if resultErr == nil {
+			normalizedResult, normalizedResultErr := normalizeJSON(result)
+			if normalizedResultErr != nil {
+				return nil, nil, nil, errors.Wrapf(normalizedResultErr, "attempting to marshal task result")
+			}
+			output, outputErr := applyResultPath(rawInput, resultPath, normalizedResult)
+			if outputErr != nil {
+				return nil, nil, &TaskError{
+					ErrorName: string(StatesResultPathMatchFailure),
+					Cause:     outputErr.Error(),
+				}, nil
+			}
+			filtered, filteredErr := applyJSONPath(output, outputPath)
+			if filteredErr != nil {
+				return nil, nil, runtimeFailure(filteredErr), nil
+			}
+			return filtered, next, nil, nil
+		}
+		// Configuration errors abort the execution
+		failure, isFailure := taskFailure(resultErr)
+		if !isFailure {
+			return nil, nil, nil, resultErr
+		}
+		// Retry?
+		var retrier *TaskRetry
+		for _, eachRetrier := range retriers {
+			if errorNameMatches(eachRetrier.ErrorEquals, failure.ErrorName) {
+				retrier = eachRetrier
+				break
+			}
+		}
+		if retrier != nil {
+			maxAttempts, interval, backoffRate := retryPolicy(retrier)
+			if retryCounts[retrier] < maxAttempts {
+				delay := time.Duration(float64(interval) *
+					math.Pow(backoffRate, float64(retryCounts[retrier])))
+				retryCounts[retrier]++
+				run.record(LocalEventTaskRetry, stateName, nil, nil, failure)
+				sleepErr := run.executor.clock.Sleep(ctx, delay)
+				if sleepErr != nil {
+					return nil, nil, nil, sleepErr
+				}
+				continue
+			}
+		}
+		// Catch?
+		for _, eachCatcher := range catchers {
+			if errorNameMatches(eachCatcher.errorEquals, failure.ErrorName) {
+				run.record(LocalEventTaskCaught, stateName, nil, nil, failure)
+				return map[string]interface{}{
+					"Error": failure.ErrorName,
+					"Cause": failure.Cause,
+				}, eachCatcher.next, nil, nil
+			}
+		}
+		return nil, nil, failure, nil
+	}
+}
+
+// contextObject returns the Context Object available to Parameters
+// via `$$` paths
+func (run *localRun) contextObject(stateName string) map[string]interface{} {
+	return map[string]interface{}{
+		"Execution": map[string]interface{}{
+			"Id":        run.execution.Name,
+			"Input":     run.executionInput,
+			"Name":      run.execution.Name,
+			"StartTime": run.execution.StartDate.Format(time.RFC3339),
+		},
+		"State": map[string]interface{}{
+			"EnteredTime": run.executor.clock.Now().Format(time.RFC3339),
+			"Name":        stateName,
+		},
+		"StateMachine": map[string]interface{}{
+			"Id":   run.executor.stateMachine.name,
+			"Name": run.executor.stateMachine.name,
+		},
+		"Task": map[string]interface{}{
+			"Token": fmt.Sprintf("%s-%s", run.execution.Name, stateName),
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Utility functions
+////////////////////////////////////////////////////////////////////////////////
+
+// retryPolicy returns the effective TaskRetry values. Zero values
+// use the AWS defaults.
+func retryPolicy(retrier *TaskRetry) (int, time.Duration, float64) {
+	maxAttempts := retrier.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	interval := retrier.IntervalSeconds
+	if interval <= 0 {
+		interval = time.Second
+	}
+	backoffRate := float64(retrier.BackoffRate)
+	if backoffRate <= 0 {
+		backoffRate = 2.0
+	}
+	return maxAttempts, interval, backoffRate
+}
+
+// errorNameMatches returns true if the error name is matched by
+// the ErrorEquals values
+func errorNameMatches(errorEquals []StateError, errorName string) bool {
+	for _, eachError := range errorEquals {
+		switch eachError {
+		case StatesAll:
+			return true
+		case StatesTaskFailed:
+			if errorName != string(StatesTimeout) {
+				return true
+			}
+		default:
+			if string(eachError) == errorName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// taskFailure converts the task error into a named failure. Errors
+// that wrap configuration problems are not failures.
+func taskFailure(err error) (*TaskError, bool) {
+	switch typedErr := err.(type) {
+	case *TaskError:
+		return typedErr, true
+	case *localHandlerError:
+		return nil, false
+	}
+	// Match the AWS Lambda Go runtime error type naming
+	errorType := reflect.TypeOf(err)
+	if errorType.Kind() == reflect.Ptr {
+		errorType = errorType.Elem()
+	}
+	return &TaskError{
+		ErrorName: errorType.Name(),
+		Cause:     err.Error(),
+	}, true
+}
+
+func runtimeFailure(err error) *TaskError {
+	return &TaskError{
+		ErrorName: string(StatesRuntime),
+		Cause:     err.Error(),
+	}
+}
+
+// localHandlerError is returned when a Lambda handler can't be invoked
+type localHandlerError struct {
+	err error
+}
+
+func (lhe *localHandlerError) Error() string {
+	return lhe.err.Error()
+}
+
+// invokeLambdaHandler invokes the Go AWS Lambda compliant handler
+// in-process
+func invokeLambdaHandler(ctx context.Context,
+	handlerSymbol interface{},
+	input interface{}) (interface{}, error) {
+
+	handler := reflect.ValueOf(handlerSymbol)
+	handlerType := handler.Type()
+	if handlerType.Kind() != reflect.Func {
+		return nil, &localHandlerError{errors.Errorf("handler %T is not a function", handlerSymbol)}
+	}
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	takesContext := handlerType.NumIn() > 0 && handlerType.In(0).Implements(contextType)
+
+	var args []reflect.Value
+	if takesContext {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+	if (handlerType.NumIn() == 1 && !takesContext) || handlerType.NumIn() == 2 {
+		jsonBytes, jsonBytesErr := json.Marshal(input)
+		if jsonBytesErr != nil {
+			return nil, &localHandlerError{jsonBytesErr}
+		}
+		eventType := handlerType.In(handlerType.NumIn() - 1)
+		event := reflect.New(eventType)
+		unmarshalErr := json.Unmarshal(jsonBytes, event.Interface())
+		if unmarshalErr != nil {
+			return nil, &TaskError{
+				ErrorName: "UnmarshalTypeError",
+				Cause:     unmarshalErr.Error(),
+			}
+		}
+		args = append(args, event.Elem())
+	}
+	if len(args) != handlerType.NumIn() {
+		return nil, &localHandlerError{errors.Errorf("handler %T has an unsupported signature", handlerSymbol)}
+	}
+	response := handler.Call(args)
+	var err error
+	if len(response) > 0 {
+		if errVal, ok := response[len(response)-1].Interface().(error); ok {
+			err = errVal
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(response) > 1 {
+		return response[0].Interface(), nil
+	}
+	return nil, nil
+}
+
+// normalizeJSON round trips the value through JSON so that paths can
+// be applied
+func normalizeJSON(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	jsonBytes, jsonBytesErr := json.Marshal(value)
+	if jsonBytesErr != nil {
+		return nil, jsonBytesErr
+	}
+	var normalized interface{}
+	unmarshalErr := json.Unmarshal(jsonBytes, &normalized)
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	return normalized, nil
+}
+
+// applyJSONPath applies an InputPath or OutputPath filter. An empty path
+// is equivalent to `$`
+func applyJSONPath(value interface{}, path string) (interface{}, error) {
+	if path == "" || path == "$" {
+		return value, nil
+	}
+	return jsonPathValue(value, path)
+}
+
+// applyResultPath inserts the result into the raw input. An empty path is
+// equivalent to `$`
+func applyResultPath(rawInput interface{}, path string, result interface{}) (interface{}, error) {
+	if path == "" || path == "$" {
+		return result, nil
+	}
+	return jsonPathSet(rawInput, path, result)
+}
+
+// resolveParameters replaces all `key.$` entries with the value selected
+// by the path. Paths that begin with `$$` select from the Context Object.
+func resolveParameters(params interface{},
+	input interface{},
+	contextObject interface{}) (interface{}, error) {
+	switch typedParams := params.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(typedParams))
+		for eachKey, eachValue := range typedParams {
+			if strings.HasSuffix(eachKey, ".$") {
+				path, pathOk := eachValue.(string)
+				if !pathOk {
+					return nil, errors.Errorf("parameter %s must be a JSONPath string", eachKey)
+				}
+				var pathValue interface{}
+				var pathValueErr error
+				if strings.HasPrefix(path, "$$") {
+					pathValue, pathValueErr = jsonPathValue(contextObject, path[1:])
+				} else {
+					pathValue, pathValueErr = jsonPathValue(input, path)
+				}
+				if pathValueErr != nil {
+					return nil, pathValueErr
+				}
+				resolved[strings.TrimSuffix(eachKey, ".$")] = pathValue
+				continue
+			}
+			resolvedValue, resolvedValueErr := resolveParameters(eachValue, input, contextObject)
+			if resolvedValueErr != nil {
+				return nil, resolvedValueErr
+			}
+			resolved[eachKey] = resolvedValue
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(typedParams))
+		for eachIndex, eachValue := range typedParams {
+			resolvedValue, resolvedValueErr := resolveParameters(eachValue, input, contextObject)
+			if resolvedValueErr != nil {
+				return nil, resolvedValueErr
+			}
+			resolved[eachIndex] = resolvedValue
+		}
+		return resolved, nil
+	}
+	return params, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Choice evaluation
+////////////////////////////////////////////////////////////////////////////////
+
+func stringVariable(input interface{}, path string) (string, bool, error) {
+	value, valueErr := jsonPathValue(input, path)
+	if valueErr != nil {
+		return "", false, valueErr
+	}
+	stringValue, stringValueOk := value.(string)
+	return stringValue, stringValueOk, nil
+}
+
+func numericVariable(input interface{}, path string) (float64, bool, error) {
+	value, valueErr := jsonPathValue(input, path)
+	if valueErr != nil {
+		return 0, false, valueErr
+	}
+	numericValue, numericValueOk := value.(float64)
+	return numericValue, numericValueOk, nil
+}
+
+func timestampVariable(input interface{}, path string) (time.Time, error) {
+	stringValue, isString, stringErr := stringVariable(input, path)
+	if stringErr != nil {
+		return time.Time{}, stringErr
+	}
+	if !isString {
+		return time.Time{}, errors.Errorf("JSONPath %s doesn't select a timestamp string", path)
+	}
+	return time.Parse(time.RFC3339, stringValue)
+}
+
+// compareTimestamp returns the comparison result, or false if the
+// variable isn't a timestamp
+func compareTimestamp(input interface{},
+	path string,
+	value time.Time) (int, bool, error) {
+	stringValue, isString, stringErr := stringVariable(input, path)
+	if stringErr != nil {
+		return 0, false, stringErr
+	}
+	if !isString {
+		return 0, false, nil
+	}
+	timestamp, timestampErr := time.Parse(time.RFC3339, stringValue)
+	if timestampErr != nil {
+		return 0, false, nil
+	}
+	switch {
+	case timestamp.Before(value):
+		return -1, true, nil
+	case timestamp.After(value):
+		return 1, true, nil
+	}
+	return 0, true, nil
+}
+
+// evaluateComparison returns the result of the Choice rule comparison
+// against the input. A Variable of the wrong type doesn't match. A
+// Variable that doesn't exist is an error.
+func evaluateComparison(comparison Comparison, input interface{}) (bool, error) {
+	switch cmp := comparison.(type) {
+	case *And:
+		for _, eachComparison := range cmp.Comparison {
+			matched, matchedErr := evaluateComparison(eachComparison, input)
+			if matchedErr != nil || !matched {
+				return false, matchedErr
+			}
+		}
+		return true, nil
+	case *Or:
+		for _, eachComparison := range cmp.Comparison {
+			matched, matchedErr := evaluateComparison(eachComparison, input)
+			if matchedErr != nil || matched {
+				return matched, matchedErr
+			}
+		}
+		return false, nil
+	case *Not:
+		matched, matchedErr := evaluateComparison(cmp.Comparison, input)
+		return !matched && matchedErr == nil, matchedErr
+
+	// Strings
+	case *StringEquals:
+		value, ok, err := stringVariable(input, cmp.Variable)
+		return ok && value == cmp.Value, err
+	case *StringLessThan:
+		value, ok, err := stringVariable(input, cmp.Variable)
+		return ok && value < cmp.Value, err
+	case *StringGreaterThan:
+		value, ok, err := stringVariable(input, cmp.Variable)
+		return ok && value > cmp.Value, err
+	case *StringLessThanEquals:
+		value, ok, err := stringVariable(input, cmp.Variable)
+		return ok && value <= cmp.Value, err
+	case *StringGreaterThanEquals:
+		value, ok, err := stringVariable(input, cmp.Variable)
+		return ok && value >= cmp.Value, err
+
+	// Numbers
+	case *NumericEquals:
+		value, ok, err := numericVariable(input, cmp.Variable)
+		return ok && value == float64(cmp.Value), err
+	case *NumericLessThan:
+		value, ok, err := numericVariable(input, cmp.Variable)
+		return ok && value < float64(cmp.Value), err
+	case *NumericGreaterThan:
+		value, ok, err := numericVariable(input, cmp.Variable)
+		return ok && value > float64(cmp.Value), err
+	case *NumericLessThanEquals:
+		value, ok, err := numericVariable(input, cmp.Variable)
+		return ok && value <= float64(cmp.Value), err
+	case *NumericGreaterThanEquals:
+		value, ok, err := numericVariable(input, cmp.Variable)
+		return ok && value >= float64(cmp.Value), err
+
+	// Booleans
+	case *BooleanEquals:
+		value, valueErr := jsonPathValue(input, cmp.Variable)
+		if valueErr != nil {
+			return false, valueErr
+		}
+		boolValue, isBool := value.(bool)
+		expected, expectedIsBool := cmp.Value.(bool)
+		return isBool && expectedIsBool && boolValue == expected, nil
+
+	// Timestamps
+	case *TimestampEquals:
+		result, ok, err := compareTimestamp(input, cmp.Variable, cmp.Value)
+		return ok && result == 0, err
+	case *TimestampLessThan:
+		result, ok, err := compareTimestamp(input, cmp.Variable, cmp.Value)
+		return ok && result < 0, err
+	case *TimestampGreaterThan:
+		result, ok, err := compareTimestamp(input, cmp.Variable, cmp.Value)
+		return ok && result > 0, err
+	case *TimestampLessThanEquals:
+		result, ok, err := compareTimestamp(input, cmp.Variable, cmp.Value)
+		return ok && result <= 0, err
+	case *TimestampGreaterThanEquals:
+		result, ok, err := compareTimestamp(input, cmp.Variable, cmp.Value)
+		return ok && result >= 0, err
+	}
+	return false, errors.Errorf("unsupported Choice comparison: %T", comparison)
+}
@@ -0,0 +1,206 @@
+package step
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sparta "github.com/mweagle/Sparta"
+	gocf "github.com/mweagle/go-cloudformation"
+)
+
+type localDieRoll struct {
+	Roll int `json:"roll"`
+}
+
+func TestLocalExecutorChoice(t *testing.T) {
+	rolls := []int{1, 2, 6}
+	rollDie := func(ctx context.Context) (localDieRoll, error) {
+		roll := rolls[0]
+		rolls = rolls[1:]
+		return localDieRoll{Roll: roll}, nil
+	}
+	lambdaFn, _ := sparta.NewAWSLambda("LocalRollDie",
+		rollDie,
+		sparta.IAMRoleDefinition{})
+
+	lambdaTaskState := NewLambdaTaskState("lambdaRollDie", lambdaFn)
+	successState := NewSuccessState("success")
+	delayState := NewWaitDelayState("tryAgainShortly", 3*time.Second)
+	choiceState := NewChoiceState("checkRoll",
+		&Not{
+			Comparison: &NumericGreaterThan{
+				Variable: "$.roll",
+				Value:    3,
+			},
+			Next: delayState,
+		}).WithDefault(successState)
+	lambdaTaskState.Next(choiceState)
+	delayState.Next(lambdaTaskState)
+
+	startTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	executor := NewLocalExecutor(NewStateMachine("LocalRollDie", lambdaTaskState)).
+		WithClock(NewVirtualClock(startTime))
+	execution, executionErr := executor.Execute(context.Background(), nil)
+	if executionErr != nil {
+		t.Fatal(executionErr)
+	}
+	if execution.Status != LocalExecutionSucceeded {
+		t.Fatalf("Unexpected execution status: %s (%s)", execution.Status, execution.Cause)
+	}
+	var output localDieRoll
+	unmarshalErr := execution.UnmarshalOutput(&output)
+	if unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	if output.Roll != 6 {
+		t.Fatalf("Unexpected output: %#v", output)
+	}
+	// Two delays on the virtual clock
+	if execution.StopDate.Sub(startTime) != 6*time.Second {
+		t.Fatalf("Unexpected virtual duration: %s", execution.StopDate.Sub(startTime))
+	}
+	t.Logf("Visited states: %v", execution.StateNames())
+}
+
+func TestLocalExecutorPaths(t *testing.T) {
+	passState := NewPassState("addResult", map[string]interface{}{
+		"status": "OK",
+	}).WithResultPath("$.result")
+	passState.WithInputPath("$.request")
+	filterState := NewPassState("filter", nil)
+	filterState.WithOutputPath("$.result")
+	passState.Next(filterState)
+
+	execution, executionErr := NewLocalExecutor(NewStateMachine("LocalPaths", passState)).
+		Execute(context.Background(), map[string]interface{}{
+			"request": map[string]interface{}{
+				"id": "42",
+			},
+		})
+	if executionErr != nil {
+		t.Fatal(executionErr)
+	}
+	output, outputOk := execution.Output.(map[string]interface{})
+	if !outputOk || output["status"] != "OK" {
+		t.Fatalf("Unexpected output: %#v", execution.Output)
+	}
+}
+
+func TestLocalExecutorRetryCatch(t *testing.T) {
+	attempts := 0
+	failingFn := func(ctx context.Context) (interface{}, error) {
+		attempts++
+		return nil, errors.New("always fails")
+	}
+	lambdaFn, _ := sparta.NewAWSLambda("LocalFailing",
+		failingFn,
+		sparta.IAMRoleDefinition{})
+	recoveredState := NewPassState("recovered", nil)
+	lambdaTaskState := NewLambdaTaskState("lambdaFailing", lambdaFn)
+	lambdaTaskState.WithRetriers(NewTaskRetry().
+		WithErrors(StatesTaskFailed).
+		WithInterval(time.Second).
+		WithMaxAttempts(2).
+		WithBackoffRate(2)).
+		WithCatchers(NewTaskCatch(recoveredState, StatesAll))
+
+	startTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	execution, executionErr := NewLocalExecutor(NewStateMachine("LocalRetry", lambdaTaskState)).
+		WithClock(NewVirtualClock(startTime)).
+		Execute(context.Background(), nil)
+	if executionErr != nil {
+		t.Fatal(executionErr)
+	}
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got: %d", attempts)
+	}
+	// 1s + 2s backoff
+	if execution.StopDate.Sub(startTime) != 3*time.Second {
+		t.Fatalf("Unexpected virtual duration: %s", execution.StopDate.Sub(startTime))
+	}
+	output, outputOk := execution.Output.(map[string]interface{})
+	if execution.Status != LocalExecutionSucceeded ||
+		!outputOk ||
+		output["Error"] != "errorString" {
+		t.Fatalf("Unexpected caught output: %#v", execution.Output)
+	}
+}
+
+func TestLocalExecutorServiceTask(t *testing.T) {
+	snsState := NewSNSTaskState("notify", SNSTaskParameters{
+		Message:  "Hello",
+		TopicArn: gocf.String("arn:aws:sns:us-west-2:123412341234:MyTopic"),
+	})
+	failState := NewFailState("failed", "NotifyFailed", nil)
+	snsState.WithCatchers(NewTaskCatch(failState, StateError("SNS.Throttled")))
+
+	executor := NewLocalExecutor(NewStateMachine("LocalSNS", snsState))
+	_, executionErr := executor.Execute(context.Background(), nil)
+	if executionErr == nil {
+		t.Fatal("Failed to reject unmocked service task")
+	}
+
+	published := []interface{}{}
+	executor.WithResourceHandler("arn:aws:states:::sns:publish",
+		func(ctx context.Context, input interface{}) (interface{}, error) {
+			published = append(published, input)
+			return nil, NewTaskError("SNS.Throttled", "too many requests")
+		})
+	execution, executionErr := executor.Execute(context.Background(), nil)
+	if executionErr != nil {
+		t.Fatal(executionErr)
+	}
+	if len(published) != 1 ||
+		execution.Status != LocalExecutionFailed ||
+		execution.Error != "NotifyFailed" {
+		t.Fatalf("Unexpected execution: %#v", execution)
+	}
+}
+
+func TestLocalExecutorMap(t *testing.T) {
+	doubleFn := func(ctx context.Context, props map[string]interface{}) (map[string]interface{}, error) {
+		value, _ := props["value"].(float64)
+		return map[string]interface{}{
+			"doubled": value * 2,
+			"index":   props["index"],
+		}, nil
+	}
+	lambdaFn, _ := sparta.NewAWSLambda("LocalDouble",
+		doubleFn,
+		sparta.IAMRoleDefinition{})
+	doubleState := NewLambdaTaskState("double", lambdaFn)
+	doubleState.WithResultSelector(map[string]interface{}{
+		"value.$": "$.doubled",
+	})
+	mapState := NewMapState("doubleAll", *NewStateMachine("doubleIterator", doubleState)).
+		WithItemsPath("$.values").
+		WithMaxConcurrency(2).
+		WithParameters(map[string]interface{}{
+			"value.$": "$$.Map.Item.Value",
+			"index.$": "$$.Map.Item.Index",
+		}).
+		WithResultPath("$.results")
+
+	execution, executionErr := NewLocalExecutor(NewStateMachine("LocalMap", mapState)).
+		Execute(context.Background(), map[string]interface{}{
+			"values": []int{1, 2, 3},
+		})
+	if executionErr != nil {
+		t.Fatal(executionErr)
+	}
+	var output struct {
+		Values  []int
+		Results []struct {
+			Value int
+		}
+	}
+	unmarshalErr := execution.UnmarshalOutput(&output)
+	if unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	if len(output.Results) != 3 || output.Results[2].Value != 6 {
+		t.Fatalf("Unexpected Map output: %#v", execution.Output)
+	}
+}
@@ -3,6 +3,7 @@ package step
 import (
 	"math/rand"
 
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -52,6 +53,21 @@ func (smtj *SageMakerTrainingJob) MarshalJSON() ([]byte, error) {
 		&smtj.parameters)
 }
 
+// statePolicyStatements returns the privileges to create the training
+// job and wait for it to complete
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/sagemaker-iam.html
+func (smtj *SageMakerTrainingJob) statePolicyStatements() []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{
+		allowStatement(regionalARN("sagemaker", "training-job/*"),
+			"sagemaker:CreateTrainingJob",
+			"sagemaker:DescribeTrainingJob",
+			"sagemaker:StopTrainingJob"),
+		allowStatement(gocf.String("*"), "sagemaker:ListTags"),
+		syncEventRuleStatement("StepFunctionsGetEventsForSageMakerTrainingJobsRule"),
+	}
+	return append(statements, sageMakerPassRoleStatements(smtj.parameters.RoleArn)...)
+}
+
 // NewSageMakerTrainingJob returns an initialized SQSTaskState
 func NewSageMakerTrainingJob(stateName string,
 	parameters SageMakerTrainingJobParameters) *SageMakerTrainingJob {
@@ -106,6 +122,20 @@ func (smtj *SageMakerTransformJob) MarshalJSON() ([]byte, error) {
 		&smtj.parameters)
 }
 
+// statePolicyStatements returns the privileges to create the transform
+// job and wait for it to complete
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/sagemaker-iam.html
+func (smtj *SageMakerTransformJob) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(regionalARN("sagemaker", "transform-job/*"),
+			"sagemaker:CreateTransformJob",
+			"sagemaker:DescribeTransformJob",
+			"sagemaker:StopTransformJob"),
+		allowStatement(gocf.String("*"), "sagemaker:ListTags"),
+		syncEventRuleStatement("StepFunctionsGetEventsForSageMakerTransformJobsRule"),
+	}
+}
+
 // NewSageMakerTransformJob returns an initialized SQSTaskState
 func NewSageMakerTransformJob(stateName string,
 	parameters SageMakerTransformJobParameters) *SageMakerTransformJob {
@@ -120,3 +150,18 @@ func NewSageMakerTransformJob(stateName string,
 	}
 	return sns
 }
+
+// sageMakerPassRoleStatements returns the privilege to pass the job's
+// execution role to SageMaker
+func sageMakerPassRoleStatements(roleArn gocf.Stringable) []spartaIAM.PolicyStatement {
+	if roleArn == nil {
+		return nil
+	}
+	passRole := allowStatement(roleArn.String(), "iam:PassRole")
+	passRole.Condition = map[string]interface{}{
+		"StringEquals": map[string]interface{}{
+			"iam:PassedToService": "sagemaker.amazonaws.com",
+		},
+	}
+	return []spartaIAM.PolicyStatement{passRole}
+}
@@ -3,6 +3,7 @@ package step
 import (
 	"math/rand"
 
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -29,8 +30,38 @@ type SNSTaskState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sns.html
 func (sts *SNSTaskState) MarshalJSON() ([]byte, error) {
+	parameters := sts.parameters
+	if sts.waitForTaskToken {
+		parameters.MessageAttributes = taskTokenMessageAttributes(parameters.MessageAttributes)
+	}
 	return sts.BaseTask.marshalMergedParams("arn:aws:states:::sns:publish",
-		&sts.parameters)
+		&parameters)
+}
+
+// WithWaitForTaskToken adds the task token to the message attributes and
+// pauses the execution until the token is returned with a TaskCallback.
+// See TaskTokenFromSNSEntity.
+func (sts *SNSTaskState) WithWaitForTaskToken() *SNSTaskState {
+	sts.waitForTaskToken = true
+	return sts
+}
+
+// statePolicyStatements returns the privileges to publish the message
+func (sts *SNSTaskState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{}
+	for _, eachTarget := range []gocf.Stringable{sts.parameters.TopicArn,
+		sts.parameters.TargetArn} {
+		if eachTarget != nil {
+			statements = append(statements,
+				allowStatement(eachTarget.String(), "sns:Publish"))
+		}
+	}
+	// SMS messages aren't published to a resource
+	if len(statements) == 0 {
+		statements = append(statements,
+			allowStatement(gocf.String("*"), "sns:Publish"))
+	}
+	return statements
 }
 
 // NewSNSTaskState returns an initialized SNSTaskState
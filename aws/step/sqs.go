@@ -3,6 +3,7 @@ package step
 import (
 	"math/rand"
 
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -28,8 +29,27 @@ type SQSTaskState struct {
 // to turn into a stringified
 // Ref: https://docs.aws.amazon.com/step-functions/latest/dg/connectors-sqs.html
 func (sqs *SQSTaskState) MarshalJSON() ([]byte, error) {
+	parameters := sqs.parameters
+	if sqs.waitForTaskToken {
+		parameters.MessageAttributes = taskTokenMessageAttributes(parameters.MessageAttributes)
+	}
 	return sqs.BaseTask.marshalMergedParams("arn:aws:states:::sqs:sendMessage",
-		&sqs.parameters)
+		&parameters)
+}
+
+// WithWaitForTaskToken adds the task token to the message attributes and
+// pauses the execution until the token is returned with a TaskCallback.
+// See TaskTokenFromSQSMessage.
+func (sqs *SQSTaskState) WithWaitForTaskToken() *SQSTaskState {
+	sqs.waitForTaskToken = true
+	return sqs
+}
+
+// statePolicyStatements returns the privileges to send the message
+func (sqs *SQSTaskState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(sqsQueueARN(sqs.parameters.QueueURL), "sqs:SendMessage"),
+	}
 }
 
 // NewSQSTaskState returns an initialized SQSTaskState
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"time"
 
@@ -41,6 +42,9 @@ const (
 	// StatesNoChoiceMatched is a Choice state failed to find a match for the
 	// condition field extracted from its input
 	StatesNoChoiceMatched StateError = "States.NoChoiceMatched"
+	// StatesRuntime is an execution failed due to some exception that could
+	// not be processed, such as an invalid path
+	StatesRuntime StateError = "States.Runtime"
 )
 
 /*******************************************************************************
@@ -538,6 +542,11 @@ func (bis *baseInnerState) nodeID() string {
 	return fmt.Sprintf("%s-%d", bis.name, bis.id)
 }
 
+// innerState returns the common state properties
+func (bis *baseInnerState) innerState() *baseInnerState {
+	return bis
+}
+
 // marshalStateJSON for subclass marshalling of state information
 func (bis *baseInnerState) marshalStateJSON(stateType string,
 	additionalData map[string]interface{}) ([]byte, error) {
@@ -580,6 +589,7 @@ type PassState struct {
 	baseInnerState
 	ResultPath string
 	Result     interface{}
+	Parameters map[string]interface{}
 }
 
 // WithResultPath is the fluent builder for the result path
@@ -594,6 +604,13 @@ func (ps *PassState) WithResult(result interface{}) *PassState {
 	return ps
 }
 
+// WithParameters is the fluent builder for the Parameters. Keys that
+// end with `.$` are JSONPath selectors.
+func (ps *PassState) WithParameters(parameters map[string]interface{}) *PassState {
+	ps.Parameters = parameters
+	return ps
+}
+
 // Next returns the next state
 func (ps *PassState) Next(nextState MachineState) MachineState {
 	ps.next = nextState
@@ -640,6 +657,9 @@ func (ps *PassState) MarshalJSON() ([]byte, error) {
 	if ps.Result != nil {
 		additionalParams["Result"] = ps.Result
 	}
+	if ps.Parameters != nil {
+		additionalParams["Parameters"] = ps.Parameters
+	}
 	return ps.marshalStateJSON("Pass", additionalParams)
 }
 
@@ -778,7 +798,9 @@ type TaskCatch struct {
 func (tc *TaskCatch) MarshalJSON() ([]byte, error) {
 	catchJSON := map[string]interface{}{
 		"ErrorEquals": tc.errorEquals,
-		"Next":        tc.next,
+	}
+	if tc.next != nil {
+		catchJSON["Next"] = tc.next.Name()
 	}
 	return json.Marshal(catchJSON)
 }
@@ -806,6 +828,14 @@ type BaseTask struct {
 	LambdaDecorator  sparta.TemplateDecorator
 	Retriers         []*TaskRetry
 	Catchers         []*TaskCatch
+	// Parameters are merged into the task's parameters. Keys that
+	// end with `.$` are JSONPath selectors.
+	Parameters map[string]interface{}
+	// ResultSelector builds the task result from the raw result
+	ResultSelector map[string]interface{}
+	// waitForTaskToken pauses the task until the task token is returned
+	// by SendTaskSuccess or SendTaskFailure
+	waitForTaskToken bool
 }
 
 func (bt *BaseTask) marshalMergedParams(taskResourceType string,
@@ -825,6 +855,12 @@ func (bt *BaseTask) marshalMergedParams(taskResourceType string,
 	if !mapTypedErr {
 		return nil, errors.Errorf("attempting to type convert unmarshalled params to map[string]interface{}")
 	}
+	for eachKey, eachValue := range bt.Parameters {
+		mapTyped[eachKey] = eachValue
+	}
+	if bt.waitForTaskToken {
+		taskResourceType += waitForTaskTokenSuffix
+	}
 	additionalParams := bt.additionalParams()
 	additionalParams["Resource"] = taskResourceType
 	additionalParams["Parameters"] = mapTyped
@@ -845,6 +881,9 @@ func (bt *BaseTask) additionalParams() map[string]interface{} {
 	if bt.ResultPath != "" {
 		additionalParams["ResultPath"] = bt.ResultPath
 	}
+	if bt.ResultSelector != nil {
+		additionalParams["ResultSelector"] = bt.ResultSelector
+	}
 	if len(bt.Retriers) != 0 {
 		additionalParams["Retry"] = make([]map[string]interface{}, 0)
 	}
@@ -890,6 +929,20 @@ func (bt *BaseTask) WithResultPath(resultPath string) *BaseTask {
 	return bt
 }
 
+// WithParameters is the fluent builder for the Parameters. Keys that
+// end with `.$` are JSONPath selectors.
+func (bt *BaseTask) WithParameters(parameters map[string]interface{}) *BaseTask {
+	bt.Parameters = parameters
+	return bt
+}
+
+// WithResultSelector is the fluent builder for the ResultSelector. Keys
+// that end with `.$` are JSONPath selectors applied to the raw result.
+func (bt *BaseTask) WithResultSelector(resultSelector map[string]interface{}) *BaseTask {
+	bt.ResultSelector = resultSelector
+	return bt
+}
+
 // WithTimeout is the fluent builder for BaseTask
 func (bt *BaseTask) WithTimeout(timeout time.Duration) *BaseTask {
 	bt.TimeoutSeconds = timeout
@@ -938,6 +991,12 @@ func (bt *BaseTask) WithOutputPath(outputPath string) TransitionState {
 	return bt
 }
 
+// baseTask returns the embedded BaseTask for states that delegate to
+// a task resource
+func (bt *BaseTask) baseTask() *BaseTask {
+	return bt
+}
+
 // MarshalJSON to prevent inadvertent composition
 func (bt *BaseTask) MarshalJSON() ([]byte, error) {
 
@@ -1010,10 +1069,49 @@ func NewLambdaTaskState(stateName string, lambdaFn *sparta.LambdaAWSInfo) *Lambd
 // to turn into a stringified Ref:
 func (ts *LambdaTaskState) MarshalJSON() ([]byte, error) {
 	additionalParams := ts.BaseTask.additionalParams()
+	if ts.waitForTaskToken {
+		// The function is invoked with the lambda:invoke service
+		// integration and the task token in the payload. The state
+		// completes when the token is returned with SendTaskSuccess or
+		// SendTaskFailure, not when the function returns. See
+		// TaskTokenPayload and TaskCallback.
+		payload := map[string]interface{}{
+			TaskTokenAttributeName + ".$": ContextTaskToken.String(),
+			"Input.$":                     "$",
+		}
+		if ts.Parameters != nil {
+			delete(payload, "Input.$")
+			payload["Input"] = ts.Parameters
+		}
+		additionalParams["Resource"] = "arn:aws:states:::lambda:invoke" + waitForTaskTokenSuffix
+		additionalParams["Parameters"] = map[string]interface{}{
+			"FunctionName": gocf.GetAtt(ts.lambdaLogicalResourceName, "Arn"),
+			"Payload":      payload,
+		}
+		return ts.marshalStateJSON("Task", additionalParams)
+	}
 	additionalParams["Resource"] = gocf.GetAtt(ts.lambdaLogicalResourceName, "Arn")
+	if ts.Parameters != nil {
+		additionalParams["Parameters"] = ts.Parameters
+	}
 	return ts.marshalStateJSON("Task", additionalParams)
 }
 
+// WithWaitForTaskToken invokes the function with a TaskTokenPayload and
+// pauses the execution until the token is returned with a TaskCallback
+func (ts *LambdaTaskState) WithWaitForTaskToken() *LambdaTaskState {
+	ts.waitForTaskToken = true
+	return ts
+}
+
+// statePolicyStatements returns the privileges to invoke the function
+func (ts *LambdaTaskState) statePolicyStatements() []spartaIAM.PolicyStatement {
+	return []spartaIAM.PolicyStatement{
+		allowStatement(gocf.GetAtt(ts.lambdaLogicalResourceName, "Arn"),
+			"lambda:InvokeFunction"),
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // WaitDelay
 ////////////////////////////////////////////////////////////////////////////////
@@ -1382,10 +1480,14 @@ func (ps *ParallelState) Next(nextState MachineState) MachineState {
 
 // AdjacentStates returns nodes reachable from this node
 func (ps *ParallelState) AdjacentStates() []MachineState {
-	if ps.next == nil {
-		return nil
+	adjacent := []MachineState{}
+	if ps.next != nil {
+		adjacent = append(adjacent, ps.next)
 	}
-	return []MachineState{ps.next}
+	for _, eachCatcher := range ps.Catchers {
+		adjacent = append(adjacent, eachCatcher.next)
+	}
+	return adjacent
 }
 
 // Name returns the name of this Task state
@@ -1443,10 +1545,212 @@ func NewParallelState(parallelStateName string, states StateMachine) *ParallelSt
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// MapState
+////////////////////////////////////////////////////////////////////////////////
+
+// MapState is a state that runs the Iterator state machine for each
+// item in the input array selected by ItemsPath
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/amazon-states-language-map-state.html
+type MapState struct {
+	baseInnerState
+	Iterator       StateMachine
+	ItemsPath      string
+	MaxConcurrency int
+	Parameters     map[string]interface{}
+	ResultSelector map[string]interface{}
+	ResultPath     string
+	Retriers       []*TaskRetry
+	Catchers       []*TaskCatch
+}
+
+// WithItemsPath is the fluent builder for the path to the input array
+func (ms *MapState) WithItemsPath(itemsPath string) *MapState {
+	ms.ItemsPath = itemsPath
+	return ms
+}
+
+// WithMaxConcurrency is the fluent builder for the upper bound on the
+// number of concurrent Iterator executions. Zero means no limit.
+func (ms *MapState) WithMaxConcurrency(maxConcurrency int) *MapState {
+	ms.MaxConcurrency = maxConcurrency
+	return ms
+}
+
+// WithParameters is the fluent builder for the Parameters supplied to
+// each Iterator execution. The `$$.Map.Item.Index` and
+// `$$.Map.Item.Value` context paths select the current item.
+func (ms *MapState) WithParameters(parameters map[string]interface{}) *MapState {
+	ms.Parameters = parameters
+	return ms
+}
+
+// WithResultSelector is the fluent builder for the ResultSelector
+func (ms *MapState) WithResultSelector(resultSelector map[string]interface{}) *MapState {
+	ms.ResultSelector = resultSelector
+	return ms
+}
+
+// WithResultPath is the fluent builder for the result path
+func (ms *MapState) WithResultPath(resultPath string) *MapState {
+	ms.ResultPath = resultPath
+	return ms
+}
+
+// WithRetriers is the fluent builder for TaskState
+func (ms *MapState) WithRetriers(retries ...*TaskRetry) *MapState {
+	if ms.Retriers == nil {
+		ms.Retriers = make([]*TaskRetry, 0)
+	}
+	ms.Retriers = append(ms.Retriers, retries...)
+	return ms
+}
+
+// WithCatchers is the fluent builder for TaskState
+func (ms *MapState) WithCatchers(catch ...*TaskCatch) *MapState {
+	if ms.Catchers == nil {
+		ms.Catchers = make([]*TaskCatch, 0)
+	}
+	ms.Catchers = append(ms.Catchers, catch...)
+	return ms
+}
+
+// Next returns the next state
+func (ms *MapState) Next(nextState MachineState) MachineState {
+	ms.next = nextState
+	return nextState
+}
+
+// AdjacentStates returns nodes reachable from this node
+func (ms *MapState) AdjacentStates() []MachineState {
+	adjacent := []MachineState{}
+	if ms.next != nil {
+		adjacent = append(adjacent, ms.next)
+	}
+	for _, eachCatcher := range ms.Catchers {
+		adjacent = append(adjacent, eachCatcher.next)
+	}
+	return adjacent
+}
+
+// Name returns the name of this Map state
+func (ms *MapState) Name() string {
+	return ms.name
+}
+
+// WithComment returns the MapState comment
+func (ms *MapState) WithComment(comment string) TransitionState {
+	ms.comment = comment
+	return ms
+}
+
+// WithInputPath returns the MapState input data selector
+func (ms *MapState) WithInputPath(inputPath string) TransitionState {
+	ms.inputPath = inputPath
+	return ms
+}
+
+// WithOutputPath returns the MapState output data selector
+func (ms *MapState) WithOutputPath(outputPath string) TransitionState {
+	ms.outputPath = outputPath
+	return ms
+}
+
+// MarshalJSON for custom marshalling
+func (ms *MapState) MarshalJSON() ([]byte, error) {
+	if ms.Iterator.startAt == nil {
+		return nil, errors.Errorf("MapState %s doesn't define an Iterator", ms.name)
+	}
+	// The Iterator is a state machine without the top level fields
+	iterator := map[string]interface{}{
+		"StartAt": ms.Iterator.startAt.Name(),
+		"States":  ms.Iterator.uniqueStates,
+	}
+	if ms.Iterator.comment != "" {
+		iterator["Comment"] = ms.Iterator.comment
+	}
+	additionalParams := map[string]interface{}{
+		"Iterator": iterator,
+	}
+	if ms.ItemsPath != "" {
+		additionalParams["ItemsPath"] = ms.ItemsPath
+	}
+	if ms.MaxConcurrency != 0 {
+		additionalParams["MaxConcurrency"] = ms.MaxConcurrency
+	}
+	if ms.Parameters != nil {
+		additionalParams["Parameters"] = ms.Parameters
+	}
+	if ms.ResultSelector != nil {
+		additionalParams["ResultSelector"] = ms.ResultSelector
+	}
+	if ms.ResultPath != "" {
+		additionalParams["ResultPath"] = ms.ResultPath
+	}
+	if len(ms.Retriers) != 0 {
+		additionalParams["Retry"] = ms.Retriers
+	}
+	if ms.Catchers != nil {
+		additionalParams["Catch"] = ms.Catchers
+	}
+	return ms.marshalStateJSON("Map", additionalParams)
+}
+
+// NewMapState returns a "MapState" that runs the iterator state machine
+// for each item in the input array
+func NewMapState(mapStateName string, iterator StateMachine) *MapState {
+	return &MapState{
+		baseInnerState: baseInnerState{
+			name: mapStateName,
+			id:   rand.Int63(),
+		},
+		Iterator: iterator,
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // StateMachine
 ////////////////////////////////////////////////////////////////////////////////
 
+// StateMachineType is the type of workflow
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/concepts-standard-vs-express.html
+type StateMachineType string
+
+const (
+	// StateMachineTypeStandard is for long-running, durable, and auditable
+	// workflows. This is the default.
+	StateMachineTypeStandard StateMachineType = "STANDARD"
+	// StateMachineTypeExpress is for high-volume, event-processing
+	// workloads
+	StateMachineTypeExpress StateMachineType = "EXPRESS"
+)
+
+// StateMachineLogLevel is the execution history log level
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/cw-logs.html
+type StateMachineLogLevel string
+
+const (
+	// LogLevelAll logs all execution history events
+	LogLevelAll StateMachineLogLevel = "ALL"
+	// LogLevelError logs only error events
+	LogLevelError StateMachineLogLevel = "ERROR"
+	// LogLevelFatal logs only fatal error events
+	LogLevelFatal StateMachineLogLevel = "FATAL"
+	// LogLevelOff disables logging
+	LogLevelOff StateMachineLogLevel = "OFF"
+)
+
+// StateMachineLogging is the CloudWatch Logs configuration for the
+// execution history. The destination log group is created in the
+// template.
+type StateMachineLogging struct {
+	Level                StateMachineLogLevel
+	IncludeExecutionData bool
+	// RetentionInDays is the log group retention. Zero means the
+	// events never expire.
+	RetentionInDays int64
+}
+
 // StateMachine is the top level item
 type StateMachine struct {
 	name                 string
@@ -1455,6 +1759,14 @@ type StateMachine struct {
 	startAt              TransitionState
 	uniqueStates         map[string]MachineState
 	roleArn              gocf.Stringable
+	machineType          StateMachineType
+	logging              *StateMachineLogging
+	tracingEnabled       bool
+	tags                 map[string]string
+	eventTriggers        map[string]sparta.CloudWatchEventsRule
+	s3Triggers           map[string]S3Trigger
+	apiGatewayTrigger    *APIGatewayTrigger
+	dataFlowErrors       []error
 }
 
 //Comment sets the StateMachine comment
@@ -1469,23 +1781,111 @@ func (sm *StateMachine) WithRoleArn(roleArn gocf.Stringable) *StateMachine {
 	return sm
 }
 
-// validate performs any validation against the state machine
-// prior to marshaling
-func (sm *StateMachine) validate() []error {
-	validationErrors := make([]error, 0)
-	if sm.stateDefinitionError != nil {
-		validationErrors = append(validationErrors, sm.stateDefinitionError)
-	}
+// WithType sets the workflow type. The default type is
+// StateMachineTypeStandard.
+func (sm *StateMachine) WithType(machineType StateMachineType) *StateMachine {
+	sm.machineType = machineType
+	return sm
+}
 
-	// TODO - add Catcher validator
-	/*
-		Each Catcher MUST contain a field named “ErrorEquals”, specified exactly as with the Retrier “ErrorEquals” field, and a field named “Next” whose value MUST be a string exactly matching a State Name.
+// WithLogging sends the execution history to a CloudWatch Logs log group
+// that is created in the template
+func (sm *StateMachine) WithLogging(logging *StateMachineLogging) *StateMachine {
+	sm.logging = logging
+	return sm
+}
 
-		When a state reports an error and either there is no Retry field, or retries have failed to resolve the error, the interpreter scans through the Catchers in array order, and when the Error Name appears in the value of a Catcher’s “ErrorEquals” field, transitions the machine to the state named in the value of the “Next” field.
+// WithTracing enables or disables AWS X-Ray tracing
+func (sm *StateMachine) WithTracing(enabled bool) *StateMachine {
+	sm.tracingEnabled = enabled
+	return sm
+}
 
-		The reserved name “States.ALL” appearing in a Retrier’s “ErrorEquals” field is a wild-card and matches any Error Name. Such a value MUST appear alone in the “ErrorEquals” array and MUST appear in the last Catcher in the “Catch” array.
-	*/
-	return validationErrors
+// WithTags sets the state machine resource tags
+func (sm *StateMachine) WithTags(tags map[string]string) *StateMachine {
+	sm.tags = tags
+	return sm
+}
+
+// loggingPolicyStatements returns the privileges required to deliver the
+// execution history to CloudWatch Logs
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/cw-logs.html#cloudwatch-iam-policy
+func (sm *StateMachine) loggingPolicyStatements() []spartaIAM.PolicyStatement {
+	statements := []spartaIAM.PolicyStatement{}
+	if sm.logging != nil && sm.logging.Level != LogLevelOff {
+		statements = append(statements, allowStatement(gocf.String("*"),
+			"logs:CreateLogDelivery",
+			"logs:GetLogDelivery",
+			"logs:UpdateLogDelivery",
+			"logs:DeleteLogDelivery",
+			"logs:ListLogDeliveries",
+			"logs:PutResourcePolicy",
+			"logs:DescribeResourcePolicies",
+			"logs:DescribeLogGroups"))
+	}
+	if sm.tracingEnabled {
+		statements = append(statements, allowStatement(gocf.String("*"),
+			"xray:PutTraceSegments",
+			"xray:PutTelemetryRecords",
+			"xray:GetSamplingRules",
+			"xray:GetSamplingTargets"))
+	}
+	return statements
+}
+
+// decorateStateMachineResource applies the type, logging, tracing and
+// tag options to the resource
+func (sm *StateMachine) decorateStateMachineResource(stepFunctionResourceName string,
+	stepFunctionResource *spartaCF.StepFunctionsStateMachine,
+	template *gocf.Template) {
+	if sm.machineType != "" {
+		stepFunctionResource.StateMachineType = gocf.String(string(sm.machineType))
+	}
+	if sm.logging != nil {
+		loggingConfig := &spartaCF.StepFunctionsStateMachineLogging{
+			Level:                gocf.String(string(sm.logging.Level)),
+			IncludeExecutionData: gocf.Bool(sm.logging.IncludeExecutionData),
+		}
+		if sm.logging.Level != LogLevelOff {
+			logGroupResourceName := sparta.CloudFormationResourceName(stepFunctionResourceName,
+				"LogGroup")
+			// Vended log group names avoid the resource policy size limit
+			logGroup := &gocf.LogsLogGroup{
+				LogGroupName: gocf.String(fmt.Sprintf("/aws/vendedlogs/states/%s", sm.name)),
+			}
+			if sm.logging.RetentionInDays != 0 {
+				logGroup.RetentionInDays = gocf.Integer(sm.logging.RetentionInDays)
+			}
+			template.AddResource(logGroupResourceName, logGroup)
+			loggingConfig.Destinations = []spartaCF.StepFunctionsStateMachineLogDestination{
+				{
+					CloudWatchLogsLogGroup: &spartaCF.StepFunctionsStateMachineLogGroup{
+						LogGroupArn: gocf.GetAtt(logGroupResourceName, "Arn"),
+					},
+				},
+			}
+		}
+		stepFunctionResource.LoggingConfiguration = loggingConfig
+	}
+	if sm.tracingEnabled {
+		stepFunctionResource.TracingConfiguration = &spartaCF.StepFunctionsStateMachineTracing{
+			Enabled: gocf.Bool(true),
+		}
+	}
+	if len(sm.tags) != 0 {
+		tagKeys := make([]string, 0, len(sm.tags))
+		for eachKey := range sm.tags {
+			tagKeys = append(tagKeys, eachKey)
+		}
+		sort.Strings(tagKeys)
+		for _, eachKey := range tagKeys {
+			stepFunctionResource.Tags = append(stepFunctionResource.Tags,
+				spartaCF.StepFunctionsStateMachineTag{
+					Key:   eachKey,
+					Value: sm.tags[eachKey],
+				})
+		}
+	}
 }
 
 // StateMachineDecorator is a decorator that returns a default
@@ -1508,23 +1908,14 @@ func (sm *StateMachine) StateMachineNamedDecorator(stepFunctionResourceName stri
 		noop bool,
 		logger *logrus.Logger) error {
 
-		machineErrors := sm.validate()
-		if len(machineErrors) != 0 {
-			errorText := make([]string, len(machineErrors))
-			for index := range machineErrors {
-				errorText[index] = machineErrors[index].Error()
-			}
-			return errors.Errorf("Invalid state machine. Errors: %s",
-				strings.Join(errorText, ", "))
+		validationErr := sm.validationError()
+		if validationErr != nil {
+			return validationErr
 		}
 
-		lambdaFunctionResourceNames := []string{}
-		for _, eachState := range sm.uniqueStates {
-			taskState, taskStateOk := eachState.(*LambdaTaskState)
-			if taskStateOk {
-				lambdaFunctionResourceNames = append(lambdaFunctionResourceNames,
-					taskState.lambdaLogicalResourceName)
-			}
+		// Activities are provisioned in the same stack
+		for _, eachActivity := range stateMachineActivities(sm) {
+			eachActivity.decorateTemplate(template)
 		}
 
 		// Assume policy document
@@ -1545,22 +1936,13 @@ func (sm *StateMachine) StateMachineNamedDecorator(stepFunctionResourceName stri
 			},
 		}
 		var iamRoleResourceName string
-		if len(lambdaFunctionResourceNames) != 0 {
+		// Each state contributes the privileges it requires
+		statements := stateMachinePolicyStatements(sm)
+		statements = append(statements, sm.loggingPolicyStatements()...)
+		if len(statements) != 0 {
 			statesIAMRole := &gocf.IAMRole{
 				AssumeRolePolicyDocument: AssumePolicyDocument,
 			}
-			statements := make([]spartaIAM.PolicyStatement, 0)
-			for _, eachLambdaName := range lambdaFunctionResourceNames {
-				statements = append(statements,
-					spartaIAM.PolicyStatement{
-						Effect: "Allow",
-						Action: []string{
-							"lambda:InvokeFunction",
-						},
-						Resource: gocf.GetAtt(eachLambdaName, "Arn").String(),
-					},
-				)
-			}
 			iamPolicies := gocf.IAMRolePolicyList{}
 			iamPolicies = append(iamPolicies, gocf.IAMRolePolicy{
 				PolicyDocument: sparta.ArbitraryJSONObject{
@@ -1594,17 +1976,28 @@ func (sm *StateMachine) StateMachineNamedDecorator(stepFunctionResourceName stri
 		}
 
 		// Awsome - add an AWS::StepFunction to the template with this info and roll with it...
-		stepFunctionResource := &gocf.StepFunctionsStateMachine{
+		stepFunctionResource := &spartaCF.StepFunctionsStateMachine{
 			StateMachineName: gocf.String(sm.name),
 			DefinitionString: templateExpr,
 		}
+		sm.decorateStateMachineResource(stepFunctionResourceName,
+			stepFunctionResource,
+			template)
 		if iamRoleResourceName != "" {
 			stepFunctionResource.RoleArn = gocf.GetAtt(iamRoleResourceName, "Arn").String()
 		} else if sm.roleArn != nil {
 			stepFunctionResource.RoleArn = sm.roleArn.String()
 		}
 		template.AddResource(stepFunctionResourceName, stepFunctionResource)
-		return nil
+
+		// Triggers that start executions
+		triggersErr := sm.decorateEventTriggers(serviceName,
+			stepFunctionResourceName,
+			template)
+		if triggersErr != nil {
+			return triggersErr
+		}
+		return sm.decorateAPIGatewayTrigger(stepFunctionResourceName, template)
 	}
 }
 
@@ -1636,12 +2029,19 @@ func NewStateMachine(stateMachineName string,
 		if node == nil {
 			return true
 		}
-		_, visited := uniqueStates[node.Name()]
+		existingState, visited := uniqueStates[node.Name()]
+		if visited && existingState != node {
+			duplicateStateNames[node.Name()] = true
+		}
 		return visited
 	}
 
 	for len(pendingStates) != 0 {
 		headState, tailStates := pendingStates[0], pendingStates[1:]
+		if existingState, exists := uniqueStates[headState.Name()]; exists &&
+			existingState != headState {
+			duplicateStateNames[headState.Name()] = true
+		}
 		uniqueStates[headState.Name()] = headState
 
 		switch stateNode := headState.(type) {
@@ -1674,8 +2074,17 @@ func NewStateMachine(stateMachineName string,
 	}
 	// Store duplicate state names
 	if len(duplicateStateNames) != 0 {
-		sm.stateDefinitionError = fmt.Errorf("duplicate state names: %#v", duplicateStateNames)
+		duplicateNames := make([]string, 0)
+		for eachName := range duplicateStateNames {
+			duplicateNames = append(duplicateNames, eachName)
+		}
+		sort.Strings(duplicateNames)
+		sm.stateDefinitionError = fmt.Errorf("duplicate state names: %s",
+			strings.Join(duplicateNames, ", "))
 	}
+	// The execution input type isn't known, so the checks start with
+	// the first LambdaTaskState result
+	sm.dataFlowErrors = sm.checkDataFlow(nil)
 	return sm
 }
 
@@ -2,6 +2,7 @@ package step
 
 import (
 	"context"
+	"encoding/json"
 	"math/rand"
 	"testing"
 	"time"
@@ -119,3 +120,68 @@ func TestDynamoDB(t *testing.T) {
 	}
 	t.Logf("JSON DATA:\n%s", string(stateJSON))
 }
+
+func TestMapState(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda(sparta.LambdaName(helloWorld),
+		helloWorld,
+		sparta.IAMRoleDefinition{})
+	lambdaTaskState := NewLambdaTaskState("lambdaHelloWorld", lambdaFn)
+	lambdaTaskState.WithResultSelector(map[string]interface{}{
+		"hello.$": "$.hello",
+	})
+	mapState := NewMapState("helloAll", *NewStateMachine("helloIterator", lambdaTaskState)).
+		WithItemsPath("$.items").
+		WithMaxConcurrency(5).
+		WithParameters(map[string]interface{}{
+			"item.$": "$$.Map.Item.Value",
+		})
+	successState := NewSuccessState("success")
+	mapState.Next(successState)
+
+	stateMachineName := spartaCF.UserScopedStackName("TestMapStateMachine")
+	startMachine := NewStateMachine(stateMachineName, mapState)
+	testStepProvision(t,
+		[]*sparta.LambdaAWSInfo{lambdaFn},
+		startMachine)
+}
+
+func TestParallelStateCatch(t *testing.T) {
+	recoveredState := NewPassState("recovered", nil)
+	parallelState := NewParallelState("fanOut",
+		*NewStateMachine("branch", NewPassState("branchPass", nil)))
+	parallelState.WithCatchers(NewTaskCatch(recoveredState, StatesAll))
+
+	catchJSON, catchJSONErr := json.Marshal(parallelState.Catchers)
+	if catchJSONErr != nil {
+		t.Fatal(catchJSONErr)
+	}
+	expectedJSON := `[{"ErrorEquals":["States.ALL"],"Next":"recovered"}]`
+	if string(catchJSON) != expectedJSON {
+		t.Fatalf("Expected Catch %s, got: %s", expectedJSON, string(catchJSON))
+	}
+}
+
+func TestExpressStateMachine(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda(sparta.LambdaName(helloWorld),
+		helloWorld,
+		sparta.IAMRoleDefinition{})
+	lambdaTaskState := NewLambdaTaskState("lambdaHelloWorld", lambdaFn)
+	successState := NewSuccessState("success")
+	lambdaTaskState.Next(successState)
+
+	stateMachineName := spartaCF.UserScopedStackName("TestExpressStateMachine")
+	startMachine := NewStateMachine(stateMachineName, lambdaTaskState).
+		WithType(StateMachineTypeExpress).
+		WithLogging(&StateMachineLogging{
+			Level:                LogLevelAll,
+			IncludeExecutionData: true,
+			RetentionInDays:      7,
+		}).
+		WithTracing(true).
+		WithTags(map[string]string{
+			"workload": "events",
+		})
+	testStepProvision(t,
+		[]*sparta.LambdaAWSInfo{lambdaFn},
+		startMachine)
+}
@@ -0,0 +1,355 @@
+package step
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	sparta "github.com/mweagle/Sparta"
+	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+)
+
+// S3Trigger starts an execution when objects are written to a bucket.
+// S3 doesn't notify Step Functions directly, so the trigger is a
+// CloudWatch Events rule that matches the CloudTrail data events for
+// the bucket. The account must have a CloudTrail trail that logs S3
+// data events for the bucket.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/tutorial-cloudwatch-events-s3.html
+type S3Trigger struct {
+	// BucketName is the name of the bucket
+	BucketName string
+	// KeyPrefix optionally limits the trigger to keys with the prefix
+	KeyPrefix string
+	// EventNames are the S3 API calls that start an execution. Defaults to
+	// PutObject, CopyObject and CompleteMultipartUpload.
+	EventNames []string
+}
+
+// eventsRule returns the CloudWatch Events rule that matches the
+// bucket's CloudTrail events
+func (trigger *S3Trigger) eventsRule() (sparta.CloudWatchEventsRule, error) {
+	if trigger.BucketName == "" {
+		return sparta.CloudWatchEventsRule{}, errors.Errorf("S3Trigger doesn't define a BucketName")
+	}
+	eventNames := trigger.EventNames
+	if len(eventNames) == 0 {
+		eventNames = []string{"PutObject", "CopyObject", "CompleteMultipartUpload"}
+	}
+	requestParameters := map[string]interface{}{
+		"bucketName": []string{trigger.BucketName},
+	}
+	if trigger.KeyPrefix != "" {
+		requestParameters["key"] = []interface{}{
+			map[string]string{"prefix": trigger.KeyPrefix},
+		}
+	}
+	return sparta.CloudWatchEventsRule{
+		Description: fmt.Sprintf("Object events for bucket %s", trigger.BucketName),
+		EventPattern: map[string]interface{}{
+			"source":      []string{"aws.s3"},
+			"detail-type": []string{"AWS API Call via CloudTrail"},
+			"detail": map[string]interface{}{
+				"eventSource":       []string{"s3.amazonaws.com"},
+				"eventName":         eventNames,
+				"requestParameters": requestParameters,
+			},
+		},
+	}, nil
+}
+
+// APIGatewayTrigger provisions a REST API whose methods start an
+// execution with the request body as the execution input. The methods
+// use an API Gateway service integration with StartExecution, so no
+// Lambda function is involved. The response is the StartExecution
+// response, which includes the executionArn.
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/tutorial-api-gateway.html
+type APIGatewayTrigger struct {
+	// Name is the REST API name. Defaults to the state machine name.
+	Name string
+	// StageName is the stage the API is deployed to
+	StageName string
+	// Methods maps each resource path (eg: "/orders/start") to the HTTP
+	// methods that start an execution
+	Methods map[string][]string
+}
+
+// triggerRoleResource returns an IAM role that the service principal
+// assumes to call StartExecution on the state machine
+func triggerRoleResource(principal string,
+	stepFunctionResourceName string) *gocf.IAMRole {
+	return &gocf.IAMRole{
+		AssumeRolePolicyDocument: sparta.ArbitraryJSONObject{
+			"Version": "2012-10-17",
+			"Statement": []sparta.ArbitraryJSONObject{
+				{
+					"Effect": "Allow",
+					"Principal": sparta.ArbitraryJSONObject{
+						"Service": []string{principal},
+					},
+					"Action": []string{"sts:AssumeRole"},
+				},
+			},
+		},
+		Policies: &gocf.IAMRolePolicyList{
+			gocf.IAMRolePolicy{
+				PolicyDocument: sparta.ArbitraryJSONObject{
+					"Version": "2012-10-17",
+					"Statement": []interface{}{
+						allowStatement(gocf.Ref(stepFunctionResourceName).String(),
+							"states:StartExecution"),
+					},
+				},
+				PolicyName: gocf.String("StartExecution"),
+			},
+		},
+	}
+}
+
+// WithEventTriggers starts an execution for each CloudWatch Events rule.
+// The RuleTarget Input, InputPath and InputTransformer values are applied
+// to the execution input. Rules with an EventBusName are created on that
+// event bus.
+func (sm *StateMachine) WithEventTriggers(rules map[string]sparta.CloudWatchEventsRule) *StateMachine {
+	sm.eventTriggers = rules
+	return sm
+}
+
+// WithS3Triggers starts an execution for the object events in each bucket
+func (sm *StateMachine) WithS3Triggers(triggers map[string]S3Trigger) *StateMachine {
+	sm.s3Triggers = triggers
+	return sm
+}
+
+// WithAPIGatewayTrigger starts an execution for each request to the
+// API methods
+func (sm *StateMachine) WithAPIGatewayTrigger(trigger *APIGatewayTrigger) *StateMachine {
+	sm.apiGatewayTrigger = trigger
+	return sm
+}
+
+// triggerRules returns the CloudWatch Events rules for the event and S3
+// triggers
+func (sm *StateMachine) triggerRules() (map[string]sparta.CloudWatchEventsRule, error) {
+	rules := make(map[string]sparta.CloudWatchEventsRule)
+	for eachName, eachRule := range sm.eventTriggers {
+		rules[eachName] = eachRule
+	}
+	for eachName, eachTrigger := range sm.s3Triggers {
+		if _, exists := rules[eachName]; exists {
+			return nil, errors.Errorf("state machine %s defines multiple triggers named %s",
+				sm.name,
+				eachName)
+		}
+		rule, ruleErr := eachTrigger.eventsRule()
+		if ruleErr != nil {
+			return nil, errors.Wrapf(ruleErr, "S3 trigger %s", eachName)
+		}
+		rules[eachName] = rule
+	}
+	return rules, nil
+}
+
+// decorateEventTriggers adds the CloudWatch Events rules that target
+// the state machine
+func (sm *StateMachine) decorateEventTriggers(serviceName string,
+	stepFunctionResourceName string,
+	template *gocf.Template) error {
+	rules, rulesErr := sm.triggerRules()
+	if rulesErr != nil {
+		return rulesErr
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	roleResourceName := sparta.CloudFormationResourceName(stepFunctionResourceName,
+		"EventsTriggerRole")
+	template.AddResource(roleResourceName,
+		triggerRoleResource(sparta.CloudWatchEventsPrincipal, stepFunctionResourceName))
+
+	ruleNames := make([]string, 0, len(rules))
+	for eachName := range rules {
+		ruleNames = append(ruleNames, eachName)
+	}
+	sort.Strings(ruleNames)
+	for _, eachRuleName := range ruleNames {
+		eachRuleDefinition := rules[eachRuleName]
+		if nil != eachRuleDefinition.EventPattern && eachRuleDefinition.ScheduleExpression != "" {
+			return errors.Errorf("rule %s CloudWatchEvents specifies both EventPattern and ScheduleExpression", eachRuleName)
+		}
+		if nil == eachRuleDefinition.EventPattern && eachRuleDefinition.ScheduleExpression == "" {
+			return errors.Errorf("rule %s CloudWatchEvents specifies neither EventPattern nor ScheduleExpression", eachRuleName)
+		}
+		uniqueRuleName := sparta.CloudFormationResourceName(eachRuleName, sm.name, serviceName)
+		ruleTarget, ruleTargetErr := eachRuleDefinition.RuleTarget.EventsRuleTarget(uniqueRuleName,
+			gocf.Ref(stepFunctionResourceName).String())
+		if ruleTargetErr != nil {
+			return ruleTargetErr
+		}
+		ruleTarget.RoleArn = gocf.GetAtt(roleResourceName, "Arn")
+		eventsRule := &spartaCF.EventsRule{
+			Name:         gocf.String(uniqueRuleName),
+			Description:  gocf.String(eachRuleDefinition.Description),
+			EventBusName: eachRuleDefinition.EventBusNameExpr(),
+			Targets:      []spartaCF.EventsRuleTarget{ruleTarget},
+		}
+		if nil != eachRuleDefinition.EventPattern {
+			eventsRule.EventPattern = eachRuleDefinition.EventPattern
+		} else {
+			eventsRule.ScheduleExpression = gocf.String(eachRuleDefinition.ScheduleExpression)
+		}
+		ruleResourceName := sparta.CloudFormationResourceName(fmt.Sprintf("%s-StatesEventsRule", eachRuleName),
+			stepFunctionResourceName)
+		template.AddResource(ruleResourceName, eventsRule)
+	}
+	return nil
+}
+
+// decorateAPIGatewayTrigger adds the REST API whose methods start an
+// execution
+func (sm *StateMachine) decorateAPIGatewayTrigger(stepFunctionResourceName string,
+	template *gocf.Template) error {
+	trigger := sm.apiGatewayTrigger
+	if trigger == nil {
+		return nil
+	}
+	if trigger.StageName == "" {
+		return errors.Errorf("state machine %s APIGatewayTrigger doesn't define a StageName", sm.name)
+	}
+	if len(trigger.Methods) == 0 {
+		return errors.Errorf("state machine %s APIGatewayTrigger doesn't define any Methods", sm.name)
+	}
+	apiName := trigger.Name
+	if apiName == "" {
+		apiName = sm.name
+	}
+	apiResourceName := sparta.CloudFormationResourceName(stepFunctionResourceName,
+		"APIGatewayTrigger")
+	restAPIID := gocf.Ref(apiResourceName).String()
+	template.AddResource(apiResourceName, &gocf.APIGatewayRestAPI{
+		Name:        gocf.String(apiName),
+		Description: gocf.String(fmt.Sprintf("Starts %s executions", sm.name)),
+	})
+	roleResourceName := sparta.CloudFormationResourceName(stepFunctionResourceName,
+		"APIGatewayTriggerRole")
+	template.AddResource(roleResourceName,
+		triggerRoleResource(sparta.APIGatewayPrincipal, stepFunctionResourceName))
+
+	// The body is the execution input
+	requestTemplate := gocf.Join("",
+		gocf.String(`{"input": "$util.escapeJavaScript($input.json('$'))", "stateMachineArn": "`),
+		gocf.Ref(stepFunctionResourceName),
+		gocf.String(`"}`))
+	startExecutionURI := gocf.Join("",
+		gocf.String("arn:aws:apigateway:"),
+		gocf.Ref("AWS::Region"),
+		gocf.String(":states:action/StartExecution"))
+
+	paths := make([]string, 0, len(trigger.Methods))
+	for eachPath := range trigger.Methods {
+		paths = append(paths, eachPath)
+	}
+	sort.Strings(paths)
+	methodResourceNames := []string{}
+	methodDefinitions := []*gocf.APIGatewayMethod{}
+	for _, eachPath := range paths {
+		// Create the resource for each path part
+		parentResourceID := gocf.GetAtt(apiResourceName, "RootResourceId")
+		pathAccumulator := []string{}
+		for _, eachPathPart := range strings.Split(strings.Trim(eachPath, "/"), "/") {
+			if eachPathPart == "" {
+				continue
+			}
+			pathAccumulator = append(pathAccumulator, eachPathPart)
+			pathResourceName := sparta.CloudFormationResourceName(apiResourceName,
+				strings.Join(pathAccumulator, "/"))
+			if _, exists := template.Resources[pathResourceName]; !exists {
+				template.AddResource(pathResourceName, &gocf.APIGatewayResource{
+					RestAPIID: restAPIID,
+					ParentID:  parentResourceID,
+					PathPart:  gocf.String(eachPathPart),
+				})
+			}
+			parentResourceID = gocf.Ref(pathResourceName).String()
+		}
+		for _, eachHTTPMethod := range trigger.Methods[eachPath] {
+			httpMethod := strings.ToUpper(eachHTTPMethod)
+			methodResourceName := sparta.CloudFormationResourceName(apiResourceName,
+				httpMethod,
+				eachPath)
+			if _, exists := template.Resources[methodResourceName]; exists {
+				return errors.Errorf("state machine %s APIGatewayTrigger defines %s %s more than once",
+					sm.name,
+					httpMethod,
+					eachPath)
+			}
+			methodDefinition := &gocf.APIGatewayMethod{
+				HTTPMethod:        gocf.String(httpMethod),
+				ResourceID:        parentResourceID,
+				RestAPIID:         restAPIID,
+				AuthorizationType: gocf.String("NONE"),
+				Integration: &gocf.APIGatewayMethodIntegration{
+					Type:                  gocf.String("AWS"),
+					IntegrationHTTPMethod: gocf.String("POST"),
+					URI:                   startExecutionURI,
+					Credentials:           gocf.GetAtt(roleResourceName, "Arn"),
+					PassthroughBehavior:   gocf.String("NEVER"),
+					RequestTemplates: map[string]*gocf.StringExpr{
+						"application/json": requestTemplate,
+					},
+					IntegrationResponses: &gocf.APIGatewayMethodIntegrationResponseList{
+						gocf.APIGatewayMethodIntegrationResponse{
+							StatusCode: gocf.String("200"),
+						},
+						gocf.APIGatewayMethodIntegrationResponse{
+							SelectionPattern: gocf.String(`4\d{2}`),
+							StatusCode:       gocf.String("400"),
+						},
+						gocf.APIGatewayMethodIntegrationResponse{
+							SelectionPattern: gocf.String(`5\d{2}`),
+							StatusCode:       gocf.String("500"),
+						},
+					},
+				},
+				MethodResponses: &gocf.APIGatewayMethodMethodResponseList{
+					gocf.APIGatewayMethodMethodResponse{StatusCode: gocf.String("200")},
+					gocf.APIGatewayMethodMethodResponse{StatusCode: gocf.String("400")},
+					gocf.APIGatewayMethodMethodResponse{StatusCode: gocf.String("500")},
+				},
+			}
+			template.AddResource(methodResourceName, methodDefinition)
+			methodResourceNames = append(methodResourceNames, methodResourceName)
+			methodDefinitions = append(methodDefinitions, methodDefinition)
+		}
+	}
+	// Deployments are immutable, so the logical name changes whenever the
+	// methods do. This ensures CloudFormation publishes the updated API
+	// to the stage.
+	methodDefinitionsJSON, methodDefinitionsJSONErr := json.Marshal(methodDefinitions)
+	if methodDefinitionsJSONErr != nil {
+		return errors.Wrapf(methodDefinitionsJSONErr,
+			"attempting to marshal state machine %s APIGatewayTrigger methods",
+			sm.name)
+	}
+	deploymentResourceName := sparta.CloudFormationResourceName(apiResourceName,
+		"Deployment",
+		string(methodDefinitionsJSON))
+	deployment := template.AddResource(deploymentResourceName, &gocf.APIGatewayDeployment{
+		RestAPIID: restAPIID,
+		StageName: gocf.String(trigger.StageName),
+	})
+	deployment.DependsOn = append(deployment.DependsOn, methodResourceNames...)
+	template.Outputs[fmt.Sprintf("%sURL", apiResourceName)] = &gocf.Output{
+		Description: fmt.Sprintf("%s API Gateway URL", sm.name),
+		Value: gocf.Join("",
+			gocf.String("https://"),
+			restAPIID,
+			gocf.String(".execute-api."),
+			gocf.Ref("AWS::Region"),
+			gocf.String(".amazonaws.com/"),
+			gocf.String(trigger.StageName)),
+	}
+	return nil
+}
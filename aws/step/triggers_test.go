@@ -0,0 +1,215 @@
+package step
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	sparta "github.com/mweagle/Sparta"
+	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/sirupsen/logrus"
+)
+
+func TestStateMachineTriggers(t *testing.T) {
+	startState := NewPassState("start", nil)
+	sm := NewStateMachine("TriggerMachine", startState).
+		WithEventTriggers(map[string]sparta.CloudWatchEventsRule{
+			"Nightly": {
+				ScheduleExpression: "rate(1 day)",
+				RuleTarget: &sparta.CloudWatchEventsRuleTarget{
+					Input: `{"source": "schedule"}`,
+				},
+			},
+		}).
+		WithS3Triggers(map[string]S3Trigger{
+			"Uploads": {
+				BucketName: "my-upload-bucket",
+				KeyPrefix:  "incoming/",
+			},
+		}).
+		WithAPIGatewayTrigger(&APIGatewayTrigger{
+			StageName: "v1",
+			Methods: map[string][]string{
+				"/orders/start": {"post"},
+			},
+		})
+
+	template := gocf.NewTemplate()
+	decorator := sm.StateMachineNamedDecorator("TriggerMachine")
+	decoratorErr := decorator(map[string]interface{}{},
+		"TriggerService",
+		template,
+		"S3Bucket",
+		"S3Key",
+		"BuildID",
+		nil,
+		false,
+		logrus.New())
+	if decoratorErr != nil {
+		t.Fatal(decoratorErr)
+	}
+	resourceCounts := make(map[string]int)
+	for _, eachResource := range template.Resources {
+		switch eachResource.Properties.(type) {
+		case *spartaCF.EventsRule:
+			resourceCounts["EventsRule"]++
+		case *gocf.IAMRole:
+			resourceCounts["IAMRole"]++
+		case *gocf.APIGatewayMethod:
+			resourceCounts["APIGatewayMethod"]++
+		case *gocf.APIGatewayResource:
+			resourceCounts["APIGatewayResource"]++
+		case *gocf.APIGatewayDeployment:
+			resourceCounts["APIGatewayDeployment"]++
+		}
+	}
+	expectedCounts := map[string]int{
+		"EventsRule":           2,
+		"IAMRole":              2,
+		"APIGatewayMethod":     1,
+		"APIGatewayResource":   2,
+		"APIGatewayDeployment": 1,
+	}
+	for eachType, eachCount := range expectedCounts {
+		if resourceCounts[eachType] != eachCount {
+			t.Fatalf("Expected %d %s resources, got %d", eachCount, eachType, resourceCounts[eachType])
+		}
+	}
+	templateJSON, templateJSONErr := json.Marshal(template)
+	if templateJSONErr != nil {
+		t.Fatal(templateJSONErr)
+	}
+	expectedFragments := []string{
+		`"states:StartExecution"`,
+		`"events.amazonaws.com"`,
+		`"apigateway.amazonaws.com"`,
+		`:states:action/StartExecution`,
+		`"my-upload-bucket"`,
+		`"incoming/"`,
+		`rate(1 day)`,
+	}
+	for _, eachFragment := range expectedFragments {
+		if !strings.Contains(string(templateJSON), eachFragment) {
+			t.Fatalf("Expected template to include %s, got: %s", eachFragment, string(templateJSON))
+		}
+	}
+}
+
+func TestStateMachineTriggersInvalidRule(t *testing.T) {
+	sm := NewStateMachine("InvalidTriggerMachine", NewPassState("start", nil)).
+		WithEventTriggers(map[string]sparta.CloudWatchEventsRule{
+			"Empty": {
+				Description: "No pattern or schedule",
+			},
+		})
+	decoratorErr := sm.decorateEventTriggers("TriggerService",
+		"InvalidTriggerMachine",
+		gocf.NewTemplate())
+	if decoratorErr == nil {
+		t.Fatalf("Failed to reject rule without an EventPattern or ScheduleExpression")
+	}
+	t.Logf("Rejected invalid rule: %s", decoratorErr)
+}
+
+func TestStateMachineTriggersEventBus(t *testing.T) {
+	sm := NewStateMachine("BusTriggerMachine", NewPassState("start", nil)).
+		WithEventTriggers(map[string]sparta.CloudWatchEventsRule{
+			"Orders": {
+				EventBusName: gocf.Ref("OrdersBus"),
+				EventPattern: map[string]interface{}{
+					"detail-type": []string{"OrderPlaced"},
+				},
+				RuleTarget: &sparta.CloudWatchEventsRuleTarget{
+					InputTransformer: &sparta.CloudWatchEventsInputTransformer{
+						InputPathsMap: map[string]string{
+							"orderId": "$.detail.orderId",
+						},
+						InputTemplate: `{"orderId": <orderId>}`,
+					},
+				},
+			},
+		})
+	template := gocf.NewTemplate()
+	decoratorErr := sm.decorateEventTriggers("TriggerService",
+		"BusTriggerMachine",
+		template)
+	if decoratorErr != nil {
+		t.Fatal(decoratorErr)
+	}
+	var eventsRules []*spartaCF.EventsRule
+	for _, eachResource := range template.Resources {
+		if eventsRule, isEventsRule := eachResource.Properties.(*spartaCF.EventsRule); isEventsRule {
+			eventsRules = append(eventsRules, eventsRule)
+		}
+	}
+	if len(eventsRules) != 1 {
+		t.Fatalf("Expected one EventsRule, got: %#v", template.Resources)
+	}
+	eventsRule := eventsRules[0]
+	if !reflect.DeepEqual(eventsRule.EventBusName, gocf.Ref("OrdersBus").String()) {
+		t.Fatalf("Unexpected EventBusName: %#v", eventsRule.EventBusName)
+	}
+	if len(eventsRule.Targets) != 1 {
+		t.Fatalf("Unexpected rule targets: %#v", eventsRule.Targets)
+	}
+	ruleTarget := eventsRule.Targets[0]
+	if ruleTarget.InputTransformer == nil ||
+		ruleTarget.InputTransformer.InputPathsMap["orderId"] != "$.detail.orderId" ||
+		ruleTarget.InputTransformer.InputTemplate.Literal != `{"orderId": <orderId>}` {
+		t.Fatalf("Unexpected rule target InputTransformer: %#v", ruleTarget.InputTransformer)
+	}
+	if !reflect.DeepEqual(ruleTarget.Arn, gocf.Ref("BusTriggerMachine").String()) ||
+		ruleTarget.RoleArn == nil {
+		t.Fatalf("Unexpected rule target: %#v", ruleTarget)
+	}
+
+	// RuleTargets accept only one of the input settings
+	invalidSM := NewStateMachine("InvalidBusTriggerMachine", NewPassState("start", nil)).
+		WithEventTriggers(map[string]sparta.CloudWatchEventsRule{
+			"Orders": {
+				EventBusName:       "orders",
+				ScheduleExpression: "rate(1 day)",
+				RuleTarget: &sparta.CloudWatchEventsRuleTarget{
+					Input:     `{"source": "schedule"}`,
+					InputPath: "$.detail",
+				},
+			},
+		})
+	decoratorErr = invalidSM.decorateEventTriggers("TriggerService",
+		"InvalidBusTriggerMachine",
+		gocf.NewTemplate())
+	if decoratorErr == nil {
+		t.Fatalf("Failed to reject RuleTarget with both Input and InputPath")
+	}
+}
+
+func TestAPIGatewayTriggerDeploymentName(t *testing.T) {
+	deploymentName := func(methods map[string][]string) string {
+		sm := NewStateMachine("TriggerMachine", NewPassState("start", nil)).
+			WithAPIGatewayTrigger(&APIGatewayTrigger{
+				StageName: "v1",
+				Methods:   methods,
+			})
+		template := gocf.NewTemplate()
+		decorateErr := sm.decorateAPIGatewayTrigger("TriggerMachine", template)
+		if decorateErr != nil {
+			t.Fatal(decorateErr)
+		}
+		for eachName, eachResource := range template.Resources {
+			if _, isDeployment := eachResource.Properties.(*gocf.APIGatewayDeployment); isDeployment {
+				return eachName
+			}
+		}
+		t.Fatal("Failed to find APIGatewayDeployment resource")
+		return ""
+	}
+	initialName := deploymentName(map[string][]string{"/orders/start": {"post"}})
+	if initialName != deploymentName(map[string][]string{"/orders/start": {"post"}}) {
+		t.Fatal("Expected unchanged methods to produce the same deployment name")
+	}
+	if initialName == deploymentName(map[string][]string{"/orders/start": {"post", "put"}}) {
+		t.Fatal("Expected changed methods to produce a new deployment name")
+	}
+}
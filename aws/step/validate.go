@@ -0,0 +1,628 @@
+package step
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	sparta "github.com/mweagle/Sparta"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxStateNameLength is the maximum length of a state name
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/limits.html
+const maxStateNameLength = 80
+
+// stateValidationError returns an error that identifies the state
+func stateValidationError(state MachineState, format string, args ...interface{}) error {
+	return errors.Errorf("state `%s`: %s", state.Name(), fmt.Sprintf(format, args...))
+}
+
+// transitionTargets returns all the states the state may transition to
+func transitionTargets(state MachineState) []MachineState {
+	targets := []MachineState{}
+	switch typedState := state.(type) {
+	case *ChoiceState:
+		for _, eachChoice := range typedState.Choices {
+			if eachChoice != nil && eachChoice.nextState() != nil {
+				targets = append(targets, eachChoice.nextState())
+			}
+		}
+		if typedState.Default != nil {
+			targets = append(targets, typedState.Default)
+		}
+	case TransitionState:
+		for _, eachState := range typedState.AdjacentStates() {
+			if eachState != nil {
+				targets = append(targets, eachState)
+			}
+		}
+	}
+	return targets
+}
+
+// isTerminalState returns true if the execution may end in this state
+func isTerminalState(state MachineState) bool {
+	switch state.(type) {
+	case *SuccessState, *FailState:
+		return true
+	case *ChoiceState:
+		return false
+	}
+	if innerStater, ok := state.(interface {
+		innerState() *baseInnerState
+	}); ok {
+		return innerStater.innerState().next == nil
+	}
+	return false
+}
+
+// validateSeconds verifies that the duration is a non-negative whole
+// number of seconds
+func validateSeconds(state MachineState, fieldName string, duration time.Duration) error {
+	if duration < 0 {
+		return stateValidationError(state, "%s must not be negative", fieldName)
+	}
+	if duration%time.Second != 0 {
+		return stateValidationError(state, "%s must be a whole number of seconds, got: %s",
+			fieldName,
+			duration)
+	}
+	return nil
+}
+
+// validatePath verifies that the optional path is valid
+func validatePath(state MachineState, fieldName string, path string) error {
+	if path == "" {
+		return nil
+	}
+	pathErr := validateJSONPath(path)
+	if pathErr != nil {
+		return stateValidationError(state, "invalid %s: %s", fieldName, pathErr)
+	}
+	return nil
+}
+
+// validateParameters verifies the JSONPath selectors in a Parameters or
+// ResultSelector template
+func validateParameters(state MachineState, fieldName string, params interface{}) []error {
+	validationErrors := []error{}
+	switch typedParams := params.(type) {
+	case map[string]interface{}:
+		for eachKey, eachValue := range typedParams {
+			if !strings.HasSuffix(eachKey, ".$") {
+				validationErrors = append(validationErrors,
+					validateParameters(state, fieldName, eachValue)...)
+				continue
+			}
+			path, pathOk := eachValue.(string)
+			if !pathOk {
+				validationErrors = append(validationErrors,
+					stateValidationError(state, "%s key `%s` must be a JSONPath string", fieldName, eachKey))
+				continue
+			}
+			// Context object paths
+			path = strings.TrimPrefix(path, "$")
+			if !strings.HasPrefix(path, "$") {
+				path = "$" + path
+			}
+			pathErr := validatePath(state, fmt.Sprintf("%s `%s`", fieldName, eachKey), path)
+			if pathErr != nil {
+				validationErrors = append(validationErrors, pathErr)
+			}
+		}
+	case []interface{}:
+		for _, eachValue := range typedParams {
+			validationErrors = append(validationErrors,
+				validateParameters(state, fieldName, eachValue)...)
+		}
+	}
+	return validationErrors
+}
+
+// validateErrorEquals verifies the ErrorEquals values for the
+// index'th of count retriers or catchers
+func validateErrorEquals(state MachineState,
+	fieldName string,
+	errorEquals []StateError,
+	index int,
+	count int) []error {
+	validationErrors := []error{}
+	if len(errorEquals) == 0 {
+		validationErrors = append(validationErrors,
+			stateValidationError(state, "%s[%d] must define at least one ErrorEquals value", fieldName, index))
+	}
+	for _, eachError := range errorEquals {
+		if eachError != StatesAll {
+			continue
+		}
+		if len(errorEquals) != 1 {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "%s[%d] `%s` must appear alone in ErrorEquals",
+					fieldName,
+					index,
+					StatesAll))
+		}
+		if index != count-1 {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "%s[%d] `%s` must appear in the last %s",
+					fieldName,
+					index,
+					StatesAll,
+					fieldName))
+		}
+	}
+	return validationErrors
+}
+
+// validateRetriers verifies the Retry policies
+func validateRetriers(state MachineState, retriers []*TaskRetry) []error {
+	validationErrors := []error{}
+	for index, eachRetrier := range retriers {
+		if eachRetrier == nil {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "Retry[%d] must not be nil", index))
+			continue
+		}
+		validationErrors = append(validationErrors,
+			validateErrorEquals(state, "Retry", eachRetrier.ErrorEquals, index, len(retriers))...)
+		if eachRetrier.MaxAttempts < 0 {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "Retry[%d] MaxAttempts must not be negative", index))
+		}
+		if eachRetrier.BackoffRate != 0 && eachRetrier.BackoffRate < 1.0 {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "Retry[%d] BackoffRate must be greater than or equal to 1.0", index))
+		}
+		intervalErr := validateSeconds(state,
+			fmt.Sprintf("Retry[%d] IntervalSeconds", index),
+			eachRetrier.IntervalSeconds)
+		if intervalErr != nil {
+			validationErrors = append(validationErrors, intervalErr)
+		}
+	}
+	return validationErrors
+}
+
+// validateCatchers verifies the Catch handlers
+func validateCatchers(state MachineState, catchers []*TaskCatch) []error {
+	validationErrors := []error{}
+	for index, eachCatcher := range catchers {
+		if eachCatcher == nil {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "Catch[%d] must not be nil", index))
+			continue
+		}
+		validationErrors = append(validationErrors,
+			validateErrorEquals(state, "Catch", eachCatcher.errorEquals, index, len(catchers))...)
+		if eachCatcher.next == nil {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "Catch[%d] must define a Next state", index))
+		}
+	}
+	return validationErrors
+}
+
+// validateComparison verifies that the Choice rule is well-formed
+func validateComparison(state MachineState, comparison Comparison) []error {
+	validationErrors := []error{}
+	validateVariable := func(variable string) {
+		if variable == "" {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "%T comparison must define a Variable", comparison))
+			return
+		}
+		pathErr := validatePath(state, fmt.Sprintf("%T Variable", comparison), variable)
+		if pathErr != nil {
+			validationErrors = append(validationErrors, pathErr)
+		}
+	}
+	validateNested := func(operator string, comparisons []Comparison) {
+		if len(comparisons) == 0 {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "%s operator must include at least one comparison", operator))
+		}
+		for _, eachComparison := range comparisons {
+			validationErrors = append(validationErrors, validateComparison(state, eachComparison)...)
+		}
+	}
+
+	switch cmp := comparison.(type) {
+	case nil:
+		validationErrors = append(validationErrors,
+			stateValidationError(state, "Choice comparison must not be nil"))
+	case *And:
+		validateNested("And", cmp.Comparison)
+	case *Or:
+		validateNested("Or", cmp.Comparison)
+	case *Not:
+		if cmp.Comparison == nil {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "Not operator must include a comparison"))
+		} else {
+			validationErrors = append(validationErrors, validateComparison(state, cmp.Comparison)...)
+		}
+	case *StringEquals:
+		validateVariable(cmp.Variable)
+	case *StringLessThan:
+		validateVariable(cmp.Variable)
+	case *StringGreaterThan:
+		validateVariable(cmp.Variable)
+	case *StringLessThanEquals:
+		validateVariable(cmp.Variable)
+	case *StringGreaterThanEquals:
+		validateVariable(cmp.Variable)
+	case *NumericEquals:
+		validateVariable(cmp.Variable)
+	case *NumericLessThan:
+		validateVariable(cmp.Variable)
+	case *NumericGreaterThan:
+		validateVariable(cmp.Variable)
+	case *NumericLessThanEquals:
+		validateVariable(cmp.Variable)
+	case *NumericGreaterThanEquals:
+		validateVariable(cmp.Variable)
+	case *BooleanEquals:
+		validateVariable(cmp.Variable)
+		if _, isBool := cmp.Value.(bool); !isBool {
+			validationErrors = append(validationErrors,
+				stateValidationError(state, "BooleanEquals Value must be a bool, got: %T", cmp.Value))
+		}
+	case *TimestampEquals:
+		validateVariable(cmp.Variable)
+	case *TimestampLessThan:
+		validateVariable(cmp.Variable)
+	case *TimestampGreaterThan:
+		validateVariable(cmp.Variable)
+	case *TimestampLessThanEquals:
+		validateVariable(cmp.Variable)
+	case *TimestampGreaterThanEquals:
+		validateVariable(cmp.Variable)
+	}
+	return validationErrors
+}
+
+// validateState performs the state type specific checks
+func validateState(state MachineState) []error {
+	validationErrors := []error{}
+	appendErr := func(err error) {
+		if err != nil {
+			validationErrors = append(validationErrors, err)
+		}
+	}
+	if state.Name() == "" {
+		return []error{errors.Errorf("%T must have a non-empty name", state)}
+	}
+	if len(state.Name()) > maxStateNameLength {
+		appendErr(stateValidationError(state, "name must be at most %d characters", maxStateNameLength))
+	}
+	if innerStater, ok := state.(interface {
+		innerState() *baseInnerState
+	}); ok {
+		bis := innerStater.innerState()
+		appendErr(validatePath(state, "InputPath", bis.inputPath))
+		appendErr(validatePath(state, "OutputPath", bis.outputPath))
+	}
+
+	switch typedState := state.(type) {
+	case *PassState:
+		appendErr(validatePath(state, "ResultPath", typedState.ResultPath))
+		validationErrors = append(validationErrors,
+			validateParameters(state, "Parameters", typedState.Parameters)...)
+	case *ChoiceState:
+		if len(typedState.Choices) == 0 {
+			appendErr(stateValidationError(state, "Choice state must define at least one choice"))
+		}
+		for index, eachChoice := range typedState.Choices {
+			if eachChoice == nil {
+				appendErr(stateValidationError(state, "Choices[%d] must not be nil", index))
+				continue
+			}
+			if eachChoice.nextState() == nil {
+				appendErr(stateValidationError(state, "Choices[%d] must define a Next state", index))
+			}
+			comparison, comparisonOk := eachChoice.(Comparison)
+			if !comparisonOk {
+				appendErr(stateValidationError(state, "Choices[%d] %T is not a comparison", index, eachChoice))
+				continue
+			}
+			validationErrors = append(validationErrors, validateComparison(state, comparison)...)
+		}
+	case *WaitDelay:
+		appendErr(validateSeconds(state, "Seconds", typedState.delay))
+	case *WaitUntil:
+		if typedState.Timestamp.IsZero() {
+			appendErr(stateValidationError(state, "Timestamp must be defined"))
+		}
+	case *WaitDynamicUntil:
+		if typedState.TimestampPath == "" {
+			appendErr(stateValidationError(state, "TimestampPath must be defined"))
+		}
+		appendErr(validatePath(state, "TimestampPath", typedState.TimestampPath))
+	case *ParallelState:
+		appendErr(validatePath(state, "ResultPath", typedState.ResultPath))
+		validationErrors = append(validationErrors, validateRetriers(state, typedState.Retriers)...)
+		validationErrors = append(validationErrors, validateCatchers(state, typedState.Catchers)...)
+		// The branch is a self contained state machine
+		for _, eachBranchErr := range typedState.States.validate() {
+			appendErr(stateValidationError(state, "branch %s", eachBranchErr))
+		}
+	case *MapState:
+		appendErr(validatePath(state, "ItemsPath", typedState.ItemsPath))
+		appendErr(validatePath(state, "ResultPath", typedState.ResultPath))
+		if typedState.MaxConcurrency < 0 {
+			appendErr(stateValidationError(state, "MaxConcurrency must not be negative"))
+		}
+		validationErrors = append(validationErrors,
+			validateParameters(state, "Parameters", typedState.Parameters)...)
+		validationErrors = append(validationErrors,
+			validateParameters(state, "ResultSelector", typedState.ResultSelector)...)
+		validationErrors = append(validationErrors, validateRetriers(state, typedState.Retriers)...)
+		validationErrors = append(validationErrors, validateCatchers(state, typedState.Catchers)...)
+		// The Iterator is a self contained state machine
+		for _, eachIteratorErr := range typedState.Iterator.validate() {
+			appendErr(stateValidationError(state, "iterator %s", eachIteratorErr))
+		}
+	case *ActivityTaskState:
+		if typedState.activity == nil || typedState.activity.Name == "" {
+			appendErr(stateValidationError(state, "ActivityTaskState must define a named Activity"))
+		}
+	case *LambdaTaskState:
+		if typedState.lambdaFn == nil {
+			appendErr(stateValidationError(state, "LambdaTaskState must define a Lambda function"))
+		}
+	}
+
+	// Common task checks
+	if taskState, isTask := state.(interface {
+		baseTask() *BaseTask
+	}); isTask {
+		bt := taskState.baseTask()
+		appendErr(validatePath(state, "ResultPath", bt.ResultPath))
+		validationErrors = append(validationErrors,
+			validateParameters(state, "Parameters", bt.Parameters)...)
+		validationErrors = append(validationErrors,
+			validateParameters(state, "ResultSelector", bt.ResultSelector)...)
+		appendErr(validateSeconds(state, "TimeoutSeconds", bt.TimeoutSeconds))
+		appendErr(validateSeconds(state, "HeartbeatSeconds", bt.HeartbeatSeconds))
+		if bt.HeartbeatSeconds > 0 &&
+			bt.TimeoutSeconds > 0 &&
+			bt.HeartbeatSeconds >= bt.TimeoutSeconds {
+			appendErr(stateValidationError(state,
+				"HeartbeatSeconds (%s) must be smaller than TimeoutSeconds (%s)",
+				bt.HeartbeatSeconds,
+				bt.TimeoutSeconds))
+		}
+		validationErrors = append(validationErrors, validateRetriers(state, bt.Retriers)...)
+		validationErrors = append(validationErrors, validateCatchers(state, bt.Catchers)...)
+	}
+	return validationErrors
+}
+
+// validateExpressIntegrations verifies that the states only use the
+// service integration patterns supported by Express workflows
+// Ref: https://docs.aws.amazon.com/step-functions/latest/dg/concepts-standard-vs-express.html
+func validateExpressIntegrations(sm *StateMachine) []error {
+	validationErrors := []error{}
+	for _, eachState := range sm.uniqueStates {
+		switch typedState := eachState.(type) {
+		case *ParallelState:
+			validationErrors = append(validationErrors,
+				validateExpressIntegrations(&typedState.States)...)
+			continue
+		case *MapState:
+			validationErrors = append(validationErrors,
+				validateExpressIntegrations(&typedState.Iterator)...)
+			continue
+		case *LambdaTaskState:
+			if typedState.waitForTaskToken {
+				validationErrors = append(validationErrors,
+					stateValidationError(eachState,
+						"resource `arn:aws:states:::lambda:invoke%s` is not supported by %s workflows",
+						waitForTaskTokenSuffix,
+						StateMachineTypeExpress))
+			}
+			continue
+		case *ActivityTaskState:
+			validationErrors = append(validationErrors,
+				stateValidationError(eachState,
+					"activities are not supported by %s workflows",
+					StateMachineTypeExpress))
+			continue
+		}
+		if _, isTask := eachState.(interface {
+			baseTask() *BaseTask
+		}); !isTask {
+			continue
+		}
+		stateJSON, stateJSONErr := json.Marshal(eachState)
+		if stateJSONErr != nil {
+			continue
+		}
+		var taskDefinition struct {
+			Resource interface{}
+		}
+		if json.Unmarshal(stateJSON, &taskDefinition) != nil {
+			continue
+		}
+		resource, _ := taskDefinition.Resource.(string)
+		if strings.HasSuffix(resource, ".sync") ||
+			strings.HasSuffix(resource, ".waitForTaskToken") {
+			validationErrors = append(validationErrors,
+				stateValidationError(eachState,
+					"resource `%s` is not supported by %s workflows",
+					resource,
+					StateMachineTypeExpress))
+		}
+	}
+	return validationErrors
+}
+
+// validate performs the Amazon States Language checks against the state
+// machine prior to marshaling
+// Ref: https://states-language.net/spec.html
+func (sm *StateMachine) validate() []error {
+	validationErrors := make([]error, 0)
+	if sm.stateDefinitionError != nil {
+		validationErrors = append(validationErrors, sm.stateDefinitionError)
+	}
+	if sm.startAt == nil {
+		return append(validationErrors, errors.Errorf("state machine must define a StartAt state"))
+	}
+
+	// Stable ordering
+	stateNames := make([]string, 0, len(sm.uniqueStates))
+	for eachName := range sm.uniqueStates {
+		stateNames = append(stateNames, eachName)
+	}
+	sort.Strings(stateNames)
+
+	// Per state checks, including that every transition targets a
+	// state in this machine. In particular, states in a ParallelState
+	// branch can only transition to each other.
+	inverseTransitions := make(map[string][]string)
+	for _, eachName := range stateNames {
+		eachState := sm.uniqueStates[eachName]
+		validationErrors = append(validationErrors, validateState(eachState)...)
+		for _, eachTarget := range transitionTargets(eachState) {
+			existingState, exists := sm.uniqueStates[eachTarget.Name()]
+			if !exists {
+				validationErrors = append(validationErrors,
+					stateValidationError(eachState, "transition target `%s` is not defined", eachTarget.Name()))
+				continue
+			}
+			if existingState != eachTarget {
+				validationErrors = append(validationErrors,
+					stateValidationError(eachState,
+						"transition target `%s` refers to a different state with the same name",
+						eachTarget.Name()))
+				continue
+			}
+			inverseTransitions[eachTarget.Name()] = append(inverseTransitions[eachTarget.Name()],
+				eachName)
+		}
+	}
+
+	// Branch and Iterator states are private to the ParallelState
+	// or MapState
+	for _, eachName := range stateNames {
+		var nestedStates map[string]MachineState
+		switch typedState := sm.uniqueStates[eachName].(type) {
+		case *ParallelState:
+			nestedStates = typedState.States.uniqueStates
+		case *MapState:
+			nestedStates = typedState.Iterator.uniqueStates
+		}
+		for eachNestedName := range nestedStates {
+			if _, exists := sm.uniqueStates[eachNestedName]; exists {
+				validationErrors = append(validationErrors,
+					stateValidationError(sm.uniqueStates[eachName],
+						"nested state `%s` must not be referenced outside the %T",
+						eachNestedName,
+						sm.uniqueStates[eachName]))
+			}
+		}
+	}
+
+	if sm.machineType == StateMachineTypeExpress {
+		validationErrors = append(validationErrors, validateExpressIntegrations(sm)...)
+	}
+
+	// Orphans - every state must be reachable from StartAt
+	reachable := map[string]bool{sm.startAt.Name(): true}
+	pending := []MachineState{sm.startAt}
+	for len(pending) != 0 {
+		head := pending[0]
+		pending = pending[1:]
+		for _, eachTarget := range transitionTargets(head) {
+			if !reachable[eachTarget.Name()] {
+				reachable[eachTarget.Name()] = true
+				pending = append(pending, eachTarget)
+			}
+		}
+	}
+	for _, eachName := range stateNames {
+		if !reachable[eachName] {
+			validationErrors = append(validationErrors,
+				stateValidationError(sm.uniqueStates[eachName], "state is not reachable from StartAt"))
+		}
+	}
+
+	// Every state must have a path to a terminal state
+	canTerminate := make(map[string]bool)
+	pendingNames := []string{}
+	for _, eachName := range stateNames {
+		if isTerminalState(sm.uniqueStates[eachName]) {
+			canTerminate[eachName] = true
+			pendingNames = append(pendingNames, eachName)
+		}
+	}
+	for len(pendingNames) != 0 {
+		head := pendingNames[0]
+		pendingNames = pendingNames[1:]
+		for _, eachSource := range inverseTransitions[head] {
+			if !canTerminate[eachSource] {
+				canTerminate[eachSource] = true
+				pendingNames = append(pendingNames, eachSource)
+			}
+		}
+	}
+	if len(canTerminate) == 0 {
+		validationErrors = append(validationErrors,
+			errors.Errorf("state machine doesn't define a terminal state"))
+	}
+	for _, eachName := range stateNames {
+		if !canTerminate[eachName] {
+			validationErrors = append(validationErrors,
+				stateValidationError(sm.uniqueStates[eachName], "state has no path to a terminal state"))
+		}
+	}
+	return validationErrors
+}
+
+// validationError returns a single error that includes all validation
+// errors, or nil if the machine is valid
+func (sm *StateMachine) validationError() error {
+	machineErrors := sm.validate()
+	// Nested machines are checked as part of the enclosing data flow
+	machineErrors = append(machineErrors, sm.dataFlowErrors...)
+	if len(machineErrors) == 0 {
+		return nil
+	}
+	errorText := make([]string, len(machineErrors))
+	for index := range machineErrors {
+		errorText[index] = machineErrors[index].Error()
+	}
+	return errors.Errorf("Invalid state machine %s. Errors: %s",
+		sm.name,
+		strings.Join(errorText, ", "))
+}
+
+// StateMachineValidator returns a ServiceValidationHookHandler that
+// performs the static state machine validation. Include it in the
+// WorkflowHooks.Validators slice to reject invalid state machines
+// before any resources are provisioned.
+func (sm *StateMachine) StateMachineValidator() sparta.ServiceValidationHookHandler {
+	return sparta.ServiceValidationHookFunc(func(context map[string]interface{},
+		serviceName string,
+		template *gocf.Template,
+		S3Bucket string,
+		S3Key string,
+		buildID string,
+		awsSession *session.Session,
+		noop bool,
+		logger *logrus.Logger) error {
+		validationErr := sm.validationError()
+		if validationErr != nil {
+			logger.WithFields(logrus.Fields{
+				"StateMachine": sm.name,
+			}).Error("State machine validation failed")
+		}
+		return validationErr
+	})
+}
@@ -0,0 +1,122 @@
+package step
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	sparta "github.com/mweagle/Sparta"
+)
+
+func testValidationErrors(t *testing.T,
+	stateMachine *StateMachine,
+	expectedErrorFragments ...string) {
+	validationErr := stateMachine.validationError()
+	if len(expectedErrorFragments) == 0 {
+		if validationErr != nil {
+			t.Fatalf("Unexpected validation error: %s", validationErr)
+		}
+		return
+	}
+	if validationErr == nil {
+		t.Fatalf("Failed to reject invalid state machine")
+	}
+	for _, eachFragment := range expectedErrorFragments {
+		if !strings.Contains(validationErr.Error(), eachFragment) {
+			t.Fatalf("Expected validation error to include `%s`, got: %s",
+				eachFragment,
+				validationErr)
+		}
+	}
+	t.Logf("Validation error: %s", validationErr)
+}
+
+func TestValidateStateMachine(t *testing.T) {
+	lambdaFn, _ := sparta.NewAWSLambda("ValidateRollDie",
+		lambdaRollDie,
+		sparta.IAMRoleDefinition{})
+	lambdaTaskState := NewLambdaTaskState("lambdaRollDie", lambdaFn)
+	lambdaTaskState.WithTimeout(30 * time.Second).
+		WithHeartbeat(10 * time.Second)
+	successState := NewSuccessState("success")
+	delayState := NewWaitDelayState("tryAgainShortly", 3*time.Second)
+	choiceState := NewChoiceState("checkRoll",
+		&Not{
+			Comparison: &NumericGreaterThan{
+				Variable: "$.roll",
+				Value:    3,
+			},
+			Next: delayState,
+		}).WithDefault(successState)
+	lambdaTaskState.Next(choiceState)
+	delayState.Next(lambdaTaskState)
+
+	testValidationErrors(t, NewStateMachine("ValidMachine", lambdaTaskState))
+}
+
+func TestValidateCatchAllOrdering(t *testing.T) {
+	recoveredState := NewPassState("recovered", nil)
+	throttledState := NewPassState("throttled", nil)
+	snsState := NewSNSTaskState("notify", SNSTaskParameters{Message: "Hello"})
+	snsState.WithCatchers(NewTaskCatch(recoveredState, StatesAll),
+		NewTaskCatch(throttledState, StateError("SNS.Throttled")))
+
+	testValidationErrors(t,
+		NewStateMachine("CatchAllMachine", snsState),
+		"state `notify`: Catch[0] `States.ALL` must appear in the last Catch")
+}
+
+func TestValidateJSONPath(t *testing.T) {
+	passState := NewPassState("filter", nil).WithResultPath("$.items[*]")
+	testValidationErrors(t,
+		NewStateMachine("JSONPathMachine", passState),
+		"state `filter`: invalid ResultPath")
+}
+
+func TestValidateDuplicateNames(t *testing.T) {
+	firstState := NewPassState("duplicate", nil)
+	secondState := NewPassState("duplicate", nil)
+	firstState.Next(secondState)
+	testValidationErrors(t,
+		NewStateMachine("DuplicateMachine", firstState),
+		"duplicate state names: duplicate")
+}
+
+func TestValidateHeartbeatTimeout(t *testing.T) {
+	snsState := NewSNSTaskState("notify", SNSTaskParameters{Message: "Hello"})
+	snsState.WithTimeout(10 * time.Second).
+		WithHeartbeat(10 * time.Second)
+	testValidationErrors(t,
+		NewStateMachine("HeartbeatMachine", snsState),
+		"state `notify`: HeartbeatSeconds (10s) must be smaller than TimeoutSeconds (10s)")
+}
+
+func TestValidateNoTerminalState(t *testing.T) {
+	firstState := NewWaitDelayState("first", time.Second)
+	secondState := NewWaitDelayState("second", time.Second)
+	firstState.Next(secondState)
+	secondState.Next(firstState)
+	testValidationErrors(t,
+		NewStateMachine("LoopMachine", firstState),
+		"state `first`: state has no path to a terminal state")
+}
+
+func TestValidateExpressIntegrations(t *testing.T) {
+	batchState := NewBatchTaskState("submit", BatchTaskParameters{
+		JobName: "MyJob",
+	})
+	testValidationErrors(t,
+		NewStateMachine("ExpressMachine", batchState).WithType(StateMachineTypeExpress),
+		"state `submit`: resource `arn:aws:states:::batch:submitJob.sync` is not supported by EXPRESS workflows")
+
+	lambdaFn, _ := sparta.NewAWSLambda("ExpressCallback",
+		lambdaRollDie,
+		sparta.IAMRoleDefinition{})
+	callbackState := NewLambdaTaskState("approve", lambdaFn).WithWaitForTaskToken()
+	testValidationErrors(t,
+		NewStateMachine("ExpressCallbackMachine", callbackState).WithType(StateMachineTypeExpress),
+		"state `approve`: resource `arn:aws:states:::lambda:invoke.waitForTaskToken` is not supported by EXPRESS workflows")
+	testValidationErrors(t,
+		NewStateMachine("ExpressLambdaMachine", NewLambdaTaskState("invoke", lambdaFn)).
+			WithType(StateMachineTypeExpress))
+}
@@ -15,7 +15,6 @@ import (
 	"text/template"
 	"time"
 
-	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -330,7 +329,7 @@ func Describe(serviceName string,
 			}
 		}
 		for index, eachEventSourceMapping := range eachLambda.EventSourceMappings {
-			dynamicArn := spartaCF.DynamicValueToStringExpr(eachEventSourceMapping.EventSourceArn)
+			dynamicArn := eachEventSourceMapping.eventSourceExpr()
 			jsonBytes, jsonBytesErr := json.Marshal(dynamicArn)
 			if jsonBytesErr != nil {
 				jsonBytes = []byte(fmt.Sprintf("%s-EventSourceMapping[%d]",
@@ -396,6 +395,61 @@ func Describe(serviceName string,
 			}
 		}
 	}
+
+	// Service decorators that describe their resources
+	if nil != workflowHooks {
+		// Links to Lambda functions use the logical resource name
+		lambdaNodeNames := make(map[string]string)
+		for _, eachLambda := range lambdaAWSInfos {
+			lambdaNodeNames[eachLambda.LogicalResourceName()] = eachLambda.lambdaFunctionName()
+		}
+		nodeName := func(name string) string {
+			if lambdaName, isLambda := lambdaNodeNames[name]; isLambda {
+				return lambdaName
+			}
+			return name
+		}
+		for _, eachDecorator := range workflowHooks.ServiceDecorators {
+			describer, isDescriber := eachDecorator.(ServiceDescriber)
+			if !isDescriber {
+				continue
+			}
+			descriptionInfo, descriptionInfoErr := describer.DescribeService(serviceName)
+			if descriptionInfoErr != nil {
+				return descriptionInfoErr
+			}
+			if descriptionInfo == nil {
+				continue
+			}
+			for _, eachNode := range descriptionInfo.Nodes {
+				nodeColor := eachNode.Color
+				if nodeColor == "" {
+					nodeColor = nodeColorEventSource
+				}
+				nodeIcon := eachNode.Icon
+				if nodeIcon == "" {
+					nodeIcon = iconForAWSResource(eachNode.Name)
+				}
+				writeErr = writeNode(&cytoscapeElements,
+					eachNode.Name,
+					nodeColor,
+					nodeIcon,
+					logger)
+				if writeErr != nil {
+					return writeErr
+				}
+			}
+			for _, eachLink := range descriptionInfo.Links {
+				writeErr = writeLink(&cytoscapeElements,
+					nodeName(eachLink.From),
+					nodeName(eachLink.To),
+					eachLink.Label)
+				if writeErr != nil {
+					return writeErr
+				}
+			}
+		}
+	}
 	cytoscapeBytes, cytoscapeBytesErr := json.MarshalIndent(cytoscapeElements, "", " ")
 	if cytoscapeBytesErr != nil {
 		return errors.Wrapf(cytoscapeBytesErr, "Failed to marshal cytoscape data")
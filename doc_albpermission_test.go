@@ -0,0 +1,43 @@
+package sparta
+
+import (
+	"context"
+	"net/http"
+
+	awsLambdaEvents "github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus"
+)
+
+const albListenerArn = "arn:aws:elasticloadbalancing:us-west-2:123412341234:listener/app/myALB/50dc6c495c0c9188/f2f7dc8efc522ab2"
+
+func albProcessor(ctx context.Context,
+	request awsLambdaEvents.ALBTargetGroupRequest) (awsLambdaEvents.ALBTargetGroupResponse, error) {
+	Logger().WithFields(logrus.Fields{
+		"Method": request.HTTPMethod,
+		"Path":   request.Path,
+	}).Info("ALB request")
+	return awsLambdaEvents.ALBTargetGroupResponse{
+		StatusCode:        http.StatusOK,
+		StatusDescription: "200 OK",
+		Headers: map[string]string{
+			"Content-Type": "text/plain",
+		},
+		Body: "Hello ALB",
+	}, nil
+}
+
+func ExampleALBPermission() {
+	var lambdaFunctions []*LambdaAWSInfo
+
+	albLambda, _ := NewAWSLambda(LambdaName(albProcessor),
+		albProcessor,
+		IAMRoleDefinition{})
+	albLambda.Permissions = append(albLambda.Permissions, ALBPermission{
+		ListenerArn:        albListenerArn,
+		Priority:           10,
+		PathPatterns:       []string{"/hello/*"},
+		HTTPRequestMethods: []string{"GET", "HEAD"},
+	})
+	lambdaFunctions = append(lambdaFunctions, albLambda)
+	Main("ALBLambdaApp", "Registers for ALB requests", lambdaFunctions, nil, nil)
+}
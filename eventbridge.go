@@ -0,0 +1,297 @@
+package sparta
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
+	spartaEvents "github.com/mweagle/Sparta/aws/events"
+	spartaSchema "github.com/mweagle/Sparta/aws/schema"
+	gocf "github.com/mweagle/go-cloudformation"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	reAWSAccountID     = regexp.MustCompile(`^\d{12}$`)
+	reOrganizationID   = regexp.MustCompile(`^o-[a-z0-9]{10,32}$`)
+	reEventBusName     = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,256}$`)
+	reEventArchiveName = regexp.MustCompile(`^[\.\-_A-Za-z0-9]{1,48}$`)
+)
+
+// EventBusArchive represents an EventBridge archive of the events sent to
+// an event bus. Archived events can be replayed to the bus. See
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-archive.html
+type EventBusArchive struct {
+	name string
+	// Archive description
+	Description string
+	// Number of days to retain events. Events are retained indefinitely
+	// if zero.
+	RetentionDays int64
+	// Optional event pattern that limits the archived events. All
+	// events are archived if nil.
+	EventPattern map[string]interface{}
+}
+
+// eventBusDetailType is a detail-type published to an event bus
+type eventBusDetailType struct {
+	name   string
+	sample interface{}
+}
+
+// EventBus represents an Amazon EventBridge custom event bus. Use
+// CloudWatchEventsRule.EventBusName to subscribe lambda functions to the
+// events sent to the bus. See
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-bus.html
+type EventBus struct {
+	name                string
+	archives            []*EventBusArchive
+	allowedAccounts     []string
+	allowedOrganization string
+	detailTypes         []*eventBusDetailType
+}
+
+// NewEventBus returns a new custom event bus. The name must be unique
+// within the account and region.
+func NewEventBus(name string) *EventBus {
+	return &EventBus{
+		name: name,
+	}
+}
+
+// LogicalResourceName returns the CloudFormation logical
+// resource name for this event bus
+func (bus *EventBus) LogicalResourceName() string {
+	return CloudFormationResourceName("EventBus", bus.name)
+}
+
+// Name returns the event bus name. Use this value for the
+// CloudWatchEventsRule.EventBusName field so that rules depend on the
+// event bus.
+func (bus *EventBus) Name() *gocf.StringExpr {
+	return gocf.Ref(bus.LogicalResourceName()).String()
+}
+
+// Arn returns the event bus ARN
+func (bus *EventBus) Arn() *gocf.StringExpr {
+	return gocf.GetAtt(bus.LogicalResourceName(), "Arn")
+}
+
+// AllowAccount grants the AWS account permission to put events to
+// this event bus
+func (bus *EventBus) AllowAccount(accountID string) error {
+	if !reAWSAccountID.MatchString(accountID) {
+		return errors.Errorf("invalid AWS account ID for event bus %s: %s", bus.name, accountID)
+	}
+	for _, eachAccountID := range bus.allowedAccounts {
+		if eachAccountID == accountID {
+			return errors.Errorf("account %s already allowed for event bus: %s", accountID, bus.name)
+		}
+	}
+	bus.allowedAccounts = append(bus.allowedAccounts, accountID)
+	return nil
+}
+
+// AllowOrganization grants every account in the AWS Organization
+// permission to put events to this event bus
+func (bus *EventBus) AllowOrganization(organizationID string) error {
+	if !reOrganizationID.MatchString(organizationID) {
+		return errors.Errorf("invalid AWS Organization ID for event bus %s: %s", bus.name, organizationID)
+	}
+	if bus.allowedOrganization != "" {
+		return errors.Errorf("organization %s already allowed for event bus: %s",
+			bus.allowedOrganization,
+			bus.name)
+	}
+	bus.allowedOrganization = organizationID
+	return nil
+}
+
+// NewArchive returns a new archive for the events sent to this event bus.
+// Events are retained for retentionDays, or indefinitely if zero. If
+// eventPattern is nil, all events are archived.
+func (bus *EventBus) NewArchive(name string,
+	retentionDays int64,
+	eventPattern map[string]interface{}) (*EventBusArchive, error) {
+	if !reEventArchiveName.MatchString(name) {
+		return nil, errors.Errorf("invalid archive name for event bus %s: %s", bus.name, name)
+	}
+	if retentionDays < 0 {
+		return nil, errors.Errorf("archive %s RetentionDays must not be negative", name)
+	}
+	for _, eachArchive := range bus.archives {
+		if eachArchive.name == name {
+			return nil, errors.Errorf("archive %s already defined for event bus: %s", name, bus.name)
+		}
+	}
+	archive := &EventBusArchive{
+		name:          name,
+		RetentionDays: retentionDays,
+		EventPattern:  eventPattern,
+	}
+	bus.archives = append(bus.archives, archive)
+	return archive, nil
+}
+
+// RegisterDetailType associates the detail-type with the Go type of the
+// sample value. Publishers returned by NewPublisher only accept values
+// of registered types. The value must satisfy the JSON Schema reflected
+// from its type. See aws/schema.NewJSONSchema for the supported
+// struct tags.
+func (bus *EventBus) RegisterDetailType(detailType string, sample interface{}) error {
+	if detailType == "" {
+		return errors.Errorf("detail-type must not be empty for event bus: %s", bus.name)
+	}
+	_, schemaErr := spartaSchema.NewJSONSchema(sample)
+	if schemaErr != nil {
+		return errors.Wrapf(schemaErr, "attempting to register detail-type: %s", detailType)
+	}
+	// The publisher looks up detail-types by the dereferenced type, so T
+	// and *T are the same detail-type
+	for _, eachDetailType := range bus.detailTypes {
+		if eachDetailType.name == detailType ||
+			indirectType(reflect.TypeOf(eachDetailType.sample)) == indirectType(reflect.TypeOf(sample)) {
+			return errors.Errorf("detail-type %s (%T) already registered for event bus: %s",
+				detailType,
+				sample,
+				bus.name)
+		}
+	}
+	bus.detailTypes = append(bus.detailTypes, &eventBusDetailType{
+		name:   detailType,
+		sample: sample,
+	})
+	return nil
+}
+
+// indirectType returns the type that valueType points to, if it's a pointer
+func indirectType(valueType reflect.Type) reflect.Type {
+	for valueType != nil && valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	return valueType
+}
+
+// AllowPublish grants the lambda function permission to put events to
+// this event bus
+func (bus *EventBus) AllowPublish(handler *LambdaAWSInfo) error {
+	if handler == nil {
+		return errors.Errorf("handler must not be `nil` for event bus: %s", bus.name)
+	}
+	if handler.RoleDefinition == nil {
+		return errors.Errorf("function %s must use an IAMRoleDefinition to publish to event bus: %s",
+			handler.lambdaFunctionName(),
+			bus.name)
+	}
+	handler.RoleDefinition.Privileges = append(handler.RoleDefinition.Privileges,
+		IAMRolePrivilege{
+			Actions:  []string{"events:PutEvents"},
+			Resource: bus.Arn(),
+		})
+	return nil
+}
+
+// NewPublisher returns a publisher that sends events with the given source
+// to this event bus. The publisher accepts the registered detail types.
+// Lambda functions that use the publisher must be included in AllowPublish.
+func (bus *EventBus) NewPublisher(awsSession *session.Session,
+	source string) (*spartaEvents.EventBusPublisher, error) {
+	if source == "" {
+		return nil, errors.Errorf("event source must not be empty for event bus: %s", bus.name)
+	}
+	publisher := spartaEvents.NewEventBusPublisher(awsSession, bus.name, source)
+	for _, eachDetailType := range bus.detailTypes {
+		registerErr := publisher.RegisterDetailType(eachDetailType.name, eachDetailType.sample)
+		if registerErr != nil {
+			return nil, registerErr
+		}
+	}
+	return publisher, nil
+}
+
+// export marshals the event bus to the CloudFormation template
+func (bus *EventBus) export(serviceName string, template *gocf.Template) error {
+	if !reEventBusName.MatchString(bus.name) || bus.name == "default" {
+		return errors.Errorf("invalid event bus name: %s", bus.name)
+	}
+	busResourceName := bus.LogicalResourceName()
+	template.AddResource(busResourceName, &spartaCF.EventsEventBus{
+		Name: gocf.String(bus.name),
+	})
+
+	// Cross account permissions
+	for _, eachAccountID := range bus.allowedAccounts {
+		policyResourceName := CloudFormationResourceName(fmt.Sprintf("%sPolicy", busResourceName),
+			eachAccountID)
+		template.AddResource(policyResourceName, &spartaCF.EventsEventBusPolicy{
+			Action:       gocf.String("events:PutEvents"),
+			EventBusName: bus.Name(),
+			Principal:    gocf.String(eachAccountID),
+			StatementID:  gocf.String(fmt.Sprintf("Allow%s", eachAccountID)),
+		})
+	}
+	if bus.allowedOrganization != "" {
+		policyResourceName := CloudFormationResourceName(fmt.Sprintf("%sPolicy", busResourceName),
+			bus.allowedOrganization)
+		template.AddResource(policyResourceName, &spartaCF.EventsEventBusPolicy{
+			Action:       gocf.String("events:PutEvents"),
+			EventBusName: bus.Name(),
+			Principal:    gocf.String("*"),
+			StatementID:  gocf.String(fmt.Sprintf("Allow%s", bus.allowedOrganization)),
+			Condition: &spartaCF.EventsEventBusPolicyCondition{
+				Type:  gocf.String("StringEquals"),
+				Key:   gocf.String("aws:PrincipalOrgID"),
+				Value: gocf.String(bus.allowedOrganization),
+			},
+		})
+	}
+
+	// Archives
+	for _, eachArchive := range bus.archives {
+		archiveResource := &spartaCF.EventsArchive{
+			ArchiveName: gocf.String(eachArchive.name),
+			SourceArn:   bus.Arn(),
+		}
+		if eachArchive.Description == "" {
+			archiveResource.Description = gocf.String(fmt.Sprintf("%s %s event archive",
+				serviceName,
+				bus.name))
+		} else {
+			archiveResource.Description = gocf.String(eachArchive.Description)
+		}
+		if eachArchive.RetentionDays != 0 {
+			archiveResource.RetentionDays = gocf.Integer(eachArchive.RetentionDays)
+		}
+		if eachArchive.EventPattern != nil {
+			archiveResource.EventPattern = eachArchive.EventPattern
+		}
+		archiveResourceName := CloudFormationResourceName(fmt.Sprintf("%sArchive", busResourceName),
+			eachArchive.name)
+		template.AddResource(archiveResourceName, archiveResource)
+	}
+	template.Outputs[fmt.Sprintf("%sArn", busResourceName)] = &gocf.Output{
+		Description: fmt.Sprintf("%s event bus ARN", bus.name),
+		Value:       bus.Arn(),
+	}
+	return nil
+}
+
+// ServiceDecorator returns a ServiceDecoratorHookHandler that adds the event
+// bus resources to the service template. Include it in the
+// WorkflowHooks.ServiceDecorators slice supplied to MainEx.
+func (bus *EventBus) ServiceDecorator() ServiceDecoratorHookHandler {
+	return ServiceDecoratorHookFunc(func(context map[string]interface{},
+		serviceName string,
+		template *gocf.Template,
+		S3Bucket string,
+		S3Key string,
+		buildID string,
+		awsSession *session.Session,
+		noop bool,
+		logger *logrus.Logger) error {
+		return bus.export(serviceName, template)
+	})
+}
@@ -0,0 +1,165 @@
+package sparta
+
+import (
+	"reflect"
+	"testing"
+
+	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
+	gocf "github.com/mweagle/go-cloudformation"
+)
+
+type eventBusTestOrder struct {
+	OrderID string  `json:"orderId" validate:"required"`
+	Total   float64 `json:"total" validate:"min=0"`
+}
+
+func TestEventBus(t *testing.T) {
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+
+	eventBus := NewEventBus("SpartaOrders")
+	if allowErr := eventBus.AllowAccount("123412341234"); allowErr != nil {
+		t.Fatal(allowErr)
+	}
+	if allowErr := eventBus.AllowOrganization("o-abcdefghij"); allowErr != nil {
+		t.Fatal(allowErr)
+	}
+	_, archiveErr := eventBus.NewArchive("orders", 30, map[string]interface{}{
+		"detail-type": []string{"OrderPlaced"},
+	})
+	if archiveErr != nil {
+		t.Fatal(archiveErr)
+	}
+	if registerErr := eventBus.RegisterDetailType("OrderPlaced", eventBusTestOrder{}); registerErr != nil {
+		t.Fatal(registerErr)
+	}
+	if allowErr := eventBus.AllowPublish(lambdaFn); allowErr != nil {
+		t.Fatal(allowErr)
+	}
+	lambdaFn.Permissions = append(lambdaFn.Permissions, CloudWatchEventsPermission{
+		Rules: map[string]CloudWatchEventsRule{
+			"OrderPlaced": {
+				EventBusName: eventBus.Name(),
+				EventPattern: map[string]interface{}{
+					"detail-type": []string{"OrderPlaced"},
+				},
+				RuleTarget: &CloudWatchEventsRuleTarget{
+					InputTransformer: &CloudWatchEventsInputTransformer{
+						InputPathsMap: map[string]string{
+							"order": "$.detail.orderId",
+						},
+						InputTemplate: `{"orderId": <order>}`,
+					},
+				},
+			},
+		},
+	})
+	testProvisionEx(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		nil,
+		nil,
+		&WorkflowHooks{
+			ServiceDecorators: []ServiceDecoratorHookHandler{eventBus.ServiceDecorator()},
+		},
+		false,
+		nil)
+}
+
+func TestEventBusValidation(t *testing.T) {
+	eventBus := NewEventBus("SpartaOrders")
+	if eventBus.AllowAccount("1234") == nil {
+		t.Fatal("Failed to reject invalid account ID")
+	}
+	if eventBus.AllowOrganization("org") == nil {
+		t.Fatal("Failed to reject invalid organization ID")
+	}
+	if eventBus.RegisterDetailType("OrderPlaced", eventBusTestOrder{}) != nil {
+		t.Fatal("Failed to register detail-type")
+	}
+	if eventBus.RegisterDetailType("OrderShipped", eventBusTestOrder{}) == nil {
+		t.Fatal("Failed to reject duplicate detail-type Go type")
+	}
+	if eventBus.RegisterDetailType("OrderShipped", &eventBusTestOrder{}) == nil {
+		t.Fatal("Failed to reject duplicate detail-type Go type pointer")
+	}
+	if eventBus.RegisterDetailType("OrderPlaced", struct{ ID string }{}) == nil {
+		t.Fatal("Failed to reject duplicate detail-type name")
+	}
+	_, archiveErr := eventBus.NewArchive("orders", -1, nil)
+	if archiveErr == nil {
+		t.Fatal("Failed to reject negative archive retention")
+	}
+}
+
+func TestCloudWatchEventsRuleEventBus(t *testing.T) {
+	template := gocf.NewTemplate()
+	exportErr := testExportPermission(CloudWatchEventsPermission{
+		Rules: map[string]CloudWatchEventsRule{
+			"Partner": {
+				EventBusName: "partner-bus",
+				EventPattern: map[string]interface{}{
+					"source": []string{"partner"},
+				},
+				RuleTarget: &CloudWatchEventsRuleTarget{
+					InputPath: "$.detail",
+				},
+			},
+		},
+	}, template)
+	if exportErr != nil {
+		t.Fatalf("Failed to export CloudWatchEventsPermission: %s", exportErr)
+	}
+	rules := testTemplateResources(template, "AWS::Events::Rule")
+	permissions := testTemplateResources(template, "AWS::Lambda::Permission")
+	if len(rules) != 1 || len(permissions) != 1 {
+		t.Fatalf("Expected one rule and permission, got: %#v", template.Resources)
+	}
+	rule := rules[0].(*spartaCF.EventsRule)
+	if rule.EventBusName == nil || rule.EventBusName.Literal != "partner-bus" {
+		t.Fatalf("Unexpected rule EventBusName: %#v", rule.EventBusName)
+	}
+	if !reflect.DeepEqual(rule.EventPattern, map[string]interface{}{"source": []string{"partner"}}) {
+		t.Fatalf("Unexpected rule EventPattern: %#v", rule.EventPattern)
+	}
+	if len(rule.Targets) != 1 ||
+		rule.Targets[0].InputPath.Literal != "$.detail" ||
+		!reflect.DeepEqual(rule.Targets[0].Arn, gocf.GetAtt("TestLambda", "Arn")) {
+		t.Fatalf("Unexpected rule targets: %#v", rule.Targets)
+	}
+	// The invoke permission is scoped to the rule on the custom bus
+	ruleArn := BasePermission{
+		SourceArn: gocf.Join("",
+			gocf.String("arn:aws:events:"),
+			gocf.Ref("AWS::Region"),
+			gocf.String(":"),
+			gocf.Ref("AWS::AccountId"),
+			gocf.String(":rule/"),
+			gocf.String("partner-bus"),
+			gocf.String("/"),
+			gocf.String(CloudFormationResourceName("Partner", "TestLambda", "TestService"))),
+	}.sourceArnExpr(cloudformationEventsSourceArnParts...)
+	permission := permissions[0].(gocf.LambdaPermission)
+	if !reflect.DeepEqual(permission.SourceArn, ruleArn) {
+		t.Fatalf("Unexpected rule invoke permission SourceArn: %#v", permission.SourceArn)
+	}
+
+	// RuleTargets accept only one of the input settings
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	lambdaFn.Permissions = append(lambdaFn.Permissions, CloudWatchEventsPermission{
+		Rules: map[string]CloudWatchEventsRule{
+			"Invalid": {
+				ScheduleExpression: "rate(5 minutes)",
+				RuleTarget: &CloudWatchEventsRuleTarget{
+					Input:     `{"hello": "world"}`,
+					InputPath: "$.detail",
+				},
+			},
+		},
+	})
+	testProvision(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		assertError("Failed to reject RuleTarget with both Input and InputPath"))
+}
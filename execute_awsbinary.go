@@ -13,6 +13,7 @@ import (
 	awsLambdaGo "github.com/aws/aws-lambda-go/lambda"
 	awsLambdaContext "github.com/aws/aws-lambda-go/lambdacontext"
 	cloudformationResources "github.com/mweagle/Sparta/aws/cloudformation/resources"
+	spartaSchema "github.com/mweagle/Sparta/aws/schema"
 	gocf "github.com/mweagle/go-cloudformation"
 	"github.com/sirupsen/logrus"
 )
@@ -59,6 +60,7 @@ func takesContext(handler reflect.Type) bool {
 // tappedHandler is the handler that represents this binary's mode
 func tappedHandler(handlerSymbol interface{},
 	interceptors *LambdaEventInterceptors,
+	requestValidators map[string]*spartaSchema.JSONSchema,
 	logger *logrus.Logger) interface{} {
 
 	// If there aren't any, make it a bit easier
@@ -115,6 +117,14 @@ func tappedHandler(handlerSymbol interface{},
 		ctx = context.WithValue(ctx, ContextKeyRequestLogger, logrusEntry)
 		ctx = applyInterceptors(ctx, msg, interceptors.AfterSetup)
 
+		// Reject any API Gateway requests that don't satisfy the
+		// request model before they reach the user code
+		validationErr := validateAPIGatewayRequest(requestValidators, msg)
+		if validationErr != nil {
+			logrusEntry.WithField("error", validationErr).Warn("Rejecting invalid API Gateway request")
+			return nil, validationErr
+		}
+
 		// construct arguments
 		var args []reflect.Value
 		if takesContext {
@@ -171,6 +181,7 @@ func Execute(serviceName string,
 
 	// So what if we have workflow hooks in here?
 	var interceptors *LambdaEventInterceptors
+	var requestValidators map[string]*spartaSchema.JSONSchema
 
 	/*
 		There are three types of targets:
@@ -195,6 +206,7 @@ func Execute(serviceName string,
 		if requestedLambdaFunctionName == testAWSName {
 			handlerSymbol = eachLambdaInfo.handlerSymbol
 			interceptors = eachLambdaInfo.Interceptors
+			requestValidators = eachLambdaInfo.requestValidators
 
 		}
 		// User defined custom resource handler?
@@ -250,7 +262,10 @@ func Execute(serviceName string,
 	}
 
 	// Startup our version...
-	tappedHandler := tappedHandler(handlerSymbol, interceptors, logger)
+	tappedHandler := tappedHandler(handlerSymbol,
+		interceptors,
+		requestValidators,
+		logger)
 	awsLambdaGo.Start(tappedHandler)
 	return nil
 }
@@ -248,6 +248,43 @@ type ServiceDecoratorHookHandler interface {
 		logger *logrus.Logger) error
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// ServiceDescriber
+
+// DescriptionNode is a node that a ServiceDescriber adds to the `describe`
+// graph
+type DescriptionNode struct {
+	// Name is the node name, which must be unique in the graph
+	Name string
+	// Color is the optional node color
+	Color string
+	// Icon is the optional path of the node icon, relative to the
+	// resources/describe directory
+	Icon string
+}
+
+// DescriptionLink is a directed edge between two nodes in the `describe`
+// graph. Use a LambdaAWSInfo.LogicalResourceName() value to link to a
+// Lambda function node.
+type DescriptionLink struct {
+	From  string
+	To    string
+	Label string
+}
+
+// DescriptionInfo is the set of nodes and links that a ServiceDescriber
+// adds to the `describe` graph
+type DescriptionInfo struct {
+	Nodes []DescriptionNode
+	Links []DescriptionLink
+}
+
+// ServiceDescriber is implemented by ServiceDecoratorHookHandlers that
+// provision resources that should be included in the `describe` output
+type ServiceDescriber interface {
+	DescribeService(serviceName string) (*DescriptionInfo, error)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // ServiceValidationHookHandler
 
@@ -3,11 +3,15 @@ package sparta
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/service/s3"
 	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
 	cfCustomResources "github.com/mweagle/Sparta/aws/cloudformation/resources"
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -723,10 +727,61 @@ func (perm SESPermission) descriptionInfo() ([]descriptionNode, error) {
 //
 
 // CloudWatchEventsRuleTarget specifies additional input and JSON selection
-// paths to apply prior to forwarding the event to a lambda function
+// paths to apply prior to forwarding the event to a lambda function. At most
+// one of Input, InputPath and InputTransformer may be set.
 type CloudWatchEventsRuleTarget struct {
-	Input     string
-	InputPath string
+	Input            string
+	InputPath        string
+	InputTransformer *CloudWatchEventsInputTransformer `json:"InputTransformer,omitempty"`
+}
+
+// CloudWatchEventsInputTransformer customizes the event that's forwarded to
+// the lambda function. Each InputPathsMap entry maps a name to a JSON path
+// in the event. The values are substituted for the <name> placeholders
+// in the InputTemplate. See
+// https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-transform-target-input.html
+type CloudWatchEventsInputTransformer struct {
+	InputPathsMap map[string]string
+	InputTemplate string
+}
+
+// EventsRuleTarget returns the rule target for the targetArn resource with
+// the Input, InputPath or InputTransformer values applied. The ruleName is
+// the target ID. A nil CloudWatchEventsRuleTarget returns a target without
+// any input customization.
+func (target *CloudWatchEventsRuleTarget) EventsRuleTarget(ruleName string,
+	targetArn *gocf.StringExpr) (spartaCF.EventsRuleTarget, error) {
+
+	ruleTarget := spartaCF.EventsRuleTarget{
+		Arn: targetArn,
+		ID:  gocf.String(ruleName),
+	}
+	if target == nil {
+		return ruleTarget, nil
+	}
+	inputCount := 0
+	if target.Input != "" {
+		ruleTarget.Input = gocf.String(target.Input)
+		inputCount++
+	}
+	if target.InputPath != "" {
+		ruleTarget.InputPath = gocf.String(target.InputPath)
+		inputCount++
+	}
+	if target.InputTransformer != nil {
+		if target.InputTransformer.InputTemplate == "" {
+			return ruleTarget, fmt.Errorf("rule %s InputTransformer requires an InputTemplate", ruleName)
+		}
+		ruleTarget.InputTransformer = &spartaCF.EventsRuleInputTransformer{
+			InputPathsMap: target.InputTransformer.InputPathsMap,
+			InputTemplate: gocf.String(target.InputTransformer.InputTemplate),
+		}
+		inputCount++
+	}
+	if inputCount > 1 {
+		return ruleTarget, fmt.Errorf("rule %s RuleTarget may only specify one of Input, InputPath or InputTransformer", ruleName)
+	}
+	return ruleTarget, nil
 }
 
 //
@@ -750,6 +805,23 @@ type CloudWatchEventsRule struct {
 	// Schedule pattern per http://docs.aws.amazon.com/AmazonCloudWatch/latest/DeveloperGuide/ScheduledEvents.html
 	ScheduleExpression string
 	RuleTarget         *CloudWatchEventsRuleTarget `json:"RuleTarget,omitempty"`
+	// Optional EventBridge event bus name. The rule is created on the
+	// default event bus if empty. This may be a string literal or a
+	// gocf.Stringable value, such as EventBus.Name()
+	EventBusName interface{} `json:"EventBusName,omitempty"`
+}
+
+// EventBusNameExpr returns the event bus name expression, or nil if the
+// rule is associated with the default event bus
+func (rule CloudWatchEventsRule) EventBusNameExpr() *gocf.StringExpr {
+	if rule.EventBusName == nil {
+		return nil
+	}
+	if eventBusName, isString := rule.EventBusName.(string); isString &&
+		(eventBusName == "" || eventBusName == "default") {
+		return nil
+	}
+	return spartaCF.DynamicValueToStringExpr(rule.EventBusName).String()
 }
 
 // MarshalJSON customizes the JSON representation used when serializing to the
@@ -773,6 +845,9 @@ func (rule CloudWatchEventsRule) MarshalJSON() ([]byte, error) {
 	if nil != rule.RuleTarget {
 		ruleJSON["RuleTarget"] = rule.RuleTarget
 	}
+	if eventBusName := rule.EventBusNameExpr(); eventBusName != nil {
+		ruleJSON["EventBusName"] = eventBusName
+	}
 	return json.Marshal(ruleJSON)
 }
 
@@ -819,14 +894,21 @@ func (perm CloudWatchEventsPermission) export(serviceName string,
 		}).Warn("CloudWatchEvents do not support literal ARN values")
 	}
 
-	arnPermissionForRuleName := func(ruleName string) *gocf.StringExpr {
-		return gocf.Join("",
+	// Rules on custom event buses include the bus name in the ARN
+	arnPermissionForRuleName := func(ruleName string,
+		eventBusName *gocf.StringExpr) *gocf.StringExpr {
+		arnParts := []gocf.Stringable{
 			gocf.String("arn:aws:events:"),
 			gocf.Ref("AWS::Region"),
 			gocf.String(":"),
 			gocf.Ref("AWS::AccountId"),
 			gocf.String(":rule/"),
-			gocf.String(ruleName))
+		}
+		if eventBusName != nil {
+			arnParts = append(arnParts, eventBusName, gocf.String("/"))
+		}
+		arnParts = append(arnParts, gocf.String(ruleName))
+		return gocf.Join("", arnParts...)
 	}
 
 	// Add the permission to invoke the lambda function
@@ -838,8 +920,9 @@ func (perm CloudWatchEventsPermission) export(serviceName string,
 		uniqueRuleNameMap[uniqueRuleName]++
 
 		// Add the permission
+		eventBusName := eachRuleDefinition.EventBusNameExpr()
 		basePerm := BasePermission{
-			SourceArn: arnPermissionForRuleName(uniqueRuleName),
+			SourceArn: arnPermissionForRuleName(uniqueRuleName, eventBusName),
 		}
 		_, exportErr := basePerm.export(gocf.String(CloudWatchEventsPrincipal),
 			cloudformationEventsSourceArnParts,
@@ -854,19 +937,18 @@ func (perm CloudWatchEventsPermission) export(serviceName string,
 			return "", exportErr
 		}
 
-		cwEventsRuleTargetList := gocf.EventsRuleTargetList{}
-		cwEventsRuleTargetList = append(cwEventsRuleTargetList,
-			gocf.EventsRuleTarget{
-				Arn: gocf.GetAtt(lambdaLogicalCFResourceName, "Arn"),
-				ID:  gocf.String(uniqueRuleName),
-			},
-		)
+		ruleTarget, ruleTargetErr := eachRuleDefinition.RuleTarget.EventsRuleTarget(uniqueRuleName,
+			gocf.GetAtt(lambdaLogicalCFResourceName, "Arn"))
+		if ruleTargetErr != nil {
+			return "", ruleTargetErr
+		}
 
 		// Add the rule
-		eventsRule := &gocf.EventsRule{
-			Name:        gocf.String(uniqueRuleName),
-			Description: gocf.String(eachRuleDefinition.Description),
-			Targets:     &cwEventsRuleTargetList,
+		eventsRule := &spartaCF.EventsRule{
+			Name:         gocf.String(uniqueRuleName),
+			Description:  gocf.String(eachRuleDefinition.Description),
+			EventBusName: eventBusName,
+			Targets:      []spartaCF.EventsRuleTarget{ruleTarget},
 		}
 		if nil != eachRuleDefinition.EventPattern && eachRuleDefinition.ScheduleExpression != "" {
 			return "", fmt.Errorf("rule %s CloudWatchEvents specifies both EventPattern and ScheduleExpression", eachRuleName)
@@ -1215,3 +1297,910 @@ func (perm CodeCommitPermission) descriptionInfo() ([]descriptionNode, error) {
 
 // END - CodeCommitPermission
 ///////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+// START - ALBPermission
+//
+
+// ALBLoadBalancer defines the Application Load Balancer and HTTP listener
+// that an ALBPermission creates when it doesn't reference an existing
+// listener
+type ALBLoadBalancer struct {
+	// Subnets are the IDs of the subnets, in at least two Availability
+	// Zones, that the load balancer is attached to
+	Subnets []gocf.Stringable
+	// SecurityGroups are the IDs of the load balancer security groups
+	SecurityGroups []gocf.Stringable
+	// InternetFacing creates an internet-facing load balancer. The default
+	// is an internal load balancer.
+	InternetFacing bool
+	// Port is the listener port. Defaults to 80.
+	Port int64
+}
+
+// ALBPermission struct implies that the Lambda function should be registered
+// as the target of an Application Load Balancer listener rule. The rule
+// forwards requests that match all of the conditions to a lambda
+// TargetGroup. The BasePermission.SourceArn isn't considered for this
+// configuration. The function receives an events.ALBTargetGroupRequest and
+// returns an events.ALBTargetGroupResponse.
+// See https://docs.aws.amazon.com/elasticloadbalancing/latest/application/lambda-functions.html
+// for more information.
+type ALBPermission struct {
+	BasePermission
+	// ListenerArn is the ARN of an existing listener. If it's nil, the
+	// LoadBalancer value is used to create a new load balancer.
+	ListenerArn interface{}
+	// LoadBalancer defines the load balancer to create if there
+	// isn't an existing ListenerArn
+	LoadBalancer *ALBLoadBalancer
+	// Priority is the listener rule priority (1-50000). Rules are evaluated
+	// in priority order, from the lowest value to the highest value.
+	Priority int64
+	// PathPatterns are the path patterns (eg, "/orders/*") to match
+	PathPatterns []string
+	// HostHeaders are the host names (eg, "*.example.com") to match
+	HostHeaders []string
+	// HTTPHeaders is a map of header names to the header values to match
+	HTTPHeaders map[string][]string
+	// HTTPRequestMethods are the request methods to match
+	HTTPRequestMethods []string
+	// MultiValueHeaders sends the query parameters and headers as the
+	// multi-value fields of the request
+	MultiValueHeaders bool
+}
+
+// conditions returns the listener rule conditions
+func (perm ALBPermission) conditions() []spartaCF.ElasticLoadBalancingV2RuleCondition {
+	stringList := func(values []string) *gocf.StringListExpr {
+		stringables := make([]gocf.Stringable, len(values))
+		for index, eachValue := range values {
+			stringables[index] = gocf.String(eachValue)
+		}
+		return gocf.StringList(stringables...)
+	}
+	conditions := []spartaCF.ElasticLoadBalancingV2RuleCondition{}
+	if len(perm.PathPatterns) != 0 {
+		conditions = append(conditions, spartaCF.ElasticLoadBalancingV2RuleCondition{
+			Field: gocf.String("path-pattern"),
+			PathPatternConfig: &spartaCF.ElasticLoadBalancingV2RuleConditionValues{
+				Values: stringList(perm.PathPatterns),
+			},
+		})
+	}
+	if len(perm.HostHeaders) != 0 {
+		conditions = append(conditions, spartaCF.ElasticLoadBalancingV2RuleCondition{
+			Field: gocf.String("host-header"),
+			HostHeaderConfig: &spartaCF.ElasticLoadBalancingV2RuleConditionValues{
+				Values: stringList(perm.HostHeaders),
+			},
+		})
+	}
+	// Stable ordering
+	headerNames := make([]string, 0, len(perm.HTTPHeaders))
+	for eachName := range perm.HTTPHeaders {
+		headerNames = append(headerNames, eachName)
+	}
+	sort.Strings(headerNames)
+	for _, eachName := range headerNames {
+		conditions = append(conditions, spartaCF.ElasticLoadBalancingV2RuleCondition{
+			Field: gocf.String("http-header"),
+			HTTPHeaderConfig: &spartaCF.ElasticLoadBalancingV2HTTPHeaderConfig{
+				HTTPHeaderName: gocf.String(eachName),
+				Values:         stringList(perm.HTTPHeaders[eachName]),
+			},
+		})
+	}
+	if len(perm.HTTPRequestMethods) != 0 {
+		conditions = append(conditions, spartaCF.ElasticLoadBalancingV2RuleCondition{
+			Field: gocf.String("http-request-method"),
+			HTTPRequestMethodConfig: &spartaCF.ElasticLoadBalancingV2RuleConditionValues{
+				Values: stringList(perm.HTTPRequestMethods),
+			},
+		})
+	}
+	return conditions
+}
+
+func (perm ALBPermission) export(serviceName string,
+	lambdaFunctionDisplayName string,
+	lambdaLogicalCFResourceName string,
+	template *gocf.Template,
+	S3Bucket string,
+	S3Key string,
+	logger *logrus.Logger) (string, error) {
+
+	conditions := perm.conditions()
+	if len(conditions) <= 0 {
+		return "", fmt.Errorf("function %s ALBPermission does not specify any conditions", lambdaFunctionDisplayName)
+	}
+	if perm.Priority < 1 || perm.Priority > 50000 {
+		return "", fmt.Errorf("function %s ALBPermission Priority must be between 1 and 50000, got: %d",
+			lambdaFunctionDisplayName,
+			perm.Priority)
+	}
+	if (perm.ListenerArn == nil) == (perm.LoadBalancer == nil) {
+		return "", fmt.Errorf("function %s ALBPermission must define exactly one of ListenerArn or LoadBalancer",
+			lambdaFunctionDisplayName)
+	}
+	// A function may have several ALBPermissions, so the resource names
+	// include the listener and the rule priority
+	var listenerJSON []byte
+	var listenerJSONErr error
+	if perm.ListenerArn != nil {
+		listenerJSON, listenerJSONErr = json.Marshal(perm.ListenerArn)
+	} else {
+		listenerJSON, listenerJSONErr = json.Marshal(perm.LoadBalancer)
+	}
+	if listenerJSONErr != nil {
+		return "", errors.Wrap(listenerJSONErr, "Marshaling ALBPermission listener")
+	}
+	resourceNameParts := []string{lambdaLogicalCFResourceName,
+		string(listenerJSON),
+		strconv.FormatInt(perm.Priority, 10)}
+	listenerRuleResourceName := CloudFormationResourceName("ALBListenerRule",
+		resourceNameParts...)
+	if _, exists := template.Resources[listenerRuleResourceName]; exists {
+		return "", fmt.Errorf("function %s defines more than one ALBPermission with Priority %d for the same listener",
+			lambdaFunctionDisplayName,
+			perm.Priority)
+	}
+
+	// The load balancer invokes the function on behalf of the TargetGroup,
+	// which can't be registered until the permission exists. The SourceArn
+	// is omitted to prevent a circular dependency.
+	basePerm := BasePermission{
+		SourceAccount: perm.SourceAccount,
+	}
+	permissionResourceName, exportErr := basePerm.export(gocf.String(ElasticLoadBalancingPrincipal),
+		nil,
+		lambdaFunctionDisplayName,
+		lambdaLogicalCFResourceName,
+		template,
+		S3Bucket,
+		S3Key,
+		logger)
+	if nil != exportErr {
+		return "", errors.Wrap(exportErr, "Exporting ALB invoke permission")
+	}
+
+	// TargetGroup
+	targetGroup := &spartaCF.ElasticLoadBalancingV2TargetGroup{
+		TargetType: gocf.String("lambda"),
+		Targets: []spartaCF.ElasticLoadBalancingV2TargetDescription{
+			{
+				ID: gocf.GetAtt(lambdaLogicalCFResourceName, "Arn"),
+			},
+		},
+		TargetGroupAttributes: []spartaCF.ElasticLoadBalancingV2TargetGroupAttribute{
+			{
+				Key:   gocf.String("lambda.multi_value_headers.enabled"),
+				Value: gocf.String(fmt.Sprintf("%t", perm.MultiValueHeaders)),
+			},
+		},
+	}
+	targetGroupResourceName := CloudFormationResourceName("ALBTargetGroup",
+		resourceNameParts...)
+	targetGroupResource := template.AddResource(targetGroupResourceName, targetGroup)
+	targetGroupResource.DependsOn = append(targetGroupResource.DependsOn,
+		permissionResourceName)
+
+	// Existing or new listener?
+	var listenerArn *gocf.StringExpr
+	if perm.ListenerArn != nil {
+		listenerArn = spartaCF.DynamicValueToStringExpr(perm.ListenerArn).String()
+	} else {
+		scheme := "internal"
+		if perm.LoadBalancer.InternetFacing {
+			scheme = "internet-facing"
+		}
+		port := perm.LoadBalancer.Port
+		if port == 0 {
+			port = 80
+		}
+		loadBalancer := &gocf.ElasticLoadBalancingV2LoadBalancer{
+			Scheme:  gocf.String(scheme),
+			Subnets: gocf.StringList(perm.LoadBalancer.Subnets...),
+			Type:    gocf.String("application"),
+		}
+		if len(perm.LoadBalancer.SecurityGroups) != 0 {
+			loadBalancer.SecurityGroups = gocf.StringList(perm.LoadBalancer.SecurityGroups...)
+		}
+		loadBalancerResourceName := CloudFormationResourceName("ALB",
+			resourceNameParts...)
+		template.AddResource(loadBalancerResourceName, loadBalancer)
+
+		// Requests that don't match a rule are rejected
+		listener := &spartaCF.ElasticLoadBalancingV2Listener{
+			LoadBalancerArn: gocf.Ref(loadBalancerResourceName).String(),
+			Port:            gocf.Integer(port),
+			Protocol:        gocf.String("HTTP"),
+			DefaultActions: []spartaCF.ElasticLoadBalancingV2Action{
+				{
+					Type: gocf.String("fixed-response"),
+					FixedResponseConfig: &spartaCF.ElasticLoadBalancingV2FixedResponseConfig{
+						ContentType: gocf.String("text/plain"),
+						MessageBody: gocf.String("Not Found"),
+						StatusCode:  gocf.String("404"),
+					},
+				},
+			},
+		}
+		listenerResourceName := CloudFormationResourceName("ALBListener",
+			resourceNameParts...)
+		template.AddResource(listenerResourceName, listener)
+		listenerArn = gocf.Ref(listenerResourceName).String()
+
+		template.Outputs[fmt.Sprintf("%sDNSName", loadBalancerResourceName)] = &gocf.Output{
+			Description: fmt.Sprintf("%s load balancer DNS name", lambdaFunctionDisplayName),
+			Value:       gocf.GetAtt(loadBalancerResourceName, "DNSName"),
+		}
+	}
+
+	// Forward the matching requests to the TargetGroup
+	listenerRule := &spartaCF.ElasticLoadBalancingV2ListenerRule{
+		ListenerArn: listenerArn,
+		Priority:    gocf.Integer(perm.Priority),
+		Conditions:  conditions,
+		Actions: []spartaCF.ElasticLoadBalancingV2Action{
+			{
+				Type:           gocf.String("forward"),
+				TargetGroupArn: gocf.Ref(targetGroupResourceName).String(),
+			},
+		},
+	}
+	template.AddResource(listenerRuleResourceName, listenerRule)
+	return "", nil
+}
+
+func (perm ALBPermission) descriptionInfo() ([]descriptionNode, error) {
+	nodeName := "Application Load Balancer"
+	if perm.ListenerArn != nil {
+		nodeName = describeInfoValue(perm.ListenerArn)
+	}
+	relations := []string{}
+	relations = append(relations, perm.HTTPRequestMethods...)
+	relations = append(relations, perm.HostHeaders...)
+	relations = append(relations, perm.PathPatterns...)
+	nodes := []descriptionNode{
+		{
+			Name:     nodeName,
+			Relation: strings.Join(relations, " "),
+		},
+	}
+	return nodes, nil
+}
+
+// END - ALBPermission
+///////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+// START - CognitoUserPoolPermission
+//
+
+// CognitoUserPoolTrigger is the name of a Cognito user pool LambdaConfig
+// trigger
+type CognitoUserPoolTrigger string
+
+const (
+	// CognitoPreSignUp is invoked before a user is signed up
+	CognitoPreSignUp CognitoUserPoolTrigger = "PreSignUp"
+	// CognitoPostConfirmation is invoked after a user is confirmed
+	CognitoPostConfirmation CognitoUserPoolTrigger = "PostConfirmation"
+	// CognitoPreAuthentication is invoked before a user is authenticated
+	CognitoPreAuthentication CognitoUserPoolTrigger = "PreAuthentication"
+	// CognitoPostAuthentication is invoked after a user is authenticated
+	CognitoPostAuthentication CognitoUserPoolTrigger = "PostAuthentication"
+	// CognitoPreTokenGeneration is invoked before the tokens are generated
+	CognitoPreTokenGeneration CognitoUserPoolTrigger = "PreTokenGeneration"
+	// CognitoCustomMessage is invoked to customize verification and
+	// invitation messages
+	CognitoCustomMessage CognitoUserPoolTrigger = "CustomMessage"
+	// CognitoDefineAuthChallenge is invoked to start a custom authentication
+	// flow
+	CognitoDefineAuthChallenge CognitoUserPoolTrigger = "DefineAuthChallenge"
+	// CognitoCreateAuthChallenge is invoked to create a custom challenge
+	CognitoCreateAuthChallenge CognitoUserPoolTrigger = "CreateAuthChallenge"
+	// CognitoVerifyAuthChallengeResponse is invoked to verify the answer to
+	// a custom challenge
+	CognitoVerifyAuthChallengeResponse CognitoUserPoolTrigger = "VerifyAuthChallengeResponse"
+	// CognitoUserMigration is invoked to migrate users that don't exist in
+	// the user pool
+	CognitoUserMigration CognitoUserPoolTrigger = "UserMigration"
+)
+
+var cognitoUserPoolTriggers = map[CognitoUserPoolTrigger]bool{
+	CognitoPreSignUp:                   true,
+	CognitoPostConfirmation:            true,
+	CognitoPreAuthentication:           true,
+	CognitoPostAuthentication:          true,
+	CognitoPreTokenGeneration:          true,
+	CognitoCustomMessage:               true,
+	CognitoDefineAuthChallenge:         true,
+	CognitoCreateAuthChallenge:         true,
+	CognitoVerifyAuthChallengeResponse: true,
+	CognitoUserMigration:               true,
+}
+
+// CognitoUserPoolPermission struct implies that the Lambda function should be
+// registered as one or more of a Cognito user pool's LambdaConfig triggers.
+// If the UserPool is a gocf.Ref or gocf.GetAtt to a
+// AWS::Cognito::UserPool resource in the same template, the pool's
+// LambdaConfig is updated when the template is provisioned. Otherwise
+// UserPool is the ID of an existing user pool, which is updated by a
+// CustomResource. The BasePermission.SourceArn isn't considered for this
+// configuration.
+// See https://docs.aws.amazon.com/cognito/latest/developerguide/cognito-user-identity-pools-working-with-aws-lambda-triggers.html
+// for more information.
+type CognitoUserPoolPermission struct {
+	BasePermission
+	// UserPool is a reference to the AWS::Cognito::UserPool resource or the
+	// ID (eg, "us-west-2_aBcDeFgHi") of an existing user pool
+	UserPool interface{}
+	// Triggers are the LambdaConfig triggers the function handles
+	Triggers []CognitoUserPoolTrigger
+}
+
+// templateUserPoolName returns the name of the AWS::Cognito::UserPool
+// resource in this template, or the empty string if the UserPool
+// refers to an existing user pool
+func (perm CognitoUserPoolPermission) templateUserPoolName() string {
+	userPoolJSON, userPoolJSONErr := json.Marshal(perm.UserPool)
+	if userPoolJSONErr != nil {
+		return ""
+	}
+	var refFunc gocf.RefFunc
+	if json.Unmarshal(userPoolJSON, &refFunc) == nil && refFunc.Name != "" {
+		return refFunc.Name
+	}
+	var getAttFunc gocf.GetAttFunc
+	if json.Unmarshal(userPoolJSON, &getAttFunc) == nil && getAttFunc.Resource != "" {
+		return getAttFunc.Resource
+	}
+	return ""
+}
+
+func (perm CognitoUserPoolPermission) export(serviceName string,
+	lambdaFunctionDisplayName string,
+	lambdaLogicalCFResourceName string,
+	template *gocf.Template,
+	S3Bucket string,
+	S3Key string,
+	logger *logrus.Logger) (string, error) {
+
+	if perm.UserPool == nil {
+		return "", fmt.Errorf("function %s CognitoUserPoolPermission does not specify a UserPool",
+			lambdaFunctionDisplayName)
+	}
+	if len(perm.Triggers) <= 0 {
+		return "", fmt.Errorf("function %s CognitoUserPoolPermission does not specify any Triggers",
+			lambdaFunctionDisplayName)
+	}
+	triggerNames := make([]string, len(perm.Triggers))
+	for eachIndex, eachTrigger := range perm.Triggers {
+		if !cognitoUserPoolTriggers[eachTrigger] {
+			return "", fmt.Errorf("function %s CognitoUserPoolPermission specifies unsupported trigger: %s",
+				lambdaFunctionDisplayName,
+				eachTrigger)
+		}
+		triggerNames[eachIndex] = string(eachTrigger)
+	}
+
+	// If the pool is in this template, the LambdaConfig is set by
+	// annotateCognitoUserPools after all the permissions are exported
+	poolResourceName := perm.templateUserPoolName()
+	var userPoolArn *gocf.StringExpr
+	if poolResourceName != "" {
+		userPoolArn = gocf.GetAtt(poolResourceName, "Arn")
+	} else {
+		userPoolArn = gocf.Join("",
+			gocf.String("arn:aws:cognito-idp:"),
+			gocf.Ref("AWS::Region"),
+			gocf.String(":"),
+			gocf.Ref("AWS::AccountId"),
+			gocf.String(":userpool/"),
+			spartaCF.DynamicValueToStringExpr(perm.UserPool))
+	}
+	basePerm := BasePermission{
+		SourceAccount: perm.SourceAccount,
+		SourceArn:     userPoolArn,
+	}
+	targetLambdaResourceName, err := basePerm.export(gocf.String(CognitoIdentityProviderPrincipal),
+		nil,
+		lambdaFunctionDisplayName,
+		lambdaLogicalCFResourceName,
+		template,
+		S3Bucket,
+		S3Key,
+		logger)
+	if nil != err {
+		return "", errors.Wrap(err, "Failed to export Cognito user pool permission")
+	}
+	if poolResourceName != "" {
+		return "", nil
+	}
+
+	// Make sure that the handler that manages the existing pool is registered.
+	configuratorResName, err := EnsureCustomResourceHandler(serviceName,
+		cfCustomResources.CognitoLambdaEventSource,
+		userPoolArn,
+		[]string{},
+		template,
+		S3Bucket,
+		S3Key,
+		logger)
+	if nil != err {
+		return "", errors.Wrap(err, "Exporting Cognito user pool permission handler")
+	}
+
+	// Add a custom resource invocation for this configuration
+	//////////////////////////////////////////////////////////////////////////////
+	newResource, newResourceError := newCloudFormationResource(cfCustomResources.CognitoLambdaEventSource,
+		logger)
+	if nil != newResourceError {
+		return "", newResourceError
+	}
+	customResource := newResource.(*cfCustomResources.CognitoLambdaEventSourceResource)
+	customResource.ServiceToken = gocf.GetAtt(configuratorResName, "Arn")
+	customResource.LambdaTargetArn = gocf.GetAtt(lambdaLogicalCFResourceName, "Arn")
+	customResource.UserPoolID = spartaCF.DynamicValueToStringExpr(perm.UserPool).String()
+	customResource.Triggers = triggerNames
+
+	userPoolJSON, userPoolJSONErr := json.Marshal(perm.UserPool)
+	if nil != userPoolJSONErr {
+		return "", userPoolJSONErr
+	}
+	resourceInvokerName := CloudFormationResourceName("ConfigCognito",
+		lambdaLogicalCFResourceName,
+		string(userPoolJSON))
+
+	// Add it
+	cfResource := template.AddResource(resourceInvokerName, customResource)
+	cfResource.DependsOn = append(cfResource.DependsOn,
+		targetLambdaResourceName,
+		configuratorResName)
+	return "", nil
+}
+
+func (perm CognitoUserPoolPermission) descriptionInfo() ([]descriptionNode, error) {
+	triggerNames := make([]string, len(perm.Triggers))
+	for eachIndex, eachTrigger := range perm.Triggers {
+		triggerNames[eachIndex] = string(eachTrigger)
+	}
+	nodes := []descriptionNode{
+		{
+			Name:     describeInfoValue(perm.UserPool),
+			Relation: strings.Join(triggerNames, ", "),
+		},
+	}
+	return nodes, nil
+}
+
+// END - CognitoUserPoolPermission
+///////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+// START - IoTTopicRulePermission
+//
+
+// IoTTopicRuleDefaultSQLVersion is the AWS IoT SQL version used by an
+// IoTTopicRulePermission that doesn't specify a SQLVersion
+const IoTTopicRuleDefaultSQLVersion = "2016-03-23"
+
+// IoTTopicRulePermission struct implies that the Lambda function should be
+// invoked by an AWS IoT topic rule. The permission creates the
+// AWS::IoT::TopicRule whose SQL statement selects the MQTT messages that
+// are sent to the function. The BasePermission.SourceArn isn't considered
+// for this configuration.
+// See https://docs.aws.amazon.com/iot/latest/developerguide/iot-rules.html
+// for more information.
+type IoTTopicRulePermission struct {
+	BasePermission
+	// SQL is the rule's SQL statement (eg, "SELECT * FROM 'telemetry/#'")
+	SQL string
+	// SQLVersion is the AWS IoT SQL version. Defaults to
+	// IoTTopicRuleDefaultSQLVersion.
+	SQLVersion string
+	// RuleName is the optional rule name. Rule names may only include
+	// alphanumeric characters and underscores.
+	RuleName string
+	// Description of the rule
+	Description string
+	// Disabled creates the rule in a disabled state
+	Disabled bool
+	// ErrorAction is the optional action that is performed when the rule
+	// can't invoke the function. Actions other than Lambda must include
+	// the RoleArn that AWS IoT assumes to perform the action.
+	ErrorAction *spartaCF.IoTTopicRuleAction
+}
+
+// errorActionRoleArn returns the RoleArn of the error action and whether
+// the action requires a role
+func (perm IoTTopicRulePermission) errorActionRoleArn() (*gocf.StringExpr, bool) {
+	switch {
+	case perm.ErrorAction.CloudwatchLogs != nil:
+		return perm.ErrorAction.CloudwatchLogs.RoleArn, true
+	case perm.ErrorAction.S3 != nil:
+		return perm.ErrorAction.S3.RoleArn, true
+	case perm.ErrorAction.SNS != nil:
+		return perm.ErrorAction.SNS.RoleArn, true
+	case perm.ErrorAction.SQS != nil:
+		return perm.ErrorAction.SQS.RoleArn, true
+	}
+	return nil, false
+}
+
+func (perm IoTTopicRulePermission) export(serviceName string,
+	lambdaFunctionDisplayName string,
+	lambdaLogicalCFResourceName string,
+	template *gocf.Template,
+	S3Bucket string,
+	S3Key string,
+	logger *logrus.Logger) (string, error) {
+
+	if perm.SQL == "" {
+		return "", fmt.Errorf("function %s IoTTopicRulePermission does not specify a SQL statement",
+			lambdaFunctionDisplayName)
+	}
+	if perm.ErrorAction != nil {
+		roleArn, requiresRole := perm.errorActionRoleArn()
+		if requiresRole && roleArn == nil {
+			return "", fmt.Errorf("function %s IoTTopicRulePermission ErrorAction does not specify a RoleArn",
+				lambdaFunctionDisplayName)
+		}
+	}
+	sqlVersion := perm.SQLVersion
+	if sqlVersion == "" {
+		sqlVersion = IoTTopicRuleDefaultSQLVersion
+	}
+	topicRule := &spartaCF.IoTTopicRule{
+		TopicRulePayload: &spartaCF.IoTTopicRulePayload{
+			Actions: []spartaCF.IoTTopicRuleAction{
+				{
+					Lambda: &spartaCF.IoTTopicRuleLambdaAction{
+						FunctionArn: gocf.GetAtt(lambdaLogicalCFResourceName, "Arn"),
+					},
+				},
+			},
+			AwsIotSQLVersion: gocf.String(sqlVersion),
+			ErrorAction:      perm.ErrorAction,
+			RuleDisabled:     gocf.Bool(perm.Disabled),
+			SQL:              gocf.String(perm.SQL),
+		},
+	}
+	if perm.RuleName != "" {
+		topicRule.RuleName = gocf.String(perm.RuleName)
+	}
+	if perm.Description != "" {
+		topicRule.TopicRulePayload.Description = gocf.String(perm.Description)
+	}
+	topicRuleResourceName := CloudFormationResourceName("IoTTopicRule",
+		lambdaLogicalCFResourceName,
+		perm.SQL)
+	template.AddResource(topicRuleResourceName, topicRule)
+
+	// Scope the invoke permission to the rule
+	basePerm := BasePermission{
+		SourceAccount: perm.SourceAccount,
+		SourceArn:     gocf.GetAtt(topicRuleResourceName, "Arn"),
+	}
+	_, exportErr := basePerm.export(gocf.String(IoTPrincipal),
+		nil,
+		lambdaFunctionDisplayName,
+		lambdaLogicalCFResourceName,
+		template,
+		S3Bucket,
+		S3Key,
+		logger)
+	if nil != exportErr {
+		return "", errors.Wrap(exportErr, "Failed to export IoT topic rule permission")
+	}
+	return "", nil
+}
+
+func (perm IoTTopicRulePermission) descriptionInfo() ([]descriptionNode, error) {
+	nodeName := "AWS IoT"
+	if perm.RuleName != "" {
+		nodeName = perm.RuleName
+	}
+	nodes := []descriptionNode{
+		{
+			Name:     nodeName,
+			Relation: perm.SQL,
+		},
+	}
+	return nodes, nil
+}
+
+// END - IoTTopicRulePermission
+///////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+// START - S3ObjectLambdaPermission
+//
+
+var reS3AccessPointName = regexp.MustCompile(`^[a-z0-9]([a-z0-9\-]*[a-z0-9])?$`)
+
+// s3BucketNameExpr returns the bucket name for a BasePermission.SourceArn
+// that is either a literal bucket ARN or a Ref to an AWS::S3::Bucket
+// resource, which evaluates to the bucket name. Other expressions, such as
+// a GetAtt of the bucket Arn, are rejected.
+func s3BucketNameExpr(sourceArn interface{}) (*gocf.StringExpr, error) {
+	stringARN, stringARNOk := sourceArn.(string)
+	if stringARNOk {
+		return gocf.String(strings.TrimPrefix(stringARN, "arn:aws:s3:::")), nil
+	}
+	sourceArnJSON, sourceArnJSONErr := json.Marshal(sourceArn)
+	if sourceArnJSONErr != nil {
+		return nil, sourceArnJSONErr
+	}
+	var stringExpr gocf.StringExpr
+	if json.Unmarshal(sourceArnJSON, &stringExpr) == nil && stringExpr.Literal != "" {
+		return gocf.String(strings.TrimPrefix(stringExpr.Literal, "arn:aws:s3:::")), nil
+	}
+	var refFunc gocf.RefFunc
+	if json.Unmarshal(sourceArnJSON, &refFunc) == nil && refFunc.Name != "" {
+		return gocf.Ref(refFunc.Name).String(), nil
+	}
+	return nil, errors.Errorf("SourceArn must be a literal bucket ARN or a Ref to an AWS::S3::Bucket resource, got: %s",
+		string(sourceArnJSON))
+}
+
+// S3ObjectLambdaPermission struct implies that the Lambda function transforms
+// the objects returned by an S3 Object Lambda access point. The permission
+// creates the supporting AWS::S3::AccessPoint for the bucket in
+// BasePermission.SourceArn and the AWS::S3ObjectLambda::AccessPoint. The
+// SourceArn must be a literal bucket ARN or a gocf.Ref to an AWS::S3::Bucket
+// resource. The function is granted s3-object-lambda:WriteGetObjectResponse.
+// Callers of the Object Lambda access point require lambda:InvokeFunction on
+// the function.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/transforming-objects.html
+// for more information. Functions receive an aws/s3.ObjectLambdaEvent.
+type S3ObjectLambdaPermission struct {
+	BasePermission
+	// Name is the Object Lambda access point name. Names are 3-45 lowercase
+	// letters, numbers and hyphens.
+	Name string
+	// SupportingAccessPointName is the optional name of the supporting
+	// access point. Defaults to "Name-ap".
+	SupportingAccessPointName string
+	// Actions are the transformed S3 actions: GetObject, HeadObject,
+	// ListObjects or ListObjectsV2. Defaults to GetObject.
+	Actions []string
+	// AllowedFeatures are the optional features supported by the function
+	// (eg, GetObject-Range, GetObject-PartNumber)
+	AllowedFeatures []string
+	// Payload is the optional string provided to the function in the
+	// event's configuration
+	Payload string
+	// CloudWatchMetricsEnabled enables request metrics for the access point
+	CloudWatchMetricsEnabled bool
+}
+
+// supportingAccessPointName returns the name of the supporting access point
+func (perm S3ObjectLambdaPermission) supportingAccessPointName() string {
+	if perm.SupportingAccessPointName != "" {
+		return perm.SupportingAccessPointName
+	}
+	return fmt.Sprintf("%s-ap", perm.Name)
+}
+
+func (perm S3ObjectLambdaPermission) export(serviceName string,
+	lambdaFunctionDisplayName string,
+	lambdaLogicalCFResourceName string,
+	template *gocf.Template,
+	S3Bucket string,
+	S3Key string,
+	logger *logrus.Logger) (string, error) {
+
+	if perm.SourceArn == nil {
+		return "", fmt.Errorf("function %s S3ObjectLambdaPermission does not specify a SourceArn bucket",
+			lambdaFunctionDisplayName)
+	}
+	if len(perm.Name) < 3 || len(perm.Name) > 45 || !reS3AccessPointName.MatchString(perm.Name) {
+		return "", fmt.Errorf("function %s S3ObjectLambdaPermission has an invalid Name: %s",
+			lambdaFunctionDisplayName,
+			perm.Name)
+	}
+	supportingName := perm.supportingAccessPointName()
+	if len(supportingName) < 3 || len(supportingName) > 50 ||
+		!reS3AccessPointName.MatchString(supportingName) {
+		return "", fmt.Errorf("function %s S3ObjectLambdaPermission has an invalid SupportingAccessPointName: %s",
+			lambdaFunctionDisplayName,
+			supportingName)
+	}
+	actions := perm.Actions
+	if len(actions) == 0 {
+		actions = []string{"GetObject"}
+	}
+	for _, eachAction := range actions {
+		switch eachAction {
+		case "GetObject", "HeadObject", "ListObjects", "ListObjectsV2":
+		default:
+			return "", fmt.Errorf("function %s S3ObjectLambdaPermission has an unsupported action: %s",
+				lambdaFunctionDisplayName,
+				eachAction)
+		}
+	}
+
+	bucketName, bucketNameErr := s3BucketNameExpr(perm.SourceArn)
+	if bucketNameErr != nil {
+		return "", fmt.Errorf("function %s S3ObjectLambdaPermission has an unsupported SourceArn: %s",
+			lambdaFunctionDisplayName,
+			bucketNameErr)
+	}
+
+	// The supporting access point
+	accessPointResourceName := CloudFormationResourceName("S3AccessPoint",
+		lambdaLogicalCFResourceName,
+		supportingName)
+	template.AddResource(accessPointResourceName, &spartaCF.S3AccessPoint{
+		Bucket: bucketName,
+		Name:   gocf.String(supportingName),
+	})
+
+	// And the Object Lambda access point that transforms its objects
+	awsLambda := &spartaCF.S3ObjectLambdaAwsLambda{
+		FunctionArn: gocf.GetAtt(lambdaLogicalCFResourceName, "Arn"),
+	}
+	if perm.Payload != "" {
+		awsLambda.FunctionPayload = gocf.String(perm.Payload)
+	}
+	objectLambdaConfig := &spartaCF.S3ObjectLambdaConfiguration{
+		CloudWatchMetricsEnabled: gocf.Bool(perm.CloudWatchMetricsEnabled),
+		SupportingAccessPoint:    gocf.GetAtt(accessPointResourceName, "Arn"),
+		TransformationConfigurations: []spartaCF.S3ObjectLambdaTransformationConfig{
+			{
+				Actions: stringListExpr(actions),
+				ContentTransformation: &spartaCF.S3ObjectLambdaContentTransformation{
+					AwsLambda: awsLambda,
+				},
+			},
+		},
+	}
+	if len(perm.AllowedFeatures) != 0 {
+		objectLambdaConfig.AllowedFeatures = stringListExpr(perm.AllowedFeatures)
+	}
+	objectLambdaResourceName := CloudFormationResourceName("S3ObjectLambdaAccessPoint",
+		lambdaLogicalCFResourceName,
+		perm.Name)
+	cfResource := template.AddResource(objectLambdaResourceName, &spartaCF.S3ObjectLambdaAccessPoint{
+		Name:                      gocf.String(perm.Name),
+		ObjectLambdaConfiguration: objectLambdaConfig,
+	})
+	cfResource.DependsOn = append(cfResource.DependsOn, accessPointResourceName)
+	template.Outputs[fmt.Sprintf("%sArn", objectLambdaResourceName)] = &gocf.Output{
+		Description: fmt.Sprintf("%s Object Lambda access point ARN", perm.Name),
+		Value:       gocf.GetAtt(objectLambdaResourceName, "Arn"),
+	}
+	return "", nil
+}
+
+func (perm S3ObjectLambdaPermission) descriptionInfo() ([]descriptionNode, error) {
+	actions := strings.Join(perm.Actions, "\n")
+	if actions == "" {
+		actions = "GetObject"
+	}
+	nodes := []descriptionNode{
+		{
+			Name:     perm.Name,
+			Relation: actions,
+		},
+	}
+	return nodes, nil
+}
+
+// END - S3ObjectLambdaPermission
+///////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+// START - S3BatchOperationsPermission
+//
+
+// S3BatchOperationsPermission struct implies that the Lambda function is
+// invoked by S3 Batch Operations jobs. Jobs are created outside of the
+// stack, so the permission creates the IAM role that jobs assume to invoke
+// the function, read the manifest and write the completion report. The role
+// ARN is published as a stack output. The BasePermission.SourceArn isn't
+// considered for this configuration.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/batch-ops-invoke-lambda.html
+// for more information. Functions receive an aws/s3.BatchJobEvent and return
+// an aws/s3.BatchJobResponse.
+type S3BatchOperationsPermission struct {
+	BasePermission
+	// ManifestBucketArn is the ARN of the bucket that stores job manifests.
+	// Either a literal ARN or a reference to a bucket resource.
+	ManifestBucketArn interface{}
+	// ReportBucketArn is the optional ARN of the bucket that stores job
+	// completion reports. Either a literal ARN or a reference to a bucket
+	// resource.
+	ReportBucketArn interface{}
+}
+
+// RoleLogicalResourceName returns the logical resource name of the IAM role
+// that S3 Batch Operations jobs assume to invoke the function
+func (perm S3BatchOperationsPermission) RoleLogicalResourceName(lambdaLogicalCFResourceName string) string {
+	return CloudFormationResourceName("S3BatchOperationsRole",
+		lambdaLogicalCFResourceName)
+}
+
+func (perm S3BatchOperationsPermission) export(serviceName string,
+	lambdaFunctionDisplayName string,
+	lambdaLogicalCFResourceName string,
+	template *gocf.Template,
+	S3Bucket string,
+	S3Key string,
+	logger *logrus.Logger) (string, error) {
+
+	if perm.ManifestBucketArn == nil {
+		return "", fmt.Errorf("function %s S3BatchOperationsPermission does not specify a ManifestBucketArn",
+			lambdaFunctionDisplayName)
+	}
+	bucketObjectsExpr := func(bucketArn interface{}) *gocf.StringExpr {
+		bucketPerm := BasePermission{
+			SourceArn: bucketArn,
+		}
+		return gocf.Join("", bucketPerm.sourceArnExpr(s3SourceArnParts...), gocf.String("/*"))
+	}
+	statements := []spartaIAM.PolicyStatement{
+		{
+			Action:   []string{"lambda:InvokeFunction"},
+			Effect:   "Allow",
+			Resource: gocf.GetAtt(lambdaLogicalCFResourceName, "Arn"),
+		},
+		{
+			Action:   []string{"s3:GetObject", "s3:GetObjectVersion"},
+			Effect:   "Allow",
+			Resource: bucketObjectsExpr(perm.ManifestBucketArn),
+		},
+	}
+	if perm.ReportBucketArn != nil {
+		statements = append(statements, spartaIAM.PolicyStatement{
+			Action:   []string{"s3:PutObject"},
+			Effect:   "Allow",
+			Resource: bucketObjectsExpr(perm.ReportBucketArn),
+		})
+	}
+	roleResourceName := perm.RoleLogicalResourceName(lambdaLogicalCFResourceName)
+	template.AddResource(roleResourceName, &gocf.IAMRole{
+		AssumeRolePolicyDocument: ArbitraryJSONObject{
+			"Version": "2012-10-17",
+			"Statement": []ArbitraryJSONObject{
+				{
+					"Effect": "Allow",
+					"Principal": ArbitraryJSONObject{
+						"Service": []string{S3BatchOperationsPrincipal},
+					},
+					"Action": []string{"sts:AssumeRole"},
+				},
+			},
+		},
+		Policies: &gocf.IAMRolePolicyList{
+			gocf.IAMRolePolicy{
+				PolicyDocument: ArbitraryJSONObject{
+					"Version":   "2012-10-17",
+					"Statement": statements,
+				},
+				PolicyName: gocf.String("S3BatchOperations"),
+			},
+		},
+	})
+	template.Outputs[fmt.Sprintf("%sArn", roleResourceName)] = &gocf.Output{
+		Description: fmt.Sprintf("%s S3 Batch Operations role ARN", lambdaFunctionDisplayName),
+		Value:       gocf.GetAtt(roleResourceName, "Arn"),
+	}
+	return "", nil
+}
+
+func (perm S3BatchOperationsPermission) descriptionInfo() ([]descriptionNode, error) {
+	nodes := []descriptionNode{
+		{
+			Name:     "S3 Batch Operations",
+			Relation: describeInfoValue(perm.ManifestBucketArn),
+		},
+	}
+	return nodes, nil
+}
+
+// END - S3BatchOperationsPermission
+///////////////////////////////////////////////////////////////////////////////////
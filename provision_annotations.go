@@ -27,6 +27,8 @@ func eventSourceMappingPoliciesForResource(resource *resourceRef,
 		policyStatements = append(policyStatements, CommonIAMStatements.Kinesis...)
 	} else if isResolvedResourceType(resource, template, ":sqs:", &gocf.SQSQueue{}) {
 		policyStatements = append(policyStatements, CommonIAMStatements.SQS...)
+	} else if isResolvedKafkaCluster(resource, template) {
+		policyStatements = append(policyStatements, CommonIAMStatements.MSK...)
 	} else {
 		logger.WithFields(logrus.Fields{
 			"Resource": resource,
@@ -35,6 +37,65 @@ func eventSourceMappingPoliciesForResource(resource *resourceRef,
 	return policyStatements, nil
 }
 
+// eventSourceMappingDestinationPolicies returns the statements that allow
+// the function to send discarded batches to the OnFailure destination
+func eventSourceMappingDestinationPolicies(eventSourceMapping *EventSourceMapping,
+	template *gocf.Template) ([]spartaIAM.PolicyStatement, error) {
+	if eventSourceMapping.OnFailureDestinationArn == nil {
+		return nil, nil
+	}
+	destination, destinationErr := resolveResourceRef(eventSourceMapping.OnFailureDestinationArn)
+	if destinationErr != nil {
+		return nil, errors.Wrapf(destinationErr,
+			"Failed to resolve OnFailure destination: %#v",
+			eventSourceMapping.OnFailureDestinationArn)
+	}
+	var action []string
+	if destination == nil {
+		return nil, nil
+	} else if isResolvedResourceType(destination, template, ":sqs:", &gocf.SQSQueue{}) {
+		action = []string{"sqs:SendMessage"}
+	} else if isResolvedResourceType(destination, template, ":sns:", &gocf.SNSTopic{}) {
+		action = []string{"sns:Publish"}
+	} else {
+		return nil, errors.Errorf("OnFailure destination must be an SQS queue or SNS topic: %#v",
+			eventSourceMapping.OnFailureDestinationArn)
+	}
+	return []spartaIAM.PolicyStatement{
+		{
+			Action:   action,
+			Effect:   "Allow",
+			Resource: spartaCF.DynamicValueToStringExpr(eventSourceMapping.OnFailureDestinationArn).String(),
+		},
+	}, nil
+}
+
+// eventSourceMappingKafkaPolicies returns the statements that allow the
+// function to read the Kafka credentials and to reach a self-managed
+// cluster in a VPC
+func eventSourceMappingKafkaPolicies(eventSourceMapping *EventSourceMapping) []spartaIAM.PolicyStatement {
+	kafkaSource := eventSourceMapping.Kafka
+	if kafkaSource == nil {
+		return nil
+	}
+	policyStatements := []spartaIAM.PolicyStatement{}
+	if kafkaSource.AuthenticationSecretArn != nil {
+		policyStatements = append(policyStatements, spartaIAM.PolicyStatement{
+			Action:   []string{"secretsmanager:GetSecretValue"},
+			Effect:   "Allow",
+			Resource: spartaCF.DynamicValueToStringExpr(kafkaSource.AuthenticationSecretArn).String(),
+		})
+	}
+	if kafkaSource.isSelfManaged() && len(kafkaSource.VPCSubnets) != 0 {
+		policyStatements = append(policyStatements, spartaIAM.PolicyStatement{
+			Action:   kafkaNetworkActions,
+			Effect:   "Allow",
+			Resource: gocf.String("*"),
+		})
+	}
+	return policyStatements
+}
+
 // annotationFunc represents an internal annotation function
 // called to stich the template together
 type annotationFunc func(lambdaAWSInfos []*LambdaAWSInfo,
@@ -112,6 +173,66 @@ func annotateCodePipelineEnvironments(lambdaAWSInfo *LambdaAWSInfo, logger *logr
 	}
 }
 
+// appendLambdaRolePolicy adds the policy to the IAM role of the lambda
+// function. Roles that aren't defined in the template, such as those
+// referenced by name, are not updated.
+func appendLambdaRolePolicy(lambdaAWSInfo *LambdaAWSInfo,
+	policyName string,
+	statements []spartaIAM.PolicyStatement,
+	template *gocf.Template) error {
+	// Something to push onto the resource. The resource
+	// is hopefully defined in this template. It technically
+	// could be a string literal, in which case we're not going
+	// to have a lot of luck with that...
+	cfResource, cfResourceOk := template.Resources[lambdaAWSInfo.LogicalResourceName()]
+	if !cfResourceOk {
+		return errors.Errorf("Unable to locate lambda function for annotation")
+	}
+	lambdaResource, lambdaResourceOk := cfResource.Properties.(gocf.LambdaFunction)
+	if !lambdaResourceOk {
+		return errors.Errorf("CloudFormation resource exists, but is incorrect type: %s (%v)",
+			cfResource.Properties.CfnResourceType(),
+			cfResource.Properties)
+	}
+	// Ok, go get the IAM Role
+	resourceRef, resourceRefErr := resolveResourceRef(lambdaResource.Role)
+	if resourceRefErr != nil {
+		return errors.Wrapf(resourceRefErr, "Failed to resolve IAM Role for %s: %#v",
+			policyName,
+			lambdaResource.Role)
+	}
+	// If it's not nil and also not a literal, go ahead and try and update it
+	if resourceRef != nil &&
+		resourceRef.RefType != resourceLiteral &&
+		resourceRef.RefType != resourceStringFunc {
+		// Excellent, go ahead and find the role in the template
+		// and stitch things together
+		iamRole, iamRoleExists := template.Resources[resourceRef.ResourceName]
+		if !iamRoleExists {
+			return errors.Errorf("IAM role not found: %s", resourceRef.ResourceName)
+		}
+		// Coerce to the IAMRole and update the statements
+		typedIAMRole, typedIAMRoleOk := iamRole.Properties.(gocf.IAMRole)
+		if !typedIAMRoleOk {
+			return errors.Errorf("Failed to type convert iamRole to proper IAMRole resource")
+		}
+		policyList := typedIAMRole.Policies
+		if policyList == nil {
+			policyList = &gocf.IAMRolePolicyList{}
+		}
+		*policyList = append(*policyList,
+			gocf.IAMRolePolicy{
+				PolicyDocument: ArbitraryJSONObject{
+					"Version":   "2012-10-17",
+					"Statement": statements,
+				},
+				PolicyName: gocf.String(policyName),
+			})
+		typedIAMRole.Policies = policyList
+	}
+	return nil
+}
+
 func annotateEventSourceMappings(lambdaAWSInfos []*LambdaAWSInfo,
 	template *gocf.Template,
 	logger *logrus.Logger) error {
@@ -125,78 +246,50 @@ func annotateEventSourceMappings(lambdaAWSInfos []*LambdaAWSInfo,
 		mappingIndex int,
 		resource *resourceRef) error {
 
-		annotateStatements, annotateStatementsErr := eventSourceMappingPoliciesForResource(resource,
-			template,
-			logger)
-
-		// Early exit?
-		if annotateStatementsErr != nil {
-			return annotateStatementsErr
-		} else if len(annotateStatements) <= 0 {
-			return nil
+		annotateStatements := []spartaIAM.PolicyStatement{}
+		if resource != nil {
+			resourceStatements, resourceStatementsErr := eventSourceMappingPoliciesForResource(resource,
+				template,
+				logger)
+			if resourceStatementsErr != nil {
+				return resourceStatementsErr
+			}
+			annotateStatements = resourceStatements
 		}
 		// If we have statements, let's go ahead and ensure they
-		// include a reference to our ARN
+		// include a reference to our ARN, unless they're scoped to
+		// another resource
 		populatedStatements := []spartaIAM.PolicyStatement{}
 		for _, eachStatement := range annotateStatements {
+			statementResource := eachStatement.Resource
+			if statementResource == nil {
+				statementResource = spartaCF.DynamicValueToStringExpr(eventSourceMapping.EventSourceArn).String()
+			}
 			populatedStatements = append(populatedStatements,
 				spartaIAM.PolicyStatement{
 					Action:   eachStatement.Action,
 					Effect:   "Allow",
-					Resource: spartaCF.DynamicValueToStringExpr(eventSourceMapping.EventSourceArn).String(),
+					Resource: statementResource,
 				})
 		}
+		populatedStatements = append(populatedStatements,
+			eventSourceMappingKafkaPolicies(eventSourceMapping)...)
+		destinationStatements, destinationStatementsErr := eventSourceMappingDestinationPolicies(eventSourceMapping,
+			template)
+		if destinationStatementsErr != nil {
+			return destinationStatementsErr
+		}
+		populatedStatements = append(populatedStatements, destinationStatements...)
 
-		// Something to push onto the resource. The resource
-		// is hopefully defined in this template. It technically
-		// could be a string literal, in which case we're not going
-		// to have a lot of luck with that...
-		cfResource, cfResourceOk := template.Resources[lambdaAWSInfo.LogicalResourceName()]
-		if !cfResourceOk {
-			return errors.Errorf("Unable to locate lambda function for annotation")
-		}
-		lambdaResource, lambdaResourceOk := cfResource.Properties.(gocf.LambdaFunction)
-		if !lambdaResourceOk {
-			return errors.Errorf("CloudFormation resource exists, but is incorrect type: %s (%v)",
-				cfResource.Properties.CfnResourceType(),
-				cfResource.Properties)
-		}
-		// Ok, go get the IAM Role
-		resourceRef, resourceRefErr := resolveResourceRef(lambdaResource.Role)
-		if resourceRefErr != nil {
-			return errors.Wrapf(resourceRefErr, "Failed to resolve IAM Role for event source mappings: %#v",
-				lambdaResource.Role)
-		}
-		// If it's not nil and also not a literal, go ahead and try and update it
-		if resourceRef != nil &&
-			resourceRef.RefType != resourceLiteral &&
-			resourceRef.RefType != resourceStringFunc {
-			// Excellent, go ahead and find the role in the template
-			// and stitch things together
-			iamRole, iamRoleExists := template.Resources[resourceRef.ResourceName]
-			if !iamRoleExists {
-				return errors.Errorf("IAM role not found: %s", resourceRef.ResourceName)
-			}
-			// Coerce to the IAMRole and update the statements
-			typedIAMRole, typedIAMRoleOk := iamRole.Properties.(gocf.IAMRole)
-			if !typedIAMRoleOk {
-				return errors.Errorf("Failed to type convert iamRole to proper IAMRole resource")
-			}
-			policyList := typedIAMRole.Policies
-			if policyList == nil {
-				policyList = &gocf.IAMRolePolicyList{}
-			}
-			*policyList = append(*policyList,
-				gocf.IAMRolePolicy{
-					PolicyDocument: ArbitraryJSONObject{
-						"Version":   "2012-10-17",
-						"Statement": populatedStatements,
-					},
-					PolicyName: gocf.String("LambdaEventSourceMappingPolicy"),
-				})
-			typedIAMRole.Policies = policyList
+		// Early exit?
+		if len(populatedStatements) <= 0 {
+			return nil
 		}
-		return nil
+
+		return appendLambdaRolePolicy(lambdaAWSInfo,
+			"LambdaEventSourceMappingPolicy",
+			populatedStatements,
+			template)
 	}
 	//
 	// END
@@ -213,6 +306,241 @@ func annotateEventSourceMappings(lambdaAWSInfos []*LambdaAWSInfo,
 	return nil
 }
 
+// eventInvokeDestinationPolicies returns the statement that allows the
+// function to send asynchronous invocation records to the destination
+func eventInvokeDestinationPolicies(destination interface{},
+	template *gocf.Template) ([]spartaIAM.PolicyStatement, error) {
+	if destination == nil {
+		return nil, nil
+	}
+	destinationArn := eventInvokeDestinationArn(destination)
+	resource, resourceErr := resolveResourceRef(destinationArn)
+	if resourceErr != nil {
+		return nil, errors.Wrapf(resourceErr,
+			"Failed to resolve EventInvokeConfig destination: %#v",
+			destination)
+	}
+	var action []string
+	if resource == nil {
+		return nil, nil
+	} else if isResolvedResourceType(resource, template, ":sqs:", &gocf.SQSQueue{}) {
+		action = []string{"sqs:SendMessage"}
+	} else if isResolvedResourceType(resource, template, ":sns:", &gocf.SNSTopic{}) {
+		action = []string{"sns:Publish"}
+	} else if isResolvedResourceType(resource, template, ":events:", &spartaCF.EventsEventBus{}) {
+		action = []string{"events:PutEvents"}
+	} else if isResolvedResourceType(resource, template, ":lambda:", gocf.LambdaFunction{}) {
+		action = []string{"lambda:InvokeFunction"}
+	} else {
+		return nil, errors.Errorf("EventInvokeConfig destination must be an SQS queue, SNS topic, EventBridge event bus or Lambda function: %#v",
+			destination)
+	}
+	return []spartaIAM.PolicyStatement{
+		{
+			Action:   action,
+			Effect:   "Allow",
+			Resource: destinationArn,
+		},
+	}, nil
+}
+
+// annotateEventInvokeConfigs grants the function permission to send
+// asynchronous invocation records to its destinations
+func annotateEventInvokeConfigs(lambdaAWSInfos []*LambdaAWSInfo,
+	template *gocf.Template,
+	logger *logrus.Logger) error {
+	for _, eachLambda := range lambdaAWSInfos {
+		if eachLambda.Options == nil || eachLambda.Options.EventInvokeConfig == nil {
+			continue
+		}
+		invokeConfig := eachLambda.Options.EventInvokeConfig
+		var statements []spartaIAM.PolicyStatement
+		for _, eachDestination := range []interface{}{invokeConfig.OnSuccessDestination,
+			invokeConfig.OnFailureDestination} {
+			destinationStatements, destinationStatementsErr := eventInvokeDestinationPolicies(eachDestination,
+				template)
+			if destinationStatementsErr != nil {
+				return errors.Wrapf(destinationStatementsErr,
+					"Failed to annotate EventInvokeConfig for %s",
+					eachLambda.lambdaFunctionName())
+			}
+			statements = append(statements, destinationStatements...)
+		}
+		if len(statements) <= 0 {
+			continue
+		}
+		policyErr := appendLambdaRolePolicy(eachLambda,
+			"LambdaEventInvokeConfigPolicy",
+			statements,
+			template)
+		if policyErr != nil {
+			return policyErr
+		}
+	}
+	return nil
+}
+
+// annotateDeadLetterQueues grants the function permission to send the
+// events it fails to process to its managed Dead Letter Queue
+func annotateDeadLetterQueues(lambdaAWSInfos []*LambdaAWSInfo,
+	template *gocf.Template,
+	logger *logrus.Logger) error {
+	for _, eachLambda := range lambdaAWSInfos {
+		if eachLambda.Options == nil || eachLambda.Options.ManagedDeadLetterQueue == nil {
+			continue
+		}
+		dlqResourceName := managedDeadLetterQueueResourceName(eachLambda.LogicalResourceName())
+		policyErr := appendLambdaRolePolicy(eachLambda,
+			"LambdaDeadLetterQueuePolicy",
+			[]spartaIAM.PolicyStatement{
+				{
+					Action:   []string{"sqs:SendMessage"},
+					Effect:   "Allow",
+					Resource: gocf.GetAtt(dlqResourceName, "Arn"),
+				},
+			},
+			template)
+		if policyErr != nil {
+			return errors.Wrapf(policyErr,
+				"Failed to annotate ManagedDeadLetterQueue for %s",
+				eachLambda.lambdaFunctionName())
+		}
+	}
+	return nil
+}
+
+// annotateS3ObjectLambdas grants functions that transform S3 Object Lambda
+// access point requests permission to return the transformed objects
+func annotateS3ObjectLambdas(lambdaAWSInfos []*LambdaAWSInfo,
+	template *gocf.Template,
+	logger *logrus.Logger) error {
+	for _, eachLambda := range lambdaAWSInfos {
+		for _, eachPermission := range eachLambda.Permissions {
+			switch eachPermission.(type) {
+			case S3ObjectLambdaPermission, *S3ObjectLambdaPermission:
+			default:
+				continue
+			}
+			// WriteGetObjectResponse doesn't support resource-level permissions
+			policyErr := appendLambdaRolePolicy(eachLambda,
+				"LambdaS3ObjectLambdaPolicy",
+				[]spartaIAM.PolicyStatement{
+					{
+						Action:   []string{"s3-object-lambda:WriteGetObjectResponse"},
+						Effect:   "Allow",
+						Resource: wildcardArn,
+					},
+				},
+				template)
+			if policyErr != nil {
+				return errors.Wrapf(policyErr,
+					"Failed to annotate S3ObjectLambdaPermission for %s",
+					eachLambda.lambdaFunctionName())
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// setCognitoUserPoolTriggers sets the LambdaConfig triggers of a
+// AWS::Cognito::UserPool to the function ARN. The LambdaConfig fields are
+// located by the trigger name.
+func setCognitoUserPoolTriggers(userPool reflect.Value,
+	triggers []CognitoUserPoolTrigger,
+	lambdaArn *gocf.StringExpr) error {
+	lambdaConfig := userPool.FieldByName("LambdaConfig")
+	if !lambdaConfig.IsValid() || lambdaConfig.Kind() != reflect.Ptr {
+		return errors.Errorf("CognitoUserPool doesn't define a LambdaConfig")
+	}
+	if lambdaConfig.IsNil() {
+		lambdaConfig.Set(reflect.New(lambdaConfig.Type().Elem()))
+	}
+	for _, eachTrigger := range triggers {
+		triggerField := lambdaConfig.Elem().FieldByName(string(eachTrigger))
+		if !triggerField.IsValid() ||
+			!reflect.TypeOf(lambdaArn).AssignableTo(triggerField.Type()) {
+			return errors.Errorf("CognitoUserPool LambdaConfig doesn't support the %s trigger",
+				eachTrigger)
+		}
+		triggerField.Set(reflect.ValueOf(lambdaArn))
+	}
+	return nil
+}
+
+// annotateCognitoUserPools sets the LambdaConfig of the user pools in this
+// template that are referenced by a CognitoUserPoolPermission
+func annotateCognitoUserPools(lambdaAWSInfos []*LambdaAWSInfo,
+	template *gocf.Template,
+	logger *logrus.Logger) error {
+
+	// Map of pool name to each trigger's function, to reject duplicates
+	poolTriggers := make(map[string]map[CognitoUserPoolTrigger]string)
+	for _, eachLambda := range lambdaAWSInfos {
+		for _, eachPermission := range eachLambda.Permissions {
+			var cognitoPerm CognitoUserPoolPermission
+			switch typedPermission := eachPermission.(type) {
+			case CognitoUserPoolPermission:
+				cognitoPerm = typedPermission
+			case *CognitoUserPoolPermission:
+				cognitoPerm = *typedPermission
+			default:
+				continue
+			}
+			poolName := cognitoPerm.templateUserPoolName()
+			if poolName == "" {
+				continue
+			}
+			cfResource, cfResourceExists := template.Resources[poolName]
+			if !cfResourceExists {
+				return errors.Errorf("CognitoUserPool resource not found: %s", poolName)
+			}
+			if poolTriggers[poolName] == nil {
+				poolTriggers[poolName] = make(map[CognitoUserPoolTrigger]string)
+			}
+			for _, eachTrigger := range cognitoPerm.Triggers {
+				existingFunction, exists := poolTriggers[poolName][eachTrigger]
+				if exists && existingFunction != eachLambda.lambdaFunctionName() {
+					return errors.Errorf("CognitoUserPool %s %s trigger is handled by both %s and %s",
+						poolName,
+						eachTrigger,
+						existingFunction,
+						eachLambda.lambdaFunctionName())
+				}
+				poolTriggers[poolName][eachTrigger] = eachLambda.lambdaFunctionName()
+			}
+			lambdaArn := gocf.GetAtt(eachLambda.LogicalResourceName(), "Arn")
+
+			// Update the pool in place, or replace the value type
+			var setErr error
+			switch typedPool := cfResource.Properties.(type) {
+			case *gocf.CognitoUserPool:
+				setErr = setCognitoUserPoolTriggers(reflect.ValueOf(typedPool).Elem(),
+					cognitoPerm.Triggers,
+					lambdaArn)
+			case gocf.CognitoUserPool:
+				setErr = setCognitoUserPoolTriggers(reflect.ValueOf(&typedPool).Elem(),
+					cognitoPerm.Triggers,
+					lambdaArn)
+				cfResource.Properties = typedPool
+			default:
+				return errors.Errorf("CloudFormation resource %s exists, but is incorrect type: %s",
+					poolName,
+					cfResource.Properties.CfnResourceType())
+			}
+			if setErr != nil {
+				return errors.Wrapf(setErr, "Failed to annotate CognitoUserPool %s", poolName)
+			}
+			logger.WithFields(logrus.Fields{
+				"UserPool": poolName,
+				"Triggers": cognitoPerm.Triggers,
+				"Function": eachLambda.lambdaFunctionName(),
+			}).Debug("Annotated CognitoUserPool LambdaConfig")
+		}
+	}
+	return nil
+}
+
 func annotateMaterializedTemplate(
 	lambdaAWSInfos []*LambdaAWSInfo,
 	template *gocf.Template,
@@ -220,6 +548,10 @@ func annotateMaterializedTemplate(
 	// Setup the annotation functions
 	annotationFuncs := []annotationFunc{
 		annotateEventSourceMappings,
+		annotateEventInvokeConfigs,
+		annotateDeadLetterQueues,
+		annotateS3ObjectLambdas,
+		annotateCognitoUserPools,
 	}
 	for _, eachAnnotationFunc := range annotationFuncs {
 		funcName := runtime.FuncForPC(reflect.ValueOf(eachAnnotationFunc).Pointer()).Name()
@@ -0,0 +1,323 @@
+// +build !lambdabinary
+
+package sparta
+
+import (
+	"context"
+	"time"
+
+	awsLambdaGo "github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	spartaAWS "github.com/mweagle/Sparta/aws"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// replayVisibilityTimeout is the number of seconds that received
+	// messages are hidden from other consumers while they're replayed
+	replayVisibilityTimeout = 300
+	// replayMaxBatchSize is the maximum number of messages returned by
+	// a single ReceiveMessage request
+	replayMaxBatchSize = 10
+	// replayMaxRate is the maximum number of events replayed per second
+	replayMaxRate = 1000
+	// replayRestoreTimeout limits the time spent making the messages that
+	// weren't replayed visible again
+	replayRestoreTimeout = 30 * time.Second
+)
+
+// replayInvoker invokes the function with a single event payload
+type replayInvoker func(ctx context.Context, payload []byte) error
+
+// dlqReplayer drains a Dead Letter Queue and re-invokes a function with
+// each message body
+type dlqReplayer struct {
+	queueURL  string
+	sqsSvc    sqsiface.SQSAPI
+	invoker   replayInvoker
+	rate      int
+	maxEvents int
+	noop      bool
+	logger    *logrus.Logger
+}
+
+// replay processes the queued messages. Messages are deleted only after
+// they're successfully replayed. Each message is attempted at most once, and
+// messages that aren't deleted are made visible again before returning.
+func (replayer *dlqReplayer) replay(ctx context.Context) (int, int, error) {
+	if replayer.rate <= 0 || replayer.rate > replayMaxRate {
+		return 0, 0, errors.Errorf("replay rate must be between 1 and %d, got: %d",
+			replayMaxRate,
+			replayer.rate)
+	}
+	ticker := time.NewTicker(time.Second / time.Duration(replayer.rate))
+	defer ticker.Stop()
+
+	attempted := make(map[string]bool)
+	var retainedHandles []*string
+	replayedCount := 0
+	failedCount := 0
+
+	// Whatever wasn't replayed goes back onto the queue. The replay context
+	// may have been cancelled, so the messages are restored with a new one.
+	defer func() {
+		restoreCtx, restoreCancel := context.WithTimeout(context.Background(),
+			replayRestoreTimeout)
+		defer restoreCancel()
+		for _, eachHandle := range retainedHandles {
+			_, visibilityErr := replayer.sqsSvc.ChangeMessageVisibilityWithContext(restoreCtx,
+				&sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          aws.String(replayer.queueURL),
+					ReceiptHandle:     eachHandle,
+					VisibilityTimeout: aws.Int64(0),
+				})
+			if visibilityErr != nil {
+				replayer.logger.WithField("Error", visibilityErr).
+					Warn("Failed to restore Dead Letter Queue message visibility")
+			}
+		}
+	}()
+
+	for {
+		batchSize := replayMaxBatchSize
+		if replayer.maxEvents > 0 {
+			remaining := replayer.maxEvents - len(attempted)
+			if remaining <= 0 {
+				break
+			}
+			if remaining < batchSize {
+				batchSize = remaining
+			}
+		}
+		receiveResp, receiveErr := replayer.sqsSvc.ReceiveMessageWithContext(ctx,
+			&sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(replayer.queueURL),
+				MaxNumberOfMessages: aws.Int64(int64(batchSize)),
+				VisibilityTimeout:   aws.Int64(replayVisibilityTimeout),
+				WaitTimeSeconds:     aws.Int64(1),
+			})
+		if receiveErr != nil {
+			return replayedCount, failedCount, receiveErr
+		}
+		newMessageCount := 0
+		for eachIndex, eachMessage := range receiveResp.Messages {
+			messageID := aws.StringValue(eachMessage.MessageId)
+			if attempted[messageID] {
+				continue
+			}
+			attempted[messageID] = true
+			newMessageCount++
+
+			messageLogger := replayer.logger.WithField("MessageID", messageID)
+			if replayer.noop {
+				messageLogger.WithField("Event", aws.StringValue(eachMessage.Body)).
+					Info(noopMessage("Replay"))
+				retainedHandles = append(retainedHandles, eachMessage.ReceiptHandle)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				// Including the rest of the batch
+				for _, eachRetained := range receiveResp.Messages[eachIndex:] {
+					retainedHandles = append(retainedHandles, eachRetained.ReceiptHandle)
+				}
+				return replayedCount, failedCount, ctx.Err()
+			case <-ticker.C:
+			}
+			invokeErr := replayer.invoker(ctx, []byte(aws.StringValue(eachMessage.Body)))
+			if invokeErr != nil {
+				messageLogger.WithField("Error", invokeErr).Warn("Failed to replay event")
+				retainedHandles = append(retainedHandles, eachMessage.ReceiptHandle)
+				failedCount++
+				continue
+			}
+			_, deleteErr := replayer.sqsSvc.DeleteMessageWithContext(ctx,
+				&sqs.DeleteMessageInput{
+					QueueUrl:      aws.String(replayer.queueURL),
+					ReceiptHandle: eachMessage.ReceiptHandle,
+				})
+			if deleteErr != nil {
+				return replayedCount, failedCount, errors.Wrapf(deleteErr,
+					"Failed to delete replayed message: %s",
+					messageID)
+			}
+			messageLogger.Info("Replayed event")
+			replayedCount++
+		}
+		// Stop once the queue only has messages we've already seen
+		if newMessageCount == 0 {
+			break
+		}
+	}
+	return replayedCount, failedCount, nil
+}
+
+// stackPhysicalResourceID returns the physical ID of the service's resource
+func stackPhysicalResourceID(serviceName string,
+	logicalResourceID string,
+	awsSession *session.Session) (string, error) {
+	cfSvc := cloudformation.New(awsSession)
+	describeResp, describeErr := cfSvc.DescribeStackResource(&cloudformation.DescribeStackResourceInput{
+		StackName:         aws.String(serviceName),
+		LogicalResourceId: aws.String(logicalResourceID),
+	})
+	if describeErr != nil {
+		return "", errors.Wrapf(describeErr,
+			"Failed to describe resource %s in stack %s",
+			logicalResourceID,
+			serviceName)
+	}
+	return aws.StringValue(describeResp.StackResourceDetail.PhysicalResourceId), nil
+}
+
+// deadLetterQueueURL returns the URL of the function's SQS Dead Letter Queue
+func deadLetterQueueURL(serviceName string,
+	lambdaAWSInfo *LambdaAWSInfo,
+	awsSession *session.Session) (string, error) {
+	if lambdaAWSInfo.Options == nil {
+		return "", errors.Errorf("function %s doesn't define a Dead Letter Queue",
+			lambdaAWSInfo.lambdaFunctionName())
+	}
+	// The physical ID of an AWS::SQS::Queue is its URL
+	if lambdaAWSInfo.Options.ManagedDeadLetterQueue != nil {
+		return stackPhysicalResourceID(serviceName,
+			managedDeadLetterQueueResourceName(lambdaAWSInfo.LogicalResourceName()),
+			awsSession)
+	}
+	if lambdaAWSInfo.Options.DeadLetterConfigArn == nil {
+		return "", errors.Errorf("function %s doesn't define a Dead Letter Queue",
+			lambdaAWSInfo.lambdaFunctionName())
+	}
+	dlqArn := lambdaAWSInfo.Options.DeadLetterConfigArn.String().Literal
+	parsedArn, parsedArnErr := arn.Parse(dlqArn)
+	if parsedArnErr != nil || parsedArn.Service != "sqs" {
+		return "", errors.Errorf("function %s DeadLetterConfigArn must be a literal SQS queue ARN to replay events",
+			lambdaAWSInfo.lambdaFunctionName())
+	}
+	sqsSvc := sqs.New(awsSession, aws.NewConfig().WithRegion(parsedArn.Region))
+	queueURLResp, queueURLErr := sqsSvc.GetQueueUrl(&sqs.GetQueueUrlInput{
+		QueueName:              aws.String(parsedArn.Resource),
+		QueueOwnerAWSAccountId: aws.String(parsedArn.AccountID),
+	})
+	if queueURLErr != nil {
+		return "", queueURLErr
+	}
+	return aws.StringValue(queueURLResp.QueueUrl), nil
+}
+
+// localReplayInvoker invokes the function handler in this process
+func localReplayInvoker(lambdaAWSInfo *LambdaAWSInfo,
+	logger *logrus.Logger) replayInvoker {
+	handler := awsLambdaGo.NewHandler(lambdaAWSInfo.handlerSymbol)
+	return func(ctx context.Context, payload []byte) error {
+		ctx = context.WithValue(ctx, ContextKeyLogger, logger)
+		ctx = context.WithValue(ctx, ContextKeyRequestLogger, logrus.NewEntry(logger))
+		_, invokeErr := handler.Invoke(ctx, payload)
+		return invokeErr
+	}
+}
+
+// remoteReplayInvoker synchronously invokes the provisioned function
+func remoteReplayInvoker(functionName string,
+	awsSession *session.Session) replayInvoker {
+	lambdaSvc := lambda.New(awsSession)
+	return func(ctx context.Context, payload []byte) error {
+		invokeResp, invokeErr := lambdaSvc.InvokeWithContext(ctx, &lambda.InvokeInput{
+			FunctionName:   aws.String(functionName),
+			InvocationType: aws.String(lambda.InvocationTypeRequestResponse),
+			Payload:        payload,
+		})
+		if invokeErr != nil {
+			return invokeErr
+		}
+		if invokeResp.FunctionError != nil {
+			return errors.Errorf("%s: %s",
+				aws.StringValue(invokeResp.FunctionError),
+				string(invokeResp.Payload))
+		}
+		return nil
+	}
+}
+
+// Replay drains the function's Dead Letter Queue and re-invokes the function
+// with each event, at most rate events per second. The provisioned function
+// is invoked unless local is true, in which case the handler is called in
+// this process. Successfully replayed events are deleted from the queue. If
+// maxEvents is positive, at most that many events are replayed. In noop mode
+// the events are logged and left on the queue.
+func Replay(serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	functionName string,
+	rate int,
+	maxEvents int,
+	local bool,
+	noop bool,
+	logger *logrus.Logger) error {
+
+	var lambdaAWSInfo *LambdaAWSInfo
+	for _, eachLambda := range lambdaAWSInfos {
+		if eachLambda.lambdaFunctionName() == functionName {
+			lambdaAWSInfo = eachLambda
+			break
+		}
+	}
+	if lambdaAWSInfo == nil {
+		return errors.Errorf("Unknown function name: %s", functionName)
+	}
+	awsSession := spartaAWS.NewSession(logger)
+	queueURL, queueURLErr := deadLetterQueueURL(serviceName, lambdaAWSInfo, awsSession)
+	if queueURLErr != nil {
+		return queueURLErr
+	}
+
+	var invoker replayInvoker
+	if local {
+		invoker = localReplayInvoker(lambdaAWSInfo, logger)
+	} else {
+		physicalName, physicalNameErr := stackPhysicalResourceID(serviceName,
+			lambdaAWSInfo.LogicalResourceName(),
+			awsSession)
+		if physicalNameErr != nil {
+			return physicalNameErr
+		}
+		invoker = remoteReplayInvoker(physicalName, awsSession)
+	}
+	logger.WithFields(logrus.Fields{
+		"Function":  functionName,
+		"QueueURL":  queueURL,
+		"Rate":      rate,
+		"MaxEvents": maxEvents,
+		"Local":     local,
+	}).Info("Replaying Dead Letter Queue events")
+
+	replayer := &dlqReplayer{
+		queueURL:  queueURL,
+		sqsSvc:    sqs.New(awsSession),
+		invoker:   invoker,
+		rate:      rate,
+		maxEvents: maxEvents,
+		noop:      noop,
+		logger:    logger,
+	}
+	replayedCount, failedCount, replayErr := replayer.replay(context.Background())
+	logger.WithFields(logrus.Fields{
+		"Replayed": replayedCount,
+		"Failed":   failedCount,
+	}).Info("Dead Letter Queue replay complete")
+	if replayErr != nil {
+		return replayErr
+	}
+	if failedCount != 0 {
+		return errors.Errorf("Failed to replay %d event(s) for function: %s",
+			failedCount,
+			functionName)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package sparta
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/sirupsen/logrus"
+)
+
+type mockReplaySQS struct {
+	sqsiface.SQSAPI
+	messages []*sqs.Message
+	deleted  map[string]bool
+	released map[string]bool
+}
+
+func newMockReplaySQS(messageCount int) *mockReplaySQS {
+	mock := &mockReplaySQS{
+		deleted:  make(map[string]bool),
+		released: make(map[string]bool),
+	}
+	for i := 0; i != messageCount; i++ {
+		mock.messages = append(mock.messages, &sqs.Message{
+			MessageId:     aws.String(fmt.Sprintf("message%d", i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("message%d", i)),
+			Body:          aws.String(fmt.Sprintf(`{"index": %d}`, i)),
+		})
+	}
+	return mock
+}
+
+// ReceiveMessageWithContext returns the messages that haven't been deleted,
+// including those that were already received
+func (mock *mockReplaySQS) ReceiveMessageWithContext(ctx aws.Context,
+	input *sqs.ReceiveMessageInput,
+	opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	output := &sqs.ReceiveMessageOutput{}
+	for _, eachMessage := range mock.messages {
+		if int64(len(output.Messages)) == aws.Int64Value(input.MaxNumberOfMessages) {
+			break
+		}
+		if !mock.deleted[aws.StringValue(eachMessage.ReceiptHandle)] {
+			output.Messages = append(output.Messages, eachMessage)
+		}
+	}
+	return output, nil
+}
+
+func (mock *mockReplaySQS) DeleteMessageWithContext(ctx aws.Context,
+	input *sqs.DeleteMessageInput,
+	opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	mock.deleted[aws.StringValue(input.ReceiptHandle)] = true
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (mock *mockReplaySQS) ChangeMessageVisibilityWithContext(ctx aws.Context,
+	input *sqs.ChangeMessageVisibilityInput,
+	opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	mock.released[aws.StringValue(input.ReceiptHandle)] = true
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func TestReplay(t *testing.T) {
+	logger := logrus.New()
+	for _, eachTestCase := range []struct {
+		name          string
+		messageCount  int
+		maxEvents     int
+		noop          bool
+		failIndex     int
+		expectedCalls int
+		expectedFail  int
+	}{
+		{"all", 25, 0, false, -1, 25, 0},
+		{"max", 25, 12, false, -1, 12, 0},
+		{"failure", 5, 0, false, 2, 5, 1},
+		{"noop", 5, 0, true, -1, 0, 0},
+	} {
+		mockSvc := newMockReplaySQS(eachTestCase.messageCount)
+		invokeCount := 0
+		replayer := &dlqReplayer{
+			queueURL: "https://sqs.us-west-2.amazonaws.com/123412341234/dlq",
+			sqsSvc:   mockSvc,
+			invoker: func(ctx context.Context, payload []byte) error {
+				invokeCount++
+				if string(payload) == fmt.Sprintf(`{"index": %d}`, eachTestCase.failIndex) {
+					return fmt.Errorf("replay failure")
+				}
+				return nil
+			},
+			rate:      1000,
+			maxEvents: eachTestCase.maxEvents,
+			noop:      eachTestCase.noop,
+			logger:    logger,
+		}
+		replayedCount, failedCount, replayErr := replayer.replay(context.Background())
+		if replayErr != nil {
+			t.Fatalf("%s: %s", eachTestCase.name, replayErr)
+		}
+		if invokeCount != eachTestCase.expectedCalls ||
+			failedCount != eachTestCase.expectedFail ||
+			replayedCount != eachTestCase.expectedCalls-eachTestCase.expectedFail {
+			t.Fatalf("%s: unexpected replay results. Invoked: %d, Replayed: %d, Failed: %d",
+				eachTestCase.name,
+				invokeCount,
+				replayedCount,
+				failedCount)
+		}
+		if len(mockSvc.deleted) != replayedCount {
+			t.Fatalf("%s: expected %d deleted messages, got: %d",
+				eachTestCase.name,
+				replayedCount,
+				len(mockSvc.deleted))
+		}
+		// Failed and dry-run messages are returned to the queue
+		expectedReleased := failedCount
+		if eachTestCase.noop {
+			expectedReleased = eachTestCase.messageCount
+		}
+		if len(mockSvc.released) != expectedReleased {
+			t.Fatalf("%s: expected %d released messages, got: %d",
+				eachTestCase.name,
+				expectedReleased,
+				len(mockSvc.released))
+		}
+	}
+}
+
+func TestReplayCancelled(t *testing.T) {
+	mockSvc := newMockReplaySQS(5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	replayer := &dlqReplayer{
+		queueURL: "https://sqs.us-west-2.amazonaws.com/123412341234/dlq",
+		sqsSvc:   mockSvc,
+		invoker: func(ctx context.Context, payload []byte) error {
+			// Stop the replay after the first event
+			defer cancel()
+			return ctx.Err()
+		},
+		rate:   1000,
+		logger: logrus.New(),
+	}
+	replayedCount, _, _ := replayer.replay(ctx)
+	if replayedCount != 1 || len(mockSvc.deleted) != 1 {
+		t.Fatalf("Expected 1 replayed message, got: %d", replayedCount)
+	}
+	// The messages that weren't replayed are returned to the queue
+	// even though the replay context was cancelled
+	if len(mockSvc.released) != 4 {
+		t.Fatalf("Expected 4 released messages, got: %d", len(mockSvc.released))
+	}
+}
+
+func TestReplayInvalidRate(t *testing.T) {
+	logger := logrus.New()
+	for _, eachRate := range []int{0, -1, replayMaxRate + 1, 2000000000} {
+		replayer := &dlqReplayer{
+			queueURL: "https://sqs.us-west-2.amazonaws.com/123412341234/dlq",
+			sqsSvc:   newMockReplaySQS(1),
+			invoker: func(ctx context.Context, payload []byte) error {
+				return nil
+			},
+			rate:   eachRate,
+			logger: logger,
+		}
+		if _, _, replayErr := replayer.replay(context.Background()); replayErr == nil {
+			t.Fatalf("Failed to reject replay rate: %d", eachRate)
+		}
+	}
+}
@@ -27,7 +27,7 @@ type resourceRef struct {
 }
 
 // resolvedResourceVisitor represents the signature of a function that
-// visits
+// visits. The resource is nil for event sources without an EventSourceArn.
 type resolvedResourceVisitor func(lambdaAWSInfo *LambdaAWSInfo,
 	eventSourceMapping *EventSourceMapping,
 	mappingIndex int,
@@ -105,6 +105,20 @@ func isResolvedResourceType(resource *resourceRef,
 	return false
 }
 
+// isResolvedKafkaCluster returns true if the resolved reference is an
+// Amazon MSK cluster. Template resources are compared by their
+// CloudFormation type, since there isn't a go-cloudformation type for
+// the AWS::MSK::Cluster resource.
+func isResolvedKafkaCluster(resource *resourceRef, template *gocf.Template) bool {
+	if resource.RefType == resourceLiteral ||
+		resource.RefType == resourceStringFunc {
+		return strings.Contains(resource.ResourceName, ":kafka:")
+	}
+	existingResource, existingResourceExists := template.Resources[resource.ResourceName]
+	return existingResourceExists &&
+		existingResource.Properties.CfnResourceType() == "AWS::MSK::Cluster"
+}
+
 // visitResolvedEventSourceMapping is a utility function that visits all the EventSourceMapping
 // entries for the given lambdaAWSInfo struct
 func visitResolvedEventSourceMapping(visitor resolvedResourceVisitor,
@@ -152,8 +166,9 @@ func visitResolvedEventSourceMapping(visitor resolvedResourceVisitor,
 			// and see if the Arn is supplied by either a Ref or a GetAttr
 			// function. In those cases, we need to look around in the template
 			// to go from: EventMapping -> Type -> Lambda -> LambdaIAMRole
-			// so that we can add the permissions
-			if resourceRef != nil {
+			// so that we can add the permissions. Self-managed Kafka sources
+			// don't have an EventSourceArn, but still require IAM privileges.
+			if resourceRef != nil || eachEventSource.EventSourceArn == nil {
 				annotationErr := visitEventSourceMappingRef(eachLambda,
 					eachEventSource,
 					eachIndex,
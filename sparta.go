@@ -17,6 +17,7 @@ import (
 
 	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
 	spartaIAM "github.com/mweagle/Sparta/aws/iam"
+	spartaSchema "github.com/mweagle/Sparta/aws/schema"
 	"github.com/mweagle/Sparta/system"
 	gocc "github.com/mweagle/go-cloudcondenser"
 	gocf "github.com/mweagle/go-cloudformation"
@@ -95,6 +96,7 @@ var CommonIAMStatements = struct {
 	DynamoDB []spartaIAM.PolicyStatement
 	Kinesis  []spartaIAM.PolicyStatement
 	SQS      []spartaIAM.PolicyStatement
+	MSK      []spartaIAM.PolicyStatement
 }{
 	Core: []spartaIAM.PolicyStatement{
 		{
@@ -164,6 +166,31 @@ var CommonIAMStatements = struct {
 			},
 		},
 	},
+	// https://docs.aws.amazon.com/lambda/latest/dg/with-msk.html#msk-permissions
+	MSK: []spartaIAM.PolicyStatement{
+		{
+			Effect: "Allow",
+			Action: []string{"kafka:DescribeCluster",
+				"kafka:DescribeClusterV2",
+				"kafka:GetBootstrapBrokers",
+			},
+		},
+		{
+			Effect:   "Allow",
+			Action:   kafkaNetworkActions,
+			Resource: gocf.String("*"),
+		},
+	},
+}
+
+// kafkaNetworkActions are the privileges required to consume from a Kafka
+// cluster in a VPC
+var kafkaNetworkActions = []string{"ec2:CreateNetworkInterface",
+	"ec2:DescribeNetworkInterfaces",
+	"ec2:DeleteNetworkInterface",
+	"ec2:DescribeVpcs",
+	"ec2:DescribeSubnets",
+	"ec2:DescribeSecurityGroups",
 }
 
 // RE for sanitizing names
@@ -237,6 +264,13 @@ type LambdaFunctionOptions struct {
 	// discards events after the maximum number of retries. For more information,
 	// see Dead Letter Queues in the AWS Lambda Developer Guide.
 	DeadLetterConfigArn gocf.Stringable
+	// ManagedDeadLetterQueue provisions an SQS Dead Letter Queue for the
+	// function. It can't be combined with DeadLetterConfigArn.
+	ManagedDeadLetterQueue *ManagedDeadLetterQueue
+	// EventInvokeConfig configures the retries, maximum event age and
+	// destinations of asynchronous invocations. Unlike a Dead Letter Queue,
+	// destinations receive the invocation record, including the response.
+	EventInvokeConfig *EventInvokeConfig
 	// Tags to associate with the Lambda function
 	Tags map[string]string
 	// Tracing options for XRay
@@ -245,6 +279,184 @@ type LambdaFunctionOptions struct {
 	SpartaOptions *SpartaOptions
 }
 
+// ManagedDeadLetterQueue provisions an SQS Dead Letter Queue (DLQ) for the
+// asynchronous events that the function fails to process, together with a
+// CloudWatch alarm on the number of queued events. The `replay` command
+// re-invokes the function with the queued events. See
+// https://docs.aws.amazon.com/lambda/latest/dg/invocation-async.html#dlq
+type ManagedDeadLetterQueue struct {
+	// MessageRetentionPeriod is the number of seconds (60-1209600) that
+	// events are kept. The default is the maximum, 14 days.
+	MessageRetentionPeriod int64
+	// AlarmThreshold is the number of queued events that triggers the
+	// alarm. The default is 1.
+	AlarmThreshold int64
+	// AlarmActions are notified (eg, SNS topic ARNs) when the alarm
+	// changes to the ALARM state
+	AlarmActions []gocf.Stringable
+}
+
+// managedDeadLetterQueueResourceName returns the logical resource name of the
+// DLQ provisioned for the function
+func managedDeadLetterQueueResourceName(lambdaLogicalResourceName string) string {
+	return CloudFormationResourceName(fmt.Sprintf("%sDLQ", lambdaLogicalResourceName),
+		lambdaLogicalResourceName)
+}
+
+// validate ensures the queue properties are within the AWS limits
+func (dlq *ManagedDeadLetterQueue) validate() error {
+	if dlq.MessageRetentionPeriod != 0 &&
+		(dlq.MessageRetentionPeriod < 60 || dlq.MessageRetentionPeriod > 1209600) {
+		return errors.Errorf("MessageRetentionPeriod must be between 60 and 1209600, got: %d",
+			dlq.MessageRetentionPeriod)
+	}
+	if dlq.AlarmThreshold < 0 {
+		return errors.Errorf("AlarmThreshold must not be negative, got: %d", dlq.AlarmThreshold)
+	}
+	return nil
+}
+
+// export adds the queue and alarm resources and returns the queue ARN
+func (dlq *ManagedDeadLetterQueue) export(lambdaLogicalResourceName string,
+	template *gocf.Template) (*gocf.StringExpr, error) {
+	validationErr := dlq.validate()
+	if validationErr != nil {
+		return nil, validationErr
+	}
+	messageRetentionPeriod := dlq.MessageRetentionPeriod
+	if messageRetentionPeriod == 0 {
+		messageRetentionPeriod = 1209600
+	}
+	alarmThreshold := dlq.AlarmThreshold
+	if alarmThreshold == 0 {
+		alarmThreshold = 1
+	}
+	dlqResourceName := managedDeadLetterQueueResourceName(lambdaLogicalResourceName)
+	template.AddResource(dlqResourceName, &gocf.SQSQueue{
+		MessageRetentionPeriod: gocf.Integer(messageRetentionPeriod),
+	})
+	alarm := &gocf.CloudWatchAlarm{
+		AlarmDescription: gocf.Join(" ",
+			gocf.String("Dead Letter Queue for AWS Lambda function"),
+			gocf.Ref(lambdaLogicalResourceName),
+			gocf.String("( Stack:"),
+			gocf.Ref("AWS::StackName"),
+			gocf.String(") has at least"),
+			gocf.String(fmt.Sprintf("%d", alarmThreshold)),
+			gocf.String("queued events"),
+		),
+		MetricName:         gocf.String("ApproximateNumberOfMessagesVisible"),
+		Namespace:          gocf.String("AWS/SQS"),
+		Statistic:          gocf.String("Maximum"),
+		Period:             gocf.Integer(300),
+		EvaluationPeriods:  gocf.Integer(1),
+		Threshold:          gocf.Integer(alarmThreshold),
+		ComparisonOperator: gocf.String("GreaterThanOrEqualToThreshold"),
+		Dimensions: &gocf.CloudWatchAlarmDimensionList{
+			gocf.CloudWatchAlarmDimension{
+				Name:  gocf.String("QueueName"),
+				Value: gocf.GetAtt(dlqResourceName, "QueueName"),
+			},
+		},
+		TreatMissingData: gocf.String("notBreaching"),
+	}
+	if len(dlq.AlarmActions) != 0 {
+		alarm.AlarmActions = gocf.StringList(dlq.AlarmActions...)
+	}
+	template.AddResource(CloudFormationResourceName("Alarm", dlqResourceName), alarm)
+	template.Outputs[dlqResourceName] = &gocf.Output{
+		Description: "Dead letter queue URL",
+		Value:       gocf.Ref(dlqResourceName),
+	}
+	return gocf.GetAtt(dlqResourceName, "Arn"), nil
+}
+
+// EventInvokeConfig defines how Lambda handles asynchronous invocations of
+// a function. Each destination is an SQS queue, SNS topic, EventBridge event
+// bus or Lambda function ARN, or a *LambdaAWSInfo in the same service. The
+// function's IAM role is granted permission to send to the destinations.
+// See https://docs.aws.amazon.com/lambda/latest/dg/invocation-async.html
+type EventInvokeConfig struct {
+	// MaximumRetryAttempts is the number of times (0-2) a failed invocation
+	// is retried. The nil default retries twice.
+	MaximumRetryAttempts *int64
+	// MaximumEventAgeInSeconds is the maximum age (60-21600) of an event
+	// that's sent to the function. The default is 21600.
+	MaximumEventAgeInSeconds int64
+	// OnSuccessDestination receives the records of successful invocations
+	OnSuccessDestination interface{}
+	// OnFailureDestination receives the records of invocations that failed
+	// after all retries, or that exceeded the maximum event age
+	OnFailureDestination interface{}
+}
+
+// eventInvokeDestinationArn returns the destination ARN expression
+func eventInvokeDestinationArn(destination interface{}) *gocf.StringExpr {
+	if lambdaDestination, isLambda := destination.(*LambdaAWSInfo); isLambda {
+		return gocf.GetAtt(lambdaDestination.LogicalResourceName(), "Arn")
+	}
+	return spartaCF.DynamicValueToStringExpr(destination).String()
+}
+
+// validate ensures the configuration is within the AWS limits
+func (config *EventInvokeConfig) validate() error {
+	if config.MaximumRetryAttempts != nil &&
+		(*config.MaximumRetryAttempts < 0 || *config.MaximumRetryAttempts > 2) {
+		return errors.Errorf("MaximumRetryAttempts must be between 0 and 2, got: %d",
+			*config.MaximumRetryAttempts)
+	}
+	if config.MaximumEventAgeInSeconds != 0 &&
+		(config.MaximumEventAgeInSeconds < 60 || config.MaximumEventAgeInSeconds > 21600) {
+		return errors.Errorf("MaximumEventAgeInSeconds must be between 60 and 21600, got: %d",
+			config.MaximumEventAgeInSeconds)
+	}
+	for _, eachDestination := range []interface{}{config.OnSuccessDestination,
+		config.OnFailureDestination} {
+		if lambdaDestination, isLambda := eachDestination.(*LambdaAWSInfo); isLambda &&
+			lambdaDestination == nil {
+			return errors.Errorf("EventInvokeConfig Lambda destination must not be nil")
+		}
+	}
+	return nil
+}
+
+// export adds the AWS::Lambda::EventInvokeConfig resource for the function
+func (config *EventInvokeConfig) export(lambdaLogicalResourceName string,
+	template *gocf.Template) error {
+	validationErr := config.validate()
+	if validationErr != nil {
+		return validationErr
+	}
+	invokeConfigResource := &spartaCF.LambdaEventInvokeConfig{
+		FunctionName: gocf.Ref(lambdaLogicalResourceName).String(),
+		Qualifier:    gocf.String("$LATEST"),
+	}
+	if config.MaximumRetryAttempts != nil {
+		invokeConfigResource.MaximumRetryAttempts = gocf.Integer(*config.MaximumRetryAttempts)
+	}
+	if config.MaximumEventAgeInSeconds != 0 {
+		invokeConfigResource.MaximumEventAgeInSeconds = gocf.Integer(config.MaximumEventAgeInSeconds)
+	}
+	if config.OnSuccessDestination != nil || config.OnFailureDestination != nil {
+		destinationConfig := &spartaCF.LambdaEventInvokeConfigDestinationConfig{}
+		if config.OnSuccessDestination != nil {
+			destinationConfig.OnSuccess = &spartaCF.LambdaEventInvokeConfigDestination{
+				Destination: eventInvokeDestinationArn(config.OnSuccessDestination),
+			}
+		}
+		if config.OnFailureDestination != nil {
+			destinationConfig.OnFailure = &spartaCF.LambdaEventInvokeConfigDestination{
+				Destination: eventInvokeDestinationArn(config.OnFailureDestination),
+			}
+		}
+		invokeConfigResource.DestinationConfig = destinationConfig
+	}
+	template.AddResource(CloudFormationResourceName(fmt.Sprintf("%sEventInvokeConfig", lambdaLogicalResourceName),
+		lambdaLogicalResourceName),
+		invokeConfigResource)
+	return nil
+}
+
 func defaultLambdaFunctionOptions() *LambdaFunctionOptions {
 	return &LambdaFunctionOptions{Description: "",
 		MemorySize:                   128,
@@ -422,6 +634,105 @@ func (roleDefinition *IAMRoleDefinition) logicalName(serviceName string, targetL
 ////////////////////////////////////////////////////////////////////////////////
 // START - EventSourceMapping
 
+// Kafka SourceAccessConfiguration types for the KafkaEventSource
+// AuthenticationType
+const (
+	// KafkaAuthenticationBasic authenticates to a self-managed cluster with
+	// SASL/PLAIN
+	KafkaAuthenticationBasic = "BASIC_AUTH"
+	// KafkaAuthenticationSASLSCRAM256 authenticates to a self-managed cluster
+	// with SASL/SCRAM-SHA-256
+	KafkaAuthenticationSASLSCRAM256 = "SASL_SCRAM_256_AUTH"
+	// KafkaAuthenticationSASLSCRAM512 authenticates with SASL/SCRAM-SHA-512
+	KafkaAuthenticationSASLSCRAM512 = "SASL_SCRAM_512_AUTH"
+	// KafkaAuthenticationClientCertificateTLS authenticates with mutual TLS
+	KafkaAuthenticationClientCertificateTLS = "CLIENT_CERTIFICATE_TLS_AUTH"
+)
+
+// KafkaEventSource defines the Apache Kafka properties of an
+// EventSourceMapping. For an Amazon MSK cluster, the EventSourceMapping's
+// EventSourceArn is the cluster ARN. For a self-managed cluster, the
+// EventSourceArn is nil and BootstrapServers lists the brokers.
+type KafkaEventSource struct {
+	// Topics to consume. Lambda supports a single topic per mapping.
+	Topics []string
+	// ConsumerGroupID is the optional consumer group to join
+	ConsumerGroupID string
+	// BootstrapServers are the "host:port" brokers of a self-managed
+	// cluster
+	BootstrapServers []string
+	// AuthenticationType is the type of the AuthenticationSecretArn
+	// credentials (eg, KafkaAuthenticationSASLSCRAM512)
+	AuthenticationType string
+	// AuthenticationSecretArn is the ARN of the Secrets Manager secret with
+	// the credentials. The IAM role is granted permission to read it.
+	AuthenticationSecretArn interface{}
+	// VPCSubnets are the subnet IDs used to reach a self-managed cluster in
+	// a VPC
+	VPCSubnets []string
+	// VPCSecurityGroups are the security group IDs used to reach a
+	// self-managed cluster in a VPC
+	VPCSecurityGroups []string
+}
+
+// isSelfManaged returns true if the source is a self-managed cluster
+func (kafkaSource *KafkaEventSource) isSelfManaged() bool {
+	return len(kafkaSource.BootstrapServers) != 0
+}
+
+// validate ensures the Kafka properties are consistent with the type of
+// cluster
+func (kafkaSource *KafkaEventSource) validate(eventSourceArn interface{}) error {
+	if len(kafkaSource.Topics) != 1 {
+		return errors.Errorf("Kafka event sources require exactly one topic, got: %d",
+			len(kafkaSource.Topics))
+	}
+	if kafkaSource.isSelfManaged() && eventSourceArn != nil {
+		return errors.Errorf("Kafka event sources with BootstrapServers must not define an EventSourceArn")
+	}
+	if !kafkaSource.isSelfManaged() && eventSourceArn == nil {
+		return errors.Errorf("Kafka event sources require either an MSK cluster EventSourceArn or BootstrapServers")
+	}
+	if len(kafkaSource.ConsumerGroupID) > 200 {
+		return errors.Errorf("ConsumerGroupID must be at most 200 characters, got: %d",
+			len(kafkaSource.ConsumerGroupID))
+	}
+	if (kafkaSource.AuthenticationType == "") != (kafkaSource.AuthenticationSecretArn == nil) {
+		return errors.Errorf("Kafka AuthenticationType and AuthenticationSecretArn must be defined together")
+	}
+	if (len(kafkaSource.VPCSubnets) == 0) != (len(kafkaSource.VPCSecurityGroups) == 0) {
+		return errors.Errorf("Kafka VPCSubnets and VPCSecurityGroups must be defined together")
+	}
+	if !kafkaSource.isSelfManaged() && len(kafkaSource.VPCSubnets) != 0 {
+		return errors.Errorf("Kafka VPC settings are only supported for self-managed clusters")
+	}
+	return nil
+}
+
+// sourceAccessConfigurations returns the authentication and VPC settings
+func (kafkaSource *KafkaEventSource) sourceAccessConfigurations() []spartaCF.LambdaEventSourceMappingSourceAccessConfig {
+	accessConfigs := []spartaCF.LambdaEventSourceMappingSourceAccessConfig{}
+	if kafkaSource.AuthenticationSecretArn != nil {
+		accessConfigs = append(accessConfigs, spartaCF.LambdaEventSourceMappingSourceAccessConfig{
+			Type: gocf.String(kafkaSource.AuthenticationType),
+			URI:  spartaCF.DynamicValueToStringExpr(kafkaSource.AuthenticationSecretArn).String(),
+		})
+	}
+	for _, eachSubnet := range kafkaSource.VPCSubnets {
+		accessConfigs = append(accessConfigs, spartaCF.LambdaEventSourceMappingSourceAccessConfig{
+			Type: gocf.String("VPC_SUBNET"),
+			URI:  gocf.String(fmt.Sprintf("subnet:%s", eachSubnet)),
+		})
+	}
+	for _, eachSecurityGroup := range kafkaSource.VPCSecurityGroups {
+		accessConfigs = append(accessConfigs, spartaCF.LambdaEventSourceMappingSourceAccessConfig{
+			Type: gocf.String("VPC_SECURITY_GROUP"),
+			URI:  gocf.String(fmt.Sprintf("security_group:%s", eachSecurityGroup)),
+		})
+	}
+	return accessConfigs
+}
+
 // EventSourceMapping specifies data necessary for pull-based configuration. The fields
 // directly correspond to the golang AWS SDK's CreateEventSourceMappingInput
 // (http://docs.aws.amazon.com/sdk-for-go/api/service/lambda.html#type-CreateEventSourceMappingInput)
@@ -430,6 +741,90 @@ type EventSourceMapping struct {
 	EventSourceArn   interface{}
 	Disabled         bool
 	BatchSize        int64
+	// StartingPositionTimestamp is the time to start reading from a Kinesis
+	// stream. It requires the AT_TIMESTAMP StartingPosition.
+	StartingPositionTimestamp *time.Time
+	// MaximumBatchingWindowInSeconds is the maximum time to gather records
+	// before invoking the function
+	MaximumBatchingWindowInSeconds int64
+	// MaximumRecordAgeInSeconds discards stream records older than the
+	// age. -1 keeps records until they expire.
+	MaximumRecordAgeInSeconds int64
+	// MaximumRetryAttempts is the number of times a failed stream batch is
+	// retried. The nil default retries until the record expires.
+	MaximumRetryAttempts *int64
+	// BisectBatchOnFunctionError splits a failed stream batch in two
+	// before retrying
+	BisectBatchOnFunctionError bool
+	// ParallelizationFactor is the number of batches (1-10) processed
+	// concurrently from each stream shard
+	ParallelizationFactor int64
+	// OnFailureDestinationArn is the SQS queue or SNS topic ARN that receives
+	// the details of discarded stream batches. The IAM role is granted
+	// permission to send to the destination.
+	OnFailureDestinationArn interface{}
+	// FilterPatterns are the event filter patterns. Only records that match
+	// a pattern invoke the function.
+	// Ref: https://docs.aws.amazon.com/lambda/latest/dg/invocation-eventfiltering.html
+	FilterPatterns []ArbitraryJSONObject
+	// FunctionResponseTypes are the response types the function supports.
+	// Include "ReportBatchItemFailures" to retry only the failed records.
+	// Ref: https://docs.aws.amazon.com/lambda/latest/dg/with-sqs.html#services-sqs-batchfailurereporting
+	FunctionResponseTypes []string
+	// Kafka defines the topic and cluster settings of an Amazon MSK or
+	// self-managed Apache Kafka event source
+	Kafka *KafkaEventSource
+}
+
+// eventSourceExpr returns the expression that identifies the event source.
+// Self-managed Kafka clusters are identified by their bootstrap servers.
+func (mapping *EventSourceMapping) eventSourceExpr() *gocf.StringExpr {
+	if mapping.Kafka != nil && mapping.Kafka.isSelfManaged() {
+		return gocf.String(strings.Join(mapping.Kafka.BootstrapServers, ","))
+	}
+	return spartaCF.DynamicValueToStringExpr(mapping.EventSourceArn).String()
+}
+
+// validate ensures the mapping properties are within the AWS limits
+func (mapping *EventSourceMapping) validate() error {
+	if mapping.Kafka != nil {
+		kafkaErr := mapping.Kafka.validate(mapping.EventSourceArn)
+		if kafkaErr != nil {
+			return kafkaErr
+		}
+		if mapping.StartingPosition != "TRIM_HORIZON" && mapping.StartingPosition != "LATEST" {
+			return errors.Errorf("Kafka event sources require the TRIM_HORIZON or LATEST StartingPosition, got: %s",
+				mapping.StartingPosition)
+		}
+	} else if mapping.EventSourceArn == nil {
+		return errors.Errorf("EventSourceArn is required")
+	}
+	if mapping.StartingPositionTimestamp != nil &&
+		mapping.StartingPosition != "AT_TIMESTAMP" {
+		return errors.Errorf("StartingPositionTimestamp requires the AT_TIMESTAMP StartingPosition, got: %s",
+			mapping.StartingPosition)
+	}
+	if mapping.MaximumBatchingWindowInSeconds < 0 ||
+		mapping.MaximumBatchingWindowInSeconds > 300 {
+		return errors.Errorf("MaximumBatchingWindowInSeconds must be between 0 and 300, got: %d",
+			mapping.MaximumBatchingWindowInSeconds)
+	}
+	if mapping.MaximumRecordAgeInSeconds != 0 &&
+		mapping.MaximumRecordAgeInSeconds != -1 &&
+		(mapping.MaximumRecordAgeInSeconds < 60 || mapping.MaximumRecordAgeInSeconds > 604800) {
+		return errors.Errorf("MaximumRecordAgeInSeconds must be -1 or between 60 and 604800, got: %d",
+			mapping.MaximumRecordAgeInSeconds)
+	}
+	if mapping.MaximumRetryAttempts != nil &&
+		(*mapping.MaximumRetryAttempts < -1 || *mapping.MaximumRetryAttempts > 10000) {
+		return errors.Errorf("MaximumRetryAttempts must be between -1 and 10000, got: %d",
+			*mapping.MaximumRetryAttempts)
+	}
+	if mapping.ParallelizationFactor < 0 || mapping.ParallelizationFactor > 10 {
+		return errors.Errorf("ParallelizationFactor must be between 1 and 10, got: %d",
+			mapping.ParallelizationFactor)
+	}
+	return nil
 }
 
 func (mapping *EventSourceMapping) export(serviceName string,
@@ -440,26 +835,125 @@ func (mapping *EventSourceMapping) export(serviceName string,
 	template *gocf.Template,
 	logger *logrus.Logger) error {
 
-	dynamicArn := spartaCF.DynamicValueToStringExpr(mapping.EventSourceArn)
-	eventSourceMappingResource := gocf.LambdaEventSourceMapping{
-		EventSourceArn: dynamicArn.String(),
-		FunctionName:   targetLambdaArn,
-		BatchSize:      gocf.Integer(mapping.BatchSize),
-		Enabled:        gocf.Bool(!mapping.Disabled),
+	validationErr := mapping.validate()
+	if validationErr != nil {
+		return errors.Wrapf(validationErr, "Invalid EventSourceMapping for %s", targetLambdaName)
+	}
+	eventSourceExpr := mapping.eventSourceExpr()
+	eventSourceMappingResource := spartaCF.LambdaEventSourceMapping{
+		FunctionName: targetLambdaArn,
+		BatchSize:    gocf.Integer(mapping.BatchSize),
+		Enabled:      gocf.Bool(!mapping.Disabled),
+	}
+	if mapping.EventSourceArn != nil {
+		eventSourceMappingResource.EventSourceArn = eventSourceExpr
 	}
 	if mapping.StartingPosition != "" {
 		eventSourceMappingResource.StartingPosition = gocf.String(mapping.StartingPosition)
 	}
 
 	// Unique components for the hash for the EventSource mapping
-	// resource name
+	// resource name. The optional properties are only included when
+	// they're set so that existing mappings keep the same name.
 	hashParts := []string{
 		targetLambdaName,
-		dynamicArn.String().Literal,
+		eventSourceExpr.Literal,
 		targetLambdaArn.Literal,
 		fmt.Sprintf("%d", mapping.BatchSize),
 		mapping.StartingPosition,
 	}
+	if mapping.StartingPositionTimestamp != nil {
+		eventSourceMappingResource.StartingPositionTimestamp = float64(mapping.StartingPositionTimestamp.Unix())
+		hashParts = append(hashParts,
+			fmt.Sprintf("StartingPositionTimestamp=%d", mapping.StartingPositionTimestamp.Unix()))
+	}
+	if mapping.MaximumBatchingWindowInSeconds != 0 {
+		eventSourceMappingResource.MaximumBatchingWindowInSeconds = gocf.Integer(mapping.MaximumBatchingWindowInSeconds)
+		hashParts = append(hashParts,
+			fmt.Sprintf("MaximumBatchingWindowInSeconds=%d", mapping.MaximumBatchingWindowInSeconds))
+	}
+	if mapping.MaximumRecordAgeInSeconds != 0 {
+		eventSourceMappingResource.MaximumRecordAgeInSeconds = gocf.Integer(mapping.MaximumRecordAgeInSeconds)
+		hashParts = append(hashParts,
+			fmt.Sprintf("MaximumRecordAgeInSeconds=%d", mapping.MaximumRecordAgeInSeconds))
+	}
+	if mapping.MaximumRetryAttempts != nil {
+		eventSourceMappingResource.MaximumRetryAttempts = gocf.Integer(*mapping.MaximumRetryAttempts)
+		hashParts = append(hashParts,
+			fmt.Sprintf("MaximumRetryAttempts=%d", *mapping.MaximumRetryAttempts))
+	}
+	if mapping.BisectBatchOnFunctionError {
+		eventSourceMappingResource.BisectBatchOnFunctionError = gocf.Bool(true)
+		hashParts = append(hashParts, "BisectBatchOnFunctionError")
+	}
+	if mapping.ParallelizationFactor != 0 {
+		eventSourceMappingResource.ParallelizationFactor = gocf.Integer(mapping.ParallelizationFactor)
+		hashParts = append(hashParts,
+			fmt.Sprintf("ParallelizationFactor=%d", mapping.ParallelizationFactor))
+	}
+	if mapping.OnFailureDestinationArn != nil {
+		destinationArn := spartaCF.DynamicValueToStringExpr(mapping.OnFailureDestinationArn).String()
+		eventSourceMappingResource.DestinationConfig = &spartaCF.LambdaEventSourceMappingDestinationConfig{
+			OnFailure: &spartaCF.LambdaEventSourceMappingOnFailure{
+				Destination: destinationArn,
+			},
+		}
+		destinationJSON, destinationJSONErr := json.Marshal(destinationArn)
+		if destinationJSONErr != nil {
+			return errors.Wrapf(destinationJSONErr,
+				"Failed to marshal EventSourceMapping OnFailure destination")
+		}
+		hashParts = append(hashParts, fmt.Sprintf("OnFailure=%s", string(destinationJSON)))
+	}
+	if len(mapping.FilterPatterns) != 0 {
+		filterCriteria := &spartaCF.LambdaEventSourceMappingFilterCriteria{}
+		for _, eachPattern := range mapping.FilterPatterns {
+			patternJSON, patternJSONErr := json.Marshal(eachPattern)
+			if patternJSONErr != nil {
+				return errors.Wrapf(patternJSONErr,
+					"Failed to marshal EventSourceMapping filter pattern")
+			}
+			filterCriteria.Filters = append(filterCriteria.Filters,
+				spartaCF.LambdaEventSourceMappingFilter{
+					Pattern: string(patternJSON),
+				})
+			hashParts = append(hashParts, fmt.Sprintf("Filter=%s", string(patternJSON)))
+		}
+		eventSourceMappingResource.FilterCriteria = filterCriteria
+	}
+	if len(mapping.FunctionResponseTypes) != 0 {
+		responseTypes := make([]gocf.Stringable, len(mapping.FunctionResponseTypes))
+		for index, eachResponseType := range mapping.FunctionResponseTypes {
+			responseTypes[index] = gocf.String(eachResponseType)
+		}
+		eventSourceMappingResource.FunctionResponseTypes = gocf.StringList(responseTypes...)
+		hashParts = append(hashParts,
+			fmt.Sprintf("FunctionResponseTypes=%s", strings.Join(mapping.FunctionResponseTypes, ",")))
+	}
+	if mapping.Kafka != nil {
+		kafkaSource := mapping.Kafka
+		eventSourceMappingResource.Topics = stringListExpr(kafkaSource.Topics)
+		var kafkaConfig *spartaCF.LambdaEventSourceMappingKafkaConfig
+		if kafkaSource.ConsumerGroupID != "" {
+			kafkaConfig = &spartaCF.LambdaEventSourceMappingKafkaConfig{
+				ConsumerGroupID: gocf.String(kafkaSource.ConsumerGroupID),
+			}
+		}
+		if kafkaSource.isSelfManaged() {
+			eventSourceMappingResource.SelfManagedEventSource = &spartaCF.LambdaEventSourceMappingSelfManagedSource{
+				Endpoints: &spartaCF.LambdaEventSourceMappingEndpoints{
+					KafkaBootstrapServers: stringListExpr(kafkaSource.BootstrapServers),
+				},
+			}
+			eventSourceMappingResource.SelfManagedKafkaEventSourceConfig = kafkaConfig
+		} else {
+			eventSourceMappingResource.AmazonManagedKafkaEventSourceConfig = kafkaConfig
+		}
+		eventSourceMappingResource.SourceAccessConfigurations = kafkaSource.sourceAccessConfigurations()
+		hashParts = append(hashParts,
+			fmt.Sprintf("Topics=%s", strings.Join(kafkaSource.Topics, ",")),
+			fmt.Sprintf("ConsumerGroupID=%s", kafkaSource.ConsumerGroupID))
+	}
 	hash := sha1.New()
 	for _, eachHashPart := range hashParts {
 		_, writeErr := hash.Write([]byte(eachHashPart))
@@ -722,6 +1216,10 @@ type LambdaAWSInfo struct {
 
 	// interceptors
 	Interceptors *LambdaEventInterceptors
+
+	// API Gateway request body schemas, keyed by "METHOD /resource/path",
+	// that are validated before the handler is called
+	requestValidators map[string]*spartaSchema.JSONSchema
 }
 
 // lambdaFunctionName returns the internal
@@ -845,6 +1343,13 @@ func (info *LambdaAWSInfo) LogicalResourceName() string {
 	return CloudFormationResourceName(prefix, info.lambdaFunctionName())
 }
 
+// HandlerSymbol returns the user supplied AWS Lambda handler function. It's
+// primarily used to invoke the handler in-process, as in
+// aws/step.LocalExecutor
+func (info *LambdaAWSInfo) HandlerSymbol() interface{} {
+	return info.handlerSymbol
+}
+
 func (info *LambdaAWSInfo) applyDecorators(template *gocf.Template,
 	lambdaResource gocf.LambdaFunction,
 	cfResource *gocf.Resource,
@@ -968,6 +1473,21 @@ func (info *LambdaAWSInfo) export(serviceName string,
 			TargetArn: info.Options.DeadLetterConfigArn.String(),
 		}
 	}
+	if info.Options.ManagedDeadLetterQueue != nil {
+		if info.Options.DeadLetterConfigArn != nil {
+			return errors.Errorf("function %s defines both DeadLetterConfigArn and ManagedDeadLetterQueue",
+				info.lambdaFunctionName())
+		}
+		dlqArn, dlqErr := info.Options.ManagedDeadLetterQueue.export(info.LogicalResourceName(),
+			template)
+		if dlqErr != nil {
+			return errors.Wrapf(dlqErr, "Invalid ManagedDeadLetterQueue for %s",
+				info.lambdaFunctionName())
+		}
+		lambdaResource.DeadLetterConfig = &gocf.LambdaFunctionDeadLetterConfig{
+			TargetArn: dlqArn,
+		}
+	}
 	if nil != info.Options.TracingConfig {
 		lambdaResource.TracingConfig = info.Options.TracingConfig
 	}
@@ -1013,6 +1533,16 @@ func (info *LambdaAWSInfo) export(serviceName string,
 	// Create the lambda Ref in case we need a permission or event mapping
 	functionAttr := gocf.GetAtt(info.LogicalResourceName(), "Arn")
 
+	// Asynchronous invocation configuration
+	if info.Options.EventInvokeConfig != nil {
+		invokeConfigErr := info.Options.EventInvokeConfig.export(info.LogicalResourceName(),
+			template)
+		if invokeConfigErr != nil {
+			return errors.Wrapf(invokeConfigErr, "Invalid EventInvokeConfig for %s",
+				info.lambdaFunctionName())
+		}
+	}
+
 	// Permissions
 	for _, eachPermission := range info.Permissions {
 		_, err := eachPermission.export(serviceName,
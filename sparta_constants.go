@@ -71,6 +71,14 @@ const (
 	EC2Principal = "ec2.amazonaws.com"
 	// @enum AWSPrincipal
 	LambdaPrincipal = "lambda.amazonaws.com"
+	// @enum AWSPrincipal
+	ElasticLoadBalancingPrincipal = "elasticloadbalancing.amazonaws.com"
+	// @enum AWSPrincipal
+	CognitoIdentityProviderPrincipal = "cognito-idp.amazonaws.com"
+	// @enum AWSPrincipal
+	IoTPrincipal = "iot.amazonaws.com"
+	// @enum AWSPrincipal
+	S3BatchOperationsPrincipal = "batchoperations.s3.amazonaws.com"
 )
 
 type contextKey int
@@ -83,6 +83,7 @@ var CommandLineOptions = struct {
 	Explore   *cobra.Command
 	Profile   *cobra.Command
 	Status    *cobra.Command
+	Replay    *cobra.Command
 }{}
 
 /*============================================================================*/
@@ -170,6 +171,17 @@ type optionsStatusStruct struct {
 
 var optionsStatus optionsStatusStruct
 
+/*============================================================================*/
+// Replay options
+type optionsReplayStruct struct {
+	FunctionName string `validate:"required"`
+	Rate         int    `validate:"min=1,max=1000"`
+	MaxEvents    int    `validate:"min=0"`
+	Local        bool   `validate:"-"`
+}
+
+var optionsReplay optionsReplayStruct
+
 /*============================================================================*/
 // Initialization
 // Initialize all the Cobra commands and their associated flags
@@ -327,6 +339,34 @@ func init() {
 		"r",
 		false,
 		"Redact AWS Account ID from report")
+
+	// Replay
+	CommandLineOptions.Replay = &cobra.Command{
+		Use:   "replay",
+		Short: "Replay the events in a function's Dead Letter Queue",
+		Long: `Drain the function's Dead Letter Queue and re-invoke the function with each event.
+Events are deleted once the function successfully processes them. Use --noop to list the events
+without invoking the function.`,
+		SilenceUsage: true,
+	}
+	CommandLineOptions.Replay.Flags().StringVar(&optionsReplay.FunctionName,
+		"function",
+		"",
+		"Name of the function whose Dead Letter Queue should be replayed")
+	CommandLineOptions.Replay.Flags().IntVarP(&optionsReplay.Rate,
+		"rate",
+		"r",
+		1,
+		"Maximum number of events replayed per second (1-1000)")
+	CommandLineOptions.Replay.Flags().IntVarP(&optionsReplay.MaxEvents,
+		"max",
+		"m",
+		0,
+		"Maximum number of events to replay (default=0, all events)")
+	CommandLineOptions.Replay.Flags().BoolVar(&optionsReplay.Local,
+		"local",
+		false,
+		"Invoke the function handler in this process rather than the provisioned function")
 }
 
 // CommandLineOptionsHook allows embedding applications the ability
@@ -412,6 +452,7 @@ func ParseOptions(handler CommandLineOptionsHook) error {
 		CommandLineOptions.Explore,
 		CommandLineOptions.Profile,
 		CommandLineOptions.Status,
+		CommandLineOptions.Replay,
 	}
 	for _, eachCommand := range spartaCommands {
 		eachCommand.PreRunE = func(cmd *cobra.Command, args []string) error {
@@ -160,6 +160,19 @@ func Status(serviceName string,
 	return errors.New("Status not supported for this binary")
 }
 
+// Replay is the command that re-invokes a function with the events in its
+// Dead Letter Queue
+func Replay(serviceName string,
+	lambdaAWSInfos []*LambdaAWSInfo,
+	functionName string,
+	rate int,
+	maxEvents int,
+	local bool,
+	noop bool,
+	logger *logrus.Logger) error {
+	return errors.New("Replay not supported for this binary")
+}
+
 func platformLogSysInfo(lambdaFunc string, logger *logrus.Logger) {
 	var si sysinfo.SysInfo
 	si.GetSysInfo()
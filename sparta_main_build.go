@@ -295,6 +295,26 @@ func MainEx(serviceName string,
 	}
 	CommandLineOptions.Root.AddCommand(CommandLineOptions.Status)
 
+	//////////////////////////////////////////////////////////////////////////////
+	// Replay
+	if nil == CommandLineOptions.Replay.RunE {
+		CommandLineOptions.Replay.RunE = func(cmd *cobra.Command, args []string) error {
+			validateErr := validate.Struct(optionsReplay)
+			if nil != validateErr {
+				return validateErr
+			}
+			return Replay(serviceName,
+				lambdaAWSInfos,
+				optionsReplay.FunctionName,
+				optionsReplay.Rate,
+				optionsReplay.MaxEvents,
+				optionsReplay.Local,
+				OptionsGlobal.Noop,
+				OptionsGlobal.Logger)
+		}
+	}
+	CommandLineOptions.Root.AddCommand(CommandLineOptions.Replay)
+
 	// Run it!
 	executedCmd, executeErr := CommandLineOptions.Root.ExecuteC()
 	if executeErr != nil {
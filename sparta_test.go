@@ -4,11 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	spartaCF "github.com/mweagle/Sparta/aws/cloudformation"
 	spartaCFResources "github.com/mweagle/Sparta/aws/cloudformation/resources"
+	spartaIAM "github.com/mweagle/Sparta/aws/iam"
 	gocf "github.com/mweagle/go-cloudformation"
 )
 
@@ -272,3 +276,594 @@ func TestProvisionID(t *testing.T) {
 	}
 
 }
+
+// testTemplateResources returns the properties of the template resources
+// with the given CloudFormation resource type
+func testTemplateResources(template *gocf.Template, resourceType string) []gocf.ResourceProperties {
+	var resources []gocf.ResourceProperties
+	for _, eachResource := range template.Resources {
+		if eachResource.Properties.CfnResourceType() == resourceType {
+			resources = append(resources, eachResource.Properties)
+		}
+	}
+	return resources
+}
+
+// testExportMapping exports the EventSourceMapping for the TestLambda
+// function to a new template
+func testExportMapping(t *testing.T, mapping *EventSourceMapping) *gocf.Template {
+	template := gocf.NewTemplate()
+	exportErr := mapping.export("TestService",
+		"TestLambda",
+		gocf.GetAtt("TestLambda", "Arn"),
+		"",
+		"",
+		template,
+		nil)
+	if exportErr != nil {
+		t.Fatalf("Failed to export EventSourceMapping: %s", exportErr)
+	}
+	return template
+}
+
+func TestEventSourceMappingExport(t *testing.T) {
+	baseMapping := &EventSourceMapping{
+		StartingPosition: "TRIM_HORIZON",
+		EventSourceArn:   "arn:aws:kinesis:us-west-2:123412341234:stream/orders",
+		BatchSize:        100,
+	}
+	baseTemplate := testExportMapping(t, baseMapping)
+
+	maxRetries := int64(2)
+	errorHandlingMapping := *baseMapping
+	errorHandlingMapping.MaximumRetryAttempts = &maxRetries
+	errorHandlingMapping.MaximumRecordAgeInSeconds = 3600
+	errorHandlingMapping.BisectBatchOnFunctionError = true
+	errorHandlingMapping.ParallelizationFactor = 4
+	errorHandlingMapping.OnFailureDestinationArn = "arn:aws:sqs:us-west-2:123412341234:orders-dlq"
+	errorHandlingMapping.FilterPatterns = []ArbitraryJSONObject{
+		{"data": ArbitraryJSONObject{"status": []string{"NEW"}}},
+	}
+	errorHandlingTemplate := testExportMapping(t, &errorHandlingMapping)
+	for eachName := range baseTemplate.Resources {
+		if _, exists := errorHandlingTemplate.Resources[eachName]; exists {
+			t.Fatalf("Expected EventSourceMapping name to change with its configuration")
+		}
+	}
+	mappings := testTemplateResources(errorHandlingTemplate, "AWS::Lambda::EventSourceMapping")
+	if len(mappings) != 1 {
+		t.Fatalf("Expected one EventSourceMapping, got: %#v", errorHandlingTemplate.Resources)
+	}
+	mapping := mappings[0].(spartaCF.LambdaEventSourceMapping)
+	if mapping.MaximumRetryAttempts.Literal != 2 ||
+		mapping.MaximumRecordAgeInSeconds.Literal != 3600 ||
+		!mapping.BisectBatchOnFunctionError.Literal ||
+		mapping.ParallelizationFactor.Literal != 4 {
+		t.Fatalf("Unexpected EventSourceMapping error handling: %#v", mapping)
+	}
+	if mapping.DestinationConfig == nil ||
+		mapping.DestinationConfig.OnFailure.Destination.Literal != "arn:aws:sqs:us-west-2:123412341234:orders-dlq" {
+		t.Fatalf("Unexpected EventSourceMapping DestinationConfig: %#v", mapping.DestinationConfig)
+	}
+	if mapping.FilterCriteria == nil ||
+		len(mapping.FilterCriteria.Filters) != 1 ||
+		mapping.FilterCriteria.Filters[0].Pattern != `{"data":{"status":["NEW"]}}` {
+		t.Fatalf("Unexpected EventSourceMapping FilterCriteria: %#v", mapping.FilterCriteria)
+	}
+
+	// StartingPositionTimestamp requires the AT_TIMESTAMP StartingPosition
+	invalidMapping := *baseMapping
+	invalidMapping.StartingPositionTimestamp = aws.Time(time.Now())
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	lambdaFn.EventSourceMappings = append(lambdaFn.EventSourceMappings, &invalidMapping)
+	testProvision(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		assertError("StartingPositionTimestamp without AT_TIMESTAMP"))
+}
+
+func TestKafkaEventSourceMappingExport(t *testing.T) {
+	template := testExportMapping(t, &EventSourceMapping{
+		StartingPosition: "LATEST",
+		BatchSize:        100,
+		Kafka: &KafkaEventSource{
+			Topics:                  []string{"telemetry"},
+			ConsumerGroupID:         "telemetry-reactor",
+			BootstrapServers:        []string{"broker1:9096", "broker2:9096"},
+			AuthenticationType:      KafkaAuthenticationSASLSCRAM512,
+			AuthenticationSecretArn: "arn:aws:secretsmanager:us-west-2:123412341234:secret:kafka",
+			VPCSubnets:              []string{"subnet-1"},
+			VPCSecurityGroups:       []string{"sg-1"},
+		},
+	})
+	mappings := testTemplateResources(template, "AWS::Lambda::EventSourceMapping")
+	if len(mappings) != 1 {
+		t.Fatalf("Expected one EventSourceMapping, got: %#v", template.Resources)
+	}
+	mapping := mappings[0].(spartaCF.LambdaEventSourceMapping)
+	listValues := func(listExpr *gocf.StringListExpr) []string {
+		values := []string{}
+		if listExpr != nil {
+			for _, eachValue := range listExpr.Literal {
+				values = append(values, eachValue.Literal)
+			}
+		}
+		return values
+	}
+	if mapping.EventSourceArn != nil {
+		t.Fatalf("Unexpected EventSourceArn for self-managed Kafka: %#v", mapping.EventSourceArn)
+	}
+	if !reflect.DeepEqual(listValues(mapping.Topics), []string{"telemetry"}) {
+		t.Fatalf("Unexpected Kafka topics: %#v", mapping.Topics)
+	}
+	if mapping.SelfManagedEventSource == nil ||
+		!reflect.DeepEqual(listValues(mapping.SelfManagedEventSource.Endpoints.KafkaBootstrapServers),
+			[]string{"broker1:9096", "broker2:9096"}) {
+		t.Fatalf("Unexpected Kafka bootstrap servers: %#v", mapping.SelfManagedEventSource)
+	}
+	if mapping.SelfManagedKafkaEventSourceConfig == nil ||
+		mapping.SelfManagedKafkaEventSourceConfig.ConsumerGroupID.Literal != "telemetry-reactor" {
+		t.Fatalf("Unexpected Kafka consumer group: %#v", mapping.SelfManagedKafkaEventSourceConfig)
+	}
+	accessConfigs := make(map[string]string)
+	for _, eachConfig := range mapping.SourceAccessConfigurations {
+		accessConfigs[eachConfig.Type.Literal] = eachConfig.URI.Literal
+	}
+	expectedAccessConfigs := map[string]string{
+		"SASL_SCRAM_512_AUTH": "arn:aws:secretsmanager:us-west-2:123412341234:secret:kafka",
+		"VPC_SUBNET":          "subnet:subnet-1",
+		"VPC_SECURITY_GROUP":  "security_group:sg-1",
+	}
+	if !reflect.DeepEqual(accessConfigs, expectedAccessConfigs) {
+		t.Fatalf("Unexpected Kafka SourceAccessConfigurations: %#v", accessConfigs)
+	}
+
+	mskArn := "arn:aws:kafka:us-west-2:123412341234:cluster/telemetry/abcd"
+	invalidMappings := map[string]*EventSourceMapping{
+		"multiple topics": {
+			StartingPosition: "LATEST",
+			EventSourceArn:   mskArn,
+			Kafka:            &KafkaEventSource{Topics: []string{"a", "b"}},
+		},
+		"MSK VPC settings": {
+			StartingPosition: "LATEST",
+			EventSourceArn:   mskArn,
+			Kafka: &KafkaEventSource{
+				Topics:            []string{"telemetry"},
+				VPCSubnets:        []string{"subnet-1"},
+				VPCSecurityGroups: []string{"sg-1"},
+			},
+		},
+		"missing cluster": {
+			StartingPosition: "LATEST",
+			Kafka:            &KafkaEventSource{Topics: []string{"telemetry"}},
+		},
+		"AT_TIMESTAMP": {
+			StartingPosition: "AT_TIMESTAMP",
+			EventSourceArn:   mskArn,
+			Kafka:            &KafkaEventSource{Topics: []string{"telemetry"}},
+		},
+	}
+	for eachName, eachMapping := range invalidMappings {
+		lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+			mockLambda1,
+			IAMRoleDefinition{})
+		lambdaFn.EventSourceMappings = append(lambdaFn.EventSourceMappings, eachMapping)
+		testProvision(t,
+			[]*LambdaAWSInfo{lambdaFn},
+			assertError(fmt.Sprintf("Failed to reject invalid Kafka EventSourceMapping: %s", eachName)))
+	}
+}
+
+// testExportPermission exports the permission for the TestLambda function
+// to the template
+func testExportPermission(perm LambdaPermissionExporter, template *gocf.Template) error {
+	_, exportErr := perm.export("TestService",
+		"TestLambda",
+		"TestLambda",
+		template,
+		"",
+		"",
+		nil)
+	return exportErr
+}
+
+func TestALBPermissionExport(t *testing.T) {
+	template := gocf.NewTemplate()
+	exportErr := testExportPermission(ALBPermission{
+		LoadBalancer: &ALBLoadBalancer{
+			Subnets: []gocf.Stringable{gocf.String("subnet-1"), gocf.String("subnet-2")},
+		},
+		Priority:     1,
+		PathPatterns: []string{"/orders/*"},
+		HTTPHeaders: map[string][]string{
+			"X-Tenant": {"acme"},
+		},
+	}, template)
+	if exportErr != nil {
+		t.Fatalf("Failed to export ALBPermission: %s", exportErr)
+	}
+	for _, eachType := range []string{"AWS::Lambda::Permission",
+		"AWS::ElasticLoadBalancingV2::TargetGroup",
+		"AWS::ElasticLoadBalancingV2::LoadBalancer",
+		"AWS::ElasticLoadBalancingV2::Listener",
+		"AWS::ElasticLoadBalancingV2::ListenerRule"} {
+		if len(testTemplateResources(template, eachType)) != 1 {
+			t.Fatalf("Expected one %s resource, got: %#v", eachType, template.Resources)
+		}
+	}
+	permission := testTemplateResources(template, "AWS::Lambda::Permission")[0].(gocf.LambdaPermission)
+	if permission.Principal.Literal != ElasticLoadBalancingPrincipal {
+		t.Fatalf("Unexpected ALB invoke permission principal: %#v", permission.Principal)
+	}
+	targetGroup := testTemplateResources(template,
+		"AWS::ElasticLoadBalancingV2::TargetGroup")[0].(*spartaCF.ElasticLoadBalancingV2TargetGroup)
+	if targetGroup.TargetType.Literal != "lambda" ||
+		len(targetGroup.Targets) != 1 ||
+		!reflect.DeepEqual(targetGroup.Targets[0].ID, gocf.GetAtt("TestLambda", "Arn")) {
+		t.Fatalf("Unexpected TargetGroup targets: %#v", targetGroup)
+	}
+	if len(targetGroup.TargetGroupAttributes) != 1 ||
+		targetGroup.TargetGroupAttributes[0].Key.Literal != "lambda.multi_value_headers.enabled" ||
+		targetGroup.TargetGroupAttributes[0].Value.Literal != "false" {
+		t.Fatalf("Unexpected TargetGroup attributes: %#v", targetGroup.TargetGroupAttributes)
+	}
+	listener := testTemplateResources(template,
+		"AWS::ElasticLoadBalancingV2::Listener")[0].(*spartaCF.ElasticLoadBalancingV2Listener)
+	if len(listener.DefaultActions) != 1 ||
+		listener.DefaultActions[0].FixedResponseConfig.StatusCode.Literal != "404" {
+		t.Fatalf("Unexpected Listener default actions: %#v", listener.DefaultActions)
+	}
+	listenerRule := testTemplateResources(template,
+		"AWS::ElasticLoadBalancingV2::ListenerRule")[0].(*spartaCF.ElasticLoadBalancingV2ListenerRule)
+	if listenerRule.Priority.Literal != 1 || len(listenerRule.Conditions) != 2 {
+		t.Fatalf("Unexpected ListenerRule: %#v", listenerRule)
+	}
+	pathCondition := listenerRule.Conditions[0]
+	if pathCondition.Field.Literal != "path-pattern" ||
+		pathCondition.PathPatternConfig.Values.Literal[0].Literal != "/orders/*" {
+		t.Fatalf("Unexpected path-pattern condition: %#v", pathCondition)
+	}
+	headerCondition := listenerRule.Conditions[1]
+	if headerCondition.Field.Literal != "http-header" ||
+		headerCondition.HTTPHeaderConfig.HTTPHeaderName.Literal != "X-Tenant" ||
+		headerCondition.HTTPHeaderConfig.Values.Literal[0].Literal != "acme" {
+		t.Fatalf("Unexpected http-header condition: %#v", headerCondition)
+	}
+
+	// Rules for the same function and listener have distinct resources,
+	// unless they reuse a priority
+	template = gocf.NewTemplate()
+	for _, eachPermission := range []ALBPermission{
+		{ListenerArn: albListenerArn, Priority: 1, PathPatterns: []string{"/orders/*"}},
+		{ListenerArn: albListenerArn, Priority: 2, PathPatterns: []string{"/customers/*"}},
+	} {
+		if exportErr := testExportPermission(eachPermission, template); exportErr != nil {
+			t.Fatalf("Failed to export ALBPermission: %s", exportErr)
+		}
+	}
+	rulePriorities := make(map[int64]bool)
+	for _, eachRule := range testTemplateResources(template, "AWS::ElasticLoadBalancingV2::ListenerRule") {
+		rulePriorities[eachRule.(*spartaCF.ElasticLoadBalancingV2ListenerRule).Priority.Literal] = true
+	}
+	if len(testTemplateResources(template, "AWS::ElasticLoadBalancingV2::TargetGroup")) != 2 ||
+		!rulePriorities[1] ||
+		!rulePriorities[2] {
+		t.Fatalf("Expected a TargetGroup and ListenerRule per ALBPermission, got: %#v", template.Resources)
+	}
+	duplicateErr := testExportPermission(ALBPermission{
+		ListenerArn:  albListenerArn,
+		Priority:     2,
+		PathPatterns: []string{"/invoices/*"},
+	}, template)
+	if duplicateErr == nil {
+		t.Fatalf("Failed to reject ALBPermissions with the same listener and priority")
+	}
+
+	// A rule must have at least one condition
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	lambdaFn.Permissions = append(lambdaFn.Permissions, ALBPermission{
+		ListenerArn: albListenerArn,
+		Priority:    1,
+	})
+	testProvision(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		assertError("Failed to reject ALBPermission without conditions"))
+}
+
+func TestIoTTopicRulePermissionExport(t *testing.T) {
+	topicRuleSQL := "SELECT * FROM 'telemetry/#'"
+	template := gocf.NewTemplate()
+	exportErr := testExportPermission(IoTTopicRulePermission{
+		SQL: topicRuleSQL,
+		ErrorAction: &spartaCF.IoTTopicRuleAction{
+			SQS: &spartaCF.IoTTopicRuleSQSAction{
+				QueueURL: gocf.String("https://sqs.us-west-2.amazonaws.com/123412341234/errors"),
+				RoleArn:  gocf.String("arn:aws:iam::123412341234:role/iot-errors"),
+			},
+		},
+	}, template)
+	if exportErr != nil {
+		t.Fatalf("Failed to export IoTTopicRulePermission: %s", exportErr)
+	}
+	topicRules := testTemplateResources(template, "AWS::IoT::TopicRule")
+	permissions := testTemplateResources(template, "AWS::Lambda::Permission")
+	if len(topicRules) != 1 || len(permissions) != 1 {
+		t.Fatalf("Expected one topic rule and permission, got: %#v", template.Resources)
+	}
+	topicRulePayload := topicRules[0].(*spartaCF.IoTTopicRule).TopicRulePayload
+	if topicRulePayload.SQL.Literal != topicRuleSQL ||
+		topicRulePayload.AwsIotSQLVersion.Literal != IoTTopicRuleDefaultSQLVersion ||
+		topicRulePayload.RuleDisabled.Literal {
+		t.Fatalf("Unexpected topic rule payload: %#v", topicRulePayload)
+	}
+	if len(topicRulePayload.Actions) != 1 ||
+		!reflect.DeepEqual(topicRulePayload.Actions[0].Lambda.FunctionArn, gocf.GetAtt("TestLambda", "Arn")) {
+		t.Fatalf("Unexpected topic rule actions: %#v", topicRulePayload.Actions)
+	}
+	if topicRulePayload.ErrorAction.SQS == nil ||
+		topicRulePayload.ErrorAction.SQS.QueueURL.Literal != "https://sqs.us-west-2.amazonaws.com/123412341234/errors" {
+		t.Fatalf("Unexpected topic rule error action: %#v", topicRulePayload.ErrorAction)
+	}
+	permission := permissions[0].(gocf.LambdaPermission)
+	topicRuleArn := BasePermission{
+		SourceArn: gocf.GetAtt(CloudFormationResourceName("IoTTopicRule", "TestLambda", topicRuleSQL), "Arn"),
+	}.sourceArnExpr()
+	if permission.Principal.Literal != IoTPrincipal ||
+		!reflect.DeepEqual(permission.SourceArn, topicRuleArn) {
+		t.Fatalf("Unexpected IoT invoke permission: %#v", permission)
+	}
+
+	// Error actions must include the role
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	lambdaFn.Permissions = append(lambdaFn.Permissions, IoTTopicRulePermission{
+		SQL: topicRuleSQL,
+		ErrorAction: &spartaCF.IoTTopicRuleAction{
+			SNS: &spartaCF.IoTTopicRuleSNSAction{
+				TargetArn: gocf.String("arn:aws:sns:us-west-2:123412341234:errors"),
+			},
+		},
+	})
+	testProvision(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		assertError("Failed to reject ErrorAction without a RoleArn"))
+}
+
+func TestS3ObjectLambdaPermissionExport(t *testing.T) {
+	objectLambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	objectLambdaFn.Permissions = append(objectLambdaFn.Permissions, S3ObjectLambdaPermission{
+		BasePermission: BasePermission{
+			SourceArn: "arn:aws:s3:::sparta-documents",
+		},
+		Name:            "redacted-documents",
+		Actions:         []string{"GetObject", "HeadObject"},
+		AllowedFeatures: []string{"GetObject-Range"},
+	})
+	testProvision(t, []*LambdaAWSInfo{objectLambdaFn}, nil)
+
+	template := gocf.NewTemplate()
+	exportErr := testExportPermission(S3ObjectLambdaPermission{
+		BasePermission: BasePermission{
+			SourceArn: "arn:aws:s3:::sparta-documents",
+		},
+		Name:            "redacted-documents",
+		Actions:         []string{"GetObject", "HeadObject"},
+		AllowedFeatures: []string{"GetObject-Range"},
+		Payload:         "redact=ssn",
+	}, template)
+	if exportErr != nil {
+		t.Fatalf("Failed to export S3ObjectLambdaPermission: %s", exportErr)
+	}
+	accessPoints := testTemplateResources(template, "AWS::S3::AccessPoint")
+	objectLambdaAccessPoints := testTemplateResources(template, "AWS::S3ObjectLambda::AccessPoint")
+	if len(accessPoints) != 1 || len(objectLambdaAccessPoints) != 1 || len(template.Outputs) != 1 {
+		t.Fatalf("Expected access point resources and output, got: %#v", template.Resources)
+	}
+	accessPoint := accessPoints[0].(*spartaCF.S3AccessPoint)
+	if accessPoint.Bucket.Literal != "sparta-documents" ||
+		accessPoint.Name.Literal != "redacted-documents-ap" {
+		t.Fatalf("Unexpected supporting access point: %#v", accessPoint)
+	}
+	objectLambdaAccessPoint := objectLambdaAccessPoints[0].(*spartaCF.S3ObjectLambdaAccessPoint)
+	objectLambdaConfig := objectLambdaAccessPoint.ObjectLambdaConfiguration
+	accessPointResourceName := CloudFormationResourceName("S3AccessPoint",
+		"TestLambda",
+		"redacted-documents-ap")
+	if objectLambdaAccessPoint.Name.Literal != "redacted-documents" ||
+		!reflect.DeepEqual(objectLambdaConfig.SupportingAccessPoint, gocf.GetAtt(accessPointResourceName, "Arn")) ||
+		!reflect.DeepEqual(objectLambdaConfig.AllowedFeatures, stringListExpr([]string{"GetObject-Range"})) {
+		t.Fatalf("Unexpected Object Lambda access point: %#v", objectLambdaAccessPoint)
+	}
+	if len(objectLambdaConfig.TransformationConfigurations) != 1 {
+		t.Fatalf("Unexpected Object Lambda transformations: %#v", objectLambdaConfig.TransformationConfigurations)
+	}
+	transformation := objectLambdaConfig.TransformationConfigurations[0]
+	awsLambda := transformation.ContentTransformation.AwsLambda
+	if !reflect.DeepEqual(transformation.Actions, stringListExpr([]string{"GetObject", "HeadObject"})) ||
+		!reflect.DeepEqual(awsLambda.FunctionArn, gocf.GetAtt("TestLambda", "Arn")) ||
+		awsLambda.FunctionPayload.Literal != "redact=ssn" {
+		t.Fatalf("Unexpected Object Lambda transformation: %#v", transformation)
+	}
+
+	// A Ref to a bucket resource is the bucket name
+	template = gocf.NewTemplate()
+	exportErr = testExportPermission(S3ObjectLambdaPermission{
+		BasePermission: BasePermission{
+			SourceArn: gocf.Ref("DocumentsBucket"),
+		},
+		Name: "redacted-documents",
+	}, template)
+	if exportErr != nil {
+		t.Fatalf("Failed to export S3ObjectLambdaPermission: %s", exportErr)
+	}
+	accessPoints = testTemplateResources(template, "AWS::S3::AccessPoint")
+	if len(accessPoints) != 1 ||
+		!reflect.DeepEqual(accessPoints[0].(*spartaCF.S3AccessPoint).Bucket, gocf.Ref("DocumentsBucket").String()) {
+		t.Fatalf("Unexpected supporting access point: %#v", accessPoints)
+	}
+
+	for _, eachInvalid := range []S3ObjectLambdaPermission{
+		{Name: "redacted-documents"},
+		{BasePermission: BasePermission{SourceArn: gocf.GetAtt("DocumentsBucket", "Arn")}, Name: "redacted-documents"},
+		{BasePermission: BasePermission{SourceArn: "arn:aws:s3:::sparta-documents"}, Name: "Invalid_Name"},
+		{BasePermission: BasePermission{SourceArn: "arn:aws:s3:::sparta-documents"},
+			Name:    "redacted-documents",
+			Actions: []string{"PutObject"}},
+	} {
+		lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+			mockLambda1,
+			IAMRoleDefinition{})
+		lambdaFn.Permissions = append(lambdaFn.Permissions, eachInvalid)
+		testProvision(t,
+			[]*LambdaAWSInfo{lambdaFn},
+			assertError(fmt.Sprintf("Failed to reject invalid S3ObjectLambdaPermission: %#v", eachInvalid)))
+	}
+}
+
+func TestS3BatchOperationsPermissionExport(t *testing.T) {
+	perm := S3BatchOperationsPermission{
+		ManifestBucketArn: "arn:aws:s3:::sparta-manifests",
+		ReportBucketArn:   gocf.Ref("ReportBucket"),
+	}
+	template := gocf.NewTemplate()
+	if exportErr := testExportPermission(perm, template); exportErr != nil {
+		t.Fatalf("Failed to export S3BatchOperationsPermission: %s", exportErr)
+	}
+	roleResource, roleExists := template.Resources[perm.RoleLogicalResourceName("TestLambda")]
+	if !roleExists || len(template.Outputs) != 1 {
+		t.Fatalf("Expected S3 Batch Operations role and output, got: %#v", template.Resources)
+	}
+	role := roleResource.Properties.(*gocf.IAMRole)
+	assumeRoleStatements := role.AssumeRolePolicyDocument.(ArbitraryJSONObject)["Statement"].([]ArbitraryJSONObject)
+	if len(assumeRoleStatements) != 1 ||
+		!reflect.DeepEqual(assumeRoleStatements[0]["Principal"],
+			ArbitraryJSONObject{"Service": []string{S3BatchOperationsPrincipal}}) {
+		t.Fatalf("Unexpected S3 Batch Operations trust policy: %#v", role.AssumeRolePolicyDocument)
+	}
+	if role.Policies == nil || len(*role.Policies) != 1 {
+		t.Fatalf("Expected one S3 Batch Operations role policy, got: %#v", role.Policies)
+	}
+	reportBucketArn := BasePermission{
+		SourceArn: gocf.Ref("ReportBucket"),
+	}.sourceArnExpr(s3SourceArnParts...)
+	expectedStatements := []spartaIAM.PolicyStatement{
+		{
+			Action:   []string{"lambda:InvokeFunction"},
+			Effect:   "Allow",
+			Resource: gocf.GetAtt("TestLambda", "Arn"),
+		},
+		{
+			Action:   []string{"s3:GetObject", "s3:GetObjectVersion"},
+			Effect:   "Allow",
+			Resource: gocf.Join("", gocf.String("arn:aws:s3:::sparta-manifests"), gocf.String("/*")),
+		},
+		{
+			Action:   []string{"s3:PutObject"},
+			Effect:   "Allow",
+			Resource: gocf.Join("", reportBucketArn, gocf.String("/*")),
+		},
+	}
+	policyDocument := (*role.Policies)[0].PolicyDocument.(ArbitraryJSONObject)
+	if !reflect.DeepEqual(policyDocument["Statement"], expectedStatements) {
+		t.Fatalf("Unexpected S3 Batch Operations role policy: %#v", policyDocument)
+	}
+
+	// The manifest bucket is required
+	lambdaFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	lambdaFn.Permissions = append(lambdaFn.Permissions, S3BatchOperationsPermission{})
+	testProvision(t,
+		[]*LambdaAWSInfo{lambdaFn},
+		assertError("Failed to reject S3BatchOperationsPermission without ManifestBucketArn"))
+}
+
+func TestEventInvokeConfig(t *testing.T) {
+	invokeConfigFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	successFn, _ := NewAWSLambda(LambdaName(mockLambda2),
+		mockLambda2,
+		IAMRoleDefinition{})
+	invokeConfigFn.Options.EventInvokeConfig = &EventInvokeConfig{
+		MaximumRetryAttempts:     aws.Int64(0),
+		MaximumEventAgeInSeconds: 3600,
+		OnSuccessDestination:     successFn,
+		OnFailureDestination:     "arn:aws:sqs:us-west-2:123412341234:async-failures",
+	}
+	testProvision(t, []*LambdaAWSInfo{invokeConfigFn, successFn}, nil)
+
+	// Verify the destination permissions
+	template := gocf.NewTemplate()
+	template.AddResource("OrdersBus", &spartaCF.EventsEventBus{
+		Name: gocf.String("orders"),
+	})
+	for _, eachTestCase := range []struct {
+		destination interface{}
+		action      string
+	}{
+		{"arn:aws:sns:us-west-2:123412341234:async-success", "sns:Publish"},
+		{gocf.GetAtt("OrdersBus", "Arn"), "events:PutEvents"},
+		{"arn:aws:lambda:us-west-2:123412341234:function:handler", "lambda:InvokeFunction"},
+	} {
+		statements, statementsErr := eventInvokeDestinationPolicies(eachTestCase.destination, template)
+		if statementsErr != nil {
+			t.Fatal(statementsErr)
+		}
+		if len(statements) != 1 || statements[0].Action[0] != eachTestCase.action {
+			t.Fatalf("Expected %s permission for %#v, got: %#v",
+				eachTestCase.action,
+				eachTestCase.destination,
+				statements)
+		}
+	}
+	_, statementsErr := eventInvokeDestinationPolicies("arn:aws:s3:::bucket", template)
+	if statementsErr == nil {
+		t.Fatal("Failed to reject unsupported EventInvokeConfig destination")
+	}
+
+	invalidConfig := &EventInvokeConfig{
+		MaximumRetryAttempts: aws.Int64(3),
+	}
+	if invalidConfig.export("TestLambda", gocf.NewTemplate()) == nil {
+		t.Fatal("Failed to reject invalid MaximumRetryAttempts")
+	}
+}
+
+func TestManagedDeadLetterQueue(t *testing.T) {
+	dlqFn, _ := NewAWSLambda(LambdaName(mockLambda1),
+		mockLambda1,
+		IAMRoleDefinition{})
+	dlqFn.Options.ManagedDeadLetterQueue = &ManagedDeadLetterQueue{
+		AlarmActions: []gocf.Stringable{
+			gocf.String("arn:aws:sns:us-west-2:123412341234:oncall"),
+		},
+	}
+	testProvision(t, []*LambdaAWSInfo{dlqFn}, nil)
+
+	template := gocf.NewTemplate()
+	dlqArn, dlqErr := dlqFn.Options.ManagedDeadLetterQueue.export("TestLambda", template)
+	if dlqErr != nil {
+		t.Fatal(dlqErr)
+	}
+	if len(template.Resources) != 2 || dlqArn == nil {
+		t.Fatalf("Expected queue and alarm resources, got: %#v", template.Resources)
+	}
+	invalidQueue := &ManagedDeadLetterQueue{
+		MessageRetentionPeriod: 30,
+	}
+	if _, invalidErr := invalidQueue.export("TestLambda", gocf.NewTemplate()); invalidErr == nil {
+		t.Fatal("Failed to reject invalid MessageRetentionPeriod")
+	}
+	dlqFn.Options.DeadLetterConfigArn = gocf.String("arn:aws:sqs:us-west-2:123412341234:dlq")
+	if dlqFn.export("TestService", "", "", "", "", nil, gocf.NewTemplate(), nil, nil) == nil {
+		t.Fatal("Failed to reject DeadLetterConfigArn and ManagedDeadLetterQueue")
+	}
+}